@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"io/fs"
 	"io/ioutil"
@@ -18,6 +19,7 @@ import (
 	"time"
 
 	"github.com/NYTimes/gziphandler"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/go-logr/logr"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	metrics "github.com/slok/go-http-metrics/metrics/prometheus"
@@ -31,6 +33,9 @@ import (
 	"github.com/weaveworks/weave-gitops/core/nsaccess"
 	core "github.com/weaveworks/weave-gitops/core/server"
 	"github.com/weaveworks/weave-gitops/pkg/featureflags"
+	"github.com/weaveworks/weave-gitops/pkg/git"
+	"github.com/weaveworks/weave-gitops/pkg/git/wrapper"
+	"github.com/weaveworks/weave-gitops/pkg/gitproviders"
 	"github.com/weaveworks/weave-gitops/pkg/kube"
 	"github.com/weaveworks/weave-gitops/pkg/server"
 	"github.com/weaveworks/weave-gitops/pkg/server/auth"
@@ -59,17 +64,43 @@ type Options struct {
 	MTLS        bool
 	TLSCertFile string
 	TLSKeyFile  string
+	// ClientCAFile holds the CAs of the client certificates that are
+	// accepted, and used by the client-certificate auth method
+	ClientCAFile string
 	// Stuff for profiles apparently
 	HelmRepoName      string
 	HelmRepoNamespace string
 	// OIDC
-	OIDC       auth.OIDCConfig
-	OIDCSecret string
+	OIDC              auth.OIDCConfig
+	OIDCSecret        string
+	OIDCProvidersFile string
+	// Git provider logins
+	GitHubLogin auth.GitLoginConfig
+	GitLabLogin auth.GitLoginConfig
+	// Local user sessions
+	SigningKeysSecret string
+	// OIDC sessions
+	RefreshTokenKeySecret string
+	// Server-side sessions
+	SessionStore     string
+	SessionStoreName string
+	AdminGroups      []string
+	// Sign-in throttling
+	LoginAttempts auth.LoginAttemptsConfig
 	// Dev mode
 	DevMode bool
 	// Metrics
 	EnableMetrics  bool
 	MetricsAddress string
+	// Clusters fan-out
+	ClusteredListConcurrency    int
+	ClusteredListClusterTimeout time.Duration
+	// Namespace access
+	NamespaceAccessRulesFile string
+	NamespaceAccessCacheTTL  time.Duration
+	// Pull requests
+	GitHostTypes     map[string]string
+	GitProviderCache gitproviders.CacheConfig
 }
 
 var options Options
@@ -80,7 +111,9 @@ func NewCommand() *cobra.Command {
 		RunE:  runCmd,
 	}
 
-	options = Options{}
+	options = Options{
+		LoginAttempts: auth.DefaultLoginAttemptsConfig(),
+	}
 
 	// System config
 	cmd.Flags().StringVar(&options.Host, "host", server.DefaultHost, "UI host")
@@ -94,6 +127,7 @@ func NewCommand() *cobra.Command {
 	cmd.Flags().BoolVar(&options.MTLS, "mtls", false, "disable enforce mTLS")
 	cmd.Flags().StringVar(&options.TLSCertFile, "tls-cert-file", "", "filename for the TLS certificate, in-memory generated if omitted")
 	cmd.Flags().StringVar(&options.TLSKeyFile, "tls-private-key-file", "", "filename for the TLS key, in-memory generated if omitted")
+	cmd.Flags().StringVar(&options.ClientCAFile, "client-ca-file", "", "filename for the bundle of CAs that sign the client certificates users can authenticate with")
 	// OIDC
 	cmd.Flags().StringVar(&options.OIDCSecret, "oidc-secret-name", auth.DefaultOIDCAuthSecretName, "Name of the secret that contains OIDC configuration")
 	cmd.Flags().StringVar(&options.OIDC.ClientID, "oidc-client-id", "", "The client ID for the OpenID Connect client")
@@ -101,9 +135,59 @@ func NewCommand() *cobra.Command {
 	cmd.Flags().StringVar(&options.OIDC.IssuerURL, "oidc-issuer-url", "", "The URL of the OpenID Connect issuer")
 	cmd.Flags().StringVar(&options.OIDC.RedirectURL, "oidc-redirect-url", "", "The OAuth2 redirect URL")
 	cmd.Flags().DurationVar(&options.OIDC.TokenDuration, "oidc-token-duration", time.Hour, "The duration of the ID token. It should be set in the format: number + time unit (s,m,h) e.g., 20m")
+	cmd.Flags().DurationVar(&options.OIDC.RefreshTokenDuration, "oidc-refresh-token-duration", auth.DefaultRefreshTokenDuration, "How long the refresh token is kept to renew expired ID tokens. It should be set in the format: number + time unit (s,m,h) e.g., 168h")
+	cmd.Flags().StringVar(&options.OIDC.UsernameClaim, "oidc-username-claim", auth.DefaultUsernameClaim, "The JWT claim to use as the user name, nested claims can be referenced with a dot-separated path")
+	cmd.Flags().StringVar(&options.OIDC.GroupsClaim, "oidc-groups-claim", auth.DefaultGroupsClaim, "The JWT claim to use as the user's groups, nested claims can be referenced with a dot-separated path")
+	cmd.Flags().StringVar(&options.OIDC.UsernamePrefix, "oidc-username-prefix", "", "Prefix prepended to user names, e.g. oidc:")
+	cmd.Flags().StringVar(&options.OIDC.GroupsPrefix, "oidc-groups-prefix", "", "Prefix prepended to groups, e.g. oidc:")
+	cmd.Flags().StringToStringVar(&options.OIDC.RequiredClaims, "oidc-required-claims", nil, "Claims that must be present in the ID token with the given value, e.g. hd=example.com")
+	cmd.Flags().StringVar(&options.OIDCProvidersFile, "oidc-providers-file", "", "Path to a YAML file listing additional named OIDC providers users can log in with")
+	// Git provider logins
+	for _, l := range []struct {
+		name, displayName, groups string
+		cfg                       *auth.GitLoginConfig
+	}{
+		{"github", "GitHub", "organizations or teams, as org/team", &options.GitHubLogin},
+		{"gitlab", "GitLab", "groups", &options.GitLabLogin},
+	} {
+		cmd.Flags().StringVar(&l.cfg.URL, l.name+"-url", "", fmt.Sprintf("The URL of the %s instance users log in with, if not the public one", l.displayName))
+		cmd.Flags().StringVar(&l.cfg.ClientID, l.name+"-client-id", "", fmt.Sprintf("The client ID of the %s OAuth application users log in with", l.displayName))
+		cmd.Flags().StringVar(&l.cfg.ClientSecret, l.name+"-client-secret", "", fmt.Sprintf("The client secret of the %s OAuth application users log in with", l.displayName))
+		cmd.Flags().StringVar(&l.cfg.RedirectURL, l.name+"-redirect-url", "", fmt.Sprintf("The %s OAuth2 redirect URL, ending with /oauth2/%s/callback", l.displayName, l.name))
+		cmd.Flags().StringSliceVar(&l.cfg.AllowedGroups, l.name+"-allowed-groups", nil, fmt.Sprintf("Only let members of these %s %s log in, required", l.displayName, l.groups))
+		cmd.Flags().StringVar(&l.cfg.UsernamePrefix, l.name+"-username-prefix", "", fmt.Sprintf("Prefix prepended to %s user names, defaults to %s:", l.displayName, l.name))
+		cmd.Flags().StringVar(&l.cfg.GroupsPrefix, l.name+"-groups-prefix", "", fmt.Sprintf("Prefix prepended to %s groups, defaults to %s:", l.displayName, l.name))
+	}
 	// Metrics
 	cmd.Flags().BoolVar(&options.EnableMetrics, "enable-metrics", false, "Starts the metrics listener")
 	cmd.Flags().StringVar(&options.MetricsAddress, "metrics-address", ":2112", "If the metrics listener is enabled, bind to this address")
+	// Clusters fan-out
+	cmd.Flags().IntVar(&options.ClusteredListConcurrency, "clustered-list-concurrency", clustersmngr.DefaultClusteredListConcurrency, "Maximum number of concurrent list requests made across all clusters and namespaces")
+	cmd.Flags().DurationVar(&options.ClusteredListClusterTimeout, "clustered-list-cluster-timeout", clustersmngr.DefaultClusteredListClusterTimeout, "Time to wait for list requests to a single cluster before returning partial results, 0 disables it")
+	// Local user sessions
+	cmd.Flags().StringVar(&options.SigningKeysSecret, "signing-keys-secret-name", auth.DefaultSigningKeysSecretName, "Name of the secret holding the keys that sign local user sessions, created if it doesn't exist")
+	cmd.Flags().StringVar(&options.RefreshTokenKeySecret, "refresh-token-key-secret-name", auth.DefaultRefreshTokenKeySecretName, "Name of the secret holding the key that encrypts OIDC refresh token cookies, created if it doesn't exist")
+	// Server-side sessions
+	cmd.Flags().StringVar(&options.SessionStore, "session-store", "", "Where to keep the sessions of users signed in through the UI, so that they can be listed and revoked: memory, secret or configmap. Sessions only live in cookies if empty")
+	cmd.Flags().StringVar(&options.SessionStoreName, "session-store-name", auth.DefaultSessionStoreName, "Name of the secret or config map sessions are kept in")
+	cmd.Flags().StringSliceVar(&options.AdminGroups, "admin-groups", nil, "Groups whose members can manage the sessions of other users")
+	// Sign-in throttling
+	cmd.Flags().StringVar(&options.LoginAttempts.Store, "login-attempts-store", options.LoginAttempts.Store, "Where failed sign-ins are tracked: configmap, shared by all replicas, or memory")
+	cmd.Flags().StringVar(&options.LoginAttempts.StoreName, "login-attempts-store-name", options.LoginAttempts.StoreName, "Name of the config map failed sign-ins are tracked in")
+	cmd.Flags().IntVar(&options.LoginAttempts.FreeFailures, "login-free-failures", options.LoginAttempts.FreeFailures, "Number of failed sign-ins for a user before they have to wait between attempts")
+	cmd.Flags().DurationVar(&options.LoginAttempts.BaseDelay, "login-base-delay", options.LoginAttempts.BaseDelay, "Wait after the first throttled sign-in failure, doubled with every failure")
+	cmd.Flags().DurationVar(&options.LoginAttempts.MaxDelay, "login-max-delay", options.LoginAttempts.MaxDelay, "Maximum wait between failed sign-ins for a user")
+	cmd.Flags().IntVar(&options.LoginAttempts.MaxFailures, "login-max-failures", options.LoginAttempts.MaxFailures, "Number of failed sign-ins after which a user is locked out, 0 disables it")
+	cmd.Flags().IntVar(&options.LoginAttempts.MaxFailuresPerIP, "login-max-failures-per-ip", options.LoginAttempts.MaxFailuresPerIP, "Number of failed sign-ins after which a client address is locked out, 0 disables it")
+	cmd.Flags().DurationVar(&options.LoginAttempts.LockoutDuration, "login-lockout-duration", options.LoginAttempts.LockoutDuration, "How long users and client addresses are locked out for, and failures are remembered")
+	cmd.Flags().StringSliceVar(&options.LoginAttempts.TrustedProxies, "login-trusted-proxies", nil, "Addresses or CIDR ranges of the proxies in front of the server, whose X-Forwarded-For header gives the client address of sign-ins")
+	// Namespace access
+	cmd.Flags().StringVar(&options.NamespaceAccessRulesFile, "namespace-access-rules-file", "", "File containing the list of RBAC PolicyRules a user needs in a namespace to be able to use it, the built-in rules are used if omitted")
+	cmd.Flags().DurationVar(&options.NamespaceAccessCacheTTL, "namespace-access-cache-ttl", nsaccess.DefaultCacheTTL, "How long the result of a user namespace access check is cached, 0 disables caching")
+	// Pull requests
+	cmd.Flags().StringToStringVar(&options.GitHostTypes, "git-host-types", map[string]string{}, "Specify which custom domains are running what (github, gitlab, gitea or bitbucket-server), to list their pull requests")
+	cmd.Flags().IntVar(&options.GitProviderCache.Size, "git-provider-cache-size", gitproviders.DefaultCacheSize, "Maximum number of git provider responses kept in memory, 0 disables the cache")
+	cmd.Flags().DurationVar(&options.GitProviderCache.TTL, "git-provider-cache-ttl", gitproviders.DefaultCacheTTL, "How long git provider responses are used before being revalidated with their ETag")
 
 	return cmd
 }
@@ -143,7 +227,7 @@ func runCmd(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("could not create scheme: %w", err)
 	}
 
-	rawClient, err := client.New(rest, client.Options{
+	rawClient, err := client.NewWithWatch(rest, client.Options{
 		Scheme: scheme,
 	})
 	if err != nil {
@@ -159,7 +243,26 @@ func runCmd(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("Couldn't get current namespace")
 	}
 
-	authServer, err := auth.InitAuthServer(cmd.Context(), log, rawClient, options.OIDC, options.OIDCSecret, namespace, options.AuthMethods)
+	if err := checkClientCertificateTLS(options); err != nil {
+		return err
+	}
+
+	authServer, err := auth.InitAuthServer(cmd.Context(), log, rawClient, auth.InitAuthServerOptions{
+		AuthMethods:           options.AuthMethods,
+		Namespace:             namespace,
+		OIDC:                  options.OIDC,
+		OIDCSecret:            options.OIDCSecret,
+		OIDCProvidersFile:     options.OIDCProvidersFile,
+		RefreshTokenKeySecret: options.RefreshTokenKeySecret,
+		SigningKeysSecret:     options.SigningKeysSecret,
+		LoginAttempts:         options.LoginAttempts,
+		SessionStore:          options.SessionStore,
+		SessionStoreName:      options.SessionStoreName,
+		AdminGroups:           options.AdminGroups,
+		ClientCAFile:          options.ClientCAFile,
+		GitHubLogin:           options.GitHubLogin,
+		GitLabLogin:           options.GitLabLogin,
+	})
 
 	if err != nil {
 		return fmt.Errorf("could not initialise authentication server: %w", err)
@@ -175,10 +278,40 @@ func runCmd(cmd *cobra.Command, args []string) error {
 
 	fetcher := fetcher.NewSingleClusterFetcher(rest)
 
-	clusterClientsFactory := clustersmngr.NewClientFactory(fetcher, nsaccess.NewChecker(nsaccess.DefautltWegoAppRules), log, scheme, clustersmngr.NewClustersClientsPool)
+	nsRules := nsaccess.DefautltWegoAppRules
+
+	if options.NamespaceAccessRulesFile != "" {
+		nsRules, err = nsaccess.LoadRules(options.NamespaceAccessRulesFile)
+		if err != nil {
+			return fmt.Errorf("could not load namespace access rules: %w", err)
+		}
+	}
+
+	nsChecker := nsaccess.NewChecker(nsRules)
+	if options.NamespaceAccessCacheTTL > 0 {
+		nsChecker = nsaccess.NewCachingChecker(nsRules, options.NamespaceAccessCacheTTL)
+	}
+
+	clusterClientsFactory := clustersmngr.NewClientFactory(fetcher, nsChecker, log, scheme, clustersmngr.NewClustersClientsPool,
+		clustersmngr.WithClusteredListConcurrency(options.ClusteredListConcurrency),
+		clustersmngr.WithClusteredListClusterTimeout(options.ClusteredListClusterTimeout),
+	)
 	clusterClientsFactory.Start(ctx)
 
 	coreConfig := core.NewCoreConfig(log, rest, clusterName, clusterClientsFactory)
+	coreConfig.NSAccess = nsChecker
+	// The tokens of the git providers are read from the env, e.g. GITHUB_TOKEN.
+	coreConfig.GitProviders = gitproviders.NewTokenClient(os.LookupEnv, gitproviders.NewCache(options.GitProviderCache))
+	coreConfig.GitHostTypes = options.GitHostTypes
+	coreConfig.GitClients = func(repoUrl gitproviders.RepoURL) (git.Git, error) {
+		token, err := gitproviders.GetToken(repoUrl, os.LookupEnv)
+		if err != nil {
+			return nil, err
+		}
+
+		// The providers ignore the username when the password is a token.
+		return git.New(&githttp.BasicAuth{Username: "git", Password: token}, wrapper.NewGoGit()), nil
+	}
 
 	appConfig, err := server.DefaultApplicationsConfig(log)
 	if err != nil {
@@ -281,6 +414,25 @@ func runCmd(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// checkClientCertificateTLS refuses to authenticate with client certificates
+// when the server doesn't terminate TLS, as the certificates never reach it.
+func checkClientCertificateTLS(options Options) error {
+	if !options.Insecure {
+		return nil
+	}
+
+	authMethods, err := auth.ParseAuthMethodArray(options.AuthMethods)
+	if err != nil {
+		return err
+	}
+
+	if authMethods[auth.ClientCertificate] {
+		return errors.New("the client-certificate auth method requires the server to terminate TLS, it can't be used with --insecure")
+	}
+
+	return nil
+}
+
 func listenAndServe(log logr.Logger, srv *http.Server, options Options) error {
 	if options.Insecure {
 		log.Info("TLS connections disabled")
@@ -300,10 +452,31 @@ func listenAndServe(log logr.Logger, srv *http.Server, options Options) error {
 		caCertPool := x509.NewCertPool()
 		caCertPool.AppendCertsFromPEM(caCert)
 
+		// Clients signed by the CAs of the client-certificate auth method
+		// are accepted too.
+		if options.ClientCAFile != "" {
+			if err := auth.AppendClientCAs(caCertPool, options.ClientCAFile); err != nil {
+				return err
+			}
+		}
+
 		srv.TLSConfig = &tls.Config{
 			ClientCAs:  caCertPool,
 			ClientAuth: tls.RequireAndVerifyClientCert,
 		}
+	} else if options.ClientCAFile != "" {
+		clientCAs, err := auth.LoadClientCAs(options.ClientCAFile)
+		if err != nil {
+			return err
+		}
+
+		log.Info("Using TLS, accepting client certificates", "cert_file", options.TLSCertFile, "key_file", options.TLSKeyFile, "client_ca_file", options.ClientCAFile)
+
+		// Browsers don't have a client certificate, so it's optional.
+		srv.TLSConfig = &tls.Config{
+			ClientCAs:  clientCAs,
+			ClientAuth: tls.VerifyClientCertIfGiven,
+		}
 	} else {
 		log.Info("Using TLS", "cert_file", options.TLSCertFile, "key_file", options.TLSKeyFile)
 	}
@@ -13,14 +13,18 @@ import (
 	"github.com/weaveworks/weave-gitops/cmd/internal"
 	"github.com/weaveworks/weave-gitops/pkg/adapters"
 	"github.com/weaveworks/weave-gitops/pkg/flux"
+	"github.com/weaveworks/weave-gitops/pkg/gitproviders"
 	"github.com/weaveworks/weave-gitops/pkg/kube"
 	"github.com/weaveworks/weave-gitops/pkg/names"
-	"github.com/weaveworks/weave-gitops/pkg/runner"
 	"github.com/weaveworks/weave-gitops/pkg/services"
 	"github.com/weaveworks/weave-gitops/pkg/services/profiles"
 )
 
-var profileOpts profiles.Options
+var (
+	profileOpts     profiles.Options
+	signingKeyFlags internal.SigningKeyFlags
+	cacheFlags      gitproviders.CacheConfig
+)
 
 // AddCommand provides support for adding a profile to a cluster.
 func AddCommand(opts *config.Options, client *adapters.HTTPClient) *cobra.Command {
@@ -43,6 +47,8 @@ func AddCommand(opts *config.Options, client *adapters.HTTPClient) *cobra.Comman
 	cmd.Flags().StringVar(&profileOpts.Cluster, "cluster", "", "Name of the cluster to add the profile to")
 	cmd.Flags().BoolVar(&profileOpts.AutoMerge, "auto-merge", false, "If set, 'gitops add profile' will merge automatically into the repository's branch")
 	internal.AddPRFlags(cmd, &profileOpts.HeadBranch, &profileOpts.BaseBranch, &profileOpts.Description, &profileOpts.Message, &profileOpts.Title)
+	internal.AddSigningKeyFlags(cmd.Flags(), &signingKeyFlags)
+	internal.AddGitProviderCacheFlags(cmd.Flags(), &cacheFlags)
 
 	requiredFlags := []string{"name", "config-repo", "cluster"}
 	for _, f := range requiredFlags {
@@ -67,9 +73,9 @@ func addProfileCmdPreRunE(endpoint *string) func(*cobra.Command, []string) error
 func addProfileCmdRunE(opts *config.Options, client *adapters.HTTPClient) func(*cobra.Command, []string) error {
 	return func(cmd *cobra.Command, args []string) error {
 		log := logger.NewCLILogger(os.Stdout)
-		fluxClient := flux.New(&runner.CLIRunner{})
+		fluxClient := flux.New()
 		factory := services.NewFactory(fluxClient, logger.Logr())
-		providerClient := internal.NewGitProviderClient(os.Stdout, os.LookupEnv, log)
+		providerClient := internal.NewGitProviderClient(os.Stdout, os.LookupEnv, log, cacheFlags)
 
 		err := client.ConfigureClientWithOptions(opts, os.Stdout)
 		if err != nil {
@@ -89,16 +95,28 @@ func addProfileCmdRunE(opts *config.Options, client *adapters.HTTPClient) func(*
 			return fmt.Errorf("failed to create kube client: %w", err)
 		}
 
-		_, gitProvider, err := factory.GetGitClients(context.Background(), kubeClient, providerClient, services.GitConfigParams{
+		signingKey, err := internal.LoadSigningKey(context.Background(), kubeClient, profileOpts.Namespace, signingKeyFlags, os.LookupEnv)
+		if err != nil {
+			return fmt.Errorf("failed to load signing key: %w", err)
+		}
+
+		gitClient, gitProvider, err := factory.GetGitClients(context.Background(), kubeClient, providerClient, services.GitConfigParams{
 			ConfigRepo:       profileOpts.ConfigRepo,
 			Namespace:        profileOpts.Namespace,
 			IsHelmRepository: true,
 			DryRun:           false,
+			SigningKey:       signingKey,
 		})
 		if err != nil {
 			return fmt.Errorf("failed to get git clients: %w", err)
 		}
 
+		if signingKey != nil {
+			// The git provider's API can't sign commits, so the changes
+			// are pushed with git.
+			profileOpts.GitClient = gitClient
+		}
+
 		return profiles.NewService(log).Add(context.Background(), client, gitProvider, profileOpts)
 	}
 }
@@ -7,5 +7,6 @@ type Options struct {
 	InsecureSkipTLSVerify bool
 	Username              string
 	Password              string
+	Token                 string
 	Kubeconfig            string
 }
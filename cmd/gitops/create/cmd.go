@@ -6,6 +6,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/weaveworks/weave-gitops/cmd/gitops/config"
 	"github.com/weaveworks/weave-gitops/cmd/gitops/create/dashboard"
+	"github.com/weaveworks/weave-gitops/cmd/gitops/create/user"
 )
 
 type CreateCommandFlags struct {
@@ -24,6 +25,9 @@ func GetCommand(opts *config.Options) *cobra.Command {
 gitops create dashboard ww-gitops \
   --password=$PASSWORD \
   --export > ./clusters/my-cluster/weave-gitops-dashboard.yaml
+
+# Add a local user who can sign in to the dashboard
+gitops create user add alice --groups=team-a
 		`,
 	}
 
@@ -31,6 +35,7 @@ gitops create dashboard ww-gitops \
 	cmd.PersistentFlags().DurationVar(&flags.Timeout, "timeout", 3*time.Minute, "The timeout for operations during resource creation.")
 
 	cmd.AddCommand(dashboard.DashboardCommand(opts))
+	cmd.AddCommand(user.UserCommand(opts))
 
 	return cmd
 }
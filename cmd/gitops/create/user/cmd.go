@@ -0,0 +1,333 @@
+package user
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/weaveworks/weave-gitops/cmd/gitops/cmderrors"
+	"github.com/weaveworks/weave-gitops/cmd/gitops/config"
+	clilogger "github.com/weaveworks/weave-gitops/cmd/gitops/logger"
+	"github.com/weaveworks/weave-gitops/pkg/kube"
+	"github.com/weaveworks/weave-gitops/pkg/logger"
+	"github.com/weaveworks/weave-gitops/pkg/server/auth"
+	"github.com/weaveworks/weave-gitops/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+type UserCommandFlags struct {
+	// Create command flags.
+	Export  bool
+	Timeout time.Duration
+	// Overriden global flags.
+	Password string
+	// Global flags.
+	Namespace string
+	// User flags.
+	Groups []string
+}
+
+var flags UserCommandFlags
+
+func UserCommand(opts *config.Options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "user",
+		Short: "Manage the local users that can sign in to the GitOps Dashboard",
+		Long: `Manage the local users that can sign in to the GitOps Dashboard.
+Local users are stored with a bcrypt hash of their password in the cluster-user-auth secret.`,
+		Example: `
+# Add a user who will be impersonated with the given groups
+gitops create user add alice --groups=team-a,viewers
+
+# Change the password of a user
+gitops create user rotate-password alice
+
+# Remove a user
+gitops create user remove alice
+		`,
+	}
+
+	cmd.AddCommand(addCommand(opts))
+	cmd.AddCommand(removeCommand(opts))
+	cmd.AddCommand(rotatePasswordCommand(opts))
+
+	return cmd
+}
+
+func addCommand(opts *config.Options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "add <username>",
+		Short: "Add a local user",
+		Example: `
+# Add a user, the password is read from stdin
+gitops create user add alice --groups=team-a,viewers
+
+# Export the updated secret instead of applying it
+gitops create user add alice --password=$PASSWORD --export > ./cluster-user-auth.yaml
+		`,
+		SilenceUsage:      true,
+		SilenceErrors:     true,
+		PreRunE:           userCommandPreRunE,
+		RunE:              addCommandRunE(opts),
+		DisableAutoGenTag: true,
+	}
+
+	cmd.Flags().StringVar(&flags.Password, "password", "", "The password of the user. If not set it is read from stdin.")
+	cmd.Flags().StringSliceVar(&flags.Groups, "groups", nil, "The groups the user is impersonated with.")
+
+	return cmd
+}
+
+func removeCommand(opts *config.Options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "remove <username>",
+		Short: "Remove a local user",
+		Example: `
+# Remove a user
+gitops create user remove alice
+		`,
+		SilenceUsage:      true,
+		SilenceErrors:     true,
+		PreRunE:           userCommandPreRunE,
+		RunE:              removeCommandRunE(opts),
+		DisableAutoGenTag: true,
+	}
+
+	return cmd
+}
+
+func rotatePasswordCommand(opts *config.Options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rotate-password <username>",
+		Short: "Change the password of a local user",
+		Example: `
+# Change the password of a user, the password is read from stdin
+gitops create user rotate-password alice
+		`,
+		SilenceUsage:      true,
+		SilenceErrors:     true,
+		PreRunE:           userCommandPreRunE,
+		RunE:              rotatePasswordCommandRunE(opts),
+		DisableAutoGenTag: true,
+	}
+
+	cmd.Flags().StringVar(&flags.Password, "password", "", "The new password of the user. If not set it is read from stdin.")
+
+	return cmd
+}
+
+func userCommandPreRunE(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return cmderrors.ErrNoName
+	}
+
+	if len(args) > 1 {
+		return cmderrors.ErrMultipleNames
+	}
+
+	return nil
+}
+
+func addCommandRunE(opts *config.Options) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		username := args[0]
+
+		return updateLocalUsers(cmd, func(log logger.Logger, users []auth.LocalUser) ([]auth.LocalUser, error) {
+			hash, err := readPasswordHash(log)
+			if err != nil {
+				return nil, err
+			}
+
+			users, err = auth.AddLocalUser(users, auth.LocalUser{
+				Username:     username,
+				PasswordHash: hash,
+				Groups:       flags.Groups,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			log.Successf("Added user %s", username)
+
+			return users, nil
+		})
+	}
+}
+
+func removeCommandRunE(opts *config.Options) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		username := args[0]
+
+		return updateLocalUsers(cmd, func(log logger.Logger, users []auth.LocalUser) ([]auth.LocalUser, error) {
+			users, err := auth.RemoveLocalUser(users, username)
+			if err != nil {
+				return nil, err
+			}
+
+			log.Successf("Removed user %s", username)
+
+			return users, nil
+		})
+	}
+}
+
+func rotatePasswordCommandRunE(opts *config.Options) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		username := args[0]
+
+		return updateLocalUsers(cmd, func(log logger.Logger, users []auth.LocalUser) ([]auth.LocalUser, error) {
+			if auth.FindLocalUser(users, username) == nil {
+				return nil, fmt.Errorf("%w: %s", auth.ErrLocalUserNotFound, username)
+			}
+
+			hash, err := readPasswordHash(log)
+			if err != nil {
+				return nil, err
+			}
+
+			users, err = auth.SetLocalUserPassword(users, username, hash)
+			if err != nil {
+				return nil, err
+			}
+
+			log.Successf("Changed the password of user %s", username)
+
+			return users, nil
+		})
+	}
+}
+
+func readPasswordHash(log logger.Logger) (string, error) {
+	password := flags.Password
+
+	if password == "" {
+		var err error
+
+		password, err = utils.ReadPasswordFromStdin(log, "Please enter the password of the user: ")
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return auth.HashPassword(password)
+}
+
+// updateLocalUsers reads the users in the cluster user secret, applies update
+// to them and then either writes the secret back or exports it.
+func updateLocalUsers(cmd *cobra.Command, update func(logger.Logger, []auth.LocalUser) ([]auth.LocalUser, error)) error {
+	var err error
+
+	if flags.Namespace, err = cmd.Flags().GetString("namespace"); err != nil {
+		return err
+	}
+
+	if flags.Password, err = cmd.Flags().GetString("password"); err != nil {
+		return err
+	}
+
+	if flags.Export, err = cmd.Flags().GetBool("export"); err != nil {
+		return err
+	}
+
+	if flags.Timeout, err = cmd.Flags().GetDuration("timeout"); err != nil {
+		return err
+	}
+
+	var output io.Writer
+
+	if flags.Export {
+		output = &bytes.Buffer{}
+	} else {
+		output = os.Stdout
+	}
+
+	log := clilogger.NewCLILogger(output)
+
+	ctx, cancel := context.WithTimeout(context.Background(), flags.Timeout)
+	defer cancel()
+
+	kubeClient, err := kube.NewKubeHTTPClient()
+	if err != nil {
+		return fmt.Errorf("error creating Kubernetes client: %w", err)
+	}
+
+	secret := &corev1.Secret{}
+	key := client.ObjectKey{Name: auth.ClusterUserAuthSecretName, Namespace: flags.Namespace}
+
+	exists := true
+
+	if err := kubeClient.Get(ctx, key, secret); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return fmt.Errorf("error reading secret %s: %w", key, err)
+		}
+
+		exists = false
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      key.Name,
+				Namespace: key.Namespace,
+			},
+		}
+	}
+
+	users, err := auth.LocalUsersFromSecret(secret)
+	if err != nil {
+		return err
+	}
+
+	users, err = update(log, users)
+	if err != nil {
+		return err
+	}
+
+	if err := auth.SetLocalUsers(secret, users); err != nil {
+		return err
+	}
+
+	if flags.Export {
+		exported := &corev1.Secret{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: corev1.SchemeGroupVersion.String(),
+				Kind:       "Secret",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      secret.Name,
+				Namespace: secret.Namespace,
+			},
+			Type: secret.Type,
+			Data: secret.Data,
+		}
+
+		out, err := yaml.Marshal(exported)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("---")
+		fmt.Println(string(out))
+
+		return nil
+	}
+
+	if exists {
+		err = kubeClient.Update(ctx, secret)
+	} else {
+		err = kubeClient.Create(ctx, secret)
+	}
+
+	if err != nil {
+		return fmt.Errorf("error writing secret %s: %w", key, err)
+	}
+
+	log.Successf("Updated secret %s", key)
+
+	return nil
+}
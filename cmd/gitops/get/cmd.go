@@ -8,6 +8,7 @@ import (
 	"github.com/weaveworks/weave-gitops/cmd/gitops/get/clusters"
 	"github.com/weaveworks/weave-gitops/cmd/gitops/get/credentials"
 	"github.com/weaveworks/weave-gitops/cmd/gitops/get/profiles"
+	"github.com/weaveworks/weave-gitops/cmd/gitops/get/pullrequests"
 	"github.com/weaveworks/weave-gitops/cmd/gitops/get/templates"
 	"github.com/weaveworks/weave-gitops/cmd/gitops/get/templates/terraform"
 	"github.com/weaveworks/weave-gitops/pkg/adapters"
@@ -25,7 +26,10 @@ gitops get templates
 gitops get credentials
 
 # Get all CAPI clusters
-gitops get clusters`,
+gitops get clusters
+
+# Get the pull requests created by Weave GitOps
+gitops get pullrequests --repo-url https://github.com/owner/config-repo`,
 	}
 
 	templateCommand := templates.TemplateCommand(opts, client)
@@ -37,6 +41,7 @@ gitops get clusters`,
 	cmd.AddCommand(clusters.ClusterCommand(opts, client))
 	cmd.AddCommand(profiles.ProfilesCommand(opts, client))
 	cmd.AddCommand(bcrypt.HashCommand(opts))
+	cmd.AddCommand(pullrequests.PullRequestsCommand())
 
 	return cmd
 }
@@ -0,0 +1,124 @@
+package pullrequests
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/weaveworks/weave-gitops/cmd/gitops/logger"
+	"github.com/weaveworks/weave-gitops/cmd/internal"
+	"github.com/weaveworks/weave-gitops/pkg/gitproviders"
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+type pullRequestsFlags struct {
+	RepoURL      string
+	State        string
+	BranchPrefix string
+	Label        string
+	Cache        gitproviders.CacheConfig
+}
+
+var flags pullRequestsFlags
+
+func PullRequestsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "pullrequest",
+		Aliases:       []string{"pullrequests", "pr", "prs"},
+		Short:         "Show the pull requests created by Weave GitOps in a repository",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Example: `
+	# Get the open pull requests created by Weave GitOps
+	gitops get pullrequests --repo-url https://github.com/owner/config-repo --state open
+
+	# Get the pull requests with a custom label
+	gitops get pullrequests --repo-url https://github.com/owner/config-repo --label gitops
+	`,
+		PreRunE: getPullRequestsCmdPreRunE,
+		RunE:    getPullRequestsCmdRunE,
+	}
+
+	cmd.Flags().StringVar(&flags.RepoURL, "repo-url", "", "URL of the repository to list the pull requests of")
+	cmd.Flags().StringVar(&flags.State, "state", "", "Only list the pull requests in this state (open, merged or closed)")
+	cmd.Flags().StringVar(&flags.BranchPrefix, "branch-prefix", "", fmt.Sprintf("Prefix of the branches of the pull requests to list (default %q, unless --label is set)", gitproviders.PullRequestBranchPrefix))
+	cmd.Flags().StringVar(&flags.Label, "label", "", fmt.Sprintf("Label of the pull requests to list (default %q, unless --branch-prefix is set)", gitproviders.PullRequestLabel))
+	internal.AddGitProviderCacheFlags(cmd.Flags(), &flags.Cache)
+
+	return cmd
+}
+
+func getPullRequestsCmdPreRunE(cmd *cobra.Command, args []string) error {
+	if flags.RepoURL == "" {
+		return fmt.Errorf("--repo-url is required")
+	}
+
+	switch gitproviders.PullRequestState(flags.State) {
+	case "", gitproviders.PullRequestStateOpen, gitproviders.PullRequestStateMerged, gitproviders.PullRequestStateClosed:
+		return nil
+	default:
+		return fmt.Errorf("invalid --state %q, it must be one of open, merged or closed", flags.State)
+	}
+}
+
+func getPullRequestsCmdRunE(cmd *cobra.Command, args []string) error {
+	repoUrl, err := gitproviders.NewRepoURL(flags.RepoURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse repo url: %w", err)
+	}
+
+	providerClient := internal.NewGitProviderClient(os.Stdout, os.LookupEnv, logger.NewCLILogger(os.Stdout), flags.Cache)
+
+	provider, err := providerClient.GetProvider(repoUrl, gitproviders.GetAccountType)
+	if err != nil {
+		return err
+	}
+
+	filter := gitproviders.PullRequestFilter{BranchPrefix: flags.BranchPrefix, Label: flags.Label}
+	if filter.BranchPrefix == "" && filter.Label == "" {
+		filter = gitproviders.DefaultPullRequestFilter()
+	}
+
+	prs, err := provider.ListPullRequests(context.Background(), repoUrl, gitproviders.PullRequestState(flags.State), filter)
+	if err != nil {
+		return fmt.Errorf("failed to list pull requests: %w", err)
+	}
+
+	w := printers.GetNewTabWriter(os.Stdout)
+
+	defer w.Flush()
+
+	printPullRequests(w, prs)
+
+	return nil
+}
+
+func printPullRequests(w io.Writer, prs []gitproviders.PullRequest) {
+	if len(prs) == 0 {
+		fmt.Fprintln(w, "No pull requests found")
+		return
+	}
+
+	fmt.Fprintln(w, "NUMBER\tTITLE\tBRANCH\tSTATE\tMERGEABLE\tCI\tURL")
+
+	for _, pr := range prs {
+		mergeable := "-"
+		if pr.Mergeable != nil {
+			mergeable = fmt.Sprintf("%t", *pr.Mergeable)
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			pr.Number,
+			strings.ReplaceAll(pr.Title, "\t", " "),
+			pr.SourceBranch+" -> "+pr.TargetBranch,
+			pr.State,
+			mergeable,
+			pr.CIStatus,
+			pr.WebURL,
+		)
+	}
+}
@@ -0,0 +1,37 @@
+package rotate
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/weaveworks/weave-gitops/cmd/gitops/config"
+	"github.com/weaveworks/weave-gitops/cmd/gitops/rotate/deploykey"
+	"github.com/weaveworks/weave-gitops/cmd/gitops/rotate/signingkey"
+)
+
+type RotateCommandFlags struct {
+	Timeout time.Duration
+}
+
+var flags RotateCommandFlags
+
+func GetCommand(opts *config.Options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rotate",
+		Short: "Rotates a credential",
+		Example: `
+# Rotate the key that signs the sessions of local dashboard users
+gitops rotate signing-key
+
+# Rotate the deploy key of a GitRepository
+gitops rotate deploy-key flux-system
+		`,
+	}
+
+	cmd.PersistentFlags().DurationVar(&flags.Timeout, "timeout", 30*time.Second, "The timeout for operations during the rotation.")
+
+	cmd.AddCommand(signingkey.SigningKeyCommand(opts))
+	cmd.AddCommand(deploykey.DeployKeyCommand(opts))
+
+	return cmd
+}
@@ -0,0 +1,163 @@
+package deploykey
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta2"
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+	"github.com/weaveworks/weave-gitops/cmd/gitops/config"
+	clilogger "github.com/weaveworks/weave-gitops/cmd/gitops/logger"
+	"github.com/weaveworks/weave-gitops/cmd/internal"
+	"github.com/weaveworks/weave-gitops/pkg/flux"
+	"github.com/weaveworks/weave-gitops/pkg/gitproviders"
+	"github.com/weaveworks/weave-gitops/pkg/kube"
+	"github.com/weaveworks/weave-gitops/pkg/services/auth"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+
+	// defaultTimeout leaves time for the GitRepository to reconcile, unless
+	// --timeout is set.
+	defaultTimeout = 2 * time.Minute
+)
+
+type DeployKeyCommandFlags struct {
+	Output string
+	Cache  gitproviders.CacheConfig
+	// Rotate command flags.
+	Timeout time.Duration
+	// Global flags.
+	Namespace string
+}
+
+var flags DeployKeyCommandFlags
+
+func DeployKeyCommand(opts *config.Options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "deploy-key <gitrepository>",
+		Short: "Rotate the deploy key of a GitRepository",
+		Long: `Rotate the deploy key of a GitRepository.
+A new key pair is generated, its public key is uploaded to the git provider and its private key
+replaces the one in the GitRepository's secret. The GitRepository is then reconciled, and the old
+key is only deleted from the git provider once it fetched with the new one. If it fails to, the old
+key is restored. The token of the git provider is read from its environment variable, e.g.
+GITHUB_TOKEN.
+
+The secret is annotated with the time of the rotation and the fingerprint of the new key, and the
+rotation is printed as JSON with --output=json, so that rotations can be audited.`,
+		Example: `
+# Rotate the deploy key of the flux-system GitRepository
+gitops rotate deploy-key flux-system --namespace flux-system
+
+# Rotate the deploy key and record the rotation
+gitops rotate deploy-key flux-system --output json >> deploy-key-rotations.log
+		`,
+		Args:              cobra.ExactArgs(1),
+		SilenceUsage:      true,
+		SilenceErrors:     true,
+		RunE:              deployKeyCommandRunE(opts),
+		DisableAutoGenTag: true,
+	}
+
+	cmd.Flags().StringVar(&flags.Output, "output", outputText, "The output format of the rotation, text or json.")
+	internal.AddGitProviderCacheFlags(cmd.Flags(), &flags.Cache)
+
+	return cmd
+}
+
+func deployKeyCommandRunE(opts *config.Options) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		var err error
+
+		if flags.Namespace, err = cmd.Flags().GetString("namespace"); err != nil {
+			return err
+		}
+
+		if flags.Timeout, err = cmd.Flags().GetDuration("timeout"); err != nil {
+			return err
+		}
+
+		if !cmd.Flags().Changed("timeout") {
+			flags.Timeout = defaultTimeout
+		}
+
+		if flags.Output != outputText && flags.Output != outputJSON {
+			return fmt.Errorf("unknown output format %q, expected %s or %s", flags.Output, outputText, outputJSON)
+		}
+
+		var (
+			out  io.Writer = os.Stdout
+			logs           = clilogger.Logr()
+		)
+
+		if flags.Output == outputJSON {
+			// Only the rotation is printed to stdout.
+			out = os.Stderr
+			logs = logr.Discard()
+		}
+
+		log := clilogger.NewCLILogger(out)
+
+		ctx, cancel := context.WithTimeout(context.Background(), flags.Timeout)
+		defer cancel()
+
+		kubeClient, err := kube.NewKubeHTTPClient()
+		if err != nil {
+			return fmt.Errorf("error creating Kubernetes client: %w", err)
+		}
+
+		name := types.NamespacedName{Name: args[0], Namespace: flags.Namespace}
+
+		repository := &sourcev1.GitRepository{}
+		if err := kubeClient.Get(ctx, name, repository); err != nil {
+			return fmt.Errorf("error getting GitRepository %s: %w", name, err)
+		}
+
+		repoUrl, err := gitproviders.NewRepoURL(repository.Spec.URL)
+		if err != nil {
+			return fmt.Errorf("error normalizing URL of GitRepository %s: %w", name, err)
+		}
+
+		gitProvider, err := internal.NewGitProviderClient(out, os.LookupEnv, log, flags.Cache).GetProvider(repoUrl, gitproviders.GetAccountType)
+		if err != nil {
+			return fmt.Errorf("error obtaining git provider token: %w", err)
+		}
+
+		authSvc := auth.NewAuthService(flux.New(), kubeClient, gitProvider, logs)
+
+		log.Actionf("Rotating deploy key of GitRepository %s ...", name)
+
+		rotation, err := authSvc.RotateDeployKey(ctx, name)
+		if rotation == nil {
+			return err
+		}
+
+		if flags.Output == outputJSON {
+			if err := json.NewEncoder(os.Stdout).Encode(rotation); err != nil {
+				return err
+			}
+		} else {
+			log.Successf("Deploy key %s replaced %s in secret %s, fetched revision %s", rotation.NewFingerprint, rotation.OldFingerprint, rotation.Secret, rotation.Revision)
+		}
+
+		if err != nil {
+			// The new key is in use, but the old one is still on the git provider.
+			return err
+		}
+
+		if !rotation.OldKeyDeleted {
+			log.Warningf("Deploy key %s wasn't found on the git provider", rotation.OldFingerprint)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,121 @@
+package signingkey
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/weaveworks/weave-gitops/cmd/gitops/config"
+	clilogger "github.com/weaveworks/weave-gitops/cmd/gitops/logger"
+	"github.com/weaveworks/weave-gitops/pkg/kube"
+	"github.com/weaveworks/weave-gitops/pkg/server/auth"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type SigningKeyCommandFlags struct {
+	SecretName string
+	Retain     int
+	// Rotate command flags.
+	Timeout time.Duration
+	// Global flags.
+	Namespace string
+}
+
+var flags SigningKeyCommandFlags
+
+func SigningKeyCommand(opts *config.Options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "signing-key",
+		Short: "Rotate the key that signs the sessions of local dashboard users",
+		Long: `Rotate the key that signs the sessions of local dashboard users.
+A new key is generated and becomes the active one. The previous keys are kept, up to --retain keys
+in total, so that existing sessions stay valid until they expire. Running servers load the new key
+without a restart. The secret is created if it doesn't exist.`,
+		Example: `
+# Rotate the signing key, keeping the previous one
+gitops rotate signing-key
+
+# Rotate the signing key and invalidate all existing sessions
+gitops rotate signing-key --retain=1
+		`,
+		SilenceUsage:      true,
+		SilenceErrors:     true,
+		RunE:              signingKeyCommandRunE(opts),
+		DisableAutoGenTag: true,
+	}
+
+	cmd.Flags().StringVar(&flags.SecretName, "secret-name", auth.DefaultSigningKeysSecretName, "The name of the secret holding the signing keys.")
+	cmd.Flags().IntVar(&flags.Retain, "retain", auth.DefaultSigningKeysRetained, "The number of keys to keep, including the new one.")
+
+	return cmd
+}
+
+func signingKeyCommandRunE(opts *config.Options) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		var err error
+
+		if flags.Namespace, err = cmd.Flags().GetString("namespace"); err != nil {
+			return err
+		}
+
+		if flags.Timeout, err = cmd.Flags().GetDuration("timeout"); err != nil {
+			return err
+		}
+
+		log := clilogger.NewCLILogger(os.Stdout)
+
+		ctx, cancel := context.WithTimeout(context.Background(), flags.Timeout)
+		defer cancel()
+
+		kubeClient, err := kube.NewKubeHTTPClient()
+		if err != nil {
+			return fmt.Errorf("error creating Kubernetes client: %w", err)
+		}
+
+		key := client.ObjectKey{Name: flags.SecretName, Namespace: flags.Namespace}
+		secret := &corev1.Secret{}
+
+		exists := true
+
+		if err := kubeClient.Get(ctx, key, secret); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return fmt.Errorf("error reading secret %s: %w", key, err)
+			}
+
+			exists = false
+			secret = &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      key.Name,
+					Namespace: key.Namespace,
+				},
+				Type: corev1.SecretTypeOpaque,
+			}
+		}
+
+		log.Actionf("Rotating signing key in secret %s ...", key)
+
+		id, err := auth.RotateSigningKeys(secret, flags.Retain, time.Now())
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			err = kubeClient.Update(ctx, secret)
+		} else {
+			err = kubeClient.Create(ctx, secret)
+		}
+
+		if err != nil {
+			return fmt.Errorf("error writing secret %s: %w", key, err)
+		}
+
+		log.Successf("Signing key %s is now active, %d key(s) are accepted", id, len(secret.Data)-1)
+
+		return nil
+	}
+}
@@ -13,13 +13,17 @@ import (
 	"github.com/weaveworks/weave-gitops/cmd/internal"
 	"github.com/weaveworks/weave-gitops/pkg/adapters"
 	"github.com/weaveworks/weave-gitops/pkg/flux"
+	"github.com/weaveworks/weave-gitops/pkg/gitproviders"
 	"github.com/weaveworks/weave-gitops/pkg/kube"
-	"github.com/weaveworks/weave-gitops/pkg/runner"
 	"github.com/weaveworks/weave-gitops/pkg/services"
 	"github.com/weaveworks/weave-gitops/pkg/services/profiles"
 )
 
-var profileOpts profiles.Options
+var (
+	profileOpts     profiles.Options
+	signingKeyFlags internal.SigningKeyFlags
+	cacheFlags      gitproviders.CacheConfig
+)
 
 // UpdateCommand provides support for updating a profile that is installed on a cluster.
 func UpdateCommand(opts *config.Options, client *adapters.HTTPClient) *cobra.Command {
@@ -42,6 +46,8 @@ func UpdateCommand(opts *config.Options, client *adapters.HTTPClient) *cobra.Com
 	cmd.Flags().StringVar(&profileOpts.Cluster, "cluster", "", "Name of the cluster where the profile is installed")
 	cmd.Flags().BoolVar(&profileOpts.AutoMerge, "auto-merge", false, "If set, 'gitops update profile' will merge automatically into the repository's branch")
 	internal.AddPRFlags(cmd, &profileOpts.HeadBranch, &profileOpts.BaseBranch, &profileOpts.Description, &profileOpts.Message, &profileOpts.Title)
+	internal.AddSigningKeyFlags(cmd.Flags(), &signingKeyFlags)
+	internal.AddGitProviderCacheFlags(cmd.Flags(), &cacheFlags)
 
 	requiredFlags := []string{"name", "config-repo", "cluster", "version"}
 	for _, f := range requiredFlags {
@@ -66,9 +72,9 @@ func updateProfileCmdPreRunE(endpoint *string) func(*cobra.Command, []string) er
 func updateProfileCmdRunE(opts *config.Options, client *adapters.HTTPClient) func(*cobra.Command, []string) error {
 	return func(cmd *cobra.Command, args []string) error {
 		log := logger.NewCLILogger(os.Stdout)
-		fluxClient := flux.New(&runner.CLIRunner{})
+		fluxClient := flux.New()
 		factory := services.NewFactory(fluxClient, logger.Logr())
-		providerClient := internal.NewGitProviderClient(os.Stdout, os.LookupEnv, log)
+		providerClient := internal.NewGitProviderClient(os.Stdout, os.LookupEnv, log, cacheFlags)
 
 		err := client.ConfigureClientWithOptions(opts, os.Stdout)
 		if err != nil {
@@ -90,16 +96,28 @@ func updateProfileCmdRunE(opts *config.Options, client *adapters.HTTPClient) fun
 			return fmt.Errorf("failed to create kube client: %w", err)
 		}
 
-		_, gitProvider, err := factory.GetGitClients(context.Background(), kubeClient, providerClient, services.GitConfigParams{
+		signingKey, err := internal.LoadSigningKey(context.Background(), kubeClient, profileOpts.Namespace, signingKeyFlags, os.LookupEnv)
+		if err != nil {
+			return fmt.Errorf("failed to load signing key: %w", err)
+		}
+
+		gitClient, gitProvider, err := factory.GetGitClients(context.Background(), kubeClient, providerClient, services.GitConfigParams{
 			ConfigRepo:       profileOpts.ConfigRepo,
 			Namespace:        profileOpts.Namespace,
 			IsHelmRepository: true,
 			DryRun:           false,
+			SigningKey:       signingKey,
 		})
 		if err != nil {
 			return fmt.Errorf("failed to get git clients: %w", err)
 		}
 
+		if signingKey != nil {
+			// The git provider's API can't sign commits, so the changes
+			// are pushed with git.
+			profileOpts.GitClient = gitClient
+		}
+
 		return profiles.NewService(log).Update(context.Background(), client, gitProvider, profileOpts)
 	}
 }
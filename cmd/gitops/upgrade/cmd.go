@@ -9,13 +9,17 @@ import (
 	"github.com/weaveworks/weave-gitops/cmd/gitops/logger"
 	"github.com/weaveworks/weave-gitops/cmd/internal"
 	"github.com/weaveworks/weave-gitops/pkg/flux"
+	"github.com/weaveworks/weave-gitops/pkg/gitproviders"
 	"github.com/weaveworks/weave-gitops/pkg/kube"
-	"github.com/weaveworks/weave-gitops/pkg/runner"
 	"github.com/weaveworks/weave-gitops/pkg/services"
 	"github.com/weaveworks/weave-gitops/pkg/upgrade"
 )
 
-var upgradeCmdFlags upgrade.UpgradeValues
+var (
+	upgradeCmdFlags upgrade.UpgradeValues
+	signingKeyFlags internal.SigningKeyFlags
+	cacheFlags      gitproviders.CacheConfig
+)
 
 var example = `  # Upgrade Weave GitOps
   gitops upgrade --version 0.0.17 --config-repo https://github.com/my-org/my-management-cluster.git
@@ -37,11 +41,13 @@ func init() {
 	Cmd.PersistentFlags().StringVar(&upgradeCmdFlags.ConfigRepo, "config-repo", "", "URL of external repository that will hold automation manifests")
 	Cmd.PersistentFlags().StringVar(&upgradeCmdFlags.Version, "version", "", "Version of Weave GitOps Enterprise to be installed")
 	Cmd.PersistentFlags().StringVar(&upgradeCmdFlags.BaseBranch, "base", "", "The base branch to open the pull request against")
-	Cmd.PersistentFlags().StringVar(&upgradeCmdFlags.HeadBranch, "branch", "tier-upgrade-enterprise", "The branch to create the pull request from")
+	Cmd.PersistentFlags().StringVar(&upgradeCmdFlags.HeadBranch, "branch", "tier-upgrade-enterprise", fmt.Sprintf("The branch to create the pull request from, prefixed with %q", gitproviders.PullRequestBranchPrefix))
 	Cmd.PersistentFlags().StringVar(&upgradeCmdFlags.ClusterPath, "path", "", "The path within the Git repository containing files for the current cluster")
 	Cmd.PersistentFlags().StringVar(&upgradeCmdFlags.CommitMessage, "commit-message", "Upgrade to WGE", "The commit message")
 	Cmd.PersistentFlags().StringArrayVar(&upgradeCmdFlags.Values, "set", []string{}, "set profile values on the command line (can specify multiple or separate values with commas: key1=val1,key2=val2)")
 	Cmd.PersistentFlags().BoolVar(&upgradeCmdFlags.DryRun, "dry-run", false, "Output the generated profile without creating a pull request")
+	internal.AddSigningKeyFlags(Cmd.PersistentFlags(), &signingKeyFlags)
+	internal.AddGitProviderCacheFlags(Cmd.PersistentFlags(), &cacheFlags)
 
 	cobra.CheckErr(Cmd.MarkPersistentFlagRequired("version"))
 }
@@ -64,15 +70,21 @@ func upgradeCmdRunE() func(*cobra.Command, []string) error {
 		upgradeCmdFlags.Namespace = namespace
 
 		log := logger.NewCLILogger(os.Stdout)
-		fluxClient := flux.New(&runner.CLIRunner{})
+		fluxClient := flux.New()
 		factory := services.NewFactory(fluxClient, logger.Logr())
 
-		providerClient := internal.NewGitProviderClient(os.Stdout, os.LookupEnv, log)
+		providerClient := internal.NewGitProviderClient(os.Stdout, os.LookupEnv, log, cacheFlags)
+
+		signingKey, err := internal.LoadSigningKey(ctx, kubeClient, upgradeCmdFlags.Namespace, signingKeyFlags, os.LookupEnv)
+		if err != nil {
+			return fmt.Errorf("failed to load signing key: %w", err)
+		}
 
 		gitClient, gitProvider, err := factory.GetGitClients(ctx, kubeClient, providerClient, services.GitConfigParams{
 			ConfigRepo: upgradeCmdFlags.ConfigRepo,
 			Namespace:  upgradeCmdFlags.Namespace,
 			DryRun:     upgradeCmdFlags.DryRun,
+			SigningKey: signingKey,
 		})
 		if err != nil {
 			return fmt.Errorf("failed to get git clients: %w", err)
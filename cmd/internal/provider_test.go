@@ -8,6 +8,7 @@ import (
 	"github.com/fluxcd/go-git-providers/gitprovider"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
 	"github.com/weaveworks/weave-gitops/pkg/gitproviders"
 	"github.com/weaveworks/weave-gitops/pkg/logger/loggerfakes"
 )
@@ -30,6 +31,10 @@ func fakeEnvLookupExists(key string) (string, bool) {
 		return githubToken, true
 	} else if key == "GITLAB_TOKEN" {
 		return gitlabToken, true
+	} else if key == "GITEA_TOKEN" {
+		return "gitea-token", true
+	} else if key == "GITEA_URL" {
+		return "http://localhost:3000", true
 	} else {
 		return "", false
 	}
@@ -43,13 +48,13 @@ var _ = Describe("Get git provider", func() {
 	Context("Invalid git provider name", func() {
 		It("invalid token key returns an error", func() {
 			fakeLogger = &loggerfakes.FakeLogger{}
-			client = NewGitProviderClient(os.Stdout, fakeEnvLookupExists, fakeLogger)
+			client = NewGitProviderClient(os.Stdout, fakeEnvLookupExists, fakeLogger, gitproviders.DefaultCacheConfig())
 			repoUrl, _ = gitproviders.NewRepoURL("ssh://git@some-bucket.com/weaveworks/weave-gitops.git")
 
 			provider, err := client.GetProvider(repoUrl, fakeAccountGetterSuccess)
 			Expect(provider).To(BeNil())
 
-			_, expectedErr := getTokenVarName(repoUrl.Provider())
+			_, expectedErr := gitproviders.TokenVarName(repoUrl.Provider())
 			Expect(err).To(MatchError(fmt.Errorf("could not determine git provider token name: %w", expectedErr)))
 		})
 	})
@@ -58,7 +63,7 @@ var _ = Describe("Get git provider", func() {
 		Describe("github token", func() {
 			BeforeEach(func() {
 				fakeLogger = &loggerfakes.FakeLogger{}
-				client = NewGitProviderClient(os.Stdout, fakeEnvLookupExists, fakeLogger)
+				client = NewGitProviderClient(os.Stdout, fakeEnvLookupExists, fakeLogger, gitproviders.DefaultCacheConfig())
 				repoUrl, _ = gitproviders.NewRepoURL("ssh://git@github.com/weaveworks/weave-gitops.git")
 			})
 
@@ -73,7 +78,12 @@ var _ = Describe("Get git provider", func() {
 				provider, err := client.GetProvider(repoUrl, fakeAccountGetterSuccess)
 
 				Expect(err).To(BeNil())
-				expectedProvider, _ := gitproviders.New(gitproviders.Config{Provider: repoUrl.Provider(), Token: githubToken}, repoUrl.Owner(), fakeAccountGetterSuccess)
+				expectedProvider, _ := gitproviders.New(gitproviders.Config{
+					Provider: repoUrl.Provider(),
+					Hostname: "github.com",
+					Token:    githubToken,
+					Cache:    gitproviders.NewCache(gitproviders.DefaultCacheConfig()),
+				}, repoUrl.Owner(), fakeAccountGetterSuccess)
 				Expect(provider).To(Equal(expectedProvider))
 				Expect(fakeLogger.WarningfCallCount()).To(Equal(0), "we should not write out a warning message to the user if a token is set")
 			})
@@ -82,7 +92,7 @@ var _ = Describe("Get git provider", func() {
 		Describe("gitlab token", func() {
 			BeforeEach(func() {
 				fakeLogger = &loggerfakes.FakeLogger{}
-				client = NewGitProviderClient(os.Stdout, fakeEnvLookupExists, fakeLogger)
+				client = NewGitProviderClient(os.Stdout, fakeEnvLookupExists, fakeLogger, gitproviders.DefaultCacheConfig())
 				repoUrl, _ = gitproviders.NewRepoURL("ssh://git@gitlab.com/weaveworks/weave-gitops.git")
 			})
 
@@ -100,5 +110,26 @@ var _ = Describe("Get git provider", func() {
 				Expect(provider.GetProviderDomain()).To(Equal("gitlab.com"))
 			})
 		})
+
+		Describe("gitea token", func() {
+			It("uses the API URL from the environment", func() {
+				viper.Set("git-host-types", "gitea.acme.org=gitea")
+
+				client = NewGitProviderClient(os.Stdout, fakeEnvLookupExists, &loggerfakes.FakeLogger{}, gitproviders.DefaultCacheConfig())
+				repoUrl, _ = gitproviders.NewRepoURL("ssh://git@gitea.acme.org:2222/weaveworks/weave-gitops.git")
+
+				provider, err := client.GetProvider(repoUrl, fakeAccountGetterError)
+				Expect(err).NotTo(HaveOccurred())
+
+				expectedProvider, _ := gitproviders.New(gitproviders.Config{
+					Provider: gitproviders.GitProviderGitea,
+					Hostname: "gitea.acme.org",
+					Token:    "gitea-token",
+					APIURL:   "http://localhost:3000",
+					Cache:    gitproviders.NewCache(gitproviders.DefaultCacheConfig()),
+				}, repoUrl.Owner(), nil)
+				Expect(provider).To(Equal(expectedProvider))
+			})
+		})
 	})
 })
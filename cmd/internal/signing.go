@@ -0,0 +1,42 @@
+package internal
+
+import (
+	"context"
+	"errors"
+
+	"github.com/spf13/pflag"
+	"github.com/weaveworks/weave-gitops/pkg/git"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SigningKeyPassphraseVar holds the passphrase of an encrypted signing key
+// file.
+const SigningKeyPassphraseVar = "WEAVE_GITOPS_SIGNING_KEY_PASSPHRASE"
+
+// SigningKeyFlags locate the key that commits are signed with.
+type SigningKeyFlags struct {
+	File   string
+	Secret string
+}
+
+func AddSigningKeyFlags(flags *pflag.FlagSet, signingKey *SigningKeyFlags) {
+	flags.StringVar(&signingKey.File, "signing-key-file", "", "File containing the OpenPGP or SSH private key to sign commits with, an encrypted key's passphrase is read from $"+SigningKeyPassphraseVar)
+	flags.StringVar(&signingKey.Secret, "signing-key-secret", "", "Name of the secret in the namespace containing the OpenPGP or SSH private key to sign commits with in its '"+git.SigningKeySecretKey+"' key, and an encrypted key's passphrase in '"+git.SigningKeyPassphraseSecretKey+"'")
+}
+
+// LoadSigningKey returns the key set by the flags, or nil if commits aren't
+// signed.
+func LoadSigningKey(ctx context.Context, c client.Client, namespace string, signingKey SigningKeyFlags, lookupEnvFunc func(key string) (string, bool)) (*git.SigningKey, error) {
+	switch {
+	case signingKey.File != "" && signingKey.Secret != "":
+		return nil, errors.New("only one of --signing-key-file and --signing-key-secret can be set")
+	case signingKey.File != "":
+		passphrase, _ := lookupEnvFunc(SigningKeyPassphraseVar)
+		return git.LoadSigningKeyFile(signingKey.File, []byte(passphrase))
+	case signingKey.Secret != "":
+		return git.LoadSigningKeySecret(ctx, c, types.NamespacedName{Name: signingKey.Secret, Namespace: namespace})
+	default:
+		return nil, nil
+	}
+}
@@ -5,11 +5,15 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -33,6 +37,8 @@ type Client interface {
 
 	// ClusteredList loops through the list of clusters and namespaces the client has access and
 	// queries the list of objects for each of them in parallel.
+	// The number of concurrent requests and the time spent on each cluster are bounded, clusters
+	// failing to answer in time are reported in a ClusteredListError along with the partial results.
 	// This method supports pagination with a caveat, the client.Limit passed will be multiplied
 	// by the number of clusters and namespaces, we decided to do this to avoid the complex coordination
 	// that would be required to make sure the number of items returned match the limit passed.
@@ -45,9 +51,44 @@ type Client interface {
 	Scoped(cluster string) (client.Client, error)
 }
 
+const (
+	// DefaultClusteredListConcurrency is the default maximum number of list
+	// requests ClusteredList keeps in flight at the same time.
+	DefaultClusteredListConcurrency = 100
+	// DefaultClusteredListClusterTimeout is the default time ClusteredList waits
+	// for all the list requests to a single cluster to complete.
+	DefaultClusteredListClusterTimeout = 30 * time.Second
+)
+
+// ClientOption configures a Client created by NewClient.
+type ClientOption func(*clustersClient)
+
+// WithClusteredListConcurrency sets the maximum number of list requests
+// ClusteredList keeps in flight at the same time across all clusters and namespaces.
+// Values lower than 1 are ignored.
+func WithClusteredListConcurrency(concurrency int) ClientOption {
+	return func(c *clustersClient) {
+		if concurrency > 0 {
+			c.concurrency = concurrency
+		}
+	}
+}
+
+// WithClusteredListClusterTimeout sets the deadline for listing objects on a single
+// cluster. Clusters that do not answer in time are reported in the returned
+// ClusteredListError while the results of the other clusters are kept.
+// A zero value disables the per-cluster deadline.
+func WithClusteredListClusterTimeout(timeout time.Duration) ClientOption {
+	return func(c *clustersClient) {
+		c.clusterTimeout = timeout
+	}
+}
+
 type clustersClient struct {
-	pool       ClientsPool
-	namespaces map[string][]v1.Namespace
+	pool           ClientsPool
+	namespaces     map[string][]v1.Namespace
+	concurrency    int
+	clusterTimeout time.Duration
 }
 
 type ListError struct {
@@ -77,11 +118,19 @@ func (cle ClusteredListError) Error() string {
 	return strings.Join(errs, "; ")
 }
 
-func NewClient(clientsPool ClientsPool, namespaces map[string][]v1.Namespace) Client {
-	return &clustersClient{
-		pool:       clientsPool,
-		namespaces: namespaces,
+func NewClient(clientsPool ClientsPool, namespaces map[string][]v1.Namespace, opts ...ClientOption) Client {
+	c := &clustersClient{
+		pool:           clientsPool,
+		namespaces:     namespaces,
+		concurrency:    DefaultClusteredListConcurrency,
+		clusterTimeout: DefaultClusteredListClusterTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(c)
 	}
+
+	return c
 }
 
 func (c *clustersClient) ClientsPool() ClientsPool {
@@ -117,16 +166,32 @@ func (c *clustersClient) ClusteredList(ctx context.Context, clist ClusteredObjec
 	}
 
 	var (
-		errs = ClusteredListError{}
-		wg   = sync.WaitGroup{}
+		errs      = ClusteredListError{}
+		errsMu    = sync.Mutex{}
+		jobs      = []listJob{}
+		deadlines = []*clusterDeadline{}
+		start     = time.Now()
 	)
 
+	defer func() {
+		for _, d := range deadlines {
+			d.stop()
+		}
+	}()
+
+	namespaceLabels := extractNamespaceLabels(opts...)
+
 	for clusterName, cc := range c.pool.Clients() {
-		namespaces := c.namespaces[clusterName]
+		namespaces := filterNamespaces(c.namespaces[clusterName], namespaceLabels)
 		if !namespaced {
 			namespaces = []v1.Namespace{{}}
 		}
 
+		// every namespace of a cluster shares the same deadline so a slow
+		// cluster can't hold the whole fan-out for longer than clusterTimeout.
+		deadline := &clusterDeadline{parent: ctx, timeout: c.clusterTimeout}
+		deadlines = append(deadlines, deadline)
+
 		for _, ns := range namespaces {
 			nsContinueToken := paginationInfo.Get(clusterName, ns.Name)
 
@@ -136,29 +201,65 @@ func (c *clustersClient) ClusteredList(ctx context.Context, clist ClusteredObjec
 				continue
 			}
 
-			listOpts := append(opts, client.Continue(nsContinueToken))
-			listOpts = append(listOpts, client.InNamespace(ns.Name))
+			// jobs run after the loop, so each one needs its own copy of the options.
+			listOpts := make([]client.ListOption, 0, len(opts)+2)
+			listOpts = append(listOpts, opts...)
+			listOpts = append(listOpts, client.Continue(nsContinueToken), client.InNamespace(ns.Name))
+
+			jobs = append(jobs, listJob{
+				deadline:  deadline,
+				cluster:   clusterName,
+				namespace: ns.Name,
+				client:    cc,
+				opts:      listOpts,
+			})
+		}
+	}
+
+	queue := make(chan listJob, len(jobs))
+	for _, j := range jobs {
+		queue <- j
+	}
 
-			wg.Add(1)
+	close(queue)
+
+	workers := c.concurrency
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
 
-			go func(clusterName string, nsName string, c client.Client, optsWithNamespace ...client.ListOption) {
-				defer wg.Done()
+	wg := sync.WaitGroup{}
 
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := range queue {
 				list := clist.NewList()
 
-				if err := c.List(ctx, list, optsWithNamespace...); err != nil {
-					errs.Add(ListError{Cluster: clusterName, Namespace: nsName, Err: err})
+				if err := j.run(list); err != nil {
+					if errors.Is(err, context.DeadlineExceeded) {
+						clusteredListTimeouts.WithLabelValues(j.cluster).Inc()
+					}
+
+					errsMu.Lock()
+					errs.Add(ListError{Cluster: j.cluster, Namespace: j.namespace, Err: err})
+					errsMu.Unlock()
 				}
 
-				paginationInfo.Set(clusterName, nsName, list.GetContinue())
+				paginationInfo.Set(j.cluster, j.namespace, list.GetContinue())
 
-				clist.AddObjectList(clusterName, list)
-			}(clusterName, ns.Name, cc, listOpts...)
-		}
+				clist.AddObjectList(j.cluster, list)
+			}
+		}()
 	}
 
 	wg.Wait()
 
+	clusteredListDuration.WithLabelValues(strconv.FormatBool(len(errs.Errors) == 0)).Observe(time.Since(start).Seconds())
+
 	continueToken, err := encodeToBase64(paginationInfo)
 	if err != nil {
 		return fmt.Errorf("failed encoding pagination info: %w", err)
@@ -173,6 +274,101 @@ func (c *clustersClient) ClusteredList(ctx context.Context, clist ClusteredObjec
 	return nil
 }
 
+// clusterDeadline is the deadline shared by the jobs of a cluster. It starts
+// when the first of them runs, so that clusters waiting for a worker behind a
+// slow one don't time out before they're even asked.
+type clusterDeadline struct {
+	parent  context.Context
+	timeout time.Duration
+
+	once   sync.Once
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (d *clusterDeadline) context() context.Context {
+	d.once.Do(func() {
+		if d.timeout <= 0 {
+			d.ctx = d.parent
+			return
+		}
+
+		d.ctx, d.cancel = context.WithTimeout(d.parent, d.timeout)
+	})
+
+	return d.ctx
+}
+
+func (d *clusterDeadline) stop() {
+	d.once.Do(func() {})
+
+	if d.cancel != nil {
+		d.cancel()
+	}
+}
+
+// listJob is a single list request of a ClusteredList fan-out.
+type listJob struct {
+	deadline  *clusterDeadline
+	cluster   string
+	namespace string
+	client    client.Client
+	opts      []client.ListOption
+}
+
+func (j listJob) run(list client.ObjectList) error {
+	ctx := j.deadline.context()
+
+	// the cluster deadline may have expired while this job was queued.
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("request not started: %w", err)
+	}
+
+	if err := j.client.List(ctx, list, j.opts...); err != nil {
+		if ctxErr := ctx.Err(); errors.Is(ctxErr, context.DeadlineExceeded) {
+			return fmt.Errorf("cluster timed out: %w", ctxErr)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// MatchingNamespaceLabels restricts a ClusteredList of namespaced objects to the
+// namespaces with the given labels. Unlike client.MatchingLabels, it selects
+// namespaces rather than the listed objects, before they are paginated.
+type MatchingNamespaceLabels map[string]string
+
+// ApplyToList does nothing, the namespaces are selected by ClusteredList.
+func (m MatchingNamespaceLabels) ApplyToList(*client.ListOptions) {}
+
+func extractNamespaceLabels(opts ...client.ListOption) labels.Selector {
+	for _, o := range opts {
+		if v, ok := o.(MatchingNamespaceLabels); ok {
+			return labels.SelectorFromSet(labels.Set(v))
+		}
+	}
+
+	return nil
+}
+
+func filterNamespaces(namespaces []v1.Namespace, selector labels.Selector) []v1.Namespace {
+	if selector == nil {
+		return namespaces
+	}
+
+	filtered := []v1.Namespace{}
+
+	for _, ns := range namespaces {
+		if selector.Matches(labels.Set(ns.Labels)) {
+			filtered = append(filtered, ns)
+		}
+	}
+
+	return filtered
+}
+
 func extractContinueToken(opts ...client.ListOption) string {
 	for _, o := range opts {
 		switch v := o.(type) {
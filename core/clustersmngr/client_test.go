@@ -5,9 +5,11 @@ import (
 	"errors"
 	"strconv"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	sourcev1 "github.com/fluxcd/source-controller/api/v1beta2"
 	corev1 "k8s.io/api/core/v1"
@@ -19,6 +21,7 @@ import (
 	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
 	"github.com/fluxcd/pkg/apis/meta"
 	"github.com/weaveworks/weave-gitops/core/clustersmngr"
+	"github.com/weaveworks/weave-gitops/core/clustersmngr/clustersmngrfakes"
 	"github.com/weaveworks/weave-gitops/pkg/kube"
 	"github.com/weaveworks/weave-gitops/pkg/server/auth"
 	rbacv1 "k8s.io/api/rbac/v1"
@@ -273,6 +276,106 @@ func TestClientCLusteredListErrors(t *testing.T) {
 	g.Expect(errors.As(cerr, &errs)).To(BeTrue())
 }
 
+func TestClientClusteredListClusterTimeout(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	scheme, err := kube.CreateScheme()
+	g.Expect(err).To(BeNil())
+
+	fastClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&kustomizev1.Kustomization{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "ns1",
+		},
+	}).Build()
+
+	clientsPool := &clustersmngrfakes.FakeClientsPool{}
+	clientsPool.ClientsReturns(map[string]client.Client{
+		"fast": fastClient,
+		"slow": blockingClient{Client: fastClient},
+	})
+
+	nsMap := map[string][]corev1.Namespace{
+		"fast": {{ObjectMeta: v1.ObjectMeta{Name: "ns1"}}},
+		"slow": {{ObjectMeta: v1.ObjectMeta{Name: "ns1"}}, {ObjectMeta: v1.ObjectMeta{Name: "ns2"}}},
+	}
+
+	clustersClient := clustersmngr.NewClient(clientsPool, nsMap,
+		clustersmngr.WithClusteredListConcurrency(1),
+		clustersmngr.WithClusteredListClusterTimeout(100*time.Millisecond),
+	)
+
+	cklist := clustersmngr.NewClusteredList(func() client.ObjectList {
+		return &kustomizev1.KustomizationList{}
+	})
+
+	cerr := clustersClient.ClusteredList(context.Background(), cklist, true)
+	g.Expect(cerr).ToNot(BeNil())
+
+	var errs clustersmngr.ClusteredListError
+
+	g.Expect(errors.As(cerr, &errs)).To(BeTrue())
+	g.Expect(errs.Errors).To(HaveLen(2))
+
+	for _, e := range errs.Errors {
+		g.Expect(e.Cluster).To(Equal("slow"))
+		g.Expect(errors.Is(e.Err, context.DeadlineExceeded)).To(BeTrue())
+	}
+
+	klist := cklist.Lists()["fast"][0].(*kustomizev1.KustomizationList)
+	g.Expect(klist.Items).To(HaveLen(1))
+	g.Expect(klist.Items[0].Name).To(Equal("myapp"))
+}
+
+func TestClientClusteredListClusterTimeoutStartsWhenClusterRuns(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	scheme, err := kube.CreateScheme()
+	g.Expect(err).To(BeNil())
+
+	fastClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&kustomizev1.Kustomization{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "myapp",
+			Namespace: "ns1",
+		},
+	}).Build()
+
+	clientsPool := &clustersmngrfakes.FakeClientsPool{}
+	clientsPool.ClientsReturns(map[string]client.Client{
+		"fast": fastClient,
+		"slow": blockingClient{Client: fastClient},
+	})
+
+	nsMap := map[string][]corev1.Namespace{
+		"fast": {{ObjectMeta: v1.ObjectMeta{Name: "ns1"}}},
+		"slow": {{ObjectMeta: v1.ObjectMeta{Name: "ns1"}}},
+	}
+
+	// The single worker runs the clusters one after the other, in any order,
+	// so the fast cluster sometimes waits for the slow one to time out.
+	clustersClient := clustersmngr.NewClient(clientsPool, nsMap,
+		clustersmngr.WithClusteredListConcurrency(1),
+		clustersmngr.WithClusteredListClusterTimeout(10*time.Millisecond),
+	)
+
+	for i := 0; i < 50; i++ {
+		cklist := clustersmngr.NewClusteredList(func() client.ObjectList {
+			return &kustomizev1.KustomizationList{}
+		})
+
+		cerr := clustersClient.ClusteredList(context.Background(), cklist, true)
+
+		var errs clustersmngr.ClusteredListError
+
+		g.Expect(errors.As(cerr, &errs)).To(BeTrue())
+		g.Expect(errs.Errors).To(HaveLen(1))
+		g.Expect(errs.Errors[0].Cluster).To(Equal("slow"))
+
+		klist := cklist.Lists()["fast"][0].(*kustomizev1.KustomizationList)
+		g.Expect(klist.Items).To(HaveLen(1))
+	}
+}
+
 func TestClientList(t *testing.T) {
 	g := NewGomegaWithT(t)
 	ns := createNamespace(g)
@@ -460,6 +563,17 @@ func TestClientPatch(t *testing.T) {
 	g.Expect(k.Spec.Path).To(Equal("/foo"))
 }
 
+// blockingClient is a client whose List calls only return once the context is done.
+type blockingClient struct {
+	client.Client
+}
+
+func (c blockingClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	<-ctx.Done()
+
+	return ctx.Err()
+}
+
 func createNamespace(g *GomegaWithT) *corev1.Namespace {
 	ns := &corev1.Namespace{}
 	ns.Name = "kube-test-" + rand.String(5)
@@ -11,13 +11,18 @@ import (
 	"github.com/cheshir/ttlcache"
 	"github.com/go-logr/logr"
 	"github.com/hashicorp/go-multierror"
+	"github.com/weaveworks/weave-gitops/core/logger"
 	"github.com/weaveworks/weave-gitops/core/nsaccess"
 	"github.com/weaveworks/weave-gitops/pkg/server/auth"
 	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	apiruntime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/metadata/metadatainformer"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 	"sigs.k8s.io/cli-utils/pkg/flowcontrol"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -58,7 +63,8 @@ type ClientsFactory interface {
 	GetImpersonatedDiscoveryClient(ctx context.Context, user *auth.UserPrincipal, clusterName string) (*discovery.DiscoveryClient, error)
 	// UpdateClusters updates the clusters list
 	UpdateClusters(ctx context.Context) error
-	// UpdateNamespaces updates the namespaces all namespaces for all clusters
+	// UpdateNamespaces updates the namespaces all namespaces for all clusters,
+	// and discards the cached namespace access of clusters whose RBAC bindings changed
 	UpdateNamespaces(ctx context.Context) error
 	// UpdateUserNamespaces updates the cache of accessible namespaces for the user
 	UpdateUserNamespaces(ctx context.Context, user *auth.UserPrincipal)
@@ -87,23 +93,30 @@ type clientsFactory struct {
 	clustersNamespaces *ClustersNamespaces
 	// lists of namespaces accessible by the user on every cluster
 	usersNamespaces *UsersNamespaces
+	// informers of the RoleBindings and ClusterRoleBindings of every cluster,
+	// used to invalidate namespace access caches
+	clustersRBAC *ClustersRBAC
 
 	initialClustersLoad chan bool
 	scheme              *apiruntime.Scheme
 	newClustersPool     ClusterPoolFactoryFn
+	// options applied to every Client created by the factory
+	clientOpts []ClientOption
 }
 
-func NewClientFactory(fetcher ClusterFetcher, nsChecker nsaccess.Checker, logger logr.Logger, scheme *apiruntime.Scheme, clusterPoolFactory ClusterPoolFactoryFn) ClientsFactory {
+func NewClientFactory(fetcher ClusterFetcher, nsChecker nsaccess.Checker, logger logr.Logger, scheme *apiruntime.Scheme, clusterPoolFactory ClusterPoolFactoryFn, clientOpts ...ClientOption) ClientsFactory {
 	return &clientsFactory{
 		clustersFetcher:     fetcher,
 		nsChecker:           nsChecker,
 		clusters:            &Clusters{},
 		clustersNamespaces:  &ClustersNamespaces{},
 		usersNamespaces:     &UsersNamespaces{Cache: ttlcache.New(userNamespaceResolution)},
+		clustersRBAC:        &ClustersRBAC{},
 		log:                 logger,
 		initialClustersLoad: make(chan bool),
 		scheme:              scheme,
 		newClustersPool:     clusterPoolFactory,
+		clientOpts:          clientOpts,
 	}
 }
 
@@ -191,9 +204,74 @@ func (cf *clientsFactory) UpdateNamespaces(ctx context.Context) error {
 		}
 	}
 
+	if err := cf.updateRBAC(); err != nil {
+		result = multierror.Append(result, err)
+	}
+
 	return result.ErrorOrNil()
 }
 
+// updateRBAC watches the RoleBindings and ClusterRoleBindings of the clusters that aren't watched
+// yet, to invalidate the namespace access caches of a cluster when any of them changes.
+func (cf *clientsFactory) updateRBAC() error {
+	var result *multierror.Error
+
+	for _, cluster := range cf.clusters.Get() {
+		cluster := cluster
+
+		if err := cf.clustersRBAC.Watch(cluster.Name, func(stop <-chan struct{}) error {
+			return cf.watchRBAC(cluster, stop)
+		}); err != nil {
+			result = multierror.Append(result, fmt.Errorf("failed watching RBAC bindings of cluster %s: %w", cluster.Name, err))
+		}
+	}
+
+	return result.ErrorOrNil()
+}
+
+func (cf *clientsFactory) watchRBAC(cluster Cluster, stop <-chan struct{}) error {
+	config, err := ClientConfigAsServer(cluster)
+	if err != nil {
+		return err
+	}
+
+	// watches stay open for longer than the client timeout.
+	config = rest.CopyConfig(config)
+	config.Timeout = 0
+
+	client, err := metadata.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	invalidate := func() {
+		cf.log.V(logger.LogLevelDebug).Info("RBAC bindings changed, invalidating namespace access", "cluster", cluster.Name)
+		cf.nsChecker.InvalidateCluster(cluster.Server)
+		cf.usersNamespaces.Clear()
+	}
+
+	factory := metadatainformer.NewSharedInformerFactory(client, 0)
+
+	for _, resource := range []string{"rolebindings", "clusterrolebindings"} {
+		informer := factory.ForResource(rbacv1.SchemeGroupVersion.WithResource(resource)).Informer()
+
+		informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				// the bindings listed when the informer starts aren't changes.
+				if informer.HasSynced() {
+					invalidate()
+				}
+			},
+			UpdateFunc: func(oldObj, newObj interface{}) { invalidate() },
+			DeleteFunc: func(obj interface{}) { invalidate() },
+		})
+	}
+
+	factory.Start(stop)
+
+	return nil
+}
+
 func (cf *clientsFactory) GetClustersNamespaces() map[string][]v1.Namespace {
 	return cf.clustersNamespaces.namespaces
 }
@@ -204,6 +282,7 @@ func (cf *clientsFactory) syncCaches() {
 	if newHash != cf.clustersHash {
 		cf.clustersNamespaces.Clear()
 		cf.usersNamespaces.Clear()
+		cf.clustersRBAC.Clear()
 		cf.clustersHash = newHash
 	}
 }
@@ -239,7 +318,7 @@ func (cf *clientsFactory) GetImpersonatedClient(ctx context.Context, user *auth.
 		result = multierror.Append(result, err)
 	}
 
-	return NewClient(pool, cf.userNsList(ctx, user)), result.ErrorOrNil()
+	return NewClient(pool, cf.userNsList(ctx, user), cf.clientOpts...), result.ErrorOrNil()
 }
 
 func (cf *clientsFactory) GetImpersonatedClientForCluster(ctx context.Context, user *auth.UserPrincipal, clusterName string) (Client, error) {
@@ -267,7 +346,7 @@ func (cf *clientsFactory) GetImpersonatedClientForCluster(ctx context.Context, u
 		return nil, fmt.Errorf("failed adding cluster client to pool: %w", err)
 	}
 
-	return NewClient(pool, cf.userNsList(ctx, user)), nil
+	return NewClient(pool, cf.userNsList(ctx, user), cf.clientOpts...), nil
 }
 
 func (cf *clientsFactory) GetImpersonatedDiscoveryClient(ctx context.Context, user *auth.UserPrincipal, clusterName string) (*discovery.DiscoveryClient, error) {
@@ -329,7 +408,7 @@ func (cf *clientsFactory) GetServerClient(ctx context.Context) (Client, error) {
 		result = multierror.Append(result, err)
 	}
 
-	return NewClient(pool, cf.clustersNamespaces.namespaces), result.ErrorOrNil()
+	return NewClient(pool, cf.clustersNamespaces.namespaces, cf.clientOpts...), result.ErrorOrNil()
 }
 
 func (cf *clientsFactory) UpdateUserNamespaces(ctx context.Context, user *auth.UserPrincipal) {
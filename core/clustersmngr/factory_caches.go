@@ -111,6 +111,51 @@ func (cn *ClustersNamespaces) Get(cluster string) []v1.Namespace {
 	return cn.namespaces[cluster]
 }
 
+// ClustersRBAC keeps track of the informers watching the RBAC bindings of
+// every cluster.
+type ClustersRBAC struct {
+	sync.Mutex
+	stops map[string]chan struct{}
+}
+
+// Watch calls start for a cluster that isn't watched yet, the informers it
+// starts run until stop is closed by Clear.
+func (cr *ClustersRBAC) Watch(cluster string, start func(stop <-chan struct{}) error) error {
+	cr.Lock()
+	defer cr.Unlock()
+
+	if cr.stops == nil {
+		cr.stops = make(map[string]chan struct{})
+	}
+
+	if _, found := cr.stops[cluster]; found {
+		return nil
+	}
+
+	stop := make(chan struct{})
+
+	if err := start(stop); err != nil {
+		close(stop)
+		return err
+	}
+
+	cr.stops[cluster] = stop
+
+	return nil
+}
+
+// Clear stops the informers of every cluster.
+func (cr *ClustersRBAC) Clear() {
+	cr.Lock()
+	defer cr.Unlock()
+
+	for _, stop := range cr.stops {
+		close(stop)
+	}
+
+	cr.stops = make(map[string]chan struct{})
+}
+
 type UsersNamespaces struct {
 	Cache *ttlcache.Cache
 }
@@ -1,6 +1,7 @@
 package clustersmngr_test
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -117,3 +118,35 @@ func newTestCluster(name, server string) clustersmngr.Cluster {
 		Server: server,
 	}
 }
+
+func TestClustersRBAC(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cr := clustersmngr.ClustersRBAC{}
+
+	var stops []<-chan struct{}
+
+	start := func(stop <-chan struct{}) error {
+		stops = append(stops, stop)
+		return nil
+	}
+
+	g.Expect(cr.Watch("cluster-1", start)).To(Succeed())
+	g.Expect(cr.Watch("cluster-1", start)).To(Succeed())
+	g.Expect(stops).To(HaveLen(1), "a cluster is only watched once")
+
+	g.Expect(cr.Watch("cluster-2", func(stop <-chan struct{}) error {
+		return errors.New("unreachable")
+	})).NotTo(Succeed())
+	g.Expect(cr.Watch("cluster-2", start)).To(Succeed(), "a failed watch is retried")
+	g.Expect(stops).To(HaveLen(2))
+
+	cr.Clear()
+
+	for _, stop := range stops {
+		g.Expect(stop).To(BeClosed())
+	}
+
+	g.Expect(cr.Watch("cluster-1", start)).To(Succeed())
+	g.Expect(stops).To(HaveLen(3))
+}
@@ -0,0 +1,26 @@
+package clustersmngr
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	clusteredListDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "gitops",
+		Subsystem: "clustersmngr",
+		Name:      "clustered_list_duration_seconds",
+		Help:      "Duration of ClusteredList fan-outs across all clusters and namespaces.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"success"})
+
+	clusteredListTimeouts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gitops",
+		Subsystem: "clustersmngr",
+		Name:      "clustered_list_timeouts_total",
+		Help:      "Number of ClusteredList requests that hit the per-cluster timeout.",
+	}, []string{"cluster"})
+)
+
+func init() {
+	prometheus.MustRegister(clusteredListDuration, clusteredListTimeouts)
+}
@@ -2,8 +2,15 @@ package nsaccess
 
 import (
 	"context"
+	"crypto/sha256"
 	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/cheshir/ttlcache"
 	authorizationv1 "k8s.io/api/authorization/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
@@ -11,6 +18,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 	typedauth "k8s.io/client-go/kubernetes/typed/authorization/v1"
 	"k8s.io/client-go/rest"
+	"sigs.k8s.io/yaml"
 )
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
@@ -49,26 +57,76 @@ var DefautltWegoAppRules = []rbacv1.PolicyRule{
 	},
 }
 
+// DefaultCacheTTL is how long the result of a namespace access check is cached by the caching checker
+const DefaultCacheTTL = 5 * time.Minute
+
+// How often expired access checks are removed from the cache.
+const cacheResolution = 30 * time.Second
+
+// LoadRules reads a YAML or JSON list of PolicyRules from the given file, to be used
+// instead of DefautltWegoAppRules to decide whether a user can use a namespace
+func LoadRules(path string) ([]rbacv1.PolicyRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading rules file: %w", err)
+	}
+
+	rules := []rbacv1.PolicyRule{}
+
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("parsing rules file %s: %w", path, err)
+	}
+
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("no rules found in %s", path)
+	}
+
+	return rules, nil
+}
+
 // Checker contains methods for validing user access to Kubernetes namespaces, based on a set of PolicyRules
 //counterfeiter:generate . Checker
 type Checker interface {
 	// FilterAccessibleNamespaces returns a filtered list of namespaces to which a user has access to
 	FilterAccessibleNamespaces(ctx context.Context, cfg *rest.Config, namespaces []corev1.Namespace) ([]corev1.Namespace, error)
+	// InvalidateCluster discards any cached access result for the cluster served at the given address,
+	// it should be called when the RBAC configuration of the cluster changes
+	InvalidateCluster(server string)
 }
 
 type simpleChecker struct {
 	rules []rbacv1.PolicyRule
+
+	// cache is nil when access results are not cached
+	cache *ttlcache.Cache
+	ttl   time.Duration
+	// generations is incremented for a cluster when its cache is invalidated,
+	// entries from older generations are never read again and expire on their own.
+	generations   map[string]uint64
+	generationsMu sync.RWMutex
 }
 
+// NewChecker returns a Checker that asks the cluster about every namespace on every call
 func NewChecker(rules []rbacv1.PolicyRule) Checker {
-	return simpleChecker{rules: rules}
+	return &simpleChecker{rules: rules}
+}
+
+// NewCachingChecker returns a Checker that caches the access result of each user,
+// cluster and namespace for the given ttl
+func NewCachingChecker(rules []rbacv1.PolicyRule, ttl time.Duration) Checker {
+	return &simpleChecker{
+		rules:       rules,
+		cache:       ttlcache.New(cacheResolution),
+		ttl:         ttl,
+		generations: map[string]uint64{},
+	}
 }
 
-func (sc simpleChecker) FilterAccessibleNamespaces(ctx context.Context, cfg *rest.Config, namespaces []corev1.Namespace) ([]corev1.Namespace, error) {
+func (sc *simpleChecker) FilterAccessibleNamespaces(ctx context.Context, cfg *rest.Config, namespaces []corev1.Namespace) ([]corev1.Namespace, error) {
 	result := []corev1.Namespace{}
 
 	for _, ns := range namespaces {
-		ok, err := userCanUseNamespace(ctx, cfg, ns, sc.rules)
+		ok, err := sc.userCanUseNamespace(ctx, cfg, ns)
 		if err != nil {
 			return nil, fmt.Errorf("user namespace access: %w", err)
 		}
@@ -81,6 +139,56 @@ func (sc simpleChecker) FilterAccessibleNamespaces(ctx context.Context, cfg *res
 	return result, nil
 }
 
+func (sc *simpleChecker) InvalidateCluster(server string) {
+	if sc.cache == nil {
+		return
+	}
+
+	sc.generationsMu.Lock()
+	defer sc.generationsMu.Unlock()
+
+	sc.generations[server]++
+}
+
+func (sc *simpleChecker) userCanUseNamespace(ctx context.Context, cfg *rest.Config, ns corev1.Namespace) (bool, error) {
+	if sc.cache == nil {
+		return userCanUseNamespace(ctx, cfg, ns, sc.rules)
+	}
+
+	key := sc.cacheKey(cfg, ns.Name)
+
+	if val, found := sc.cache.Get(key); found {
+		return val.(bool), nil
+	}
+
+	ok, err := userCanUseNamespace(ctx, cfg, ns, sc.rules)
+	if err != nil {
+		return false, err
+	}
+
+	sc.cache.Set(key, ok, sc.ttl)
+
+	return ok, nil
+}
+
+// cacheKey identifies the user by the credentials in the config, which are either a
+// token or the impersonated user and groups, and the cluster by its address.
+func (sc *simpleChecker) cacheKey(cfg *rest.Config, namespace string) uint64 {
+	sc.generationsMu.RLock()
+	generation := sc.generations[cfg.Host]
+	sc.generationsMu.RUnlock()
+
+	groups := append([]string{}, cfg.Impersonate.Groups...)
+	sort.Strings(groups)
+
+	user := cfg.Impersonate.UserName + ":" + strings.Join(groups, ",")
+	if cfg.Impersonate.UserName == "" {
+		user = fmt.Sprintf("token:%x", sha256.Sum256([]byte(cfg.BearerToken)))
+	}
+
+	return ttlcache.StringKey(fmt.Sprintf("%s:%d:%s:%s", cfg.Host, generation, user, namespace))
+}
+
 func userCanUseNamespace(ctx context.Context, cfg *rest.Config, ns corev1.Namespace, rules []rbacv1.PolicyRule) (bool, error) {
 	auth, err := newAuthClient(cfg)
 	if err != nil {
@@ -2,11 +2,19 @@ package nsaccess
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	. "github.com/onsi/gomega"
 	"github.com/weaveworks/weave-gitops/pkg/kube"
+	authorizationv1 "k8s.io/api/authorization/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -246,6 +254,95 @@ func TestFilterAccessibleNamespaces(t *testing.T) {
 	})
 }
 
+func TestCachingChecker(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	rules := []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{""},
+			Resources: []string{"pods"},
+			Verbs:     []string{"get", "list"},
+		},
+	}
+
+	var reviews int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&reviews, 1)
+
+		review := authorizationv1.SelfSubjectRulesReview{
+			TypeMeta: metav1.TypeMeta{Kind: "SelfSubjectRulesReview", APIVersion: "authorization.k8s.io/v1"},
+			Status: authorizationv1.SubjectRulesReviewStatus{
+				ResourceRules: []authorizationv1.ResourceRule{
+					{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get", "list"}},
+				},
+			},
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		g.Expect(json.NewEncoder(w).Encode(review)).To(Succeed())
+	}))
+	defer server.Close()
+
+	namespaces := []corev1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "ns1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "ns2"}},
+	}
+
+	aliceCfg := &rest.Config{Host: server.URL, Impersonate: rest.ImpersonationConfig{UserName: "alice"}}
+	bobCfg := &rest.Config{Host: server.URL, Impersonate: rest.ImpersonationConfig{UserName: "bob"}}
+
+	checker := NewCachingChecker(rules, time.Minute)
+
+	filtered, err := checker.FilterAccessibleNamespaces(context.Background(), aliceCfg, namespaces)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(filtered).To(HaveLen(2))
+	g.Expect(atomic.LoadInt32(&reviews)).To(Equal(int32(2)))
+
+	_, err = checker.FilterAccessibleNamespaces(context.Background(), aliceCfg, namespaces)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(atomic.LoadInt32(&reviews)).To(Equal(int32(2)), "results should be cached for the same user")
+
+	_, err = checker.FilterAccessibleNamespaces(context.Background(), bobCfg, namespaces)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(atomic.LoadInt32(&reviews)).To(Equal(int32(4)), "results should not be shared between users")
+
+	checker.InvalidateCluster(server.URL)
+
+	_, err = checker.FilterAccessibleNamespaces(context.Background(), aliceCfg, namespaces)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(atomic.LoadInt32(&reviews)).To(Equal(int32(6)), "invalidated results should be checked again")
+}
+
+func TestLoadRules(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dir := t.TempDir()
+
+	rulesFile := filepath.Join(dir, "rules.yaml")
+	g.Expect(os.WriteFile(rulesFile, []byte(`
+- apiGroups: [""]
+  resources: ["pods"]
+  verbs: ["get", "list"]
+- apiGroups: ["kustomize.toolkit.fluxcd.io"]
+  resources: ["kustomizations"]
+  verbs: ["get"]
+`), 0600)).To(Succeed())
+
+	rules, err := LoadRules(rulesFile)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(rules).To(Equal([]rbacv1.PolicyRule{
+		{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get", "list"}},
+		{APIGroups: []string{"kustomize.toolkit.fluxcd.io"}, Resources: []string{"kustomizations"}, Verbs: []string{"get"}},
+	}))
+
+	emptyFile := filepath.Join(dir, "empty.yaml")
+	g.Expect(os.WriteFile(emptyFile, []byte("[]"), 0600)).To(Succeed())
+
+	_, err = LoadRules(emptyFile)
+	g.Expect(err).To(MatchError(ContainSubstring("no rules found")))
+}
+
 func newNamespace(ctx context.Context, k client.Client, g *GomegaWithT) *corev1.Namespace {
 	ns := &corev1.Namespace{}
 	ns.Name = "kube-test-" + rand.String(5)
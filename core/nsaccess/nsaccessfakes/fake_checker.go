@@ -26,6 +26,11 @@ type FakeChecker struct {
 		result1 []v1.Namespace
 		result2 error
 	}
+	InvalidateClusterStub        func(string)
+	invalidateClusterMutex       sync.RWMutex
+	invalidateClusterArgsForCall []struct {
+		arg1 string
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -101,11 +106,45 @@ func (fake *FakeChecker) FilterAccessibleNamespacesReturnsOnCall(i int, result1
 	}{result1, result2}
 }
 
+func (fake *FakeChecker) InvalidateCluster(arg1 string) {
+	fake.invalidateClusterMutex.Lock()
+	fake.invalidateClusterArgsForCall = append(fake.invalidateClusterArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	stub := fake.InvalidateClusterStub
+	fake.recordInvocation("InvalidateCluster", []interface{}{arg1})
+	fake.invalidateClusterMutex.Unlock()
+	if stub != nil {
+		fake.InvalidateClusterStub(arg1)
+	}
+}
+
+func (fake *FakeChecker) InvalidateClusterCallCount() int {
+	fake.invalidateClusterMutex.RLock()
+	defer fake.invalidateClusterMutex.RUnlock()
+	return len(fake.invalidateClusterArgsForCall)
+}
+
+func (fake *FakeChecker) InvalidateClusterCalls(stub func(string)) {
+	fake.invalidateClusterMutex.Lock()
+	defer fake.invalidateClusterMutex.Unlock()
+	fake.InvalidateClusterStub = stub
+}
+
+func (fake *FakeChecker) InvalidateClusterArgsForCall(i int) string {
+	fake.invalidateClusterMutex.RLock()
+	defer fake.invalidateClusterMutex.RUnlock()
+	argsForCall := fake.invalidateClusterArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeChecker) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
 	fake.filterAccessibleNamespacesMutex.RLock()
 	defer fake.filterAccessibleNamespacesMutex.RUnlock()
+	fake.invalidateClusterMutex.RLock()
+	defer fake.invalidateClusterMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cheshir/ttlcache"
+	"github.com/fluxcd/go-git-providers/gitprovider"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta2"
+	"github.com/weaveworks/weave-gitops/core/clustersmngr"
+	pb "github.com/weaveworks/weave-gitops/pkg/api/core"
+	"github.com/weaveworks/weave-gitops/pkg/gitproviders"
+	"github.com/weaveworks/weave-gitops/pkg/server/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// commitsPageSize is how many commits are listed at a time when looking
+	// for the commit of a revision.
+	commitsPageSize = 100
+	// commitsMaxPages bounds how far back in the history of a branch the
+	// commit of a revision is looked for.
+	commitsMaxPages = 5
+
+	// Commits don't change, the TTL only keeps the cache from growing.
+	commitCacheTTL        = time.Hour
+	commitCacheResolution = time.Minute
+)
+
+func (cs *coreServer) GetCommitInfo(ctx context.Context, msg *pb.GetCommitInfoRequest) (*pb.GetCommitInfoResponse, error) {
+	if cs.gitProviders == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "git providers are not configured")
+	}
+
+	if msg.GitRepositoryName == "" || msg.Revision == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "bad request: a GitRepository and a revision are required")
+	}
+
+	clustersClient, err := cs.clientsFactory.GetImpersonatedClientForCluster(ctx, auth.Principal(ctx), msg.ClusterName)
+	if err != nil {
+		return nil, fmt.Errorf("error getting impersonating client: %w", err)
+	}
+
+	// The GitRepository is read with the user's permissions, so the server's
+	// tokens are only used for the repositories the user has access to.
+	repository := &sourcev1.GitRepository{}
+	key := client.ObjectKey{
+		Name:      msg.GitRepositoryName,
+		Namespace: msg.Namespace,
+	}
+
+	if err := clustersClient.Get(ctx, msg.ClusterName, key, repository); err != nil {
+		return nil, err
+	}
+
+	commit, err := cs.commitInfo(ctx, repository.Spec.URL, msg.Revision)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.GetCommitInfoResponse{Commit: commit}, nil
+}
+
+// commitInfo resolves a revision of a GitRepository to its commit in the git
+// provider of the repository.
+func (cs *coreServer) commitInfo(ctx context.Context, url, revision string) (*pb.CommitInfo, error) {
+	branch, sha := splitRevision(revision)
+	if sha == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "bad request: revision %q has no commit", revision)
+	}
+
+	repoUrl, err := cs.newRepoURL(url)
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "unknown git provider: %s", err.Error())
+	}
+
+	key := ttlcache.StringKey(repoUrl.String() + "@" + sha)
+
+	if val, found := cs.commits.Get(key); found {
+		if info, ok := val.(gitprovider.CommitInfo); ok {
+			return commitInfoToProto(revision, info), nil
+		}
+	}
+
+	provider, err := cs.gitProviders.GetProvider(repoUrl, gitproviders.GetAccountType)
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, err.Error())
+	}
+
+	for page := 1; page <= commitsMaxPages; page++ {
+		commits, err := provider.GetCommits(ctx, repoUrl, branch, commitsPageSize, page)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "getting commits of %s: %s", repoUrl, err.Error())
+		}
+
+		for _, commit := range commits {
+			info := commit.Get()
+
+			if strings.HasPrefix(info.Sha, sha) {
+				cs.commits.Set(key, info, commitCacheTTL)
+
+				return commitInfoToProto(revision, info), nil
+			}
+		}
+
+		if len(commits) < commitsPageSize {
+			break
+		}
+	}
+
+	return nil, status.Errorf(codes.NotFound, "commit %s not found in the recent history of %s", sha, repoUrl)
+}
+
+// lastAppliedCommit resolves the last applied revision of a Kustomization to
+// its commit, if it comes from a GitRepository. Errors are only logged, so
+// that the Kustomization is still served.
+func (cs *coreServer) lastAppliedCommit(ctx context.Context, clustersClient clustersmngr.Client, clusterName string, kustomization *pb.Kustomization) *pb.CommitInfo {
+	if cs.gitProviders == nil || kustomization.LastAppliedRevision == "" || kustomization.SourceRef == nil ||
+		kustomization.SourceRef.Kind != pb.FluxObjectKind_KindGitRepository {
+		return nil
+	}
+
+	namespace := kustomization.SourceRef.Namespace
+	if namespace == "" {
+		namespace = kustomization.Namespace
+	}
+
+	repository := &sourcev1.GitRepository{}
+	key := client.ObjectKey{
+		Name:      kustomization.SourceRef.Name,
+		Namespace: namespace,
+	}
+
+	if err := clustersClient.Get(ctx, clusterName, key, repository); err != nil {
+		cs.logger.Error(err, "getting the source of the Kustomization", "kustomization", kustomization.Name)
+		return nil
+	}
+
+	commit, err := cs.commitInfo(ctx, repository.Spec.URL, kustomization.LastAppliedRevision)
+	if err != nil {
+		cs.logger.Error(err, "resolving the last applied revision of the Kustomization", "kustomization", kustomization.Name)
+		return nil
+	}
+
+	return commit
+}
+
+// splitRevision splits a revision of a GitRepository into its branch, or
+// tag, and commit, e.g. main/abc123 or main@sha1:abc123. The branch is empty
+// for revisions of a commit, which are looked for on the default branch.
+func splitRevision(revision string) (string, string) {
+	if i := strings.LastIndex(revision, "@"); i >= 0 {
+		sha := revision[i+1:]
+		if j := strings.Index(sha, ":"); j >= 0 {
+			sha = sha[j+1:]
+		}
+
+		return revision[:i], sha
+	}
+
+	if i := strings.LastIndex(revision, "/"); i >= 0 {
+		return revision[:i], revision[i+1:]
+	}
+
+	return "", revision
+}
+
+func commitInfoToProto(revision string, info gitprovider.CommitInfo) *pb.CommitInfo {
+	return &pb.CommitInfo{
+		Revision: revision,
+		Sha:      info.Sha,
+		Message:  info.Message,
+		Author:   info.Author,
+		Date:     info.CreatedAt.Format(time.RFC3339),
+		Url:      info.URL,
+	}
+}
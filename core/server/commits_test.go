@@ -0,0 +1,168 @@
+package server_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/go-git-providers/gitprovider"
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta2"
+	. "github.com/onsi/gomega"
+	"github.com/weaveworks/weave-gitops/core/clustersmngr"
+	pb "github.com/weaveworks/weave-gitops/pkg/api/core"
+	"github.com/weaveworks/weave-gitops/pkg/gitproviders/gitprovidersfakes"
+	"github.com/weaveworks/weave-gitops/pkg/kube"
+	"github.com/weaveworks/weave-gitops/pkg/vendorfakes/fakegitprovider"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestGetCommitInfo(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	ctx := context.Background()
+
+	scheme, err := kube.CreateScheme()
+	g.Expect(err).To(BeNil())
+
+	k, err := client.New(k8sEnv.Rest, client.Options{
+		Scheme: scheme,
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	ns := newNamespace(ctx, k, g)
+
+	repo := &sourcev1.GitRepository{
+		Spec: sourcev1.GitRepositorySpec{
+			URL:       "https://github.com/commits-owner/config.git",
+			Reference: &sourcev1.GitRepositoryRef{Branch: "main"},
+		},
+	}
+	repo.Name = "config"
+	repo.Namespace = ns.Name
+	g.Expect(k.Create(ctx, repo)).To(Succeed())
+
+	commit := &fakegitprovider.Commit{}
+	commit.GetReturns(gitprovider.CommitInfo{
+		Sha:       "abc123def456",
+		Author:    "jane",
+		Message:   "Bump podinfo to 6.1.0",
+		CreatedAt: time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC),
+		URL:       "https://github.com/commits-owner/config/commit/abc123def456",
+	})
+
+	other := &fakegitprovider.Commit{}
+	other.GetReturns(gitprovider.CommitInfo{Sha: "0123456789ab"})
+
+	provider := &gitprovidersfakes.FakeGitProvider{}
+	provider.GetCommitsReturns([]gitprovider.Commit{other, commit}, nil)
+
+	providers := &gitprovidersfakes.FakeClient{}
+	providers.GetProviderReturns(provider, nil)
+
+	cfg := makeServerConfig(k, t)
+	cfg.GitProviders = providers
+	c := makeServer(cfg, t)
+
+	t.Run("resolves a revision to its commit", func(t *testing.T) {
+		res, err := c.GetCommitInfo(ctx, &pb.GetCommitInfoRequest{
+			GitRepositoryName: repo.Name,
+			Namespace:         repo.Namespace,
+			ClusterName:       clustersmngr.DefaultCluster,
+			Revision:          "main/abc123def456",
+		})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(res.Commit.Revision).To(Equal("main/abc123def456"))
+		g.Expect(res.Commit.Sha).To(Equal("abc123def456"))
+		g.Expect(res.Commit.Message).To(Equal("Bump podinfo to 6.1.0"))
+		g.Expect(res.Commit.Author).To(Equal("jane"))
+		g.Expect(res.Commit.Date).To(Equal("2022-06-01T00:00:00Z"))
+		g.Expect(res.Commit.Url).To(Equal("https://github.com/commits-owner/config/commit/abc123def456"))
+
+		_, _, branch, _, page := provider.GetCommitsArgsForCall(0)
+		g.Expect(branch).To(Equal("main"))
+		g.Expect(page).To(Equal(1))
+	})
+
+	t.Run("caches commits by SHA", func(t *testing.T) {
+		calls := provider.GetCommitsCallCount()
+
+		res, err := c.GetCommitInfo(ctx, &pb.GetCommitInfoRequest{
+			GitRepositoryName: repo.Name,
+			Namespace:         repo.Namespace,
+			ClusterName:       clustersmngr.DefaultCluster,
+			Revision:          "main/abc123def456",
+		})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(res.Commit.Sha).To(Equal("abc123def456"))
+		g.Expect(provider.GetCommitsCallCount()).To(Equal(calls))
+	})
+
+	t.Run("returns not found for unknown commits", func(t *testing.T) {
+		_, err := c.GetCommitInfo(ctx, &pb.GetCommitInfoRequest{
+			GitRepositoryName: repo.Name,
+			Namespace:         repo.Namespace,
+			ClusterName:       clustersmngr.DefaultCluster,
+			Revision:          "main/fedcba",
+		})
+		g.Expect(status.Code(err)).To(Equal(codes.NotFound))
+	})
+
+	t.Run("requires a revision", func(t *testing.T) {
+		_, err := c.GetCommitInfo(ctx, &pb.GetCommitInfoRequest{
+			GitRepositoryName: repo.Name,
+			Namespace:         repo.Namespace,
+			ClusterName:       clustersmngr.DefaultCluster,
+		})
+		g.Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+
+	t.Run("resolves the last applied revision of a Kustomization", func(t *testing.T) {
+		kust := &kustomizev1.Kustomization{
+			Spec: kustomizev1.KustomizationSpec{
+				Path: "./apps",
+				SourceRef: kustomizev1.CrossNamespaceSourceReference{
+					Kind: sourcev1.GitRepositoryKind,
+					Name: repo.Name,
+				},
+			},
+		}
+		kust.Name = "apps"
+		kust.Namespace = ns.Name
+		g.Expect(k.Create(ctx, kust)).To(Succeed())
+
+		kust.Status.LastAppliedRevision = "main/abc123def456"
+		g.Expect(k.Status().Update(ctx, kust)).To(Succeed())
+
+		res, err := c.GetKustomization(ctx, &pb.GetKustomizationRequest{
+			Name:           kust.Name,
+			Namespace:      kust.Namespace,
+			ClusterName:    clustersmngr.DefaultCluster,
+			WithCommitInfo: true,
+		})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(res.Kustomization.LastAppliedCommit.Message).To(Equal("Bump podinfo to 6.1.0"))
+
+		res, err = c.GetKustomization(ctx, &pb.GetKustomizationRequest{
+			Name:        kust.Name,
+			Namespace:   kust.Namespace,
+			ClusterName: clustersmngr.DefaultCluster,
+		})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(res.Kustomization.LastAppliedCommit).To(BeNil())
+	})
+}
+
+func TestGetCommitInfo_notConfigured(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	c, _ := makeGRPCServer(k8sEnv.Rest, t)
+
+	_, err := c.GetCommitInfo(context.Background(), &pb.GetCommitInfoRequest{
+		GitRepositoryName: "config",
+		Revision:          "main/abc123",
+	})
+	g.Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+}
@@ -42,7 +42,9 @@ func (cs *coreServer) ListEvents(ctx context.Context, msg *pb.ListEventsRequest)
 		"involvedObject.namespace": msg.InvolvedObject.Namespace,
 	}
 
-	if err := list(ctx, k8s, temporarilyEmptyAppName, msg.InvolvedObject.Namespace, clist, fields); err != nil {
+	opts := append(tenantListOptions(msg.Tenant), fields)
+
+	if err := list(ctx, k8s, temporarilyEmptyAppName, msg.InvolvedObject.Namespace, clist, opts...); err != nil {
 		return nil, fmt.Errorf("could not get events: %w", err)
 	}
 
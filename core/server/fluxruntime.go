@@ -63,6 +63,10 @@ func (cs *coreServer) ListFluxRuntimeObjects(ctx context.Context, msg *pb.ListFl
 			continue
 		}
 
+		if msg.Tenant != "" && fluxNs.Labels[TenantLabel] != msg.Tenant {
+			continue
+		}
+
 		opts := client.MatchingLabels{
 			coretypes.PartOfLabel: FluxNamespacePartOf,
 		}
@@ -32,7 +32,7 @@ func (cs *coreServer) ListKustomizations(ctx context.Context, msg *pb.ListKustom
 		return &kustomizev1.KustomizationList{}
 	})
 
-	opts := []client.ListOption{}
+	opts := tenantListOptions(msg.Tenant)
 	if msg.Pagination != nil {
 		opts = append(opts, client.Limit(msg.Pagination.PageSize))
 		opts = append(opts, client.Continue(msg.Pagination.PageToken))
@@ -108,5 +108,9 @@ func (cs *coreServer) GetKustomization(ctx context.Context, msg *pb.GetKustomiza
 
 	res.ApiVersion = apiVersion
 
+	if msg.WithCommitInfo {
+		res.LastAppliedCommit = cs.lastAppliedCommit(ctx, clustersClient, msg.ClusterName, res)
+	}
+
 	return &pb.GetKustomizationResponse{Kustomization: res}, nil
 }
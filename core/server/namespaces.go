@@ -0,0 +1,34 @@
+package server
+
+import (
+	"context"
+	"sort"
+
+	"github.com/weaveworks/weave-gitops/core/server/types"
+	pb "github.com/weaveworks/weave-gitops/pkg/api/core"
+	"github.com/weaveworks/weave-gitops/pkg/server/auth"
+)
+
+func (cs *coreServer) ListNamespaces(ctx context.Context, msg *pb.ListNamespacesRequest) (*pb.ListNamespacesResponse, error) {
+	results := []*pb.Namespace{}
+
+	for clusterName, namespaces := range cs.clientsFactory.GetUserNamespaces(auth.Principal(ctx)) {
+		for _, ns := range namespaces {
+			if msg.Tenant != "" && ns.Labels[TenantLabel] != msg.Tenant {
+				continue
+			}
+
+			results = append(results, types.NamespaceToProto(ns, clusterName))
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].ClusterName != results[j].ClusterName {
+			return results[i].ClusterName < results[j].ClusterName
+		}
+
+		return results[i].Name < results[j].Name
+	})
+
+	return &pb.ListNamespacesResponse{Namespaces: results}, nil
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	pb "github.com/weaveworks/weave-gitops/pkg/api/core"
+	"github.com/weaveworks/weave-gitops/pkg/server/auth"
+	authzv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func (cs *coreServer) GetUserPermissions(ctx context.Context, msg *pb.GetUserPermissionsRequest) (*pb.GetUserPermissionsResponse, error) {
+	principal := auth.Principal(ctx)
+
+	res := &pb.GetUserPermissionsResponse{
+		Permissions: []*pb.ObjectPermissions{},
+	}
+
+	if principal != nil {
+		res.PrincipalId = principal.ID
+		res.PrincipalGroups = principal.Groups
+	}
+
+	clients := map[string]client.Client{}
+
+	for _, ref := range msg.Objects {
+		perms := &pb.ObjectPermissions{Object: ref}
+
+		if err := cs.checkObjectPermissions(ctx, principal, clients, ref, perms); err != nil {
+			perms.Error = err.Error()
+		}
+
+		res.Permissions = append(res.Permissions, perms)
+	}
+
+	return res, nil
+}
+
+func (cs *coreServer) checkObjectPermissions(ctx context.Context, principal *auth.UserPrincipal, clients map[string]client.Client, ref *pb.ClusterObjectRef, perms *pb.ObjectPermissions) error {
+	gvk, err := cs.primaryKinds.Lookup(ref.Kind)
+	if err != nil {
+		return err
+	}
+
+	c, ok := clients[ref.ClusterName]
+	if !ok {
+		c, err = cs.impersonatedClusterClient(ctx, principal, ref.ClusterName)
+		if err != nil {
+			return err
+		}
+
+		clients[ref.ClusterName] = c
+	}
+
+	resource, _ := meta.UnsafeGuessKindToResource(*gvk)
+
+	checks := []struct {
+		result *bool
+		attrs  []authzv1.ResourceAttributes
+	}{
+		{
+			result: &perms.CanPatch,
+			attrs: []authzv1.ResourceAttributes{
+				{Verb: "patch", Group: resource.Group, Version: resource.Version, Resource: resource.Resource, Name: ref.Name, Namespace: ref.Namespace},
+			},
+		},
+		{
+			result: &perms.CanReadEvents,
+			attrs: []authzv1.ResourceAttributes{
+				{Verb: "list", Resource: "events", Namespace: ref.Namespace},
+			},
+		},
+		{
+			result: &perms.CanReadPods,
+			attrs: []authzv1.ResourceAttributes{
+				{Verb: "list", Resource: "pods", Namespace: ref.Namespace},
+				{Verb: "get", Resource: "pods", Subresource: "log", Namespace: ref.Namespace},
+			},
+		},
+		{
+			result: &perms.CanReadSecrets,
+			attrs: []authzv1.ResourceAttributes{
+				{Verb: "get", Resource: "secrets", Namespace: ref.Namespace},
+			},
+		},
+	}
+
+	for _, check := range checks {
+		allowed := true
+
+		for i := range check.attrs {
+			ok, err := canI(ctx, c, &check.attrs[i])
+			if err != nil {
+				return err
+			}
+
+			allowed = allowed && ok
+		}
+
+		*check.result = allowed
+	}
+
+	return nil
+}
+
+func (cs *coreServer) impersonatedClusterClient(ctx context.Context, principal *auth.UserPrincipal, clusterName string) (client.Client, error) {
+	clustersClient, err := cs.clientsFactory.GetImpersonatedClientForCluster(ctx, principal, clusterName)
+	if err != nil {
+		return nil, fmt.Errorf("error getting impersonating client: %w", err)
+	}
+
+	c, err := clustersClient.Scoped(clusterName)
+	if err != nil {
+		return nil, fmt.Errorf("getting cluster client: %w", err)
+	}
+
+	return c, nil
+}
+
+// canI asks the API server, as the impersonated user, whether the
+// given action is allowed.
+func canI(ctx context.Context, c client.Client, attrs *authzv1.ResourceAttributes) (bool, error) {
+	review := &authzv1.SelfSubjectAccessReview{
+		Spec: authzv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: attrs,
+		},
+	}
+
+	if err := c.Create(ctx, review); err != nil {
+		return false, fmt.Errorf("creating self subject access review: %w", err)
+	}
+
+	return review.Status.Allowed, nil
+}
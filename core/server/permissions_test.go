@@ -0,0 +1,51 @@
+package server_test
+
+import (
+	"context"
+	"testing"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
+	. "github.com/onsi/gomega"
+	api "github.com/weaveworks/weave-gitops/pkg/api/core"
+)
+
+func TestGetUserPermissions(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	ctx := context.Background()
+
+	c, _ := makeGRPCServer(k8sEnv.Rest, t)
+
+	res, err := c.GetUserPermissions(ctx, &api.GetUserPermissionsRequest{
+		Objects: []*api.ClusterObjectRef{
+			{
+				Kind:        kustomizev1.KustomizationKind,
+				Name:        "my-kustomization",
+				Namespace:   "default",
+				ClusterName: "Default",
+			},
+			{
+				Kind:        "NotAKind",
+				Name:        "foo",
+				Namespace:   "default",
+				ClusterName: "Default",
+			},
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	g.Expect(res.PrincipalId).To(Equal("anne"))
+	g.Expect(res.PrincipalGroups).To(ConsistOf("system:masters"))
+	g.Expect(res.Permissions).To(HaveLen(2))
+
+	perms := res.Permissions[0]
+	g.Expect(perms.Error).To(BeEmpty())
+	g.Expect(perms.Object.Name).To(Equal("my-kustomization"))
+	g.Expect(perms.CanPatch).To(BeTrue())
+	g.Expect(perms.CanReadEvents).To(BeTrue())
+	g.Expect(perms.CanReadPods).To(BeTrue())
+	g.Expect(perms.CanReadSecrets).To(BeTrue())
+
+	g.Expect(res.Permissions[1].Error).To(ContainSubstring("not supported"))
+	g.Expect(res.Permissions[1].CanPatch).To(BeFalse())
+}
@@ -0,0 +1,853 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fluxcd/go-git-providers/gitprovider"
+	helmv2 "github.com/fluxcd/helm-controller/api/v2beta1"
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta2"
+	"github.com/google/uuid"
+	pb "github.com/weaveworks/weave-gitops/pkg/api/core"
+	"github.com/weaveworks/weave-gitops/pkg/git"
+	"github.com/weaveworks/weave-gitops/pkg/gitproviders"
+	"github.com/weaveworks/weave-gitops/pkg/server/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+	authzv1 "k8s.io/api/authorization/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// manifestChange sets fields of the manifest of an object.
+type manifestChange struct {
+	title  string
+	fields []manifestField
+}
+
+// manifestField is a scalar field of a manifest, it's removed if the value is
+// nil.
+type manifestField struct {
+	path  []string
+	value *yaml.Node
+}
+
+func (cs *coreServer) ProposeChange(ctx context.Context, msg *pb.ProposeChangeRequest) (*pb.ProposeChangeResponse, error) {
+	if cs.gitProviders == nil || cs.gitClients == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "git providers are not configured")
+	}
+
+	obj, err := getReconcilableObject(msg.Kind)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "bad request: %s", err.Error())
+	}
+
+	gvk := obj.GroupVersionKind()
+
+	change, err := toManifestChange(msg, gvk.Kind)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "bad request: %s", err.Error())
+	}
+
+	principal := auth.Principal(ctx)
+
+	clustersClient, err := cs.clientsFactory.GetImpersonatedClientForCluster(ctx, principal, msg.ClusterName)
+	if err != nil {
+		return nil, fmt.Errorf("error getting impersonating client: %w", err)
+	}
+
+	c, err := clustersClient.Scoped(msg.ClusterName)
+	if err != nil {
+		return nil, fmt.Errorf("getting cluster client: %w", err)
+	}
+
+	key := client.ObjectKey{Name: msg.Name, Namespace: msg.Namespace}
+	if err := c.Get(ctx, key, obj.AsClientObject()); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, status.Errorf(codes.NotFound, err.Error())
+		}
+
+		return nil, fmt.Errorf("getting object: %w", err)
+	}
+
+	// Proposing a change is like making it, with the server's tokens, so
+	// the user must be allowed to change the object in the cluster.
+	resource, _ := meta.UnsafeGuessKindToResource(gvk)
+
+	allowed, err := canI(ctx, c, &authzv1.ResourceAttributes{
+		Verb:      "patch",
+		Group:     resource.Group,
+		Version:   resource.Version,
+		Resource:  resource.Resource,
+		Name:      msg.Name,
+		Namespace: msg.Namespace,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !allowed {
+		return nil, status.Errorf(codes.PermissionDenied, "%s is not allowed to patch %s %s/%s", principal.ID, gvk.Kind, msg.Namespace, msg.Name)
+	}
+
+	repository, path, err := managingSource(ctx, c, obj.AsClientObject(), gvk.Kind)
+	if err != nil {
+		return nil, err
+	}
+
+	repoUrl, err := cs.newRepoURL(repository.Spec.URL)
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "GitRepository %s/%s: %s", repository.Namespace, repository.Name, err.Error())
+	}
+
+	if repoUrl.Provider() == gitproviders.GitProviderLocal {
+		// The repository would be on the server's filesystem.
+		return nil, status.Errorf(codes.FailedPrecondition, "GitRepository %s/%s: changes can't be proposed to local repositories", repository.Namespace, repository.Name)
+	}
+
+	provider, err := cs.gitProviders.GetProvider(repoUrl, gitproviders.GetAccountType)
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, err.Error())
+	}
+
+	branch := ""
+	if repository.Spec.Reference != nil {
+		branch = repository.Spec.Reference.Branch
+	}
+
+	if branch == "" {
+		branch, err = provider.GetDefaultBranch(ctx, repoUrl)
+		if err != nil {
+			return nil, fmt.Errorf("getting the default branch of %s: %w", repoUrl, err)
+		}
+	}
+
+	manifestPath, content, err := cs.changeManifest(ctx, repoUrl, branch, path, gvk, key, change)
+	if err != nil {
+		return nil, err
+	}
+
+	info := gitproviders.PullRequestInfo{
+		Title:         msg.Title,
+		Description:   msg.Description,
+		CommitMessage: change.title,
+		TargetBranch:  branch,
+		NewBranch:     gitproviders.PullRequestBranchPrefix + uuid.New().String(),
+		Files: []gitprovider.CommitFile{{
+			Path:    &manifestPath,
+			Content: &content,
+		}},
+	}
+
+	if info.Title == "" {
+		info.Title = change.title
+	}
+
+	if info.Description == "" {
+		info.Description = fmt.Sprintf("%s, proposed by %s in cluster %s.", change.title, principal.ID, msg.ClusterName)
+	}
+
+	cs.logger.Info("Proposing change",
+		"user", principal.ID,
+		"kind", gvk.Kind,
+		"name", msg.Name,
+		"namespace", msg.Namespace,
+		"repository", repoUrl.String(),
+		"path", manifestPath,
+	)
+
+	pr, err := provider.CreatePullRequest(ctx, repoUrl, info)
+	if err != nil {
+		return nil, fmt.Errorf("creating pull request: %w", err)
+	}
+
+	prInfo := pr.Get()
+
+	return &pb.ProposeChangeResponse{
+		PullRequest: &pb.PullRequest{
+			RepoUrl:      repoUrl.String(),
+			Number:       int32(prInfo.Number),
+			Title:        info.Title,
+			Description:  info.Description,
+			Url:          prInfo.WebURL,
+			SourceBranch: info.NewBranch,
+			TargetBranch: info.TargetBranch,
+			State:        string(gitproviders.PullRequestStateOpen),
+			Mergeability: mergeabilityUnknown,
+			CreatedAt:    time.Now().Format(time.RFC3339),
+		},
+		Path: manifestPath,
+	}, nil
+}
+
+// managingSource returns the GitRepository and the path in it of the
+// Kustomization that applies the object.
+func managingSource(ctx context.Context, c client.Client, obj client.Object, kind string) (*sourcev1.GitRepository, string, error) {
+	name := obj.GetLabels()[KustomizeNameKey]
+	namespace := obj.GetLabels()[KustomizeNamespaceKey]
+
+	if name == "" || namespace == "" {
+		return nil, "", status.Errorf(codes.FailedPrecondition, "%s %s/%s is not managed by a Kustomization", kind, obj.GetNamespace(), obj.GetName())
+	}
+
+	kustomization := &kustomizev1.Kustomization{}
+	if err := c.Get(ctx, client.ObjectKey{Name: name, Namespace: namespace}, kustomization); err != nil {
+		return nil, "", fmt.Errorf("getting Kustomization %s/%s: %w", namespace, name, err)
+	}
+
+	sourceRef := kustomization.Spec.SourceRef
+	if sourceRef.Kind != sourcev1.GitRepositoryKind {
+		return nil, "", status.Errorf(codes.FailedPrecondition, "Kustomization %s/%s applies a %s, changes can only be proposed to a GitRepository", namespace, name, sourceRef.Kind)
+	}
+
+	if sourceRef.Namespace == "" {
+		sourceRef.Namespace = namespace
+	}
+
+	repository := &sourcev1.GitRepository{}
+	if err := c.Get(ctx, client.ObjectKey{Name: sourceRef.Name, Namespace: sourceRef.Namespace}, repository); err != nil {
+		return nil, "", fmt.Errorf("getting GitRepository %s/%s: %w", sourceRef.Namespace, sourceRef.Name, err)
+	}
+
+	return repository, kustomization.Spec.Path, nil
+}
+
+// changeManifest changes the manifest of the object found in the directory
+// of the repository, and returns its path and changed content.
+func (cs *coreServer) changeManifest(ctx context.Context, repoUrl gitproviders.RepoURL, branch, dir string, gvk schema.GroupVersionKind, key client.ObjectKey, change manifestChange) (string, string, error) {
+	dir = strings.Trim(filepath.ToSlash(filepath.Clean(dir)), "/")
+	if dir == "." {
+		dir = ""
+	}
+
+	// The path must not lead out of the clone.
+	if dir == ".." || strings.HasPrefix(dir, "../") {
+		return "", "", status.Errorf(codes.FailedPrecondition, "the Kustomization's path %q is outside of its repository", dir)
+	}
+
+	gitClient, err := cs.gitClients(repoUrl)
+	if err != nil {
+		return "", "", status.Errorf(codes.FailedPrecondition, err.Error())
+	}
+
+	opts := []git.CloneOption{git.WithDepth(1)}
+	if dir != "" {
+		opts = append(opts, git.WithSparsePaths(dir))
+	}
+
+	repoDir, err := os.MkdirTemp("", "propose-change-")
+	if err != nil {
+		return "", "", fmt.Errorf("failed creating temp. directory to clone repo: %w", err)
+	}
+	defer os.RemoveAll(repoDir)
+
+	if _, err := gitClient.Clone(ctx, repoDir, cloneURL(repoUrl), branch, opts...); err != nil {
+		return "", "", fmt.Errorf("failed cloning repo: %s: %w", repoUrl, err)
+	}
+
+	path, index, err := findManifest(repoDir, dir, gvk, key)
+	if err != nil {
+		return "", "", err
+	}
+
+	data, err := os.ReadFile(filepath.Join(repoDir, filepath.FromSlash(path)))
+	if err != nil {
+		return "", "", fmt.Errorf("reading manifest: %w", err)
+	}
+
+	before := string(data)
+	after := before
+
+	for _, f := range change.fields {
+		if after, err = changeManifestField(after, index, f); err != nil {
+			return "", "", status.Errorf(codes.FailedPrecondition, "changing %s in %s: %s", strings.Join(f.path, "."), path, err.Error())
+		}
+	}
+
+	if before == after {
+		return "", "", status.Errorf(codes.FailedPrecondition, "the manifest in %s already has the change", path)
+	}
+
+	return path, after, nil
+}
+
+// cloneURL returns the HTTPS URL of the repository, as the git clients
+// authenticate with the tokens of the git providers.
+func cloneURL(repoUrl gitproviders.RepoURL) string {
+	u := repoUrl.URL()
+	path := u.Path
+
+	if repoUrl.Provider() == gitproviders.GitProviderBitbucketServer {
+		path = "/scm" + path
+	}
+
+	return fmt.Sprintf("https://%s%s", u.Hostname(), path)
+}
+
+// findManifest finds the manifest of the object in the YAML files of the
+// directory, and returns its file and the index of its document in it.
+// Manifests without a namespace match objects of any namespace, as the
+// Kustomization may set it.
+func findManifest(repoDir, dir string, gvk schema.GroupVersionKind, key client.ObjectKey) (string, int, error) {
+	type match struct {
+		path  string
+		index int
+	}
+
+	var exact, other []match
+
+	err := filepath.WalkDir(filepath.Join(repoDir, dir), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if ext := filepath.Ext(path); ext != ".yaml" && ext != ".yml" {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		docs, err := decodeManifests(data)
+		if err != nil {
+			// Files that aren't valid YAML can't have the manifest.
+			return nil
+		}
+
+		rel, err := filepath.Rel(repoDir, path)
+		if err != nil {
+			return err
+		}
+
+		for i, doc := range docs {
+			namespace, ok := manifestMatches(doc, gvk, key.Name)
+			if !ok {
+				continue
+			}
+
+			m := match{path: filepath.ToSlash(rel), index: i}
+
+			switch namespace {
+			case key.Namespace:
+				exact = append(exact, m)
+			case "":
+				other = append(other, m)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", 0, fmt.Errorf("finding manifest: %w", err)
+	}
+
+	matches := exact
+	if len(matches) == 0 {
+		matches = other
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", 0, status.Errorf(codes.NotFound, "no manifest of %s %s/%s found in %q", gvk.Kind, key.Namespace, key.Name, dir)
+	case 1:
+		return matches[0].path, matches[0].index, nil
+	default:
+		return "", 0, status.Errorf(codes.FailedPrecondition, "found %d manifests of %s %s/%s in %q", len(matches), gvk.Kind, key.Namespace, key.Name, dir)
+	}
+}
+
+// manifestMatches returns whether the document is a manifest of an object of
+// the kind and name, and its namespace.
+func manifestMatches(doc *yaml.Node, gvk schema.GroupVersionKind, name string) (string, bool) {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return "", false
+	}
+
+	root := doc.Content[0]
+	metadata := mappingValue(root, "metadata")
+
+	apiVersion, err := schema.ParseGroupVersion(scalarValue(mappingValue(root, "apiVersion")))
+	if err != nil || apiVersion.Group != gvk.Group {
+		return "", false
+	}
+
+	if scalarValue(mappingValue(root, "kind")) != gvk.Kind || scalarValue(mappingValue(metadata, "name")) != name {
+		return "", false
+	}
+
+	return scalarValue(mappingValue(metadata, "namespace")), true
+}
+
+func decodeManifests(data []byte) ([]*yaml.Node, error) {
+	docs := []*yaml.Node{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+
+	for {
+		doc := &yaml.Node{}
+		if err := dec.Decode(doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				return docs, nil
+			}
+
+			return nil, err
+		}
+
+		docs = append(docs, doc)
+	}
+}
+
+// changeManifestField sets a field of the manifest in the document of the
+// file content. The lines of the field are changed in place, so that the
+// rest of the file is kept as it is, unless the manifest uses flow style or
+// multi-line values, which are encoded again.
+func changeManifestField(content string, index int, f manifestField) (string, error) {
+	docs, err := decodeManifests([]byte(content))
+	if err != nil {
+		return "", fmt.Errorf("decoding manifest: %w", err)
+	}
+
+	if index >= len(docs) {
+		return "", fmt.Errorf("the manifest has no document %d", index)
+	}
+
+	root := docs[index].Content[0]
+
+	lines := strings.SplitAfter(content, "\n")
+
+	if changed, ok := changeManifestLines(lines, root, f); ok {
+		return strings.Join(changed, ""), nil
+	}
+
+	if err := setManifestField(root, f.path, f.value); err != nil {
+		return "", err
+	}
+
+	// Only the changed document is re-encoded, the others are left as they
+	// are.
+	encoded, err := encodeManifests([]*yaml.Node{docs[index]})
+	if err != nil {
+		return "", err
+	}
+
+	start, end := manifestDocumentLines(lines, root.Line-1)
+
+	return strings.Join(lines[:start], "") + encoded + strings.Join(lines[end:], ""), nil
+}
+
+// manifestDocumentLines returns the range of the lines of the document
+// containing the given line, between the "---" separators around it.
+func manifestDocumentLines(lines []string, line int) (int, int) {
+	start, end := 0, len(lines)
+
+	for i := line; i >= 0 && i < len(lines); i-- {
+		if isDocumentSeparator(lines[i]) {
+			start = i + 1
+			break
+		}
+	}
+
+	for i := line + 1; i < len(lines); i++ {
+		if isDocumentSeparator(lines[i]) {
+			end = i
+			break
+		}
+	}
+
+	return start, end
+}
+
+func isDocumentSeparator(line string) bool {
+	return strings.TrimRight(line, " \t\r\n") == "---"
+}
+
+// changeManifestLines changes the lines of a field in a block style mapping,
+// it returns false if that's not possible.
+func changeManifestLines(lines []string, node *yaml.Node, f manifestField) ([]string, bool) {
+	// New maps are indented like the existing ones.
+	step := 2
+
+	for i, key := range f.path {
+		if node.Kind != yaml.MappingNode || node.Style&yaml.FlowStyle != 0 || len(node.Content) == 0 {
+			return nil, false
+		}
+
+		var keyNode, value *yaml.Node
+
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			if node.Content[j].Value == key {
+				keyNode, value = node.Content[j], node.Content[j+1]
+				break
+			}
+		}
+
+		switch {
+		case value == nil && f.value == nil:
+			return lines, true
+		case value == nil:
+			return insertManifestLines(lines, node, f.path[i:], f.value, step)
+		case i < len(f.path)-1:
+			if value.Kind == yaml.MappingNode && len(value.Content) > 0 && value.Content[0].Column > keyNode.Column {
+				step = value.Content[0].Column - keyNode.Column
+			}
+
+			node = value
+
+			continue
+		case value.Kind != yaml.ScalarNode || value.Line != keyNode.Line:
+			return nil, false
+		case f.value == nil:
+			line := lines[keyNode.Line-1]
+			if strings.TrimSpace(line[:keyNode.Column-1]) != "" {
+				// The key follows the dash of a list item.
+				return nil, false
+			}
+
+			return append(lines[:keyNode.Line-1:keyNode.Line-1], lines[keyNode.Line:]...), true
+		default:
+			return replaceManifestScalar(lines, value, f.value)
+		}
+	}
+
+	return nil, false
+}
+
+// replaceManifestScalar replaces a scalar on a single line, keeping its
+// quotes and the comment after it.
+func replaceManifestScalar(lines []string, current, value *yaml.Node) ([]string, bool) {
+	line := lines[current.Line-1]
+	start := current.Column - 1
+	end := -1
+
+	if current.Value == "" || start >= len(line) {
+		return nil, false
+	}
+
+	switch current.Style {
+	case 0:
+		if strings.HasPrefix(line[start:], current.Value) {
+			end = start + len(current.Value)
+		}
+	case yaml.DoubleQuotedStyle, yaml.SingleQuotedStyle:
+		end = closingQuote(line, start)
+	}
+
+	if end < 0 {
+		return nil, false
+	}
+
+	replacement := *value
+	if current.Tag == value.Tag {
+		replacement.Style = current.Style
+	}
+
+	rendered, ok := renderManifestScalar(&replacement)
+	if !ok {
+		return nil, false
+	}
+
+	lines[current.Line-1] = line[:start] + rendered + line[end:]
+
+	return lines, true
+}
+
+// closingQuote returns the end of the quoted scalar starting at start, or -1.
+func closingQuote(line string, start int) int {
+	quote := line[start]
+	if quote != '"' && quote != '\'' {
+		return -1
+	}
+
+	for i := start + 1; i < len(line); i++ {
+		switch {
+		case quote == '"' && line[i] == '\\':
+			i++
+		case line[i] == quote && quote == '\'' && i+1 < len(line) && line[i+1] == '\'':
+			i++
+		case line[i] == quote:
+			return i + 1
+		}
+	}
+
+	return -1
+}
+
+// insertManifestLines adds the missing keys of the path after the last entry
+// of the mapping, with the indentation of its keys.
+func insertManifestLines(lines []string, node *yaml.Node, path []string, value *yaml.Node, step int) ([]string, bool) {
+	rendered, ok := renderManifestScalar(value)
+	if !ok {
+		return nil, false
+	}
+
+	indent := node.Content[0].Column - 1
+	end := node.Content[len(node.Content)-2].Line
+
+	for i := end; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		lineIndent := len(lines[i]) - len(strings.TrimLeft(lines[i], " "))
+		if lineIndent < indent || (lineIndent == indent && !strings.HasPrefix(trimmed, "- ")) {
+			break
+		}
+
+		end = i + 1
+	}
+
+	added := []string{}
+
+	for i, key := range path {
+		line := strings.Repeat(" ", indent+step*i) + key + ":"
+		if i == len(path)-1 {
+			line += " " + rendered
+		}
+
+		added = append(added, line+"\n")
+	}
+
+	if !strings.HasSuffix(lines[end-1], "\n") {
+		lines[end-1] += "\n"
+	}
+
+	res := append([]string{}, lines[:end]...)
+	res = append(res, added...)
+
+	return append(res, lines[end:]...), true
+}
+
+// renderManifestScalar encodes a scalar, quoting it if needed, e.g. for
+// strings that look like numbers.
+func renderManifestScalar(value *yaml.Node) (string, bool) {
+	out, err := yaml.Marshal(value)
+	if err != nil {
+		return "", false
+	}
+
+	rendered := strings.TrimSuffix(string(out), "\n")
+
+	return rendered, !strings.Contains(rendered, "\n")
+}
+
+// encodeManifests encodes the documents keeping their comments.
+func encodeManifests(docs []*yaml.Node) (string, error) {
+	buf := &bytes.Buffer{}
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2)
+
+	for _, doc := range docs {
+		if err := enc.Encode(doc); err != nil {
+			return "", fmt.Errorf("encoding manifest: %w", err)
+		}
+	}
+
+	if err := enc.Close(); err != nil {
+		return "", fmt.Errorf("encoding manifest: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+func scalarValue(node *yaml.Node) string {
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return ""
+	}
+
+	return node.Value
+}
+
+// setManifestField sets a scalar field, creating the maps on its path, or
+// removes it if the value is nil. The comments of the field are kept.
+func setManifestField(node *yaml.Node, path []string, value *yaml.Node) error {
+	for _, key := range path[:len(path)-1] {
+		next := mappingValue(node, key)
+		if next == nil {
+			if value == nil {
+				return nil
+			}
+
+			next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			node.Content = append(node.Content, stringNode(key), next)
+		}
+
+		if next.Kind != yaml.MappingNode {
+			return fmt.Errorf("%s is not a map", key)
+		}
+
+		node = next
+	}
+
+	last := path[len(path)-1]
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != last {
+			continue
+		}
+
+		if value == nil {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return nil
+		}
+
+		current := node.Content[i+1]
+		if current.Kind != yaml.ScalarNode {
+			return fmt.Errorf("%s is not a scalar", last)
+		}
+
+		if current.Tag != value.Tag {
+			current.Style = 0
+		}
+
+		current.Tag = value.Tag
+		current.Value = value.Value
+
+		return nil
+	}
+
+	if value != nil {
+		node.Content = append(node.Content, stringNode(last), value)
+	}
+
+	return nil
+}
+
+func stringNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+func toManifestChange(msg *pb.ProposeChangeRequest, kind string) (manifestChange, error) {
+	object := fmt.Sprintf("%s %s/%s", kind, msg.Namespace, msg.Name)
+
+	switch change := msg.Change.(type) {
+	case *pb.ProposeChangeRequest_Suspend:
+		title := "Resume " + object
+		if change.Suspend {
+			title = "Suspend " + object
+		}
+
+		return manifestChange{
+			title: title,
+			fields: []manifestField{{
+				path:  []string{"spec", "suspend"},
+				value: &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(change.Suspend)},
+			}},
+		}, nil
+
+	case *pb.ProposeChangeRequest_Interval:
+		if _, err := time.ParseDuration(change.Interval); err != nil {
+			return manifestChange{}, fmt.Errorf("invalid interval %q: %w", change.Interval, err)
+		}
+
+		return manifestChange{
+			title: fmt.Sprintf("Set the interval of %s to %s", object, change.Interval),
+			fields: []manifestField{{
+				path:  []string{"spec", "interval"},
+				value: stringNode(change.Interval),
+			}},
+		}, nil
+
+	case *pb.ProposeChangeRequest_ChartVersion:
+		if change.ChartVersion == "" {
+			return manifestChange{}, errors.New("the chart version is required")
+		}
+
+		var path []string
+
+		switch kind {
+		case helmv2.HelmReleaseKind:
+			path = []string{"spec", "chart", "spec", "version"}
+		case sourcev1.HelmChartKind:
+			path = []string{"spec", "version"}
+		default:
+			return manifestChange{}, fmt.Errorf("a %s has no chart version", kind)
+		}
+
+		return manifestChange{
+			title:  fmt.Sprintf("Set the chart version of %s to %s", object, change.ChartVersion),
+			fields: []manifestField{{path: path, value: stringNode(change.ChartVersion)}},
+		}, nil
+
+	case *pb.ProposeChangeRequest_SourceRef:
+		ref := change.SourceRef
+		if ref == nil || ref.Kind == "" || ref.Name == "" {
+			return manifestChange{}, errors.New("the kind and name of the source are required")
+		}
+
+		var path []string
+
+		switch kind {
+		case kustomizev1.KustomizationKind:
+			path = []string{"spec", "sourceRef"}
+		case helmv2.HelmReleaseKind:
+			path = []string{"spec", "chart", "spec", "sourceRef"}
+		case sourcev1.HelmChartKind:
+			if ref.Namespace != "" {
+				return manifestChange{}, errors.New("the source of a HelmChart must be in its namespace")
+			}
+
+			path = []string{"spec", "sourceRef"}
+		default:
+			return manifestChange{}, fmt.Errorf("a %s has no source", kind)
+		}
+
+		var namespace *yaml.Node
+		if ref.Namespace != "" {
+			namespace = stringNode(ref.Namespace)
+		}
+
+		source := fmt.Sprintf("%s %s", ref.Kind, ref.Name)
+		if ref.Namespace != "" {
+			source = fmt.Sprintf("%s %s/%s", ref.Kind, ref.Namespace, ref.Name)
+		}
+
+		return manifestChange{
+			title: fmt.Sprintf("Set the source of %s to %s", object, source),
+			fields: []manifestField{
+				{path: append(path[:len(path):len(path)], "kind"), value: stringNode(ref.Kind)},
+				{path: append(path[:len(path):len(path)], "name"), value: stringNode(ref.Name)},
+				{path: append(path[:len(path):len(path)], "namespace"), value: namespace},
+			},
+		}, nil
+
+	default:
+		return manifestChange{}, errors.New("a change is required")
+	}
+}
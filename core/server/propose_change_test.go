@@ -0,0 +1,221 @@
+package server_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fluxcd/go-git-providers/gitprovider"
+	helmv2 "github.com/fluxcd/helm-controller/api/v2beta1"
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta2"
+	. "github.com/onsi/gomega"
+	"github.com/weaveworks/weave-gitops/core/server"
+	pb "github.com/weaveworks/weave-gitops/pkg/api/core"
+	"github.com/weaveworks/weave-gitops/pkg/git"
+	"github.com/weaveworks/weave-gitops/pkg/git/gitfakes"
+	"github.com/weaveworks/weave-gitops/pkg/gitproviders"
+	"github.com/weaveworks/weave-gitops/pkg/gitproviders/gitprovidersfakes"
+	"github.com/weaveworks/weave-gitops/pkg/kube"
+	"github.com/weaveworks/weave-gitops/pkg/vendorfakes/fakegitprovider"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const podinfoRelease = `# podinfo is the demo app
+apiVersion: helm.toolkit.fluxcd.io/v2beta1
+kind: HelmRelease
+metadata:
+  name: podinfo
+spec:
+  interval: 5m
+  chart:
+    spec:
+      chart: podinfo
+      version: 6.0.0 # pinned until 6.1 is tested
+      sourceRef:
+        kind: HelmRepository
+        name: podinfo
+`
+
+func TestProposeChange(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	ctx := context.Background()
+
+	scheme, err := kube.CreateScheme()
+	g.Expect(err).To(BeNil())
+
+	k, err := client.New(k8sEnv.Rest, client.Options{
+		Scheme: scheme,
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	ns := newNamespace(ctx, k, g)
+
+	repo := &sourcev1.GitRepository{
+		Spec: sourcev1.GitRepositorySpec{
+			URL:       "ssh://git@github.com/propose-change-owner/config.git",
+			Reference: &sourcev1.GitRepositoryRef{Branch: "main"},
+		},
+	}
+	repo.Name = "config"
+	repo.Namespace = ns.Name
+	g.Expect(k.Create(ctx, repo)).To(Succeed())
+
+	kust := &kustomizev1.Kustomization{
+		Spec: kustomizev1.KustomizationSpec{
+			Path: "./apps",
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Kind: sourcev1.GitRepositoryKind,
+				Name: repo.Name,
+			},
+		},
+	}
+	kust.Name = "apps"
+	kust.Namespace = ns.Name
+	g.Expect(k.Create(ctx, kust)).To(Succeed())
+
+	release := &helmv2.HelmRelease{
+		Spec: helmv2.HelmReleaseSpec{
+			Chart: helmv2.HelmChartTemplate{
+				Spec: helmv2.HelmChartTemplateSpec{
+					Chart: "podinfo",
+					SourceRef: helmv2.CrossNamespaceObjectReference{
+						Kind: sourcev1.HelmRepositoryKind,
+						Name: "podinfo",
+					},
+				},
+			},
+		},
+	}
+	release.Name = "podinfo"
+	release.Namespace = ns.Name
+	release.Labels = map[string]string{
+		server.KustomizeNameKey:      kust.Name,
+		server.KustomizeNamespaceKey: kust.Namespace,
+	}
+	g.Expect(k.Create(ctx, release)).To(Succeed())
+
+	gitClient := &gitfakes.FakeGit{}
+	gitClient.CloneStub = func(_ context.Context, path, _, _ string, _ ...git.CloneOption) (bool, error) {
+		dir := filepath.Join(path, "apps", "podinfo")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return false, err
+		}
+
+		return true, os.WriteFile(filepath.Join(dir, "release.yaml"), []byte(podinfoRelease), 0644)
+	}
+
+	fakePR := &fakegitprovider.PullRequest{}
+	fakePR.GetReturns(gitprovider.PullRequestInfo{Number: 3, WebURL: "https://github.com/propose-change-owner/config/pull/3"})
+
+	provider := &gitprovidersfakes.FakeGitProvider{}
+	provider.CreatePullRequestReturns(fakePR, nil)
+
+	providers := &gitprovidersfakes.FakeClient{}
+	providers.GetProviderReturns(provider, nil)
+
+	cfg := makeServerConfig(k, t)
+	cfg.GitProviders = providers
+	cfg.GitClients = func(gitproviders.RepoURL) (git.Git, error) {
+		return gitClient, nil
+	}
+	c := makeServer(cfg, t)
+
+	res, err := c.ProposeChange(ctx, &pb.ProposeChangeRequest{
+		Kind:        pb.FluxObjectKind_KindHelmRelease,
+		Name:        release.Name,
+		Namespace:   release.Namespace,
+		ClusterName: "Default",
+		Change:      &pb.ProposeChangeRequest_ChartVersion{ChartVersion: "6.1.0"},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(res.Path).To(Equal("apps/podinfo/release.yaml"))
+	g.Expect(res.PullRequest.Number).To(Equal(int32(3)))
+	g.Expect(res.PullRequest.TargetBranch).To(Equal("main"))
+
+	_, _, url, branch, _ := gitClient.CloneArgsForCall(0)
+	g.Expect(url).To(Equal("https://github.com/propose-change-owner/config.git"))
+	g.Expect(branch).To(Equal("main"))
+
+	_, _, info := provider.CreatePullRequestArgsForCall(0)
+	g.Expect(info.NewBranch).To(HavePrefix(gitproviders.PullRequestBranchPrefix))
+	g.Expect(info.Files).To(HaveLen(1))
+	g.Expect(*info.Files[0].Content).To(Equal(strings.Replace(podinfoRelease, "version: 6.0.0", "version: 6.1.0", 1)))
+
+	_, err = c.ProposeChange(ctx, &pb.ProposeChangeRequest{
+		Kind:        pb.FluxObjectKind_KindHelmRelease,
+		Name:        release.Name,
+		Namespace:   release.Namespace,
+		ClusterName: "Default",
+		Change:      &pb.ProposeChangeRequest_Interval{Interval: "5m"},
+	})
+	g.Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+
+	_, err = c.ProposeChange(ctx, &pb.ProposeChangeRequest{
+		Kind:        pb.FluxObjectKind_KindKustomization,
+		Name:        kust.Name,
+		Namespace:   kust.Namespace,
+		ClusterName: "Default",
+		Change:      &pb.ProposeChangeRequest_ChartVersion{ChartVersion: "6.1.0"},
+	})
+	g.Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+
+	_, err = c.ProposeChange(ctx, &pb.ProposeChangeRequest{
+		Kind:        pb.FluxObjectKind_KindKustomization,
+		Name:        kust.Name,
+		Namespace:   kust.Namespace,
+		ClusterName: "Default",
+		Change:      &pb.ProposeChangeRequest_Suspend{Suspend: true},
+	})
+	g.Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+
+	clones := gitClient.CloneCallCount()
+
+	kust.Spec.Path = "./apps/../../.."
+	g.Expect(k.Update(ctx, kust)).To(Succeed())
+
+	_, err = c.ProposeChange(ctx, &pb.ProposeChangeRequest{
+		Kind:        pb.FluxObjectKind_KindHelmRelease,
+		Name:        release.Name,
+		Namespace:   release.Namespace,
+		ClusterName: "Default",
+		Change:      &pb.ProposeChangeRequest_ChartVersion{ChartVersion: "6.1.0"},
+	})
+	g.Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+	g.Expect(err).To(MatchError(ContainSubstring("outside of its repository")))
+	g.Expect(gitClient.CloneCallCount()).To(Equal(clones))
+
+	kust.Spec.Path = "./apps"
+	g.Expect(k.Update(ctx, kust)).To(Succeed())
+
+	repo.Spec.URL = "file:///tmp/config"
+	g.Expect(k.Update(ctx, repo)).To(Succeed())
+
+	_, err = c.ProposeChange(ctx, &pb.ProposeChangeRequest{
+		Kind:        pb.FluxObjectKind_KindHelmRelease,
+		Name:        release.Name,
+		Namespace:   release.Namespace,
+		ClusterName: "Default",
+		Change:      &pb.ProposeChangeRequest_ChartVersion{ChartVersion: "6.1.0"},
+	})
+	g.Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+	g.Expect(gitClient.CloneCallCount()).To(Equal(clones))
+}
+
+func TestProposeChange_notConfigured(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	c, _ := makeGRPCServer(k8sEnv.Rest, t)
+
+	_, err := c.ProposeChange(context.Background(), &pb.ProposeChangeRequest{
+		Kind:   pb.FluxObjectKind_KindKustomization,
+		Name:   "apps",
+		Change: &pb.ProposeChangeRequest_Suspend{Suspend: true},
+	})
+	g.Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+}
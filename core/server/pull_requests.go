@@ -0,0 +1,247 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/fluxcd/go-git-providers/gitprovider"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta2"
+	"github.com/hashicorp/go-multierror"
+	"github.com/weaveworks/weave-gitops/core/clustersmngr"
+	pb "github.com/weaveworks/weave-gitops/pkg/api/core"
+	"github.com/weaveworks/weave-gitops/pkg/gitproviders"
+	"github.com/weaveworks/weave-gitops/pkg/server/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	mergeabilityMergeable   = "mergeable"
+	mergeabilityConflicting = "conflicting"
+	mergeabilityUnknown     = "unknown"
+)
+
+func (cs *coreServer) ListPullRequests(ctx context.Context, msg *pb.ListPullRequestsRequest) (*pb.ListPullRequestsResponse, error) {
+	if cs.gitProviders == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "git providers are not configured")
+	}
+
+	state, err := toPullRequestState(msg.State)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	}
+
+	repoUrls, respErrors, err := cs.visibleRepoURLs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if msg.RepoUrl != "" {
+		repoUrl, err := cs.visibleRepoURL(repoUrls, msg.RepoUrl)
+		if err != nil {
+			return nil, err
+		}
+
+		repoUrls = []gitproviders.RepoURL{repoUrl}
+	}
+
+	filter := gitproviders.PullRequestFilter{BranchPrefix: msg.BranchPrefix, Label: msg.Label}
+	if filter.BranchPrefix == "" && filter.Label == "" {
+		filter = gitproviders.DefaultPullRequestFilter()
+	}
+
+	results := []*pb.PullRequest{}
+
+	for _, repoUrl := range repoUrls {
+		prs, err := cs.listRepoPullRequests(ctx, repoUrl, state, filter)
+		if err != nil {
+			cs.logger.Error(err, "failed to list pull requests", "repoUrl", repoUrl.String())
+			respErrors = append(respErrors, &pb.ListError{Message: fmt.Sprintf("%s: %s", repoUrl, err)})
+
+			continue
+		}
+
+		for _, pr := range prs {
+			results = append(results, pullRequestToProto(repoUrl, pr))
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].CreatedAt > results[j].CreatedAt
+	})
+
+	return &pb.ListPullRequestsResponse{
+		PullRequests: results,
+		Errors:       respErrors,
+	}, nil
+}
+
+func (cs *coreServer) GetPullRequest(ctx context.Context, msg *pb.GetPullRequestRequest) (*pb.GetPullRequestResponse, error) {
+	if cs.gitProviders == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "git providers are not configured")
+	}
+
+	if msg.RepoUrl == "" || msg.Number <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "bad request: a repository URL and a pull request number are required")
+	}
+
+	repoUrls, _, err := cs.visibleRepoURLs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	repoUrl, err := cs.visibleRepoURL(repoUrls, msg.RepoUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := cs.gitProviders.GetProvider(repoUrl, gitproviders.GetAccountType)
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, err.Error())
+	}
+
+	pr, err := provider.GetPullRequest(ctx, repoUrl, int(msg.Number))
+	if err != nil {
+		if errors.Is(err, gitprovider.ErrNotFound) {
+			return nil, status.Errorf(codes.NotFound, "pull request %d not found in %s", msg.Number, repoUrl)
+		}
+
+		return nil, status.Errorf(codes.Internal, "getting pull request %d: %s", msg.Number, err.Error())
+	}
+
+	return &pb.GetPullRequestResponse{
+		PullRequest: pullRequestToProto(repoUrl, *pr),
+	}, nil
+}
+
+func (cs *coreServer) listRepoPullRequests(ctx context.Context, repoUrl gitproviders.RepoURL, state gitproviders.PullRequestState, filter gitproviders.PullRequestFilter) ([]gitproviders.PullRequest, error) {
+	provider, err := cs.gitProviders.GetProvider(repoUrl, gitproviders.GetAccountType)
+	if err != nil {
+		return nil, err
+	}
+
+	return provider.ListPullRequests(ctx, repoUrl, state, filter)
+}
+
+// newRepoURL parses a repository URL with the custom domains of the git
+// providers the server is configured with.
+func (cs *coreServer) newRepoURL(url string) (gitproviders.RepoURL, error) {
+	return gitproviders.NewRepoURLWithHostTypes(url, cs.gitHostTypes)
+}
+
+// visibleRepoURLs returns the URLs of the GitRepositories the user can see,
+// as the server's tokens must only be used for the repositories the user
+// has access to through the clusters.
+func (cs *coreServer) visibleRepoURLs(ctx context.Context) ([]gitproviders.RepoURL, []*pb.ListError, error) {
+	respErrors := []*pb.ListError{}
+
+	clustersClient, err := cs.clientsFactory.GetImpersonatedClient(ctx, auth.Principal(ctx))
+	if err != nil {
+		if merr, ok := err.(*multierror.Error); ok {
+			for _, err := range merr.Errors {
+				if cerr, ok := err.(*clustersmngr.ClientError); ok {
+					respErrors = append(respErrors, &pb.ListError{ClusterName: cerr.ClusterName, Message: cerr.Error()})
+				}
+			}
+		}
+	}
+
+	clist := clustersmngr.NewClusteredList(func() client.ObjectList {
+		return &sourcev1.GitRepositoryList{}
+	})
+
+	if err := clustersClient.ClusteredList(ctx, clist, true); err != nil {
+		var errs clustersmngr.ClusteredListError
+		if !errors.As(err, &errs) {
+			return nil, nil, err
+		}
+
+		for _, e := range errs.Errors {
+			respErrors = append(respErrors, &pb.ListError{ClusterName: e.Cluster, Namespace: e.Namespace, Message: e.Err.Error()})
+		}
+	}
+
+	seen := map[string]bool{}
+	repoUrls := []gitproviders.RepoURL{}
+
+	for _, lists := range clist.Lists() {
+		for _, l := range lists {
+			list, ok := l.(*sourcev1.GitRepositoryList)
+			if !ok {
+				continue
+			}
+
+			for _, repository := range list.Items {
+				repoUrl, err := cs.newRepoURL(repository.Spec.URL)
+				if err != nil {
+					// Repositories on unknown hosts have no pull requests to list.
+					continue
+				}
+
+				if repoUrl.Provider() == gitproviders.GitProviderLocal || seen[repoUrl.String()] {
+					continue
+				}
+
+				seen[repoUrl.String()] = true
+				repoUrls = append(repoUrls, repoUrl)
+			}
+		}
+	}
+
+	return repoUrls, respErrors, nil
+}
+
+func (cs *coreServer) visibleRepoURL(repoUrls []gitproviders.RepoURL, url string) (gitproviders.RepoURL, error) {
+	repoUrl, err := cs.newRepoURL(url)
+	if err != nil {
+		return gitproviders.RepoURL{}, status.Errorf(codes.InvalidArgument, "bad request: %s", err.Error())
+	}
+
+	for _, u := range repoUrls {
+		if u.String() == repoUrl.String() {
+			return u, nil
+		}
+	}
+
+	return gitproviders.RepoURL{}, status.Errorf(codes.NotFound, "no GitRepository found for %s", url)
+}
+
+func toPullRequestState(state string) (gitproviders.PullRequestState, error) {
+	switch s := gitproviders.PullRequestState(state); s {
+	case "", gitproviders.PullRequestStateOpen, gitproviders.PullRequestStateMerged, gitproviders.PullRequestStateClosed:
+		return s, nil
+	default:
+		return "", fmt.Errorf("bad request: unknown pull request state %q", state)
+	}
+}
+
+func pullRequestToProto(repoUrl gitproviders.RepoURL, pr gitproviders.PullRequest) *pb.PullRequest {
+	mergeability := mergeabilityUnknown
+
+	if pr.Mergeable != nil {
+		if *pr.Mergeable {
+			mergeability = mergeabilityMergeable
+		} else {
+			mergeability = mergeabilityConflicting
+		}
+	}
+
+	return &pb.PullRequest{
+		RepoUrl:      repoUrl.String(),
+		Number:       int32(pr.Number),
+		Title:        pr.Title,
+		Description:  pr.Description,
+		Url:          pr.WebURL,
+		SourceBranch: pr.SourceBranch,
+		TargetBranch: pr.TargetBranch,
+		Labels:       pr.Labels,
+		State:        string(pr.State),
+		Mergeability: mergeability,
+		CiStatus:     string(pr.CIStatus),
+		CreatedAt:    pr.CreatedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,98 @@
+package server_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fluxcd/go-git-providers/gitprovider"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta2"
+	. "github.com/onsi/gomega"
+	pb "github.com/weaveworks/weave-gitops/pkg/api/core"
+	"github.com/weaveworks/weave-gitops/pkg/gitproviders"
+	"github.com/weaveworks/weave-gitops/pkg/gitproviders/gitprovidersfakes"
+	"github.com/weaveworks/weave-gitops/pkg/kube"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestListPullRequests(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	ctx := context.Background()
+
+	scheme, err := kube.CreateScheme()
+	g.Expect(err).To(BeNil())
+
+	k, err := client.New(k8sEnv.Rest, client.Options{
+		Scheme: scheme,
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	ns := newNamespace(ctx, k, g)
+	repo := &sourcev1.GitRepository{
+		Spec: sourcev1.GitRepositorySpec{
+			URL:       "https://github.com/pull-requests-owner/config.git",
+			Reference: &sourcev1.GitRepositoryRef{},
+		},
+	}
+	repo.Name = "config"
+	repo.Namespace = ns.Name
+	g.Expect(k.Create(ctx, repo)).To(Succeed())
+
+	provider := &gitprovidersfakes.FakeGitProvider{}
+	provider.ListPullRequestsReturns([]gitproviders.PullRequest{
+		{
+			Number:       2,
+			SourceBranch: "weave-gitops-abc",
+			State:        gitproviders.PullRequestStateOpen,
+			Mergeable:    gitprovider.BoolVar(false),
+			CIStatus:     gitproviders.CIStatusSuccess,
+			CreatedAt:    time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}, nil)
+
+	providers := &gitprovidersfakes.FakeClient{}
+	providers.GetProviderReturns(provider, nil)
+
+	cfg := makeServerConfig(k, t)
+	cfg.GitProviders = providers
+	c := makeServer(cfg, t)
+
+	res, err := c.ListPullRequests(ctx, &pb.ListPullRequestsRequest{
+		RepoUrl: "git@github.com:pull-requests-owner/config",
+		State:   "open",
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(res.PullRequests).To(HaveLen(1))
+	g.Expect(res.PullRequests[0].Number).To(Equal(int32(2)))
+	g.Expect(res.PullRequests[0].RepoUrl).To(Equal("ssh://git@github.com/pull-requests-owner/config.git"))
+	g.Expect(res.PullRequests[0].Mergeability).To(Equal("conflicting"))
+	g.Expect(res.PullRequests[0].CiStatus).To(Equal("success"))
+	g.Expect(res.PullRequests[0].CreatedAt).To(Equal("2022-06-01T00:00:00Z"))
+
+	_, _, state, filter := provider.ListPullRequestsArgsForCall(0)
+	g.Expect(state).To(Equal(gitproviders.PullRequestStateOpen))
+	g.Expect(filter).To(Equal(gitproviders.DefaultPullRequestFilter()))
+
+	_, err = c.ListPullRequests(ctx, &pb.ListPullRequestsRequest{
+		RepoUrl: "https://github.com/pull-requests-owner/hidden",
+	})
+	g.Expect(status.Code(err)).To(Equal(codes.NotFound))
+
+	_, err = c.ListPullRequests(ctx, &pb.ListPullRequestsRequest{State: "draft"})
+	g.Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+}
+
+func TestGetPullRequest_notConfigured(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	c, _ := makeGRPCServer(k8sEnv.Rest, t)
+
+	_, err := c.GetPullRequest(context.Background(), &pb.GetPullRequestRequest{
+		RepoUrl: "https://github.com/owner/repo",
+		Number:  1,
+	})
+	g.Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+}
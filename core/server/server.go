@@ -4,12 +4,15 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/cheshir/ttlcache"
 	"github.com/go-logr/logr"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/weaveworks/weave-gitops/core/clustersmngr"
 	"github.com/weaveworks/weave-gitops/core/logger"
 	"github.com/weaveworks/weave-gitops/core/nsaccess"
 	pb "github.com/weaveworks/weave-gitops/pkg/api/core"
+	"github.com/weaveworks/weave-gitops/pkg/git"
+	"github.com/weaveworks/weave-gitops/pkg/gitproviders"
 	"github.com/weaveworks/weave-gitops/pkg/telemetry"
 	"k8s.io/client-go/rest"
 )
@@ -36,6 +39,11 @@ type coreServer struct {
 	nsChecker      nsaccess.Checker
 	clientsFactory clustersmngr.ClientsFactory
 	primaryKinds   *PrimaryKinds
+	gitProviders   gitproviders.Client
+	gitHostTypes   map[string]string
+	gitClients     GitClientFactory
+	// commits caches the commits revisions are resolved to.
+	commits *ttlcache.Cache
 }
 
 type CoreServerConfig struct {
@@ -45,8 +53,21 @@ type CoreServerConfig struct {
 	NSAccess       nsaccess.Checker
 	ClientsFactory clustersmngr.ClientsFactory
 	PrimaryKinds   *PrimaryKinds
+	// GitProviders gets the clients of the git providers that pull requests
+	// are listed from and revisions are resolved with, neither is served if
+	// it's nil.
+	GitProviders gitproviders.Client
+	// GitHostTypes maps the custom domains of git providers to the provider
+	// they run, e.g. gitea.example.com=gitea.
+	GitHostTypes map[string]string
+	// GitClients makes the git clients that clone the repositories changes
+	// are proposed to, changes can't be proposed if it's nil.
+	GitClients GitClientFactory
 }
 
+// GitClientFactory makes a git client that can clone the repository.
+type GitClientFactory func(repoUrl gitproviders.RepoURL) (git.Git, error)
+
 func NewCoreConfig(log logr.Logger, cfg *rest.Config, clusterName string, clusterClientFactory clustersmngr.ClientsFactory) CoreServerConfig {
 	return CoreServerConfig{
 		log:            log.WithName("core-server"),
@@ -71,5 +92,9 @@ func NewCoreServer(cfg CoreServerConfig) (pb.CoreServer, error) {
 		nsChecker:      cfg.NSAccess,
 		clientsFactory: cfg.ClientsFactory,
 		primaryKinds:   cfg.PrimaryKinds,
+		gitProviders:   cfg.GitProviders,
+		gitHostTypes:   cfg.GitHostTypes,
+		gitClients:     cfg.GitClients,
+		commits:        ttlcache.New(commitCacheResolution),
 	}, nil
 }
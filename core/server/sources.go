@@ -31,7 +31,7 @@ func (cs *coreServer) ListGitRepositories(ctx context.Context, msg *pb.ListGitRe
 		return &sourcev1.GitRepositoryList{}
 	})
 
-	if err := clustersClient.ClusteredList(ctx, clist, true); err != nil {
+	if err := clustersClient.ClusteredList(ctx, clist, true, tenantListOptions(msg.Tenant)...); err != nil {
 		return nil, err
 	}
 
@@ -78,7 +78,7 @@ func (cs *coreServer) ListHelmRepositories(ctx context.Context, msg *pb.ListHelm
 		return &sourcev1.HelmRepositoryList{}
 	})
 
-	if err := clustersClient.ClusteredList(ctx, clist, true); err != nil {
+	if err := clustersClient.ClusteredList(ctx, clist, true, tenantListOptions(msg.Tenant)...); err != nil {
 		return nil, err
 	}
 
@@ -125,7 +125,7 @@ func (cs *coreServer) ListHelmCharts(ctx context.Context, msg *pb.ListHelmCharts
 		return &sourcev1.HelmChartList{}
 	})
 
-	if err := clustersClient.ClusteredList(ctx, clist, true); err != nil {
+	if err := clustersClient.ClusteredList(ctx, clist, true, tenantListOptions(msg.Tenant)...); err != nil {
 		return nil, err
 	}
 
@@ -176,7 +176,7 @@ func (cs *coreServer) ListBuckets(ctx context.Context, msg *pb.ListBucketRequest
 
 	clusterUserNamespaces := cs.clientsFactory.GetUserNamespaces(auth.Principal(ctx))
 
-	if err := clustersClient.ClusteredList(ctx, clist, true); err != nil {
+	if err := clustersClient.ClusteredList(ctx, clist, true, tenantListOptions(msg.Tenant)...); err != nil {
 		return nil, err
 	}
 
@@ -226,7 +226,7 @@ func (cs *coreServer) ListOCIRepositories(ctx context.Context, msg *pb.ListOCIRe
 		return &sourcev1.OCIRepositoryList{}
 	})
 
-	if err := clustersClient.ClusteredList(ctx, clist, true); err != nil {
+	if err := clustersClient.ClusteredList(ctx, clist, true, tenantListOptions(msg.Tenant)...); err != nil {
 		return nil, err
 	}
 
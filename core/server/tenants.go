@@ -0,0 +1,304 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	helmv2 "github.com/fluxcd/helm-controller/api/v2beta1"
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta2"
+	"github.com/hashicorp/go-multierror"
+	"github.com/weaveworks/weave-gitops/core/clustersmngr"
+	pb "github.com/weaveworks/weave-gitops/pkg/api/core"
+	"github.com/weaveworks/weave-gitops/pkg/server/auth"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/kustomize/kstatus/status"
+)
+
+// TenantLabel is the namespace label flux uses to mark which tenant
+// a namespace belongs to.
+const TenantLabel = "toolkit.fluxcd.io/tenant"
+
+// tenantObjectKinds are the flux kinds that are reported as being
+// owned by a tenant when they live in one of its namespaces.
+var tenantObjectKinds = []schema.GroupVersionKind{
+	kustomizev1.GroupVersion.WithKind(kustomizev1.KustomizationKind),
+	helmv2.GroupVersion.WithKind(helmv2.HelmReleaseKind),
+	sourcev1.GroupVersion.WithKind(sourcev1.GitRepositoryKind),
+	sourcev1.GroupVersion.WithKind(sourcev1.HelmRepositoryKind),
+	sourcev1.GroupVersion.WithKind(sourcev1.HelmChartKind),
+	sourcev1.GroupVersion.WithKind(sourcev1.BucketKind),
+	sourcev1.GroupVersion.WithKind(sourcev1.OCIRepositoryKind),
+}
+
+func (cs *coreServer) ListTenants(ctx context.Context, msg *pb.ListTenantsRequest) (*pb.ListTenantsResponse, error) {
+	tenants, respErrors, err := cs.collectTenants(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	results := []*pb.Tenant{}
+	for _, t := range tenants {
+		results = append(results, t)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+
+	return &pb.ListTenantsResponse{
+		Tenants: results,
+		Errors:  respErrors,
+	}, nil
+}
+
+func (cs *coreServer) GetTenant(ctx context.Context, msg *pb.GetTenantRequest) (*pb.GetTenantResponse, error) {
+	if msg.Name == "" {
+		return nil, grpcstatus.Errorf(codes.InvalidArgument, "bad request: no tenant name was specified")
+	}
+
+	tenants, respErrors, err := cs.collectTenants(ctx, msg.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	tenant, ok := tenants[msg.Name]
+	if !ok {
+		return nil, grpcstatus.Errorf(codes.NotFound, "tenant %q not found", msg.Name)
+	}
+
+	return &pb.GetTenantResponse{
+		Tenant: tenant,
+		Errors: respErrors,
+	}, nil
+}
+
+// collectTenants builds the tenants visible to the current user from the
+// tenant label on their namespaces, and the flux objects inside them.
+// If name is set, only that tenant is collected.
+func (cs *coreServer) collectTenants(ctx context.Context, name string) (map[string]*pb.Tenant, []*pb.ListError, error) {
+	respErrors := []*pb.ListError{}
+
+	principal := auth.Principal(ctx)
+
+	clustersClient, err := cs.clientsFactory.GetImpersonatedClient(ctx, principal)
+	if err != nil {
+		if merr, ok := err.(*multierror.Error); ok {
+			for _, err := range merr.Errors {
+				if cerr, ok := err.(*clustersmngr.ClientError); ok {
+					respErrors = append(respErrors, &pb.ListError{ClusterName: cerr.ClusterName, Message: cerr.Error()})
+				}
+			}
+		}
+	}
+
+	clusterUserNamespaces := cs.clientsFactory.GetUserNamespaces(principal)
+
+	tenants := map[string]*pb.Tenant{}
+
+	for clusterName, namespaces := range clusterUserNamespaces {
+		for _, ns := range namespaces {
+			tenantName := ns.Labels[TenantLabel]
+			if tenantName == "" || (name != "" && tenantName != name) {
+				continue
+			}
+
+			tenant, ok := tenants[tenantName]
+			if !ok {
+				tenant = &pb.Tenant{Name: tenantName}
+				tenants[tenantName] = tenant
+			}
+
+			addTenantNamespace(tenant, clusterName, ns.Name)
+		}
+	}
+
+	if len(tenants) == 0 {
+		return tenants, respErrors, nil
+	}
+
+	// Getting a single tenant only lists the objects in its namespaces.
+	opts := tenantListOptions(name)
+
+	for _, gvk := range tenantObjectKinds {
+		listGVK := gvk.GroupVersion().WithKind(gvk.Kind + "List")
+
+		clist := clustersmngr.NewClusteredList(func() client.ObjectList {
+			list := &unstructured.UnstructuredList{}
+			list.SetGroupVersionKind(listGVK)
+
+			return list
+		})
+
+		if err := clustersClient.ClusteredList(ctx, clist, true, opts...); err != nil {
+			var errs clustersmngr.ClusteredListError
+			if !errors.As(err, &errs) {
+				return nil, nil, err
+			}
+
+			for _, e := range errs.Errors {
+				respErrors = append(respErrors, &pb.ListError{ClusterName: e.Cluster, Namespace: e.Namespace, Message: e.Err.Error()})
+			}
+		}
+
+		for clusterName, lists := range clist.Lists() {
+			for _, l := range lists {
+				list, ok := l.(*unstructured.UnstructuredList)
+				if !ok {
+					continue
+				}
+
+				for i := range list.Items {
+					obj := &list.Items[i]
+
+					tenant, ok := tenants[GetTenant(obj.GetNamespace(), clusterName, clusterUserNamespaces)]
+					if !ok {
+						continue
+					}
+
+					addTenantObject(tenant, clusterName, gvk.Kind, obj)
+				}
+			}
+		}
+	}
+
+	for _, tenant := range tenants {
+		sortTenant(tenant)
+		tenant.Health = tenantHealth(tenant.Objects)
+	}
+
+	return tenants, respErrors, nil
+}
+
+func addTenantNamespace(tenant *pb.Tenant, clusterName, namespace string) {
+	for _, tn := range tenant.Namespaces {
+		if tn.ClusterName == clusterName {
+			tn.Namespaces = append(tn.Namespaces, namespace)
+			return
+		}
+	}
+
+	tenant.Namespaces = append(tenant.Namespaces, &pb.TenantNamespaces{
+		ClusterName: clusterName,
+		Namespaces:  []string{namespace},
+	})
+}
+
+func addTenantObject(tenant *pb.Tenant, clusterName, kind string, obj *unstructured.Unstructured) {
+	objStatus := status.UnknownStatus.String()
+	if res, err := status.Compute(obj); err == nil {
+		objStatus = res.Status.String()
+	}
+
+	suspended, _, _ := unstructured.NestedBool(obj.Object, "spec", "suspend")
+
+	tenant.Objects = append(tenant.Objects, &pb.TenantObject{
+		Kind:        kind,
+		Name:        obj.GetName(),
+		Namespace:   obj.GetNamespace(),
+		ClusterName: clusterName,
+		Status:      objStatus,
+		Suspended:   suspended,
+	})
+
+	// Kustomizations and HelmReleases can impersonate a service account
+	// when applying, which is how flux enforces tenant isolation.
+	saName, _, _ := unstructured.NestedString(obj.Object, "spec", "serviceAccountName")
+	if saName == "" {
+		return
+	}
+
+	for _, sa := range tenant.ServiceAccounts {
+		if sa.ClusterName == clusterName && sa.Namespace == obj.GetNamespace() && sa.Name == saName {
+			return
+		}
+	}
+
+	tenant.ServiceAccounts = append(tenant.ServiceAccounts, &pb.TenantServiceAccount{
+		ClusterName: clusterName,
+		Namespace:   obj.GetNamespace(),
+		Name:        saName,
+	})
+}
+
+func sortTenant(tenant *pb.Tenant) {
+	sort.Slice(tenant.Namespaces, func(i, j int) bool {
+		return tenant.Namespaces[i].ClusterName < tenant.Namespaces[j].ClusterName
+	})
+
+	for _, tn := range tenant.Namespaces {
+		sort.Strings(tn.Namespaces)
+	}
+
+	sort.Slice(tenant.Objects, func(i, j int) bool {
+		a, b := tenant.Objects[i], tenant.Objects[j]
+		if a.ClusterName != b.ClusterName {
+			return a.ClusterName < b.ClusterName
+		}
+
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+
+		return a.Name < b.Name
+	})
+
+	sort.Slice(tenant.ServiceAccounts, func(i, j int) bool {
+		a, b := tenant.ServiceAccounts[i], tenant.ServiceAccounts[j]
+		if a.ClusterName != b.ClusterName {
+			return a.ClusterName < b.ClusterName
+		}
+
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+
+		return a.Name < b.Name
+	})
+}
+
+// tenantHealth aggregates the kstatus of a tenant's objects. Suspended
+// objects are counted separately and don't affect the overall status.
+func tenantHealth(objects []*pb.TenantObject) *pb.TenantHealth {
+	health := &pb.TenantHealth{}
+
+	for _, obj := range objects {
+		if obj.Suspended {
+			health.Suspended++
+			continue
+		}
+
+		switch status.Status(obj.Status) {
+		case status.CurrentStatus:
+			health.Current++
+		case status.InProgressStatus, status.TerminatingStatus:
+			health.InProgress++
+		case status.FailedStatus:
+			health.Failed++
+		default:
+			health.Unknown++
+		}
+	}
+
+	switch {
+	case health.Failed > 0:
+		health.Status = status.FailedStatus.String()
+	case health.InProgress > 0:
+		health.Status = status.InProgressStatus.String()
+	case health.Unknown > 0:
+		health.Status = status.UnknownStatus.String()
+	default:
+		health.Status = status.CurrentStatus.String()
+	}
+
+	return health
+}
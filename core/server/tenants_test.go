@@ -0,0 +1,182 @@
+package server_test
+
+import (
+	"context"
+	"testing"
+
+	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1beta2"
+	. "github.com/onsi/gomega"
+	"github.com/weaveworks/weave-gitops/core/server"
+	pb "github.com/weaveworks/weave-gitops/pkg/api/core"
+	"github.com/weaveworks/weave-gitops/pkg/kube"
+	"github.com/weaveworks/weave-gitops/pkg/testutils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestGetTenant(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	ctx := context.Background()
+
+	c, _ := makeGRPCServer(k8sEnv.Rest, t)
+
+	scheme, err := kube.CreateScheme()
+	g.Expect(err).To(BeNil())
+
+	k, err := client.New(k8sEnv.Rest, client.Options{
+		Scheme: scheme,
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	tenantName := "team-" + rand.String(5)
+	ns1 := newTenantNamespace(ctx, k, g, tenantName)
+	ns2 := newTenantNamespace(ctx, k, g, tenantName)
+
+	kust := &kustomizev1.Kustomization{
+		Spec: kustomizev1.KustomizationSpec{
+			ServiceAccountName: "team-sa",
+			SourceRef: kustomizev1.CrossNamespaceSourceReference{
+				Kind: "GitRepository",
+			},
+		},
+	}
+	kust.Name = "team-app"
+	kust.Namespace = ns1.Name
+	g.Expect(k.Create(ctx, kust)).To(Succeed())
+
+	res, err := c.GetTenant(ctx, &pb.GetTenantRequest{Name: tenantName})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	tenant := res.Tenant
+	g.Expect(tenant.Name).To(Equal(tenantName))
+	g.Expect(tenant.Namespaces).To(HaveLen(1))
+	g.Expect(tenant.Namespaces[0].ClusterName).To(Equal("Default"))
+	g.Expect(tenant.Namespaces[0].Namespaces).To(ConsistOf(ns1.Name, ns2.Name))
+
+	g.Expect(tenant.Objects).To(HaveLen(1))
+	g.Expect(tenant.Objects[0].Kind).To(Equal(kustomizev1.KustomizationKind))
+	g.Expect(tenant.Objects[0].Name).To(Equal("team-app"))
+
+	g.Expect(tenant.ServiceAccounts).To(HaveLen(1))
+	g.Expect(tenant.ServiceAccounts[0].Name).To(Equal("team-sa"))
+	g.Expect(tenant.ServiceAccounts[0].Namespace).To(Equal(ns1.Name))
+
+	g.Expect(tenant.Health.Status).NotTo(BeEmpty())
+
+	list, err := c.ListTenants(ctx, &pb.ListTenantsRequest{})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	names := []string{}
+	for _, t := range list.Tenants {
+		names = append(names, t.Name)
+	}
+
+	g.Expect(names).To(ContainElement(tenantName))
+}
+
+func TestListKustomizations_TenantPagination(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	testutils.DeleteAllOf(g, &kustomizev1.Kustomization{})
+
+	ctx := context.Background()
+
+	c, _ := makeGRPCServer(k8sEnv.Rest, t)
+
+	scheme, err := kube.CreateScheme()
+	g.Expect(err).To(BeNil())
+
+	k, err := client.New(k8sEnv.Rest, client.Options{
+		Scheme: scheme,
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	tenantA := "team-" + rand.String(5)
+	tenantB := "team-" + rand.String(5)
+
+	nsA1 := newTenantNamespace(ctx, k, g, tenantA)
+	nsA2 := newTenantNamespace(ctx, k, g, tenantA)
+	nsB := newTenantNamespace(ctx, k, g, tenantB)
+	other := newNamespace(ctx, k, g)
+
+	for _, ns := range []string{nsA1.Name, nsA2.Name, nsB.Name, other.Name} {
+		newKustomization(ctx, "app-0", ns, k, g)
+		newKustomization(ctx, "app-1", ns, k, g)
+	}
+
+	names := func(res *pb.ListKustomizationsResponse) []string {
+		result := []string{}
+		for _, k := range res.Kustomizations {
+			result = append(result, k.Namespace+"/"+k.Name)
+		}
+
+		return result
+	}
+
+	// Every page holds one kustomization of each of the tenant's namespaces.
+	res, err := c.ListKustomizations(ctx, &pb.ListKustomizationsRequest{
+		Tenant:     tenantA,
+		Pagination: &pb.Pagination{PageSize: 1},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(names(res)).To(ConsistOf(nsA1.Name+"/app-0", nsA2.Name+"/app-0"))
+	g.Expect(res.NextPageToken).NotTo(BeEmpty())
+
+	for _, k := range res.Kustomizations {
+		g.Expect(k.Tenant).To(Equal(tenantA))
+	}
+
+	res, err = c.ListKustomizations(ctx, &pb.ListKustomizationsRequest{
+		Tenant:     tenantA,
+		Pagination: &pb.Pagination{PageSize: 1, PageToken: res.NextPageToken},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(names(res)).To(ConsistOf(nsA1.Name+"/app-1", nsA2.Name+"/app-1"))
+
+	res, err = c.ListKustomizations(ctx, &pb.ListKustomizationsRequest{
+		Tenant:     tenantA,
+		Pagination: &pb.Pagination{PageSize: 1, PageToken: res.NextPageToken},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(res.Kustomizations).To(BeEmpty())
+
+	res, err = c.ListKustomizations(ctx, &pb.ListKustomizationsRequest{Tenant: tenantB})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(names(res)).To(ConsistOf(nsB.Name+"/app-0", nsB.Name+"/app-1"))
+
+	namespaces, err := c.ListNamespaces(ctx, &pb.ListNamespacesRequest{Tenant: tenantA})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	nsNames := []string{}
+	for _, ns := range namespaces.Namespaces {
+		nsNames = append(nsNames, ns.Name)
+	}
+
+	g.Expect(nsNames).To(ConsistOf(nsA1.Name, nsA2.Name))
+}
+
+func TestGetTenant_NotFound(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	c, _ := makeGRPCServer(k8sEnv.Rest, t)
+
+	_, err := c.GetTenant(context.Background(), &pb.GetTenantRequest{Name: "no-such-tenant"})
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(status.Code(err)).To(Equal(codes.NotFound))
+}
+
+func newTenantNamespace(ctx context.Context, k client.Client, g *GomegaWithT, tenant string) corev1.Namespace {
+	ns := corev1.Namespace{}
+	ns.Name = "kube-test-" + rand.String(5)
+	ns.Labels = map[string]string{
+		server.TenantLabel: tenant,
+	}
+
+	g.Expect(k.Create(ctx, &ns)).To(Succeed())
+
+	return ns
+}
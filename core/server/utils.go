@@ -1,6 +1,7 @@
 package server
 
 import (
+	"github.com/weaveworks/weave-gitops/core/clustersmngr"
 	v1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -12,9 +13,19 @@ func getMatchingLabels(appName string) client.MatchingLabels {
 func GetTenant(namespace, clusterName string, clusterUserNamespaces map[string][]v1.Namespace) string {
 	for _, ns := range clusterUserNamespaces[clusterName] {
 		if ns.GetName() == namespace {
-			return ns.Labels["toolkit.fluxcd.io/tenant"]
+			return ns.Labels[TenantLabel]
 		}
 	}
 
 	return ""
 }
+
+// tenantListOptions restricts a ClusteredList to the namespaces of the tenant,
+// so that pages only hold its objects.
+func tenantListOptions(tenant string) []client.ListOption {
+	if tenant == "" {
+		return []client.ListOption{}
+	}
+
+	return []client.ListOption{clustersmngr.MatchingNamespaceLabels{TenantLabel: tenant}}
+}
@@ -47,7 +47,7 @@ func NewHTTPClient() *HTTPClient {
 }
 
 // EnableCLIAuth configures client to authenticate automatically with
-// with either username or password, or kubeconfig, when a request is executed.
+// with either an API token, username and password, or kubeconfig, when a request is executed.
 func (c *HTTPClient) EnableCLIAuth() *HTTPClient {
 	c.authFunc = configureAuthForClient
 	return c
@@ -93,6 +93,12 @@ func (c *HTTPClient) ConfigureClientWithOptions(opts *config.Options, out io.Wri
 }
 
 func configureAuthForClient(opts *config.Options, httpClient *HTTPClient) error {
+	if opts.Token != "" {
+		httpClient.client.SetAuthToken(opts.Token)
+
+		return nil
+	}
+
 	if opts.Username != "" && opts.Password != "" {
 		err := httpClient.signIn(opts.Username, opts.Password)
 		if err != nil {
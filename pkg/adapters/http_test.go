@@ -870,3 +870,21 @@ func TestSignin(t *testing.T) {
 		})
 	}
 }
+
+func TestAPITokenAuth(t *testing.T) {
+	opts := &config.Options{
+		Endpoint: testutils.BaseURI,
+		Username: "username",
+		Password: "pass",
+		Token:    "wgt_0123456789abcdef_secret",
+	}
+	client := adapters.NewHTTPClient().EnableCLIAuth()
+	httpmock.ActivateNonDefault(client.GetBaseClient())
+
+	defer httpmock.DeactivateAndReset()
+
+	err := client.ConfigureClientWithOptions(opts, os.Stdout)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, httpmock.GetTotalCallCount(), "should not sign in with a token")
+	assert.Equal(t, "wgt_0123456789abcdef_secret", client.GetClient().Token)
+}
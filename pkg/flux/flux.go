@@ -1,17 +1,19 @@
 package flux
 
 import (
+	"crypto/elliptic"
+	"errors"
 	"fmt"
 
+	"github.com/fluxcd/flux2/pkg/manifestgen/sourcesecret"
 	"github.com/weaveworks/weave-gitops/pkg/gitproviders"
-	"github.com/weaveworks/weave-gitops/pkg/runner"
 )
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
 
 //counterfeiter:generate . Flux
 type Flux interface {
-	CreateSecretGit(name string, repoUrl gitproviders.RepoURL, namespace string) ([]byte, error)
+	CreateSecretGit(name string, repoUrl gitproviders.RepoURL, namespace string, opts ...SecretGitOption) ([]byte, error)
 }
 
 const (
@@ -20,40 +22,65 @@ const (
 	VersionLabelKey  = "app.kubernetes.io/version"
 )
 
-type FluxClient struct {
-	runner runner.Runner
+// SecretGitOption sets the credentials of a git secret.
+type SecretGitOption func(*secretGitOptions)
+
+type secretGitOptions struct {
+	username string
+	password string
 }
 
-func New(cliRunner runner.Runner) *FluxClient {
-	return &FluxClient{
-		runner: cliRunner,
+// WithBasicAuth sets the credentials of a git secret for a repository cloned
+// over HTTPS, instead of a deploy key.
+func WithBasicAuth(username, password string) SecretGitOption {
+	return func(o *secretGitOptions) {
+		o.username = username
+		o.password = password
 	}
 }
 
+type FluxClient struct{}
+
+func New() *FluxClient {
+	return &FluxClient{}
+}
+
 var _ Flux = &FluxClient{}
 
-// CreatSecretGit Creates a Git secret returns the deploy key
-func (f *FluxClient) CreateSecretGit(name string, repoUrl gitproviders.RepoURL, namespace string) ([]byte, error) {
-	args := []string{
-		"create", "secret", "git", name,
-		"--url", repoUrl.String(),
-		"--namespace", namespace,
-		"--export",
+// CreateSecretGit generates the manifest of a git secret for the repository,
+// like `flux create secret git --export`. The secret holds the basic auth
+// credentials if they're given, for repositories cloned over HTTPS, and a new
+// ECDSA deploy key and the scanned host key of the git server otherwise.
+func (f *FluxClient) CreateSecretGit(name string, repoUrl gitproviders.RepoURL, namespace string, opts ...SecretGitOption) ([]byte, error) {
+	o := secretGitOptions{}
+	for _, opt := range opts {
+		opt(&o)
 	}
 
-	out, err := f.runFluxCmd(args...)
-	if err != nil {
-		return out, fmt.Errorf("failed to create secret git: %w", err)
-	}
+	options := sourcesecret.MakeDefaultOptions()
+	options.Name = name
+	options.Namespace = namespace
 
-	return out, nil
-}
+	switch {
+	case o.username != "" || o.password != "":
+		if o.username == "" || o.password == "" {
+			return nil, errors.New("failed to create secret git: both a username and a password are required")
+		}
+
+		options.Username = o.username
+		options.Password = o.password
+	case repoUrl.Protocol() == gitproviders.RepositoryURLProtocolSSH:
+		options.PrivateKeyAlgorithm = sourcesecret.ECDSAPrivateKeyAlgorithm
+		options.ECDSACurve = elliptic.P384()
+		options.SSHHostname = repoUrl.URL().Host
+	default:
+		return nil, fmt.Errorf("failed to create secret git: no credentials for %s repositories", repoUrl.Protocol())
+	}
 
-func (f *FluxClient) runFluxCmd(args ...string) ([]byte, error) {
-	out, err := f.runner.Run("flux", args...)
+	manifest, err := sourcesecret.Generate(options)
 	if err != nil {
-		return []byte{}, fmt.Errorf("failed to run flux with output: %s and error: %w", string(out), err)
+		return nil, fmt.Errorf("failed to create secret git: %w", err)
 	}
 
-	return out, nil
+	return []byte(manifest.Content), nil
 }
@@ -1,48 +1,121 @@
 package flux_test
 
 import (
-	"fmt"
-	"path/filepath"
+	"net"
 	"strings"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
 	"github.com/weaveworks/weave-gitops/pkg/flux"
 	"github.com/weaveworks/weave-gitops/pkg/gitproviders"
-	"github.com/weaveworks/weave-gitops/pkg/runner/runnerfakes"
+	"github.com/weaveworks/weave-gitops/pkg/testutils"
+	"golang.org/x/crypto/ssh"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
 )
 
-var (
-	runner     *runnerfakes.FakeRunner
-	fluxClient *flux.FluxClient
-)
+var fluxClient *flux.FluxClient
 
 var _ = BeforeEach(func() {
-	runner = &runnerfakes.FakeRunner{}
-	fluxClient = flux.New(runner)
+	fluxClient = flux.New()
 })
 
 var _ = Describe("CreateSecretGit", func() {
-	It("creates a git secret and returns the deploy key", func() {
-		runner.RunStub = func(s1 string, s2 ...string) ([]byte, error) {
-			return []byte(`ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQCh...`), nil
-		}
+	var (
+		hostAddr string
+		hostKey  ssh.PublicKey
+	)
+
+	BeforeEach(func() {
+		var (
+			listener net.Listener
+			err      error
+		)
+
+		listener, hostKey, err = testutils.StartSSHHost()
+		Expect(err).ShouldNot(HaveOccurred())
+		DeferCleanup(listener.Close)
+
+		hostAddr = listener.Addr().String()
 
-		repoUrl, err := gitproviders.NewRepoURL("ssh://git@github.com/foo/bar.git")
+		viper.Set("git-host-types", "127.0.0.1=gitea")
+		DeferCleanup(viper.Set, "git-host-types", "")
+	})
+
+	It("creates a git secret with a deploy key and the host key", func() {
+		repoUrl, err := gitproviders.NewRepoURL("ssh://git@" + hostAddr + "/foo/bar.git")
 		Expect(err).ShouldNot(HaveOccurred())
+
 		out, err := fluxClient.CreateSecretGit("my-secret", repoUrl, "flux-system")
 		Expect(err).ShouldNot(HaveOccurred())
-		Expect(out).To(Equal([]byte("ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQCh...")))
+		Expect(string(out)).To(HavePrefix("---\n"))
 
-		Expect(runner.RunCallCount()).To(Equal(1))
+		secret := &corev1.Secret{}
+		Expect(yaml.Unmarshal(out, secret)).To(Succeed())
+		Expect(secret.Kind).To(Equal("Secret"))
+		Expect(secret.Name).To(Equal("my-secret"))
+		Expect(secret.Namespace).To(Equal("flux-system"))
 
-		cmd, args := runner.RunArgsForCall(0)
-		Expect(cmd).To(Equal(fluxPath()))
+		signer, err := ssh.ParsePrivateKey([]byte(secret.StringData["identity"]))
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(signer.PublicKey().Type()).To(Equal("ecdsa-sha2-nistp384"))
+		Expect(secret.StringData["identity.pub"]).To(Equal(string(ssh.MarshalAuthorizedKey(signer.PublicKey()))))
 
-		Expect(strings.Join(args, " ")).To(Equal(fmt.Sprintf("create secret git my-secret --url ssh://git@github.com/foo/bar.git --namespace %s --export", "flux-system")))
+		Expect(secret.StringData["known_hosts"]).To(HavePrefix("[127.0.0.1]:"))
+		Expect(secret.StringData["known_hosts"]).To(HaveSuffix(strings.TrimSpace(string(ssh.MarshalAuthorizedKey(hostKey)))))
 	})
-})
 
-func fluxPath() string {
-	return filepath.Join("flux")
-}
+	It("generates a new deploy key every time", func() {
+		repoUrl, err := gitproviders.NewRepoURL("ssh://git@" + hostAddr + "/foo/bar.git")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		first, err := fluxClient.CreateSecretGit("my-secret", repoUrl, "flux-system")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		second, err := fluxClient.CreateSecretGit("my-secret", repoUrl, "flux-system")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(second).NotTo(Equal(first))
+	})
+
+	It("fails when the host can't be scanned", func() {
+		listener, _, err := testutils.StartSSHHost()
+		Expect(err).ShouldNot(HaveOccurred())
+		addr := listener.Addr().String()
+		Expect(listener.Close()).To(Succeed())
+
+		repoUrl, err := gitproviders.NewRepoURL("ssh://git@" + addr + "/foo/bar.git")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		_, err = fluxClient.CreateSecretGit("my-secret", repoUrl, "flux-system")
+		Expect(err).To(MatchError(ContainSubstring("SSH key scan for host")))
+	})
+
+	It("creates a git secret with basic auth for HTTPS repositories", func() {
+		repoUrl, err := gitproviders.NewRepoURL("https://github.com/foo/bar.git")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		out, err := fluxClient.CreateSecretGit("my-secret", repoUrl, "flux-system", flux.WithBasicAuth("git", "token"))
+		Expect(err).ShouldNot(HaveOccurred())
+
+		secret := &corev1.Secret{}
+		Expect(yaml.Unmarshal(out, secret)).To(Succeed())
+		Expect(secret.StringData).To(Equal(map[string]string{"username": "git", "password": "token"}))
+	})
+
+	It("requires both a username and a password", func() {
+		repoUrl, err := gitproviders.NewRepoURL("https://github.com/foo/bar.git")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		_, err = fluxClient.CreateSecretGit("my-secret", repoUrl, "flux-system", flux.WithBasicAuth("git", ""))
+		Expect(err).To(MatchError(ContainSubstring("both a username and a password are required")))
+	})
+
+	It("has no credentials for local repositories", func() {
+		repoUrl, err := gitproviders.NewRepoURL("file:///tmp/repo.git")
+		Expect(err).ShouldNot(HaveOccurred())
+
+		_, err = fluxClient.CreateSecretGit("my-secret", repoUrl, "flux-system")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -9,12 +9,13 @@ import (
 )
 
 type FakeFlux struct {
-	CreateSecretGitStub        func(string, gitproviders.RepoURL, string) ([]byte, error)
+	CreateSecretGitStub        func(string, gitproviders.RepoURL, string, ...flux.SecretGitOption) ([]byte, error)
 	createSecretGitMutex       sync.RWMutex
 	createSecretGitArgsForCall []struct {
 		arg1 string
 		arg2 gitproviders.RepoURL
 		arg3 string
+		arg4 []flux.SecretGitOption
 	}
 	createSecretGitReturns struct {
 		result1 []byte
@@ -28,20 +29,21 @@ type FakeFlux struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeFlux) CreateSecretGit(arg1 string, arg2 gitproviders.RepoURL, arg3 string) ([]byte, error) {
+func (fake *FakeFlux) CreateSecretGit(arg1 string, arg2 gitproviders.RepoURL, arg3 string, arg4 ...flux.SecretGitOption) ([]byte, error) {
 	fake.createSecretGitMutex.Lock()
 	ret, specificReturn := fake.createSecretGitReturnsOnCall[len(fake.createSecretGitArgsForCall)]
 	fake.createSecretGitArgsForCall = append(fake.createSecretGitArgsForCall, struct {
 		arg1 string
 		arg2 gitproviders.RepoURL
 		arg3 string
-	}{arg1, arg2, arg3})
+		arg4 []flux.SecretGitOption
+	}{arg1, arg2, arg3, arg4})
 	stub := fake.CreateSecretGitStub
 	fakeReturns := fake.createSecretGitReturns
-	fake.recordInvocation("CreateSecretGit", []interface{}{arg1, arg2, arg3})
+	fake.recordInvocation("CreateSecretGit", []interface{}{arg1, arg2, arg3, arg4})
 	fake.createSecretGitMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2, arg3)
+		return stub(arg1, arg2, arg3, arg4...)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -55,17 +57,17 @@ func (fake *FakeFlux) CreateSecretGitCallCount() int {
 	return len(fake.createSecretGitArgsForCall)
 }
 
-func (fake *FakeFlux) CreateSecretGitCalls(stub func(string, gitproviders.RepoURL, string) ([]byte, error)) {
+func (fake *FakeFlux) CreateSecretGitCalls(stub func(string, gitproviders.RepoURL, string, ...flux.SecretGitOption) ([]byte, error)) {
 	fake.createSecretGitMutex.Lock()
 	defer fake.createSecretGitMutex.Unlock()
 	fake.CreateSecretGitStub = stub
 }
 
-func (fake *FakeFlux) CreateSecretGitArgsForCall(i int) (string, gitproviders.RepoURL, string) {
+func (fake *FakeFlux) CreateSecretGitArgsForCall(i int) (string, gitproviders.RepoURL, string, []flux.SecretGitOption) {
 	fake.createSecretGitMutex.RLock()
 	defer fake.createSecretGitMutex.RUnlock()
 	argsForCall := fake.createSecretGitArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
 }
 
 func (fake *FakeFlux) CreateSecretGitReturns(result1 []byte, result2 error) {
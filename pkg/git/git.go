@@ -80,13 +80,56 @@ func GetProfilesPath(clusterName, profilesManifestPath string) string {
 	return filepath.Join(GetSystemPath(clusterName), profilesManifestPath)
 }
 
+// CloneOption limits what's cloned, which makes cloning large repositories
+// faster.
+type CloneOption func(*cloneOptions)
+
+type cloneOptions struct {
+	depth        int
+	singleBranch bool
+	sparsePaths  []string
+	inMemory     bool
+}
+
+// WithDepth only clones the latest commits, the whole history is cloned if
+// it's 0.
+func WithDepth(depth int) CloneOption {
+	return func(o *cloneOptions) {
+		o.depth = depth
+	}
+}
+
+// WithSingleBranch sets whether only the cloned branch is fetched, which is
+// the default.
+func WithSingleBranch(singleBranch bool) CloneOption {
+	return func(o *cloneOptions) {
+		o.singleBranch = singleBranch
+	}
+}
+
+// WithSparsePaths only checks out the files in the paths. Files outside of
+// them can't be read or changed, and are kept as they are by commits.
+func WithSparsePaths(paths ...string) CloneOption {
+	return func(o *cloneOptions) {
+		o.sparsePaths = append(o.sparsePaths, paths...)
+	}
+}
+
+// WithInMemory clones the repository in memory instead of on disk, the path
+// is ignored.
+func WithInMemory() CloneOption {
+	return func(o *cloneOptions) {
+		o.inMemory = true
+	}
+}
+
 // Git is an interface for basic Git operations on a single branch of a
 // remote repository.
 //counterfeiter:generate . Git
 type Git interface {
 	Open(path string) (*gogit.Repository, error)
 	Init(path, url, branch string) (bool, error)
-	Clone(ctx context.Context, path, url, branch string) (bool, error)
+	Clone(ctx context.Context, path, url, branch string, opts ...CloneOption) (bool, error)
 	Checkout(newBranch string) error
 	Read(path string) ([]byte, error)
 	Write(path string, content []byte) error
@@ -21,13 +21,14 @@ type FakeGit struct {
 	checkoutReturnsOnCall map[int]struct {
 		result1 error
 	}
-	CloneStub        func(context.Context, string, string, string) (bool, error)
+	CloneStub        func(context.Context, string, string, string, ...git.CloneOption) (bool, error)
 	cloneMutex       sync.RWMutex
 	cloneArgsForCall []struct {
 		arg1 context.Context
 		arg2 string
 		arg3 string
 		arg4 string
+		arg5 []git.CloneOption
 	}
 	cloneReturns struct {
 		result1 bool
@@ -242,7 +243,7 @@ func (fake *FakeGit) CheckoutReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeGit) Clone(arg1 context.Context, arg2 string, arg3 string, arg4 string) (bool, error) {
+func (fake *FakeGit) Clone(arg1 context.Context, arg2 string, arg3 string, arg4 string, arg5 ...git.CloneOption) (bool, error) {
 	fake.cloneMutex.Lock()
 	ret, specificReturn := fake.cloneReturnsOnCall[len(fake.cloneArgsForCall)]
 	fake.cloneArgsForCall = append(fake.cloneArgsForCall, struct {
@@ -250,13 +251,14 @@ func (fake *FakeGit) Clone(arg1 context.Context, arg2 string, arg3 string, arg4
 		arg2 string
 		arg3 string
 		arg4 string
-	}{arg1, arg2, arg3, arg4})
+		arg5 []git.CloneOption
+	}{arg1, arg2, arg3, arg4, arg5})
 	stub := fake.CloneStub
 	fakeReturns := fake.cloneReturns
-	fake.recordInvocation("Clone", []interface{}{arg1, arg2, arg3, arg4})
+	fake.recordInvocation("Clone", []interface{}{arg1, arg2, arg3, arg4, arg5})
 	fake.cloneMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2, arg3, arg4)
+		return stub(arg1, arg2, arg3, arg4, arg5...)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -270,17 +272,17 @@ func (fake *FakeGit) CloneCallCount() int {
 	return len(fake.cloneArgsForCall)
 }
 
-func (fake *FakeGit) CloneCalls(stub func(context.Context, string, string, string) (bool, error)) {
+func (fake *FakeGit) CloneCalls(stub func(context.Context, string, string, string, ...git.CloneOption) (bool, error)) {
 	fake.cloneMutex.Lock()
 	defer fake.cloneMutex.Unlock()
 	fake.CloneStub = stub
 }
 
-func (fake *FakeGit) CloneArgsForCall(i int) (context.Context, string, string, string) {
+func (fake *FakeGit) CloneArgsForCall(i int) (context.Context, string, string, string, []git.CloneOption) {
 	fake.cloneMutex.RLock()
 	defer fake.cloneMutex.RUnlock()
 	argsForCall := fake.cloneArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4, argsForCall.arg5
 }
 
 func (fake *FakeGit) CloneReturns(result1 bool, result2 error) {
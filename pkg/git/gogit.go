@@ -24,15 +24,22 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/weaveworks/weave-gitops/pkg/git/wrapper"
 
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
 	gogit "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/memory"
 )
 
 type GoGit struct {
@@ -40,13 +47,32 @@ type GoGit struct {
 	auth       transport.AuthMethod
 	repository *gogit.Repository
 	git        wrapper.Git
+	signingKey *SigningKey
+	// sparsePaths are the paths checked out by a sparse clone.
+	sparsePaths []string
 }
 
-func New(auth transport.AuthMethod, wrapper wrapper.Git) Git {
-	return &GoGit{
+// Option configures a GoGit client.
+type Option func(*GoGit)
+
+// WithSigningKey signs the commits with the key.
+func WithSigningKey(key *SigningKey) Option {
+	return func(g *GoGit) {
+		g.signingKey = key
+	}
+}
+
+func New(auth transport.AuthMethod, wrapper wrapper.Git, opts ...Option) Git {
+	g := &GoGit{
 		auth: auth,
 		git:  wrapper,
 	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	return g
 }
 
 // Open opens a git repository in the provided path, and returns a repository.
@@ -59,6 +85,7 @@ func (g *GoGit) Open(path string) (*gogit.Repository, error) {
 	}
 
 	g.repository = repo
+	g.sparsePaths = nil
 
 	return repo, nil
 }
@@ -79,7 +106,11 @@ func (g *GoGit) Init(path, url, branch string) (bool, error) {
 		return false, err
 	}
 
-	if _, err = r.CreateRemote(&config.RemoteConfig{
+	return g.initRepository(r, url, branch)
+}
+
+func (g *GoGit) initRepository(r *gogit.Repository, url, branch string) (bool, error) {
+	if _, err := r.CreateRemote(&config.RemoteConfig{
 		Name: gogit.DefaultRemoteName,
 		URLs: []string{url},
 	}); err != nil {
@@ -88,7 +119,7 @@ func (g *GoGit) Init(path, url, branch string) (bool, error) {
 
 	branchRef := plumbing.NewBranchReferenceName(branch)
 
-	if err = r.CreateBranch(&config.Branch{
+	if err := r.CreateBranch(&config.Branch{
 		Name:   branch,
 		Remote: gogit.DefaultRemoteName,
 		Merge:  branchRef,
@@ -99,7 +130,7 @@ func (g *GoGit) Init(path, url, branch string) (bool, error) {
 	// overwrite this by setting the reference of the Storer to a new
 	// symbolic reference (as there are no commits yet) that points
 	// the HEAD to our new branch.
-	if err = r.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, branchRef)); err != nil {
+	if err := r.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, branchRef)); err != nil {
 		return false, err
 	}
 
@@ -113,43 +144,164 @@ func (g *GoGit) Init(path, url, branch string) (bool, error) {
 //
 // If the directory is successfully initialised, it returns true, otherwise it
 // returns false.
-func (g *GoGit) Clone(ctx context.Context, path, url, branch string) (bool, error) {
+func (g *GoGit) Clone(ctx context.Context, path, url, branch string, opts ...CloneOption) (bool, error) {
+	o := cloneOptions{singleBranch: true}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	g.path = path
+	g.sparsePaths = nil
 
-	r, err := g.clone(ctx, path, url, branch, 0)
+	r, err := g.clone(ctx, path, url, branch, o)
 	if err != nil {
 		if errors.Is(err, transport.ErrEmptyRemoteRepository) ||
 			errors.Is(err, gogit.NoMatchingRefSpecError{}) {
+			if o.inMemory {
+				return g.initInMemory(url, branch)
+			}
+
 			return g.Init(path, url, branch)
 		}
 
 		return false, err
 	}
 
+	if len(o.sparsePaths) > 0 {
+		if err := sparseCheckout(r, o.sparsePaths); err != nil {
+			return false, fmt.Errorf("failed to check out %s: %w", strings.Join(o.sparsePaths, ", "), err)
+		}
+	}
+
 	g.repository = r
+	g.sparsePaths = o.sparsePaths
 
 	return true, nil
 }
 
-func (g *GoGit) clone(ctx context.Context, path, url, branch string, depth int) (*gogit.Repository, error) {
+func (g *GoGit) initInMemory(url, branch string) (bool, error) {
+	r, err := g.git.Init(memory.NewStorage(), memfs.New())
+	if err != nil {
+		return false, err
+	}
+
+	return g.initRepository(r, url, branch)
+}
+
+func (g *GoGit) clone(ctx context.Context, path, url, branch string, o cloneOptions) (*gogit.Repository, error) {
 	branchRef := plumbing.NewBranchReferenceName(branch)
-	r, err := g.git.PlainCloneContext(ctx, path, false, &gogit.CloneOptions{
+	opts := &gogit.CloneOptions{
 		URL:           url,
 		Auth:          g.auth,
 		RemoteName:    gogit.DefaultRemoteName,
 		ReferenceName: branchRef,
-		SingleBranch:  true,
-		NoCheckout:    false,
-		Progress:      nil,
-		Depth:         depth,
-		Tags:          gogit.NoTags,
+		SingleBranch:  o.singleBranch,
+		// Sparse clones only check out some of the files afterwards.
+		NoCheckout: len(o.sparsePaths) > 0,
+		Progress:   nil,
+		Depth:      o.depth,
+		Tags:       gogit.NoTags,
+	}
+
+	if o.inMemory {
+		return g.git.CloneContext(ctx, memory.NewStorage(), memfs.New(), opts)
+	}
+
+	return g.git.PlainCloneContext(ctx, path, false, opts)
+}
+
+// sparseCheckout writes the files in the paths to the worktree. The index
+// has all the files, so that commits keep the ones that aren't checked out.
+func sparseCheckout(r *gogit.Repository, paths []string) error {
+	head, err := r.Head()
+	if err != nil {
+		return err
+	}
+
+	commit, err := r.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return err
+	}
+
+	wt, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range paths {
+		if err := util.RemoveAll(wt.Filesystem, p); err != nil {
+			return err
+		}
+	}
+
+	idx := &index.Index{Version: 2}
+
+	err = tree.Files().ForEach(func(f *object.File) error {
+		entry := &index.Entry{
+			Name: f.Name,
+			Hash: f.Hash,
+			Mode: f.Mode,
+			Size: uint32(f.Size),
+		}
+		idx.Entries = append(idx.Entries, entry)
+
+		if !inPaths(f.Name, paths) {
+			return nil
+		}
+
+		if err := writeFile(wt.Filesystem, f); err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.Name, err)
+		}
+
+		info, err := wt.Filesystem.Lstat(f.Name)
+		if err != nil {
+			return err
+		}
+
+		entry.ModifiedAt = info.ModTime()
+
+		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	return r.Storer.SetIndex(idx)
+}
 
+func writeFile(fs billy.Filesystem, f *object.File) error {
+	contents, err := f.Contents()
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	if f.Mode == filemode.Symlink {
+		return fs.Symlink(contents, f.Name)
+	}
+
+	perm := os.FileMode(0644)
+	if f.Mode == filemode.Executable {
+		perm = 0755
+	}
+
+	return util.WriteFile(fs, f.Name, []byte(contents), perm)
+}
+
+// inPaths returns whether the file is one of the paths, or in one of them.
+func inPaths(file string, paths []string) bool {
+	for _, p := range paths {
+		p = strings.Trim(filepath.ToSlash(p), "/")
+		if p == "" || file == p || strings.HasPrefix(file, p+"/") {
+			return true
+		}
 	}
 
-	return r, nil
+	return false
 }
 
 // Read reads the content from the path
@@ -238,6 +390,11 @@ func (g *GoGit) Commit(message Commit, filters ...func(string) bool) (string, er
 	var changed bool
 
 	for file, stat := range status {
+		if len(g.sparsePaths) > 0 && !inPaths(file, g.sparsePaths) {
+			// The files that weren't checked out look deleted.
+			continue
+		}
+
 		if stat.Worktree == gogit.Deleted {
 			_, _ = wt.Add(file)
 			changed = true
@@ -245,9 +402,7 @@ func (g *GoGit) Commit(message Commit, filters ...func(string) bool) (string, er
 			continue
 		}
 
-		abspath := filepath.Join(g.path, file)
-
-		isLink, err := isSymLink(abspath)
+		isLink, err := isSymLink(wt.Filesystem, file)
 		if err != nil {
 			return "", err
 		}
@@ -256,7 +411,7 @@ func (g *GoGit) Commit(message Commit, filters ...func(string) bool) (string, er
 			// symlinks are OK; broken symlinks are probably a result
 			// of the bug mentioned above, but not of interest in any
 			// case.
-			if _, err := os.Stat(abspath); os.IsNotExist(err) {
+			if _, err := wt.Filesystem.Stat(file); os.IsNotExist(err) {
 				continue
 			}
 		}
@@ -285,17 +440,31 @@ func (g *GoGit) Commit(message Commit, filters ...func(string) bool) (string, er
 		return head.Hash().String(), ErrNoStagedFiles
 	}
 
-	commit, err := wt.Commit(message.Message, &gogit.CommitOptions{
+	opts := &gogit.CommitOptions{
 		Author: &object.Signature{
 			Name:  message.Name,
 			Email: message.Email,
 			When:  time.Now(),
 		},
-	})
+	}
+
+	if g.signingKey != nil && g.signingKey.openPGP != nil {
+		opts.SignKey = g.signingKey.openPGP
+	}
+
+	commit, err := wt.Commit(message.Message, opts)
 	if err != nil {
 		return "", fmt.Errorf("failed to commit changes: %w", err)
 	}
 
+	if g.signingKey != nil && g.signingKey.ssh != nil {
+		// go-git only signs with OpenPGP keys, so SSH signatures are added
+		// to the commit afterwards.
+		if commit, err = g.signingKey.signCommit(g.repository, commit); err != nil {
+			return "", err
+		}
+	}
+
 	return commit.String(), nil
 }
 
@@ -327,7 +496,17 @@ func (g *GoGit) Status() (bool, error) {
 		return false, fmt.Errorf("failed to get the worktree status: %w", err)
 	}
 
-	return status.IsClean(), nil
+	for file, stat := range status {
+		if len(g.sparsePaths) > 0 && !inPaths(file, g.sparsePaths) {
+			continue
+		}
+
+		if stat.Worktree != gogit.Unmodified || stat.Staging != gogit.Unmodified {
+			return false, nil
+		}
+	}
+
+	return true, nil
 }
 
 func (g *GoGit) Head() (string, error) {
@@ -371,7 +550,7 @@ func (g *GoGit) ValidateAccess(ctx context.Context, url string, branch string) e
 
 	defer os.RemoveAll(path)
 
-	_, err = g.clone(ctx, path, url, branch, 1)
+	_, err = g.clone(ctx, path, url, branch, cloneOptions{depth: 1, singleBranch: true})
 	if err != nil && !errors.Is(err, transport.ErrEmptyRemoteRepository) {
 		return fmt.Errorf("error validating git repo access %w", err)
 	}
@@ -385,6 +564,10 @@ func (g *GoGit) Checkout(newBranch string) error {
 		return fmt.Errorf("failed getting repository work-tree %w", err)
 	}
 
+	if len(g.sparsePaths) > 0 {
+		return g.sparseCheckoutBranch(wt, newBranch)
+	}
+
 	err = wt.Checkout(&gogit.CheckoutOptions{
 		Create: true,
 		Branch: plumbing.NewBranchReferenceName(newBranch),
@@ -402,8 +585,34 @@ func (g *GoGit) Checkout(newBranch string) error {
 	return nil
 }
 
-func isSymLink(fname string) (bool, error) {
-	info, err := os.Lstat(fname)
+// sparseCheckoutBranch checks out a branch keeping the files that weren't
+// checked out by a sparse clone, as a regular checkout would bring them back.
+func (g *GoGit) sparseCheckoutBranch(wt *gogit.Worktree, branch string) error {
+	err := wt.Checkout(&gogit.CheckoutOptions{
+		Create: true,
+		Keep:   true,
+		Branch: plumbing.NewBranchReferenceName(branch),
+	})
+	if err == nil {
+		return nil
+	}
+
+	if err := wt.Checkout(&gogit.CheckoutOptions{
+		Keep:   true,
+		Branch: plumbing.NewBranchReferenceName(branch),
+	}); err != nil {
+		return fmt.Errorf("failed checking out branch %w", err)
+	}
+
+	if err := sparseCheckout(g.repository, g.sparsePaths); err != nil {
+		return fmt.Errorf("failed checking out branch %w", err)
+	}
+
+	return nil
+}
+
+func isSymLink(fs billy.Filesystem, fname string) (bool, error) {
+	info, err := fs.Lstat(fname)
 	if err != nil {
 		return false, fmt.Errorf("failed to check if %s is a symlink: %w", fname, err)
 	}
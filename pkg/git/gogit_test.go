@@ -478,6 +478,116 @@ var _ = Describe("Checkout", func() {
 	})
 })
 
+var _ = Describe("Clone options", func() {
+	var remote string
+
+	BeforeEach(func() {
+		base := GinkgoT().TempDir()
+		remote = filepath.Join(base, "remote.git")
+		work := filepath.Join(base, "work")
+
+		executeCommand(base, "git", "init", "--bare", "--initial-branch=main", remote)
+		executeCommand(base, "git", "clone", remote, work)
+
+		commit := func(file, content string) {
+			Expect(os.MkdirAll(filepath.Join(work, filepath.Dir(file)), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(work, file), []byte(content), 0644)).To(Succeed())
+			executeCommand(work, "git", "add", file)
+			executeCommand(work, "git", "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-m", "add "+file)
+		}
+
+		commit("README.md", "readme")
+		commit("clusters/prod/app.yaml", "app")
+		commit("clusters/dev/app.yaml", "dev")
+		executeCommand(work, "git", "push", "origin", "HEAD:main")
+
+		remote = "file://" + remote
+	})
+
+	commitAndPush := func() {
+		_, err := gitClient.Commit(git.Commit{
+			Author:  git.Author{Name: "test", Email: "test@example.com"},
+			Message: "test commit",
+		})
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(gitClient.Push(context.Background())).To(Succeed())
+	}
+
+	remoteFiles := func(branch string) string {
+		return string(executeCommand(strings.TrimPrefix(remote, "file://"), "git", "ls-tree", "-r", "--name-only", branch))
+	}
+
+	It("clones only the last commit", func() {
+		_, err := gitClient.Clone(context.Background(), dir, remote, "main", git.WithDepth(1))
+		Expect(err).ShouldNot(HaveOccurred())
+
+		out := executeCommand(dir, "git", "rev-list", "--count", "HEAD")
+		Expect(strings.TrimSpace(string(out))).To(Equal("1"))
+
+		Expect(gitClient.Checkout("shallow")).To(Succeed())
+		Expect(gitClient.Write("clusters/prod/new.yaml", []byte("new"))).To(Succeed())
+		commitAndPush()
+
+		Expect(remoteFiles("shallow")).To(ContainSubstring("clusters/prod/new.yaml"))
+	})
+
+	It("checks out only the sparse paths and keeps the other files in commits", func() {
+		_, err := gitClient.Clone(context.Background(), dir, remote, "main", git.WithDepth(1), git.WithSparsePaths("clusters/prod"))
+		Expect(err).ShouldNot(HaveOccurred())
+
+		_, err = os.Stat(filepath.Join(dir, "clusters/prod/app.yaml"))
+		Expect(err).ShouldNot(HaveOccurred())
+		_, err = os.Stat(filepath.Join(dir, "README.md"))
+		Expect(errors.Is(err, os.ErrNotExist)).To(BeTrue())
+
+		isClean, err := gitClient.Status()
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(isClean).To(BeTrue())
+
+		Expect(gitClient.Checkout("sparse")).To(Succeed())
+		Expect(gitClient.Write("clusters/prod/app.yaml", []byte("changed"))).To(Succeed())
+		commitAndPush()
+
+		files := remoteFiles("sparse")
+		Expect(files).To(ContainSubstring("README.md"))
+		Expect(files).To(ContainSubstring("clusters/dev/app.yaml"))
+
+		out := executeCommand(strings.TrimPrefix(remote, "file://"), "git", "show", "sparse:clusters/prod/app.yaml")
+		Expect(string(out)).To(Equal("changed"))
+	})
+
+	It("clones into memory", func() {
+		_, err := gitClient.Clone(context.Background(), dir, remote, "main", git.WithInMemory())
+		Expect(err).ShouldNot(HaveOccurred())
+
+		entries, err := os.ReadDir(dir)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+
+		content, err := gitClient.Read("clusters/dev/app.yaml")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(string(content)).To(Equal("dev"))
+
+		Expect(gitClient.Checkout("in-memory")).To(Succeed())
+		Expect(gitClient.Remove("README.md")).To(Succeed())
+		commitAndPush()
+
+		Expect(remoteFiles("in-memory")).NotTo(ContainSubstring("README.md"))
+	})
+
+	It("initialises an empty repository in memory", func() {
+		empty := filepath.Join(GinkgoT().TempDir(), "empty.git")
+		executeCommand(dir, "git", "init", "--bare", empty)
+
+		initialised, err := gitClient.Clone(context.Background(), dir, "file://"+empty, "main", git.WithInMemory())
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(initialised).To(BeTrue())
+
+		_, err = os.Stat(filepath.Join(dir, ".git"))
+		Expect(errors.Is(err, os.ErrNotExist)).To(BeTrue())
+	})
+})
+
 func executeCommand(workingDir, cmd string, args ...string) []byte {
 	c := exec.Command(cmd, args...)
 	c.Dir = workingDir
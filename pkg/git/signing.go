@@ -0,0 +1,354 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"golang.org/x/crypto/ssh"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// SigningKeySecretKey holds the signing key in a Secret.
+	SigningKeySecretKey = "signing.key"
+	// SigningKeyPassphraseSecretKey holds the passphrase of the signing key
+	// in a Secret, if it's encrypted.
+	SigningKeyPassphraseSecretKey = "passphrase"
+
+	// sshSigNamespace is the namespace git signs and verifies commits in.
+	sshSigNamespace   = "git"
+	sshSigMagic       = "SSHSIG"
+	sshSigVersion     = 1
+	sshSigHashAlgo    = "sha512"
+	sshSigArmorStart  = "-----BEGIN SSH SIGNATURE-----"
+	sshSigArmorEnd    = "-----END SSH SIGNATURE-----"
+	sshSigArmorLength = 70
+)
+
+var ErrUnsignedCommit = errors.New("commit is not signed")
+
+// SigningKey signs commits, either with an OpenPGP key or with an SSH key as
+// supported by git since 2.34.
+type SigningKey struct {
+	openPGP *openpgp.Entity
+	ssh     ssh.Signer
+}
+
+// ParseSigningKey parses an armored OpenPGP private key, or a PEM encoded
+// SSH private key. The passphrase decrypts the key if it's encrypted.
+func ParseSigningKey(key, passphrase []byte) (*SigningKey, error) {
+	if bytes.Contains(key, []byte("BEGIN PGP PRIVATE KEY BLOCK")) {
+		return parseOpenPGPSigningKey(key, passphrase)
+	}
+
+	var (
+		signer ssh.Signer
+		err    error
+	)
+
+	if len(passphrase) > 0 {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(key, passphrase)
+	} else {
+		signer, err = ssh.ParsePrivateKey(key)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SSH signing key: %w", err)
+	}
+
+	return &SigningKey{ssh: signer}, nil
+}
+
+func parseOpenPGPSigningKey(key, passphrase []byte) (*SigningKey, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OpenPGP signing key: %w", err)
+	}
+
+	for _, entity := range entities {
+		if entity.PrivateKey == nil {
+			continue
+		}
+
+		if entity.PrivateKey.Encrypted {
+			if len(passphrase) == 0 {
+				return nil, errors.New("the OpenPGP signing key is encrypted, a passphrase is required")
+			}
+
+			if err := entity.PrivateKey.Decrypt(passphrase); err != nil {
+				return nil, fmt.Errorf("failed to decrypt OpenPGP signing key: %w", err)
+			}
+
+			for _, subkey := range entity.Subkeys {
+				if subkey.PrivateKey != nil && subkey.PrivateKey.Encrypted {
+					if err := subkey.PrivateKey.Decrypt(passphrase); err != nil {
+						return nil, fmt.Errorf("failed to decrypt OpenPGP signing subkey: %w", err)
+					}
+				}
+			}
+		}
+
+		return &SigningKey{openPGP: entity}, nil
+	}
+
+	return nil, errors.New("no OpenPGP private key found")
+}
+
+// LoadSigningKeyFile reads the signing key from a file.
+func LoadSigningKeyFile(path string, passphrase []byte) (*SigningKey, error) {
+	key, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+
+	return ParseSigningKey(key, passphrase)
+}
+
+// LoadSigningKeySecret reads the signing key, and its passphrase if any, from
+// a Secret.
+func LoadSigningKeySecret(ctx context.Context, c client.Client, name types.NamespacedName) (*SigningKey, error) {
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, name, secret); err != nil {
+		return nil, fmt.Errorf("failed to get signing key secret %s: %w", name, err)
+	}
+
+	key, ok := secret.Data[SigningKeySecretKey]
+	if !ok {
+		return nil, fmt.Errorf("secret %s has no %q key", name, SigningKeySecretKey)
+	}
+
+	return ParseSigningKey(key, secret.Data[SigningKeyPassphraseSecretKey])
+}
+
+// signCommit signs a commit made without signature, and moves the current
+// branch to the signed commit.
+func (k *SigningKey) signCommit(repo *gogit.Repository, hash plumbing.Hash) (plumbing.Hash, error) {
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to read commit %s: %w", hash, err)
+	}
+
+	payload, err := signedPayload(commit)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	signature, err := k.sshSign(payload)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to sign commit: %w", err)
+	}
+
+	commit.PGPSignature = signature
+
+	signed := repo.Storer.NewEncodedObject()
+	if err := commit.Encode(signed); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	signedHash, err := repo.Storer.SetEncodedObject(signed)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to store signed commit: %w", err)
+	}
+
+	head, err := repo.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	name := plumbing.HEAD
+	if head.Type() == plumbing.SymbolicReference {
+		name = head.Target()
+	}
+
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(name, signedHash)); err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to update %s: %w", name, err)
+	}
+
+	return signedHash, nil
+}
+
+// sshSign makes an armored signature in the format of ssh-keygen -Y sign.
+func (k *SigningKey) sshSign(message []byte) (string, error) {
+	hash := sha512.Sum512(message)
+
+	var (
+		sig *ssh.Signature
+		err error
+	)
+
+	data := sshSigSignedData(hash[:])
+
+	if signer, ok := k.ssh.(ssh.AlgorithmSigner); ok && k.ssh.PublicKey().Type() == ssh.KeyAlgoRSA {
+		// ssh-rsa signatures use SHA-1, which git rejects.
+		sig, err = signer.SignWithAlgorithm(nil, data, ssh.SigAlgoRSASHA2512)
+	} else {
+		sig, err = k.ssh.Sign(nil, data)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	blob := append([]byte(sshSigMagic), ssh.Marshal(struct {
+		Version       uint32
+		PublicKey     string
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Signature     string
+	}{
+		Version:       sshSigVersion,
+		PublicKey:     string(k.ssh.PublicKey().Marshal()),
+		Namespace:     sshSigNamespace,
+		HashAlgorithm: sshSigHashAlgo,
+		Signature:     string(ssh.Marshal(sig)),
+	})...)
+
+	encoded := base64.StdEncoding.EncodeToString(blob)
+
+	var armored strings.Builder
+
+	armored.WriteString(sshSigArmorStart + "\n")
+
+	for len(encoded) > sshSigArmorLength {
+		armored.WriteString(encoded[:sshSigArmorLength] + "\n")
+		encoded = encoded[sshSigArmorLength:]
+	}
+
+	armored.WriteString(encoded + "\n" + sshSigArmorEnd + "\n")
+
+	return armored.String(), nil
+}
+
+func sshSigSignedData(hash []byte) []byte {
+	return append([]byte(sshSigMagic), ssh.Marshal(struct {
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Hash          string
+	}{
+		Namespace:     sshSigNamespace,
+		HashAlgorithm: sshSigHashAlgo,
+		Hash:          string(hash),
+	})...)
+}
+
+// VerifyOpenPGPCommitSignature checks that a commit is signed by a key of the
+// armored OpenPGP key ring.
+func VerifyOpenPGPCommitSignature(repo *gogit.Repository, hash string, armoredKeyRing string) error {
+	commit, err := repo.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		return fmt.Errorf("failed to read commit %s: %w", hash, err)
+	}
+
+	if commit.PGPSignature == "" {
+		return ErrUnsignedCommit
+	}
+
+	if _, err := commit.Verify(armoredKeyRing); err != nil {
+		return fmt.Errorf("invalid signature of commit %s: %w", hash, err)
+	}
+
+	return nil
+}
+
+// VerifySSHCommitSignature checks that a commit is signed by the SSH key.
+func VerifySSHCommitSignature(repo *gogit.Repository, hash string, publicKey ssh.PublicKey) error {
+	commit, err := repo.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		return fmt.Errorf("failed to read commit %s: %w", hash, err)
+	}
+
+	if commit.PGPSignature == "" {
+		return ErrUnsignedCommit
+	}
+
+	if err := verifySSHSignature(commit, publicKey); err != nil {
+		return fmt.Errorf("invalid signature of commit %s: %w", hash, err)
+	}
+
+	return nil
+}
+
+func verifySSHSignature(commit *object.Commit, publicKey ssh.PublicKey) error {
+	armored := strings.TrimSpace(commit.PGPSignature)
+	if !strings.HasPrefix(armored, sshSigArmorStart) || !strings.HasSuffix(armored, sshSigArmorEnd) {
+		return errors.New("not an SSH signature")
+	}
+
+	encoded := strings.Join(strings.Fields(strings.TrimSuffix(strings.TrimPrefix(armored, sshSigArmorStart), sshSigArmorEnd)), "")
+
+	blob, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.HasPrefix(blob, []byte(sshSigMagic)) {
+		return errors.New("invalid SSH signature")
+	}
+
+	var sigBlob struct {
+		Version       uint32
+		PublicKey     string
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Signature     string
+	}
+
+	if err := ssh.Unmarshal(blob[len(sshSigMagic):], &sigBlob); err != nil {
+		return err
+	}
+
+	if sigBlob.Namespace != sshSigNamespace || sigBlob.HashAlgorithm != sshSigHashAlgo {
+		return fmt.Errorf("unsupported SSH signature namespace %q or hash algorithm %q", sigBlob.Namespace, sigBlob.HashAlgorithm)
+	}
+
+	if !bytes.Equal([]byte(sigBlob.PublicKey), publicKey.Marshal()) {
+		return errors.New("signed by another key")
+	}
+
+	sig := &ssh.Signature{}
+	if err := ssh.Unmarshal([]byte(sigBlob.Signature), sig); err != nil {
+		return err
+	}
+
+	payload, err := signedPayload(commit)
+	if err != nil {
+		return err
+	}
+
+	hash := sha512.Sum512(payload)
+
+	return publicKey.Verify(sshSigSignedData(hash[:]), sig)
+}
+
+// signedPayload returns the encoded commit without its signature, which is
+// what's signed.
+func signedPayload(commit *object.Commit) ([]byte, error) {
+	encoded := &plumbing.MemoryObject{}
+	if err := commit.EncodeWithoutSignature(encoded); err != nil {
+		return nil, err
+	}
+
+	r, err := encoded.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
@@ -0,0 +1,167 @@
+package git_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/weaveworks/weave-gitops/pkg/git"
+	"github.com/weaveworks/weave-gitops/pkg/git/wrapper"
+	"golang.org/x/crypto/ssh"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Signing commits", func() {
+	commit := func(key *git.SigningKey) string {
+		client := git.New(nil, wrapper.NewGoGit(), git.WithSigningKey(key))
+
+		_, err := client.Init(dir, "https://github.com/github/gitignore", "main")
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(client.Write("test.txt", []byte("testing"))).To(Succeed())
+
+		hash, err := client.Commit(git.Commit{
+			Author:  git.Author{Name: "test", Email: "test@example.com"},
+			Message: "signed commit",
+		})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		head, err := client.Head()
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(head).To(Equal(hash))
+
+		return hash
+	}
+
+	openPGPKey := func(passphrase []byte) ([]byte, string) {
+		entity, err := openpgp.NewEntity("test", "", "test@example.com", nil)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		public := &bytes.Buffer{}
+		w, err := armor.Encode(public, openpgp.PublicKeyType, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(entity.Serialize(w)).To(Succeed())
+		Expect(w.Close()).To(Succeed())
+
+		if passphrase != nil {
+			Expect(entity.PrivateKey.Encrypt(passphrase)).To(Succeed())
+
+			for _, subkey := range entity.Subkeys {
+				Expect(subkey.PrivateKey.Encrypt(passphrase)).To(Succeed())
+			}
+		}
+
+		private := &bytes.Buffer{}
+		w, err = armor.Encode(private, openpgp.PrivateKeyType, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(entity.SerializePrivateWithoutSigning(w, nil)).To(Succeed())
+		Expect(w.Close()).To(Succeed())
+
+		return private.Bytes(), public.String()
+	}
+
+	It("signs commits with OpenPGP keys", func() {
+		private, public := openPGPKey(nil)
+
+		key, err := git.ParseSigningKey(private, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		hash := commit(key)
+
+		repo, err := gitClient.Open(dir)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(git.VerifyOpenPGPCommitSignature(repo, hash, public)).To(Succeed())
+
+		other, _ := openPGPKey(nil)
+		otherPublic := string(other)
+		Expect(git.VerifyOpenPGPCommitSignature(repo, hash, otherPublic)).NotTo(Succeed())
+	})
+
+	It("decrypts OpenPGP keys with the passphrase", func() {
+		private, _ := openPGPKey([]byte("secret"))
+
+		_, err := git.ParseSigningKey(private, nil)
+		Expect(err).To(MatchError(ContainSubstring("a passphrase is required")))
+
+		_, err = git.ParseSigningKey(private, []byte("wrong"))
+		Expect(err).To(HaveOccurred())
+
+		_, err = git.ParseSigningKey(private, []byte("secret"))
+		Expect(err).ShouldNot(HaveOccurred())
+	})
+
+	It("signs commits with SSH keys", func() {
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		der, err := x509.MarshalPKCS8PrivateKey(private)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		keyFile := filepath.Join(GinkgoT().TempDir(), "id_ed25519")
+		Expect(os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)).To(Succeed())
+
+		key, err := git.LoadSigningKeyFile(keyFile, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		hash := commit(key)
+
+		sshPublic, err := ssh.NewPublicKey(public)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		repo, err := gitClient.Open(dir)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(git.VerifySSHCommitSignature(repo, hash, sshPublic)).To(Succeed())
+
+		otherPublic, _, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ShouldNot(HaveOccurred())
+		otherSSHPublic, err := ssh.NewPublicKey(otherPublic)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(git.VerifySSHCommitSignature(repo, hash, otherSSHPublic)).To(MatchError(ContainSubstring("signed by another key")))
+	})
+
+	It("loads RSA keys from a secret", func() {
+		private, err := rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "signing-key", Namespace: "flux-system"},
+			Data: map[string][]byte{
+				git.SigningKeySecretKey: pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(private)}),
+			},
+		}
+
+		c := fake.NewClientBuilder().WithObjects(secret).Build()
+
+		key, err := git.LoadSigningKeySecret(context.Background(), c, types.NamespacedName{Name: "signing-key", Namespace: "flux-system"})
+		Expect(err).ShouldNot(HaveOccurred())
+
+		hash := commit(key)
+
+		sshPublic, err := ssh.NewPublicKey(&private.PublicKey)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		repo, err := gitClient.Open(dir)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(git.VerifySSHCommitSignature(repo, hash, sshPublic)).To(Succeed())
+	})
+
+	It("reports unsigned commits", func() {
+		hash := commit(nil)
+
+		repo, err := gitClient.Open(dir)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(git.VerifySSHCommitSignature(repo, hash, nil)).To(MatchError(git.ErrUnsignedCommit))
+	})
+})
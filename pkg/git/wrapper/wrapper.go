@@ -3,7 +3,9 @@ package wrapper
 import (
 	"context"
 
+	"github.com/go-git/go-billy/v5"
 	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/storage"
 )
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 -generate
@@ -13,6 +15,8 @@ type Git interface {
 	PlainCloneContext(ctx context.Context, path string, isBare bool, o *gogit.CloneOptions) (*gogit.Repository, error)
 	PlainOpen(path string) (*gogit.Repository, error)
 	PlainInit(path string, isBare bool) (*gogit.Repository, error)
+	CloneContext(ctx context.Context, s storage.Storer, worktree billy.Filesystem, o *gogit.CloneOptions) (*gogit.Repository, error)
+	Init(s storage.Storer, worktree billy.Filesystem) (*gogit.Repository, error)
 }
 
 type goGit struct{}
@@ -31,6 +35,14 @@ func (g *goGit) PlainInit(path string, isBare bool) (*gogit.Repository, error) {
 	return gogit.PlainInit(path, isBare)
 }
 
+func (g *goGit) CloneContext(ctx context.Context, s storage.Storer, worktree billy.Filesystem, o *gogit.CloneOptions) (*gogit.Repository, error) {
+	return gogit.CloneContext(ctx, s, worktree, o)
+}
+
+func (g *goGit) Init(s storage.Storer, worktree billy.Filesystem) (*gogit.Repository, error) {
+	return gogit.Init(s, worktree)
+}
+
 func NewGoGit() Git {
 	return &goGit{}
 }
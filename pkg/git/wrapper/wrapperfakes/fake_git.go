@@ -5,11 +5,43 @@ import (
 	"context"
 	"sync"
 
+	billy "github.com/go-git/go-billy/v5"
 	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/storage"
 	"github.com/weaveworks/weave-gitops/pkg/git/wrapper"
 )
 
 type FakeGit struct {
+	CloneContextStub        func(context.Context, storage.Storer, billy.Filesystem, *git.CloneOptions) (*git.Repository, error)
+	cloneContextMutex       sync.RWMutex
+	cloneContextArgsForCall []struct {
+		arg1 context.Context
+		arg2 storage.Storer
+		arg3 billy.Filesystem
+		arg4 *git.CloneOptions
+	}
+	cloneContextReturns struct {
+		result1 *git.Repository
+		result2 error
+	}
+	cloneContextReturnsOnCall map[int]struct {
+		result1 *git.Repository
+		result2 error
+	}
+	InitStub        func(storage.Storer, billy.Filesystem) (*git.Repository, error)
+	initMutex       sync.RWMutex
+	initArgsForCall []struct {
+		arg1 storage.Storer
+		arg2 billy.Filesystem
+	}
+	initReturns struct {
+		result1 *git.Repository
+		result2 error
+	}
+	initReturnsOnCall map[int]struct {
+		result1 *git.Repository
+		result2 error
+	}
 	PlainCloneContextStub        func(context.Context, string, bool, *git.CloneOptions) (*git.Repository, error)
 	plainCloneContextMutex       sync.RWMutex
 	plainCloneContextArgsForCall []struct {
@@ -57,6 +89,138 @@ type FakeGit struct {
 	invocationsMutex sync.RWMutex
 }
 
+func (fake *FakeGit) CloneContext(arg1 context.Context, arg2 storage.Storer, arg3 billy.Filesystem, arg4 *git.CloneOptions) (*git.Repository, error) {
+	fake.cloneContextMutex.Lock()
+	ret, specificReturn := fake.cloneContextReturnsOnCall[len(fake.cloneContextArgsForCall)]
+	fake.cloneContextArgsForCall = append(fake.cloneContextArgsForCall, struct {
+		arg1 context.Context
+		arg2 storage.Storer
+		arg3 billy.Filesystem
+		arg4 *git.CloneOptions
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.CloneContextStub
+	fakeReturns := fake.cloneContextReturns
+	fake.recordInvocation("CloneContext", []interface{}{arg1, arg2, arg3, arg4})
+	fake.cloneContextMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeGit) CloneContextCallCount() int {
+	fake.cloneContextMutex.RLock()
+	defer fake.cloneContextMutex.RUnlock()
+	return len(fake.cloneContextArgsForCall)
+}
+
+func (fake *FakeGit) CloneContextCalls(stub func(context.Context, storage.Storer, billy.Filesystem, *git.CloneOptions) (*git.Repository, error)) {
+	fake.cloneContextMutex.Lock()
+	defer fake.cloneContextMutex.Unlock()
+	fake.CloneContextStub = stub
+}
+
+func (fake *FakeGit) CloneContextArgsForCall(i int) (context.Context, storage.Storer, billy.Filesystem, *git.CloneOptions) {
+	fake.cloneContextMutex.RLock()
+	defer fake.cloneContextMutex.RUnlock()
+	argsForCall := fake.cloneContextArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeGit) CloneContextReturns(result1 *git.Repository, result2 error) {
+	fake.cloneContextMutex.Lock()
+	defer fake.cloneContextMutex.Unlock()
+	fake.CloneContextStub = nil
+	fake.cloneContextReturns = struct {
+		result1 *git.Repository
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGit) CloneContextReturnsOnCall(i int, result1 *git.Repository, result2 error) {
+	fake.cloneContextMutex.Lock()
+	defer fake.cloneContextMutex.Unlock()
+	fake.CloneContextStub = nil
+	if fake.cloneContextReturnsOnCall == nil {
+		fake.cloneContextReturnsOnCall = make(map[int]struct {
+			result1 *git.Repository
+			result2 error
+		})
+	}
+	fake.cloneContextReturnsOnCall[i] = struct {
+		result1 *git.Repository
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGit) Init(arg1 storage.Storer, arg2 billy.Filesystem) (*git.Repository, error) {
+	fake.initMutex.Lock()
+	ret, specificReturn := fake.initReturnsOnCall[len(fake.initArgsForCall)]
+	fake.initArgsForCall = append(fake.initArgsForCall, struct {
+		arg1 storage.Storer
+		arg2 billy.Filesystem
+	}{arg1, arg2})
+	stub := fake.InitStub
+	fakeReturns := fake.initReturns
+	fake.recordInvocation("Init", []interface{}{arg1, arg2})
+	fake.initMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeGit) InitCallCount() int {
+	fake.initMutex.RLock()
+	defer fake.initMutex.RUnlock()
+	return len(fake.initArgsForCall)
+}
+
+func (fake *FakeGit) InitCalls(stub func(storage.Storer, billy.Filesystem) (*git.Repository, error)) {
+	fake.initMutex.Lock()
+	defer fake.initMutex.Unlock()
+	fake.InitStub = stub
+}
+
+func (fake *FakeGit) InitArgsForCall(i int) (storage.Storer, billy.Filesystem) {
+	fake.initMutex.RLock()
+	defer fake.initMutex.RUnlock()
+	argsForCall := fake.initArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeGit) InitReturns(result1 *git.Repository, result2 error) {
+	fake.initMutex.Lock()
+	defer fake.initMutex.Unlock()
+	fake.InitStub = nil
+	fake.initReturns = struct {
+		result1 *git.Repository
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGit) InitReturnsOnCall(i int, result1 *git.Repository, result2 error) {
+	fake.initMutex.Lock()
+	defer fake.initMutex.Unlock()
+	fake.InitStub = nil
+	if fake.initReturnsOnCall == nil {
+		fake.initReturnsOnCall = make(map[int]struct {
+			result1 *git.Repository
+			result2 error
+		})
+	}
+	fake.initReturnsOnCall[i] = struct {
+		result1 *git.Repository
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeGit) PlainCloneContext(arg1 context.Context, arg2 string, arg3 bool, arg4 *git.CloneOptions) (*git.Repository, error) {
 	fake.plainCloneContextMutex.Lock()
 	ret, specificReturn := fake.plainCloneContextReturnsOnCall[len(fake.plainCloneContextArgsForCall)]
@@ -256,6 +420,10 @@ func (fake *FakeGit) PlainOpenReturnsOnCall(i int, result1 *git.Repository, resu
 func (fake *FakeGit) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.cloneContextMutex.RLock()
+	defer fake.cloneContextMutex.RUnlock()
+	fake.initMutex.RLock()
+	defer fake.initMutex.RUnlock()
 	fake.plainCloneContextMutex.RLock()
 	defer fake.plainCloneContextMutex.RUnlock()
 	fake.plainInitMutex.RLock()
@@ -0,0 +1,245 @@
+package gitproviders
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fluxcd/go-git-providers/gitprovider"
+)
+
+const (
+	DefaultCacheSize = 1000
+	DefaultCacheTTL  = time.Minute
+)
+
+// CacheConfig configures how the read calls of git providers are cached.
+type CacheConfig struct {
+	// Size is the maximum number of cached responses, nothing is cached if
+	// it's 0.
+	Size int
+	// TTL is how long the result of a read call is used without asking the
+	// provider again. The responses are then revalidated with their ETag, so
+	// that unchanged ones don't count against the rate limits.
+	TTL time.Duration
+}
+
+func DefaultCacheConfig() CacheConfig {
+	return CacheConfig{
+		Size: DefaultCacheSize,
+		TTL:  DefaultCacheTTL,
+	}
+}
+
+// Cache holds the responses of git providers. It's shared by the providers
+// made for the same tokens, so that it outlives them.
+type Cache struct {
+	config CacheConfig
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// order has the most recently used entries first.
+	order *list.List
+}
+
+type cacheEntry struct {
+	key   string
+	value interface{}
+	// expires is zero for entries that are kept until they're evicted.
+	expires time.Time
+}
+
+func NewCache(config CacheConfig) *Cache {
+	return &Cache{
+		config:  config,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+// get returns the value of the key, and whether it's still fresh.
+func (c *Cache) get(key string) (interface{}, bool, bool) {
+	if c == nil {
+		return nil, false, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false, false
+	}
+
+	c.order.MoveToFront(el)
+
+	entry := el.Value.(*cacheEntry)
+	fresh := entry.expires.IsZero() || time.Now().Before(entry.expires)
+
+	return entry.value, fresh, true
+}
+
+// set stores the value of the key for ttl, or until it's evicted if ttl is
+// 0, evicting the least recently used entries beyond the size of the cache.
+func (c *Cache) set(key string, value interface{}, ttl time.Duration) {
+	if c == nil || c.config.Size <= 0 {
+		return
+	}
+
+	entry := &cacheEntry{key: key, value: value}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		el.Value = entry
+		c.order.MoveToFront(el)
+
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+
+	for c.order.Len() > c.config.Size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// deletePrefix deletes the entries whose key starts with the prefix.
+func (c *Cache) deletePrefix(prefix string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, el := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			c.order.Remove(el)
+			delete(c.entries, key)
+		}
+	}
+}
+
+// cachePrefix keeps the responses of providers made with different tokens
+// apart, as they may not have access to the same repositories.
+func cachePrefix(config Config) string {
+	return fmt.Sprintf("%s/%s/%x/", config.Provider, config.Hostname, sha256.Sum256([]byte(config.Token)))
+}
+
+// cachingProvider decorates a GitProvider, caching the results of its read
+// calls for the TTL of the cache. The results of a repository are dropped
+// when the provider changes it.
+type cachingProvider struct {
+	GitProvider
+	cache    *Cache
+	prefix   string
+	provider GitProviderName
+}
+
+func newCachingProvider(provider GitProvider, config Config) GitProvider {
+	if config.Cache == nil || config.Cache.config.Size <= 0 {
+		return provider
+	}
+
+	return cachingProvider{
+		GitProvider: provider,
+		cache:       config.Cache,
+		prefix:      cachePrefix(config) + "calls/",
+		provider:    config.Provider,
+	}
+}
+
+func (p cachingProvider) repoKey(repoUrl RepoURL) string {
+	return p.prefix + repoUrl.String() + "/"
+}
+
+// cached returns the cached result of the call, or calls it and caches its
+// result.
+func (p cachingProvider) cached(key string, call func() (interface{}, error)) (interface{}, error) {
+	if value, fresh, ok := p.cache.get(key); ok && fresh {
+		cacheRequests.WithLabelValues(string(p.provider), cacheResultHit).Inc()
+		return value, nil
+	}
+
+	cacheRequests.WithLabelValues(string(p.provider), cacheResultMiss).Inc()
+
+	value, err := call()
+	if err != nil {
+		return nil, err
+	}
+
+	p.cache.set(key, value, p.cache.config.TTL)
+
+	return value, nil
+}
+
+func (p cachingProvider) GetDefaultBranch(ctx context.Context, repoUrl RepoURL) (string, error) {
+	value, err := p.cached(p.repoKey(repoUrl)+"default-branch", func() (interface{}, error) {
+		return p.GitProvider.GetDefaultBranch(ctx, repoUrl)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return value.(string), nil
+}
+
+func (p cachingProvider) GetCommits(ctx context.Context, repoUrl RepoURL, targetBranch string, pageSize int, pageToken int) ([]gitprovider.Commit, error) {
+	key := fmt.Sprintf("%scommits/%s/%d/%d", p.repoKey(repoUrl), targetBranch, pageSize, pageToken)
+
+	value, err := p.cached(key, func() (interface{}, error) {
+		return p.GitProvider.GetCommits(ctx, repoUrl, targetBranch, pageSize, pageToken)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	commits := value.([]gitprovider.Commit)
+
+	return append([]gitprovider.Commit{}, commits...), nil
+}
+
+func (p cachingProvider) GetRepoDirFiles(ctx context.Context, repoUrl RepoURL, dirPath, targetBranch string) ([]*gitprovider.CommitFile, error) {
+	key := fmt.Sprintf("%sfiles/%s/%s", p.repoKey(repoUrl), targetBranch, dirPath)
+
+	value, err := p.cached(key, func() (interface{}, error) {
+		return p.GitProvider.GetRepoDirFiles(ctx, repoUrl, dirPath, targetBranch)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The files are copied, as callers may change them.
+	files := value.([]*gitprovider.CommitFile)
+	res := make([]*gitprovider.CommitFile, len(files))
+
+	for i, f := range files {
+		file := *f
+		res[i] = &file
+	}
+
+	return res, nil
+}
+
+func (p cachingProvider) CreatePullRequest(ctx context.Context, repoUrl RepoURL, prInfo PullRequestInfo) (gitprovider.PullRequest, error) {
+	defer p.cache.deletePrefix(p.repoKey(repoUrl))
+
+	return p.GitProvider.CreatePullRequest(ctx, repoUrl, prInfo)
+}
+
+func (p cachingProvider) MergePullRequest(ctx context.Context, repoUrl RepoURL, pullRequestNumber int, commitMesage string) error {
+	defer p.cache.deletePrefix(p.repoKey(repoUrl))
+
+	return p.GitProvider.MergePullRequest(ctx, repoUrl, pullRequestNumber, commitMesage)
+}
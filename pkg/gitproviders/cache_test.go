@@ -0,0 +1,148 @@
+package gitproviders
+
+import (
+	"context"
+	"time"
+
+	"github.com/fluxcd/go-git-providers/gitprovider"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// countingProvider counts the read calls that reach it.
+type countingProvider struct {
+	GitProvider
+	calls int
+}
+
+func (p *countingProvider) GetDefaultBranch(ctx context.Context, repoUrl RepoURL) (string, error) {
+	p.calls++
+	return "main", nil
+}
+
+func (p *countingProvider) GetRepoDirFiles(ctx context.Context, repoUrl RepoURL, dirPath, targetBranch string) ([]*gitprovider.CommitFile, error) {
+	p.calls++
+
+	path, content := dirPath+"/a.yaml", "a: b"
+
+	return []*gitprovider.CommitFile{{Path: &path, Content: &content}}, nil
+}
+
+func (p *countingProvider) MergePullRequest(ctx context.Context, repoUrl RepoURL, pullRequestNumber int, commitMesage string) error {
+	return nil
+}
+
+var _ = Describe("Cache", func() {
+	It("evicts the least recently used entries", func() {
+		cache := NewCache(CacheConfig{Size: 2})
+
+		cache.set("a", 1, 0)
+		cache.set("b", 2, 0)
+		_, _, _ = cache.get("a")
+		cache.set("c", 3, 0)
+
+		_, _, ok := cache.get("b")
+		Expect(ok).To(BeFalse())
+
+		value, fresh, ok := cache.get("a")
+		Expect(ok).To(BeTrue())
+		Expect(fresh).To(BeTrue())
+		Expect(value).To(Equal(1))
+	})
+
+	It("keeps expired entries as stale", func() {
+		cache := NewCache(CacheConfig{Size: 1})
+
+		cache.set("a", 1, time.Nanosecond)
+		time.Sleep(time.Millisecond)
+
+		_, fresh, ok := cache.get("a")
+		Expect(ok).To(BeTrue())
+		Expect(fresh).To(BeFalse())
+	})
+
+	It("doesn't cache anything without a size", func() {
+		cache := NewCache(CacheConfig{})
+
+		cache.set("a", 1, 0)
+
+		_, _, ok := cache.get("a")
+		Expect(ok).To(BeFalse())
+	})
+})
+
+var _ = Describe("cachingProvider", func() {
+	var (
+		inner    *countingProvider
+		provider GitProvider
+		repoUrl  RepoURL
+	)
+
+	BeforeEach(func() {
+		var err error
+		repoUrl, err = NewRepoURL("ssh://git@github.com/owner/repo.git")
+		Expect(err).NotTo(HaveOccurred())
+
+		inner = &countingProvider{}
+		provider = newCachingProvider(inner, Config{
+			Provider: GitProviderGitHub,
+			Hostname: "github.com",
+			Token:    "token",
+			Cache:    NewCache(CacheConfig{Size: 10, TTL: time.Minute}),
+		})
+	})
+
+	It("isn't used without a cache", func() {
+		Expect(newCachingProvider(inner, Config{})).To(BeIdenticalTo(inner))
+	})
+
+	It("caches read calls", func() {
+		for i := 0; i < 2; i++ {
+			branch, err := provider.GetDefaultBranch(context.Background(), repoUrl)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(branch).To(Equal("main"))
+		}
+
+		Expect(inner.calls).To(Equal(1))
+	})
+
+	It("returns copies of the cached files", func() {
+		files, err := provider.GetRepoDirFiles(context.Background(), repoUrl, "dir", "main")
+		Expect(err).NotTo(HaveOccurred())
+
+		other := "changed"
+		files[0].Content = &other
+
+		files, err = provider.GetRepoDirFiles(context.Background(), repoUrl, "dir", "main")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*files[0].Content).To(Equal("a: b"))
+		Expect(inner.calls).To(Equal(1))
+	})
+
+	It("keeps the calls of different tokens apart", func() {
+		other := newCachingProvider(inner, Config{
+			Provider: GitProviderGitHub,
+			Hostname: "github.com",
+			Token:    "other",
+			Cache:    provider.(cachingProvider).cache,
+		})
+
+		_, err := provider.GetDefaultBranch(context.Background(), repoUrl)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = other.GetDefaultBranch(context.Background(), repoUrl)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(inner.calls).To(Equal(2))
+	})
+
+	It("drops the results of a repository when it's changed", func() {
+		_, err := provider.GetDefaultBranch(context.Background(), repoUrl)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(provider.MergePullRequest(context.Background(), repoUrl, 1, "merge")).To(Succeed())
+
+		_, err = provider.GetDefaultBranch(context.Background(), repoUrl)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inner.calls).To(Equal(2))
+	})
+})
@@ -46,6 +46,10 @@ func (p *dryrunProvider) UploadDeployKey(_ context.Context, repoUrl RepoURL, dep
 	return nil
 }
 
+func (p *dryrunProvider) DeleteDeployKey(_ context.Context, repoUrl RepoURL, deployKey []byte) error {
+	return nil
+}
+
 func (p *dryrunProvider) CreatePullRequest(_ context.Context, repoUrl RepoURL, prInfo PullRequestInfo) (gitprovider.PullRequest, error) {
 	return nil, nil
 }
@@ -65,3 +69,11 @@ func (p *dryrunProvider) GetRepoDirFiles(_ context.Context, repoUrl RepoURL, dir
 func (p *dryrunProvider) MergePullRequest(ctx context.Context, repoUrl RepoURL, pullRequestNumber int, commitMesage string) error {
 	return nil
 }
+
+func (p *dryrunProvider) ListPullRequests(_ context.Context, repoUrl RepoURL, state PullRequestState, filter PullRequestFilter) ([]PullRequest, error) {
+	return []PullRequest{}, nil
+}
+
+func (p *dryrunProvider) GetPullRequest(_ context.Context, repoUrl RepoURL, number int) (*PullRequest, error) {
+	return nil, gitprovider.ErrNotFound
+}
@@ -2,6 +2,7 @@ package gitproviders
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/fluxcd/go-git-providers/github"
 	"github.com/fluxcd/go-git-providers/gitlab"
@@ -14,7 +15,13 @@ type GitProviderName string
 const (
 	GitProviderGitHub GitProviderName = "github"
 	GitProviderGitLab GitProviderName = "gitlab"
-	tokenTypeOauth    string          = "oauth2"
+	// GitProviderGitea and GitProviderBitbucketServer are implemented with
+	// their REST APIs.
+	GitProviderGitea           GitProviderName = "gitea"
+	GitProviderBitbucketServer GitProviderName = "bitbucket-server"
+	// GitProviderLocal works on bare repositories on the local filesystem.
+	GitProviderLocal GitProviderName = "local"
+	tokenTypeOauth   string          = "oauth2"
 )
 
 // Config defines the configuration for connecting to a GitProvider.
@@ -29,6 +36,24 @@ type Config struct {
 	// Token contains the token used to authenticate with the
 	// Provider.
 	Token string
+
+	// APIURL is the base URL of the Provider's API, when it isn't served
+	// over HTTPS on Hostname, e.g. http://localhost:3000. Only used by
+	// the Gitea and Bitbucket Server providers.
+	APIURL string
+
+	// Cache holds the responses of the Provider, they aren't cached if it's
+	// nil.
+	Cache *Cache
+}
+
+// apiBaseURL returns the URL the REST API of the Provider is served under.
+func apiBaseURL(config Config) string {
+	if config.APIURL != "" {
+		return strings.TrimSuffix(config.APIURL, "/")
+	}
+
+	return "https://" + config.Hostname
 }
 
 func buildGitProvider(config Config) (gitprovider.Client, string, error) {
@@ -40,6 +65,7 @@ func buildGitProvider(config Config) (gitprovider.Client, string, error) {
 	case GitProviderGitHub:
 		opts := []gitprovider.ClientOption{
 			gitprovider.WithOAuth2Token(config.Token),
+			gitprovider.WithPostChainTransportHook(rateLimitTransportHook(config)),
 		}
 
 		// Quirk of ggp, if using github.com or gitlab.com and you prepend
@@ -60,6 +86,7 @@ func buildGitProvider(config Config) (gitprovider.Client, string, error) {
 		opts := []gitprovider.ClientOption{
 			gitprovider.WithOAuth2Token(config.Token),
 			gitprovider.WithConditionalRequests(true),
+			gitprovider.WithPostChainTransportHook(rateLimitTransportHook(config)),
 		}
 
 		// Quirk, see above
@@ -25,6 +25,19 @@ type FakeGitProvider struct {
 		result1 gitprovider.PullRequest
 		result2 error
 	}
+	DeleteDeployKeyStub        func(context.Context, gitproviders.RepoURL, []byte) error
+	deleteDeployKeyMutex       sync.RWMutex
+	deleteDeployKeyArgsForCall []struct {
+		arg1 context.Context
+		arg2 gitproviders.RepoURL
+		arg3 []byte
+	}
+	deleteDeployKeyReturns struct {
+		result1 error
+	}
+	deleteDeployKeyReturnsOnCall map[int]struct {
+		result1 error
+	}
 	DeployKeyExistsStub        func(context.Context, gitproviders.RepoURL) (bool, error)
 	deployKeyExistsMutex       sync.RWMutex
 	deployKeyExistsArgsForCall []struct {
@@ -80,6 +93,21 @@ type FakeGitProvider struct {
 	getProviderDomainReturnsOnCall map[int]struct {
 		result1 string
 	}
+	GetPullRequestStub        func(context.Context, gitproviders.RepoURL, int) (*gitproviders.PullRequest, error)
+	getPullRequestMutex       sync.RWMutex
+	getPullRequestArgsForCall []struct {
+		arg1 context.Context
+		arg2 gitproviders.RepoURL
+		arg3 int
+	}
+	getPullRequestReturns struct {
+		result1 *gitproviders.PullRequest
+		result2 error
+	}
+	getPullRequestReturnsOnCall map[int]struct {
+		result1 *gitproviders.PullRequest
+		result2 error
+	}
 	GetRepoDirFilesStub        func(context.Context, gitproviders.RepoURL, string, string) ([]*gitprovider.CommitFile, error)
 	getRepoDirFilesMutex       sync.RWMutex
 	getRepoDirFilesArgsForCall []struct {
@@ -110,6 +138,22 @@ type FakeGitProvider struct {
 		result1 *gitprovider.RepositoryVisibility
 		result2 error
 	}
+	ListPullRequestsStub        func(context.Context, gitproviders.RepoURL, gitproviders.PullRequestState, gitproviders.PullRequestFilter) ([]gitproviders.PullRequest, error)
+	listPullRequestsMutex       sync.RWMutex
+	listPullRequestsArgsForCall []struct {
+		arg1 context.Context
+		arg2 gitproviders.RepoURL
+		arg3 gitproviders.PullRequestState
+		arg4 gitproviders.PullRequestFilter
+	}
+	listPullRequestsReturns struct {
+		result1 []gitproviders.PullRequest
+		result2 error
+	}
+	listPullRequestsReturnsOnCall map[int]struct {
+		result1 []gitproviders.PullRequest
+		result2 error
+	}
 	MergePullRequestStub        func(context.Context, gitproviders.RepoURL, int, string) error
 	mergePullRequestMutex       sync.RWMutex
 	mergePullRequestArgsForCall []struct {
@@ -221,6 +265,74 @@ func (fake *FakeGitProvider) CreatePullRequestReturnsOnCall(i int, result1 gitpr
 	}{result1, result2}
 }
 
+func (fake *FakeGitProvider) DeleteDeployKey(arg1 context.Context, arg2 gitproviders.RepoURL, arg3 []byte) error {
+	var arg3Copy []byte
+	if arg3 != nil {
+		arg3Copy = make([]byte, len(arg3))
+		copy(arg3Copy, arg3)
+	}
+	fake.deleteDeployKeyMutex.Lock()
+	ret, specificReturn := fake.deleteDeployKeyReturnsOnCall[len(fake.deleteDeployKeyArgsForCall)]
+	fake.deleteDeployKeyArgsForCall = append(fake.deleteDeployKeyArgsForCall, struct {
+		arg1 context.Context
+		arg2 gitproviders.RepoURL
+		arg3 []byte
+	}{arg1, arg2, arg3Copy})
+	stub := fake.DeleteDeployKeyStub
+	fakeReturns := fake.deleteDeployKeyReturns
+	fake.recordInvocation("DeleteDeployKey", []interface{}{arg1, arg2, arg3Copy})
+	fake.deleteDeployKeyMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeGitProvider) DeleteDeployKeyCallCount() int {
+	fake.deleteDeployKeyMutex.RLock()
+	defer fake.deleteDeployKeyMutex.RUnlock()
+	return len(fake.deleteDeployKeyArgsForCall)
+}
+
+func (fake *FakeGitProvider) DeleteDeployKeyCalls(stub func(context.Context, gitproviders.RepoURL, []byte) error) {
+	fake.deleteDeployKeyMutex.Lock()
+	defer fake.deleteDeployKeyMutex.Unlock()
+	fake.DeleteDeployKeyStub = stub
+}
+
+func (fake *FakeGitProvider) DeleteDeployKeyArgsForCall(i int) (context.Context, gitproviders.RepoURL, []byte) {
+	fake.deleteDeployKeyMutex.RLock()
+	defer fake.deleteDeployKeyMutex.RUnlock()
+	argsForCall := fake.deleteDeployKeyArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeGitProvider) DeleteDeployKeyReturns(result1 error) {
+	fake.deleteDeployKeyMutex.Lock()
+	defer fake.deleteDeployKeyMutex.Unlock()
+	fake.DeleteDeployKeyStub = nil
+	fake.deleteDeployKeyReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeGitProvider) DeleteDeployKeyReturnsOnCall(i int, result1 error) {
+	fake.deleteDeployKeyMutex.Lock()
+	defer fake.deleteDeployKeyMutex.Unlock()
+	fake.DeleteDeployKeyStub = nil
+	if fake.deleteDeployKeyReturnsOnCall == nil {
+		fake.deleteDeployKeyReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.deleteDeployKeyReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeGitProvider) DeployKeyExists(arg1 context.Context, arg2 gitproviders.RepoURL) (bool, error) {
 	fake.deployKeyExistsMutex.Lock()
 	ret, specificReturn := fake.deployKeyExistsReturnsOnCall[len(fake.deployKeyExistsArgsForCall)]
@@ -472,6 +584,72 @@ func (fake *FakeGitProvider) GetProviderDomainReturnsOnCall(i int, result1 strin
 	}{result1}
 }
 
+func (fake *FakeGitProvider) GetPullRequest(arg1 context.Context, arg2 gitproviders.RepoURL, arg3 int) (*gitproviders.PullRequest, error) {
+	fake.getPullRequestMutex.Lock()
+	ret, specificReturn := fake.getPullRequestReturnsOnCall[len(fake.getPullRequestArgsForCall)]
+	fake.getPullRequestArgsForCall = append(fake.getPullRequestArgsForCall, struct {
+		arg1 context.Context
+		arg2 gitproviders.RepoURL
+		arg3 int
+	}{arg1, arg2, arg3})
+	stub := fake.GetPullRequestStub
+	fakeReturns := fake.getPullRequestReturns
+	fake.recordInvocation("GetPullRequest", []interface{}{arg1, arg2, arg3})
+	fake.getPullRequestMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeGitProvider) GetPullRequestCallCount() int {
+	fake.getPullRequestMutex.RLock()
+	defer fake.getPullRequestMutex.RUnlock()
+	return len(fake.getPullRequestArgsForCall)
+}
+
+func (fake *FakeGitProvider) GetPullRequestCalls(stub func(context.Context, gitproviders.RepoURL, int) (*gitproviders.PullRequest, error)) {
+	fake.getPullRequestMutex.Lock()
+	defer fake.getPullRequestMutex.Unlock()
+	fake.GetPullRequestStub = stub
+}
+
+func (fake *FakeGitProvider) GetPullRequestArgsForCall(i int) (context.Context, gitproviders.RepoURL, int) {
+	fake.getPullRequestMutex.RLock()
+	defer fake.getPullRequestMutex.RUnlock()
+	argsForCall := fake.getPullRequestArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeGitProvider) GetPullRequestReturns(result1 *gitproviders.PullRequest, result2 error) {
+	fake.getPullRequestMutex.Lock()
+	defer fake.getPullRequestMutex.Unlock()
+	fake.GetPullRequestStub = nil
+	fake.getPullRequestReturns = struct {
+		result1 *gitproviders.PullRequest
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGitProvider) GetPullRequestReturnsOnCall(i int, result1 *gitproviders.PullRequest, result2 error) {
+	fake.getPullRequestMutex.Lock()
+	defer fake.getPullRequestMutex.Unlock()
+	fake.GetPullRequestStub = nil
+	if fake.getPullRequestReturnsOnCall == nil {
+		fake.getPullRequestReturnsOnCall = make(map[int]struct {
+			result1 *gitproviders.PullRequest
+			result2 error
+		})
+	}
+	fake.getPullRequestReturnsOnCall[i] = struct {
+		result1 *gitproviders.PullRequest
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeGitProvider) GetRepoDirFiles(arg1 context.Context, arg2 gitproviders.RepoURL, arg3 string, arg4 string) ([]*gitprovider.CommitFile, error) {
 	fake.getRepoDirFilesMutex.Lock()
 	ret, specificReturn := fake.getRepoDirFilesReturnsOnCall[len(fake.getRepoDirFilesArgsForCall)]
@@ -604,6 +782,73 @@ func (fake *FakeGitProvider) GetRepoVisibilityReturnsOnCall(i int, result1 *gitp
 	}{result1, result2}
 }
 
+func (fake *FakeGitProvider) ListPullRequests(arg1 context.Context, arg2 gitproviders.RepoURL, arg3 gitproviders.PullRequestState, arg4 gitproviders.PullRequestFilter) ([]gitproviders.PullRequest, error) {
+	fake.listPullRequestsMutex.Lock()
+	ret, specificReturn := fake.listPullRequestsReturnsOnCall[len(fake.listPullRequestsArgsForCall)]
+	fake.listPullRequestsArgsForCall = append(fake.listPullRequestsArgsForCall, struct {
+		arg1 context.Context
+		arg2 gitproviders.RepoURL
+		arg3 gitproviders.PullRequestState
+		arg4 gitproviders.PullRequestFilter
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.ListPullRequestsStub
+	fakeReturns := fake.listPullRequestsReturns
+	fake.recordInvocation("ListPullRequests", []interface{}{arg1, arg2, arg3, arg4})
+	fake.listPullRequestsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeGitProvider) ListPullRequestsCallCount() int {
+	fake.listPullRequestsMutex.RLock()
+	defer fake.listPullRequestsMutex.RUnlock()
+	return len(fake.listPullRequestsArgsForCall)
+}
+
+func (fake *FakeGitProvider) ListPullRequestsCalls(stub func(context.Context, gitproviders.RepoURL, gitproviders.PullRequestState, gitproviders.PullRequestFilter) ([]gitproviders.PullRequest, error)) {
+	fake.listPullRequestsMutex.Lock()
+	defer fake.listPullRequestsMutex.Unlock()
+	fake.ListPullRequestsStub = stub
+}
+
+func (fake *FakeGitProvider) ListPullRequestsArgsForCall(i int) (context.Context, gitproviders.RepoURL, gitproviders.PullRequestState, gitproviders.PullRequestFilter) {
+	fake.listPullRequestsMutex.RLock()
+	defer fake.listPullRequestsMutex.RUnlock()
+	argsForCall := fake.listPullRequestsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeGitProvider) ListPullRequestsReturns(result1 []gitproviders.PullRequest, result2 error) {
+	fake.listPullRequestsMutex.Lock()
+	defer fake.listPullRequestsMutex.Unlock()
+	fake.ListPullRequestsStub = nil
+	fake.listPullRequestsReturns = struct {
+		result1 []gitproviders.PullRequest
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGitProvider) ListPullRequestsReturnsOnCall(i int, result1 []gitproviders.PullRequest, result2 error) {
+	fake.listPullRequestsMutex.Lock()
+	defer fake.listPullRequestsMutex.Unlock()
+	fake.ListPullRequestsStub = nil
+	if fake.listPullRequestsReturnsOnCall == nil {
+		fake.listPullRequestsReturnsOnCall = make(map[int]struct {
+			result1 []gitproviders.PullRequest
+			result2 error
+		})
+	}
+	fake.listPullRequestsReturnsOnCall[i] = struct {
+		result1 []gitproviders.PullRequest
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeGitProvider) MergePullRequest(arg1 context.Context, arg2 gitproviders.RepoURL, arg3 int, arg4 string) error {
 	fake.mergePullRequestMutex.Lock()
 	ret, specificReturn := fake.mergePullRequestReturnsOnCall[len(fake.mergePullRequestArgsForCall)]
@@ -806,6 +1051,8 @@ func (fake *FakeGitProvider) Invocations() map[string][][]interface{} {
 	defer fake.invocationsMutex.RUnlock()
 	fake.createPullRequestMutex.RLock()
 	defer fake.createPullRequestMutex.RUnlock()
+	fake.deleteDeployKeyMutex.RLock()
+	defer fake.deleteDeployKeyMutex.RUnlock()
 	fake.deployKeyExistsMutex.RLock()
 	defer fake.deployKeyExistsMutex.RUnlock()
 	fake.getCommitsMutex.RLock()
@@ -814,10 +1061,14 @@ func (fake *FakeGitProvider) Invocations() map[string][][]interface{} {
 	defer fake.getDefaultBranchMutex.RUnlock()
 	fake.getProviderDomainMutex.RLock()
 	defer fake.getProviderDomainMutex.RUnlock()
+	fake.getPullRequestMutex.RLock()
+	defer fake.getPullRequestMutex.RUnlock()
 	fake.getRepoDirFilesMutex.RLock()
 	defer fake.getRepoDirFilesMutex.RUnlock()
 	fake.getRepoVisibilityMutex.RLock()
 	defer fake.getRepoVisibilityMutex.RUnlock()
+	fake.listPullRequestsMutex.RLock()
+	defer fake.listPullRequestsMutex.RUnlock()
 	fake.mergePullRequestMutex.RLock()
 	defer fake.mergePullRequestMutex.RUnlock()
 	fake.repositoryExistsMutex.RLock()
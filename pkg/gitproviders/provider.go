@@ -1,6 +1,7 @@
 package gitproviders
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/weaveworks/weave-gitops/pkg/utils"
+	"golang.org/x/crypto/ssh"
 
 	"github.com/fluxcd/go-git-providers/gitprovider"
 )
@@ -35,11 +37,19 @@ type GitProvider interface {
 	GetDefaultBranch(ctx context.Context, repoUrl RepoURL) (string, error)
 	GetRepoVisibility(ctx context.Context, repoUrl RepoURL) (*gitprovider.RepositoryVisibility, error)
 	UploadDeployKey(ctx context.Context, repoUrl RepoURL, deployKey []byte) error
+	// DeleteDeployKey deletes the deploy key with the public key, keeping the
+	// other deploy keys. It returns gitprovider.ErrNotFound if there's none.
+	DeleteDeployKey(ctx context.Context, repoUrl RepoURL, deployKey []byte) error
 	CreatePullRequest(ctx context.Context, repoUrl RepoURL, prInfo PullRequestInfo) (gitprovider.PullRequest, error)
 	GetCommits(ctx context.Context, repoUrl RepoURL, targetBranch string, pageSize int, pageToken int) ([]gitprovider.Commit, error)
 	GetProviderDomain() string
 	GetRepoDirFiles(ctx context.Context, repoUrl RepoURL, dirPath, targetBranch string) ([]*gitprovider.CommitFile, error)
 	MergePullRequest(ctx context.Context, repoUrl RepoURL, pullRequestNumber int, commitMesage string) error
+	// ListPullRequests lists the latest pull requests of a repository that
+	// match the filter, of any state if it's empty. The filter is applied
+	// before the mergeability and CI status of the pull requests are fetched.
+	ListPullRequests(ctx context.Context, repoUrl RepoURL, state PullRequestState, filter PullRequestFilter) ([]PullRequest, error)
+	GetPullRequest(ctx context.Context, repoUrl RepoURL, number int) (*PullRequest, error)
 }
 
 type PullRequestInfo struct {
@@ -55,6 +65,21 @@ type PullRequestInfo struct {
 type AccountTypeGetter func(provider gitprovider.Client, domain string, owner string) (ProviderAccountType, error)
 
 func New(config Config, owner string, getAccountType AccountTypeGetter) (GitProvider, error) {
+	switch config.Provider {
+	case GitProviderLocal:
+		return NewLocal(), nil
+	case GitProviderGitea, GitProviderBitbucketServer:
+		if config.Token == "" {
+			return nil, fmt.Errorf("failed to build git provider: no git provider token present")
+		}
+
+		if config.Provider == GitProviderGitea {
+			return newCachingProvider(newGiteaGitProvider(config), config), nil
+		}
+
+		return newCachingProvider(newBitbucketServerGitProvider(config), config), nil
+	}
+
 	provider, domain, err := buildGitProvider(config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build git provider: %w", err)
@@ -66,16 +91,16 @@ func New(config Config, owner string, getAccountType AccountTypeGetter) (GitProv
 	}
 
 	if accountType == AccountTypeOrg {
-		return orgGitProvider{
+		return newCachingProvider(orgGitProvider{
 			domain:   domain,
 			provider: provider,
-		}, nil
+		}, config), nil
 	}
 
-	return userGitProvider{
+	return newCachingProvider(userGitProvider{
 		domain:   domain,
 		provider: provider,
-	}, nil
+	}, config), nil
 }
 
 func deployKeyExists(ctx context.Context, repo gitprovider.UserRepository) (bool, error) {
@@ -111,6 +136,43 @@ func uploadDeployKey(ctx context.Context, repo gitprovider.UserRepository, deplo
 	return nil
 }
 
+func deleteDeployKey(ctx context.Context, repo gitprovider.UserRepository, deployKey []byte) error {
+	keys, err := repo.DeployKeys().List(ctx)
+	if err != nil {
+		return fmt.Errorf("error listing deploy keys: %w", err)
+	}
+
+	for _, k := range keys {
+		if !sameDeployKey(k.Get().Key, deployKey) {
+			continue
+		}
+
+		if err := k.Delete(ctx); err != nil {
+			return fmt.Errorf("error deleting deploy key %s: %w", k.Get().Name, err)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("deploy key not found: %w", gitprovider.ErrNotFound)
+}
+
+// sameDeployKey returns whether two public keys in the authorized_keys format
+// are the same key, as the providers drop or change their comments.
+func sameDeployKey(a, b []byte) bool {
+	keyA, _, _, _, err := ssh.ParseAuthorizedKey(a)
+	if err != nil {
+		return false
+	}
+
+	keyB, _, _, _, err := ssh.ParseAuthorizedKey(b)
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(keyA.Marshal(), keyB.Marshal())
+}
+
 func createPullRequest(ctx context.Context, repo gitprovider.UserRepository, prInfo PullRequestInfo) (gitprovider.PullRequest, error) {
 	repoInfo := repo.Get()
 
@@ -0,0 +1,564 @@
+package gitproviders
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/fluxcd/go-git-providers/gitprovider"
+)
+
+// bitbucketServerGitProvider talks to the API of a Bitbucket Server (or Data
+// Center) instance. The owner of a repository is the key of its project, or
+// ~username for personal repositories.
+type bitbucketServerGitProvider struct {
+	domain  string
+	baseURL string
+	client  *restClient
+}
+
+var _ GitProvider = bitbucketServerGitProvider{}
+
+func newBitbucketServerGitProvider(config Config) GitProvider {
+	baseURL := apiBaseURL(config)
+
+	return bitbucketServerGitProvider{
+		domain:  config.Hostname,
+		baseURL: baseURL,
+		client:  newRESTClient(baseURL+"/rest", "Bearer "+config.Token, newRateLimitTransport(nil, config)),
+	}
+}
+
+type bitbucketServerRepository struct {
+	Slug    string `json:"slug"`
+	Public  bool   `json:"public"`
+	Project struct {
+		Key string `json:"key"`
+	} `json:"project"`
+}
+
+type bitbucketServerRef struct {
+	ID           string `json:"id"`
+	DisplayID    string `json:"displayId"`
+	LatestCommit string `json:"latestCommit,omitempty"`
+}
+
+type bitbucketServerAccessKey struct {
+	Key struct {
+		ID    int64  `json:"id,omitempty"`
+		Text  string `json:"text"`
+		Label string `json:"label"`
+	} `json:"key"`
+	Permission string `json:"permission"`
+}
+
+type bitbucketServerCommit struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+	Author  struct {
+		Name         string `json:"name"`
+		EmailAddress string `json:"emailAddress"`
+	} `json:"author"`
+	// AuthorTimestamp is in milliseconds since the epoch.
+	AuthorTimestamp int64 `json:"authorTimestamp"`
+}
+
+type bitbucketServerPullRequest struct {
+	ID          int    `json:"id"`
+	Version     int    `json:"version"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	State       string `json:"state"`
+	// CreatedDate is in milliseconds since the epoch.
+	CreatedDate int64              `json:"createdDate"`
+	FromRef     bitbucketServerRef `json:"fromRef"`
+	ToRef       bitbucketServerRef `json:"toRef"`
+	Links       struct {
+		Self []struct {
+			Href string `json:"href"`
+		} `json:"self"`
+	} `json:"links"`
+}
+
+type bitbucketServerPullRequestRef struct {
+	ID         string `json:"id"`
+	Repository struct {
+		Slug    string `json:"slug"`
+		Project struct {
+			Key string `json:"key"`
+		} `json:"project"`
+	} `json:"repository"`
+}
+
+type bitbucketServerBuildStats struct {
+	Successful int `json:"successful"`
+	InProgress int `json:"inProgress"`
+	Failed     int `json:"failed"`
+}
+
+type bitbucketServerBrowse struct {
+	Children struct {
+		Values []struct {
+			Path struct {
+				ToString string `json:"toString"`
+			} `json:"path"`
+			Type string `json:"type"`
+		} `json:"values"`
+	} `json:"children"`
+}
+
+func (p bitbucketServerGitProvider) repoPath(repoUrl RepoURL) string {
+	return fmt.Sprintf("/projects/%s/repos/%s", url.PathEscape(repoUrl.Owner()), url.PathEscape(repoUrl.RepositoryName()))
+}
+
+func (p bitbucketServerGitProvider) apiPath(repoUrl RepoURL) string {
+	return "/api/1.0" + p.repoPath(repoUrl)
+}
+
+func (p bitbucketServerGitProvider) getRepo(ctx context.Context, repoUrl RepoURL) (*bitbucketServerRepository, error) {
+	var repo bitbucketServerRepository
+	if err := p.client.do(ctx, http.MethodGet, p.apiPath(repoUrl), nil, nil, &repo); err != nil {
+		return nil, fmt.Errorf("error getting repository %s/%s: %w", repoUrl.Owner(), repoUrl.RepositoryName(), err)
+	}
+
+	return &repo, nil
+}
+
+func (p bitbucketServerGitProvider) RepositoryExists(ctx context.Context, repoUrl RepoURL) (bool, error) {
+	if _, err := p.getRepo(ctx, repoUrl); err != nil {
+		if errors.Is(err, gitprovider.ErrNotFound) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("could not get verify repository exists  %w", err)
+	}
+
+	return true, nil
+}
+
+func (p bitbucketServerGitProvider) DeployKeyExists(ctx context.Context, repoUrl RepoURL) (bool, error) {
+	var keys struct {
+		Values []bitbucketServerAccessKey `json:"values"`
+	}
+
+	if err := p.client.do(ctx, http.MethodGet, "/keys/1.0"+p.repoPath(repoUrl)+"/ssh", url.Values{"limit": {"1000"}}, nil, &keys); err != nil {
+		return false, fmt.Errorf("error getting deploy key %s: %w", DeployKeyName, err)
+	}
+
+	for _, k := range keys.Values {
+		if k.Key.Label == DeployKeyName {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (p bitbucketServerGitProvider) GetDefaultBranch(ctx context.Context, repoUrl RepoURL) (string, error) {
+	var ref bitbucketServerRef
+	if err := p.client.do(ctx, http.MethodGet, p.apiPath(repoUrl)+"/branches/default", nil, nil, &ref); err != nil {
+		return "main", fmt.Errorf("error getting default branch of %s/%s: %w", repoUrl.Owner(), repoUrl.RepositoryName(), err)
+	}
+
+	return ref.DisplayID, nil
+}
+
+func (p bitbucketServerGitProvider) GetRepoVisibility(ctx context.Context, repoUrl RepoURL) (*gitprovider.RepositoryVisibility, error) {
+	repo, err := p.getRepo(ctx, repoUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	if repo.Public {
+		return gitprovider.RepositoryVisibilityVar(gitprovider.RepositoryVisibilityPublic), nil
+	}
+
+	return gitprovider.RepositoryVisibilityVar(gitprovider.RepositoryVisibilityPrivate), nil
+}
+
+func (p bitbucketServerGitProvider) UploadDeployKey(ctx context.Context, repoUrl RepoURL, deployKey []byte) error {
+	key := bitbucketServerAccessKey{Permission: "REPO_WRITE"}
+	key.Key.Text = string(deployKey)
+	key.Key.Label = DeployKeyName
+
+	if err := p.client.do(ctx, http.MethodPost, "/keys/1.0"+p.repoPath(repoUrl)+"/ssh", nil, key, nil); err != nil {
+		if errors.Is(err, gitprovider.ErrNotFound) {
+			return ErrRepositoryNoPermissionsOrDoesNotExist
+		}
+
+		return fmt.Errorf("error uploading deploy key %s", err)
+	}
+
+	return nil
+}
+
+func (p bitbucketServerGitProvider) DeleteDeployKey(ctx context.Context, repoUrl RepoURL, deployKey []byte) error {
+	var keys struct {
+		Values []bitbucketServerAccessKey `json:"values"`
+	}
+
+	keysPath := "/keys/1.0" + p.repoPath(repoUrl) + "/ssh"
+
+	if err := p.client.do(ctx, http.MethodGet, keysPath, url.Values{"limit": {"1000"}}, nil, &keys); err != nil {
+		return fmt.Errorf("error listing deploy keys: %w", err)
+	}
+
+	for _, k := range keys.Values {
+		if !sameDeployKey([]byte(k.Key.Text), deployKey) {
+			continue
+		}
+
+		if err := p.client.do(ctx, http.MethodDelete, keysPath+"/"+strconv.FormatInt(k.Key.ID, 10), nil, nil, nil); err != nil {
+			return fmt.Errorf("error deleting deploy key %s: %w", k.Key.Label, err)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("deploy key not found: %w", gitprovider.ErrNotFound)
+}
+
+func (p bitbucketServerGitProvider) CreatePullRequest(ctx context.Context, repoUrl RepoURL, prInfo PullRequestInfo) (gitprovider.PullRequest, error) {
+	if prInfo.TargetBranch == "" {
+		branch, err := p.GetDefaultBranch(ctx, repoUrl)
+		if err != nil {
+			return nil, err
+		}
+
+		prInfo.TargetBranch = branch
+	}
+
+	if !prInfo.SkipAddingFilesOnCreation {
+		if err := p.commitFiles(ctx, repoUrl, prInfo); err != nil {
+			return nil, fmt.Errorf("error creating commit %s: %w", prInfo.NewBranch, err)
+		}
+	}
+
+	req := map[string]interface{}{
+		"title":       prInfo.Title,
+		"description": prInfo.Description,
+		"fromRef":     p.pullRequestRef(repoUrl, prInfo.NewBranch),
+		"toRef":       p.pullRequestRef(repoUrl, prInfo.TargetBranch),
+	}
+
+	var pr bitbucketServerPullRequest
+	if err := p.client.do(ctx, http.MethodPost, p.apiPath(repoUrl)+"/pull-requests", nil, req, &pr); err != nil {
+		return nil, fmt.Errorf("error creating pull request %s: %w", prInfo.Title, err)
+	}
+
+	return p.toPullRequest(pr), nil
+}
+
+func (p bitbucketServerGitProvider) pullRequestRef(repoUrl RepoURL, branch string) bitbucketServerPullRequestRef {
+	ref := bitbucketServerPullRequestRef{ID: "refs/heads/" + branch}
+	ref.Repository.Slug = repoUrl.RepositoryName()
+	ref.Repository.Project.Key = repoUrl.Owner()
+
+	return ref
+}
+
+func (p bitbucketServerGitProvider) toPullRequest(pr bitbucketServerPullRequest) gitprovider.PullRequest {
+	info := gitprovider.PullRequestInfo{Number: pr.ID, Merged: pr.State == "MERGED"}
+	if len(pr.Links.Self) > 0 {
+		info.WebURL = pr.Links.Self[0].Href
+	}
+
+	return restPullRequest{info: info, object: pr}
+}
+
+// commitFiles creates the new branch of the pull request from the target
+// branch, and commits each file to it. Bitbucket Server has no API to delete
+// files, so files without content are rejected.
+func (p bitbucketServerGitProvider) commitFiles(ctx context.Context, repoUrl RepoURL, prInfo PullRequestInfo) error {
+	for _, f := range prInfo.Files {
+		if f.Path != nil && f.Content == nil {
+			return fmt.Errorf("deleting %s is not supported by Bitbucket Server", *f.Path)
+		}
+	}
+
+	req := map[string]string{
+		"name":       prInfo.NewBranch,
+		"startPoint": "refs/heads/" + prInfo.TargetBranch,
+	}
+
+	var branch bitbucketServerRef
+	if err := p.client.do(ctx, http.MethodPost, "/branch-utils/1.0"+p.repoPath(repoUrl)+"/branches", nil, req, &branch); err != nil {
+		return fmt.Errorf("error creating branch %s: %w", prInfo.NewBranch, err)
+	}
+
+	head := branch.LatestCommit
+
+	for _, f := range prInfo.Files {
+		if f.Path == nil {
+			continue
+		}
+
+		commit, err := p.putFile(ctx, repoUrl, prInfo.NewBranch, head, *f.Path, *f.Content, prInfo.CommitMessage)
+		if err != nil {
+			return fmt.Errorf("error committing %s: %w", *f.Path, err)
+		}
+
+		head = commit.ID
+	}
+
+	return nil
+}
+
+// putFile commits the content of a file on top of the given head commit of
+// the branch.
+func (p bitbucketServerGitProvider) putFile(ctx context.Context, repoUrl RepoURL, branch, head, path, content, message string) (*bitbucketServerCommit, error) {
+	ref := url.Values{"at": {"refs/heads/" + branch}}
+
+	var existing string
+
+	err := p.client.do(ctx, http.MethodGet, p.apiPath(repoUrl)+"/raw/"+pathEscape(path), ref, nil, &existing)
+
+	exists := err == nil
+	if err != nil && !errors.Is(err, gitprovider.ErrNotFound) {
+		return nil, err
+	}
+
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+
+	fields := map[string]string{
+		"content": content,
+		"message": message,
+		"branch":  branch,
+	}
+
+	if exists {
+		// Existing files can only be changed given the commit they were
+		// last seen at.
+		fields["sourceCommitId"] = head
+	}
+
+	for k, v := range fields {
+		if err := w.WriteField(k, v); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	var commit bitbucketServerCommit
+	if err := p.client.send(ctx, http.MethodPut, p.apiPath(repoUrl)+"/browse/"+pathEscape(path), nil, w.FormDataContentType(), body, &commit); err != nil {
+		return nil, err
+	}
+
+	return &commit, nil
+}
+
+func (p bitbucketServerGitProvider) GetCommits(ctx context.Context, repoUrl RepoURL, targetBranch string, pageSize int, pageToken int) ([]gitprovider.Commit, error) {
+	if pageToken < 1 {
+		pageToken = 1
+	}
+
+	query := url.Values{
+		"until": {"refs/heads/" + targetBranch},
+		"limit": {strconv.Itoa(pageSize)},
+		"start": {strconv.Itoa((pageToken - 1) * pageSize)},
+	}
+
+	var commits struct {
+		Values []bitbucketServerCommit `json:"values"`
+	}
+
+	if err := p.client.do(ctx, http.MethodGet, p.apiPath(repoUrl)+"/commits", query, nil, &commits); err != nil {
+		return nil, fmt.Errorf("error getting commits: %s", err)
+	}
+
+	res := make([]gitprovider.Commit, 0, len(commits.Values))
+	for _, c := range commits.Values {
+		res = append(res, restCommit{
+			info: gitprovider.CommitInfo{
+				Sha:       c.ID,
+				Author:    c.Author.Name,
+				Message:   c.Message,
+				CreatedAt: time.UnixMilli(c.AuthorTimestamp).UTC(),
+				URL:       p.baseURL + p.repoPath(repoUrl) + "/commits/" + c.ID,
+			},
+			object: c,
+		})
+	}
+
+	return res, nil
+}
+
+func (p bitbucketServerGitProvider) GetProviderDomain() string {
+	return p.domain
+}
+
+// GetRepoDirFiles returns the files found in a directory of a repository,
+// not recursively.
+func (p bitbucketServerGitProvider) GetRepoDirFiles(ctx context.Context, repoUrl RepoURL, dirPath, targetBranch string) ([]*gitprovider.CommitFile, error) {
+	query := url.Values{"at": {"refs/heads/" + targetBranch}, "limit": {"1000"}}
+
+	var dir bitbucketServerBrowse
+	if err := p.client.do(ctx, http.MethodGet, p.apiPath(repoUrl)+"/browse/"+pathEscape(dirPath), query, nil, &dir); err != nil {
+		return nil, err
+	}
+
+	ref := url.Values{"at": query["at"]}
+	files := []*gitprovider.CommitFile{}
+
+	for _, e := range dir.Children.Values {
+		if e.Type != "FILE" {
+			continue
+		}
+
+		path := e.Path.ToString
+		if dirPath != "" {
+			path = dirPath + "/" + path
+		}
+
+		var content string
+		if err := p.client.do(ctx, http.MethodGet, p.apiPath(repoUrl)+"/raw/"+pathEscape(path), ref, nil, &content); err != nil {
+			return nil, err
+		}
+
+		files = append(files, &gitprovider.CommitFile{Path: &path, Content: &content})
+	}
+
+	return files, nil
+}
+
+// MergePullRequest merges a pull request given the repository's URL and the PR's number with a commit message.
+func (p bitbucketServerGitProvider) MergePullRequest(ctx context.Context, repoUrl RepoURL, pullRequestNumber int, commitMesage string) error {
+	prPath := fmt.Sprintf("%s/pull-requests/%d", p.apiPath(repoUrl), pullRequestNumber)
+
+	// Merging needs the version of the pull request, so that it isn't merged
+	// after being changed.
+	var pr bitbucketServerPullRequest
+	if err := p.client.do(ctx, http.MethodGet, prPath, nil, nil, &pr); err != nil {
+		return err
+	}
+
+	query := url.Values{"version": {strconv.Itoa(pr.Version)}}
+
+	return p.client.do(ctx, http.MethodPost, prPath+"/merge", query, map[string]string{"message": commitMesage}, nil)
+}
+
+func (p bitbucketServerGitProvider) ListPullRequests(ctx context.Context, repoUrl RepoURL, state PullRequestState, filter PullRequestFilter) ([]PullRequest, error) {
+	query := url.Values{
+		"state": {"ALL"},
+		"order": {"NEWEST"},
+		"limit": {strconv.Itoa(pullRequestsPageSize)},
+	}
+
+	switch state {
+	case PullRequestStateOpen:
+		query.Set("state", "OPEN")
+	case PullRequestStateMerged:
+		query.Set("state", "MERGED")
+	case PullRequestStateClosed:
+		query.Set("state", "DECLINED")
+	}
+
+	var prs struct {
+		Values []bitbucketServerPullRequest `json:"values"`
+	}
+
+	if err := p.client.do(ctx, http.MethodGet, p.apiPath(repoUrl)+"/pull-requests", query, nil, &prs); err != nil {
+		return nil, fmt.Errorf("error listing pull requests: %w", err)
+	}
+
+	res := []PullRequest{}
+
+	for _, pr := range prs.Values {
+		converted := p.toPullRequestInfo(pr)
+		if !filter.Matches(converted) {
+			continue
+		}
+
+		if err := p.addPullRequestStatus(ctx, repoUrl, pr, &converted); err != nil {
+			return nil, err
+		}
+
+		res = append(res, converted)
+	}
+
+	return res, nil
+}
+
+func (p bitbucketServerGitProvider) GetPullRequest(ctx context.Context, repoUrl RepoURL, number int) (*PullRequest, error) {
+	var pr bitbucketServerPullRequest
+	if err := p.client.do(ctx, http.MethodGet, fmt.Sprintf("%s/pull-requests/%d", p.apiPath(repoUrl), number), nil, nil, &pr); err != nil {
+		return nil, fmt.Errorf("error getting pull request %d: %w", number, err)
+	}
+
+	res := p.toPullRequestInfo(pr)
+	if err := p.addPullRequestStatus(ctx, repoUrl, pr, &res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// toPullRequestInfo converts a pull request, without its mergeability and
+// builds. Bitbucket Server has no labels.
+func (p bitbucketServerGitProvider) toPullRequestInfo(pr bitbucketServerPullRequest) PullRequest {
+	res := PullRequest{
+		Number:       pr.ID,
+		Title:        pr.Title,
+		Description:  pr.Description,
+		WebURL:       p.toPullRequest(pr).Get().WebURL,
+		SourceBranch: pr.FromRef.DisplayID,
+		TargetBranch: pr.ToRef.DisplayID,
+		Labels:       []string{},
+		State:        PullRequestStateClosed,
+		CIStatus:     CIStatusNone,
+		CreatedAt:    time.UnixMilli(pr.CreatedDate).UTC(),
+	}
+
+	switch pr.State {
+	case "OPEN":
+		res.State = PullRequestStateOpen
+	case "MERGED":
+		res.State = PullRequestStateMerged
+	}
+
+	return res
+}
+
+// addPullRequestStatus checks whether an open pull request can be merged and
+// the builds of its head.
+func (p bitbucketServerGitProvider) addPullRequestStatus(ctx context.Context, repoUrl RepoURL, pr bitbucketServerPullRequest, res *PullRequest) error {
+	if res.State != PullRequestStateOpen {
+		return nil
+	}
+
+	var merge struct {
+		CanMerge bool `json:"canMerge"`
+	}
+
+	if err := p.client.do(ctx, http.MethodGet, fmt.Sprintf("%s/pull-requests/%d/merge", p.apiPath(repoUrl), pr.ID), nil, nil, &merge); err != nil {
+		return fmt.Errorf("error checking if pull request %d can be merged: %w", pr.ID, err)
+	}
+
+	res.Mergeable = gitprovider.BoolVar(merge.CanMerge)
+
+	var stats bitbucketServerBuildStats
+	if err := p.client.do(ctx, http.MethodGet, "/build-status/1.0/commits/stats/"+url.PathEscape(pr.FromRef.LatestCommit), nil, nil, &stats); err != nil {
+		return fmt.Errorf("error getting build status: %w", err)
+	}
+
+	switch {
+	case stats.Failed > 0:
+		res.CIStatus = CIStatusFailure
+	case stats.InProgress > 0:
+		res.CIStatus = CIStatusPending
+	case stats.Successful > 0:
+		res.CIStatus = CIStatusSuccess
+	}
+
+	return nil
+}
@@ -0,0 +1,239 @@
+package gitproviders
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/fluxcd/go-git-providers/gitprovider"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+)
+
+var _ = Describe("Bitbucket Server Provider", func() {
+	const repoPath = "/rest/api/1.0/projects/PROJ/repos/repo"
+
+	var (
+		server   *httptest.Server
+		provider GitProvider
+		repoUrl  RepoURL
+		requests []*http.Request
+		handlers map[string]http.HandlerFunc
+	)
+
+	BeforeEach(func() {
+		requests = nil
+		handlers = map[string]http.HandlerFunc{}
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r)
+
+			h, ok := handlers[r.Method+" "+r.URL.Path]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+
+			h(w, r)
+		}))
+
+		viper.Set("git-host-types", "bitbucket.acme.org=bitbucket-server")
+
+		var err error
+		repoUrl, err = NewRepoURL("ssh://git@bitbucket.acme.org:7999/PROJ/repo.git")
+		Expect(err).NotTo(HaveOccurred())
+
+		provider, err = New(Config{Provider: GitProviderBitbucketServer, Hostname: "bitbucket.acme.org", Token: "token", APIURL: server.URL}, repoUrl.Owner(), nil)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	jsonResponse := func(v interface{}) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(v)
+		}
+	}
+
+	It("gets the repository", func() {
+		handlers["GET "+repoPath] = jsonResponse(map[string]interface{}{"slug": "repo", "public": true})
+
+		exists, err := provider.RepositoryExists(context.Background(), repoUrl)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(exists).To(BeTrue())
+		Expect(requests[0].Header.Get("Authorization")).To(Equal("Bearer token"))
+
+		visibility, err := provider.GetRepoVisibility(context.Background(), repoUrl)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*visibility).To(Equal(gitprovider.RepositoryVisibilityPublic))
+	})
+
+	It("gets the default branch", func() {
+		handlers["GET "+repoPath+"/branches/default"] = jsonResponse(bitbucketServerRef{ID: "refs/heads/develop", DisplayID: "develop"})
+
+		branch, err := provider.GetDefaultBranch(context.Background(), repoUrl)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(branch).To(Equal("develop"))
+	})
+
+	It("manages the deploy key", func() {
+		var uploaded bitbucketServerAccessKey
+
+		handlers["GET /rest/keys/1.0/projects/PROJ/repos/repo/ssh"] = jsonResponse(map[string]interface{}{"values": []interface{}{}})
+		handlers["POST /rest/keys/1.0/projects/PROJ/repos/repo/ssh"] = func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewDecoder(r.Body).Decode(&uploaded)
+			w.WriteHeader(http.StatusCreated)
+		}
+
+		exists, err := provider.DeployKeyExists(context.Background(), repoUrl)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(exists).To(BeFalse())
+
+		Expect(provider.UploadDeployKey(context.Background(), repoUrl, []byte("ssh-rsa AAAA"))).To(Succeed())
+		Expect(uploaded.Key.Label).To(Equal(DeployKeyName))
+		Expect(uploaded.Key.Text).To(Equal("ssh-rsa AAAA"))
+		Expect(uploaded.Permission).To(Equal("REPO_WRITE"))
+	})
+
+	It("creates a pull request with the files", func() {
+		var (
+			branch map[string]string
+			puts   []map[string]string
+			pr     map[string]interface{}
+		)
+
+		handlers["POST /rest/branch-utils/1.0/projects/PROJ/repos/repo/branches"] = func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewDecoder(r.Body).Decode(&branch)
+			_ = json.NewEncoder(w).Encode(bitbucketServerRef{ID: "refs/heads/feature", LatestCommit: "head"})
+		}
+		handlers["GET "+repoPath+"/raw/existing.yaml"] = func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("old"))
+		}
+
+		putFile := func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.ParseMultipartForm(1 << 20)).To(Succeed())
+
+			fields := map[string]string{"path": r.URL.Path}
+			for k, v := range r.MultipartForm.Value {
+				fields[k] = v[0]
+			}
+
+			puts = append(puts, fields)
+			_ = json.NewEncoder(w).Encode(bitbucketServerCommit{ID: "commit-" + r.URL.Path[len(repoPath+"/browse/"):]})
+		}
+		handlers["PUT "+repoPath+"/browse/added.yaml"] = putFile
+		handlers["PUT "+repoPath+"/browse/existing.yaml"] = putFile
+		handlers["POST "+repoPath+"/pull-requests"] = func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewDecoder(r.Body).Decode(&pr)
+			_, _ = w.Write([]byte(`{"id": 7, "version": 0, "state": "OPEN", "links": {"self": [{"href": "https://bitbucket.acme.org/pr/7"}]}}`))
+		}
+
+		added, existing := "added.yaml", "existing.yaml"
+		content := "foo: bar"
+
+		res, err := provider.CreatePullRequest(context.Background(), repoUrl, PullRequestInfo{
+			Title:         "title",
+			CommitMessage: "message",
+			TargetBranch:  "main",
+			NewBranch:     "feature",
+			Files: []gitprovider.CommitFile{
+				{Path: &added, Content: &content},
+				{Path: &existing, Content: &content},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Get().Number).To(Equal(7))
+		Expect(res.Get().WebURL).To(Equal("https://bitbucket.acme.org/pr/7"))
+
+		Expect(branch).To(Equal(map[string]string{"name": "feature", "startPoint": "refs/heads/main"}))
+		Expect(puts).To(Equal([]map[string]string{
+			{"path": repoPath + "/browse/added.yaml", "content": content, "message": "message", "branch": "feature"},
+			{"path": repoPath + "/browse/existing.yaml", "content": content, "message": "message", "branch": "feature", "sourceCommitId": "commit-added.yaml"},
+		}))
+		Expect(pr["fromRef"]).To(HaveKeyWithValue("id", "refs/heads/feature"))
+		Expect(pr["toRef"]).To(HaveKeyWithValue("id", "refs/heads/main"))
+	})
+
+	It("refuses to delete files", func() {
+		removed := "removed.yaml"
+
+		_, err := provider.CreatePullRequest(context.Background(), repoUrl, PullRequestInfo{
+			TargetBranch: "main",
+			NewBranch:    "feature",
+			Files:        []gitprovider.CommitFile{{Path: &removed}},
+		})
+		Expect(err).To(MatchError(ContainSubstring("deleting removed.yaml is not supported")))
+		Expect(requests).To(BeEmpty())
+	})
+
+	It("gets commits", func() {
+		handlers["GET "+repoPath+"/commits"] = func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"values": [{"id": "sha", "message": "msg", "author": {"name": "author"}, "authorTimestamp": 1600000000000}]}`))
+		}
+
+		commits, err := provider.GetCommits(context.Background(), repoUrl, "main", 10, 2)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(commits).To(HaveLen(1))
+		Expect(commits[0].Get().Sha).To(Equal("sha"))
+		Expect(commits[0].Get().CreatedAt.Unix()).To(Equal(int64(1600000000)))
+		Expect(commits[0].Get().URL).To(Equal(server.URL + "/projects/PROJ/repos/repo/commits/sha"))
+
+		query := requests[0].URL.Query()
+		Expect(query.Get("until")).To(Equal("refs/heads/main"))
+		Expect(query.Get("start")).To(Equal("10"))
+	})
+
+	It("gets the files of a directory", func() {
+		handlers["GET "+repoPath+"/browse/dir"] = func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"children": {"values": [{"path": {"toString": "a.yaml"}, "type": "FILE"}, {"path": {"toString": "sub"}, "type": "DIRECTORY"}]}}`))
+		}
+		handlers["GET "+repoPath+"/raw/dir/a.yaml"] = func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("a: b"))
+		}
+
+		files, err := provider.GetRepoDirFiles(context.Background(), repoUrl, "dir", "main")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(HaveLen(1))
+		Expect(*files[0].Path).To(Equal("dir/a.yaml"))
+		Expect(*files[0].Content).To(Equal("a: b"))
+	})
+
+	It("merges pull requests at their current version", func() {
+		handlers["GET "+repoPath+"/pull-requests/7"] = jsonResponse(map[string]interface{}{"id": 7, "version": 4})
+		handlers["POST "+repoPath+"/pull-requests/7/merge"] = func(w http.ResponseWriter, r *http.Request) {}
+
+		Expect(provider.MergePullRequest(context.Background(), repoUrl, 7, "merge it")).To(Succeed())
+		Expect(requests[1].URL.Query().Get("version")).To(Equal("4"))
+	})
+
+	It("lists pull requests with their mergeability and builds", func() {
+		handlers["GET "+repoPath+"/pull-requests"] = func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"values": [
+				{"id": 8, "state": "OPEN", "fromRef": {"displayId": "weave-gitops-abc", "latestCommit": "abc"}, "toRef": {"displayId": "main"}, "createdDate": 1600000000000},
+				{"id": 7, "state": "DECLINED", "fromRef": {"displayId": "weave-gitops-def"}},
+				{"id": 6, "state": "OPEN", "fromRef": {"displayId": "feature", "latestCommit": "def"}}
+			]}`))
+		}
+		handlers["GET "+repoPath+"/pull-requests/8/merge"] = jsonResponse(map[string]interface{}{"canMerge": true})
+		handlers["GET /rest/build-status/1.0/commits/stats/abc"] = jsonResponse(bitbucketServerBuildStats{Successful: 1, InProgress: 1})
+
+		prs, err := provider.ListPullRequests(context.Background(), repoUrl, "", DefaultPullRequestFilter())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requests[0].URL.Query().Get("state")).To(Equal("ALL"))
+		Expect(prs).To(HaveLen(2))
+
+		Expect(prs[0].Number).To(Equal(8))
+		Expect(prs[0].State).To(Equal(PullRequestStateOpen))
+		Expect(prs[0].SourceBranch).To(Equal("weave-gitops-abc"))
+		Expect(prs[0].Mergeable).To(Equal(gitprovider.BoolVar(true)))
+		Expect(prs[0].CIStatus).To(Equal(CIStatusPending))
+		Expect(prs[0].CreatedAt.Unix()).To(Equal(int64(1600000000)))
+
+		Expect(prs[1].State).To(Equal(PullRequestStateClosed))
+		Expect(prs[1].Mergeable).To(BeNil())
+	})
+})
@@ -0,0 +1,481 @@
+package gitproviders
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/fluxcd/go-git-providers/gitprovider"
+)
+
+// giteaGitProvider talks to the API of a Gitea instance. Users and
+// organizations own repositories the same way, so no account type is needed.
+type giteaGitProvider struct {
+	domain string
+	client *restClient
+}
+
+var _ GitProvider = giteaGitProvider{}
+
+func newGiteaGitProvider(config Config) GitProvider {
+	return giteaGitProvider{
+		domain: config.Hostname,
+		client: newRESTClient(apiBaseURL(config)+"/api/v1", "token "+config.Token, newRateLimitTransport(nil, config)),
+	}
+}
+
+type giteaRepository struct {
+	DefaultBranch string `json:"default_branch"`
+	Private       bool   `json:"private"`
+	Internal      bool   `json:"internal"`
+}
+
+type giteaDeployKey struct {
+	ID       int64  `json:"id,omitempty"`
+	Title    string `json:"title"`
+	Key      string `json:"key"`
+	ReadOnly bool   `json:"read_only"`
+}
+
+type giteaCommit struct {
+	SHA     string `json:"sha"`
+	HTMLURL string `json:"html_url"`
+	Commit  struct {
+		Message string `json:"message"`
+		Author  struct {
+			Name  string    `json:"name"`
+			Email string    `json:"email"`
+			Date  time.Time `json:"date"`
+		} `json:"author"`
+		Tree struct {
+			SHA string `json:"sha"`
+		} `json:"tree"`
+	} `json:"commit"`
+}
+
+type giteaPullRequest struct {
+	Number    int       `json:"number"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	HTMLURL   string    `json:"html_url"`
+	State     string    `json:"state"`
+	Merged    bool      `json:"merged"`
+	Mergeable bool      `json:"mergeable"`
+	CreatedAt time.Time `json:"created_at"`
+	Labels    []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	Head struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+}
+
+type giteaCombinedStatus struct {
+	State      string `json:"state"`
+	TotalCount int    `json:"total_count"`
+}
+
+type giteaContent struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Type string `json:"type"`
+	SHA  string `json:"sha"`
+}
+
+type giteaFileOperation struct {
+	Operation string `json:"operation"`
+	Path      string `json:"path"`
+	Content   string `json:"content,omitempty"`
+	SHA       string `json:"sha,omitempty"`
+}
+
+func (p giteaGitProvider) repoPath(repoUrl RepoURL) string {
+	return fmt.Sprintf("/repos/%s/%s", url.PathEscape(repoUrl.Owner()), url.PathEscape(repoUrl.RepositoryName()))
+}
+
+func (p giteaGitProvider) getRepo(ctx context.Context, repoUrl RepoURL) (*giteaRepository, error) {
+	var repo giteaRepository
+	if err := p.client.do(ctx, http.MethodGet, p.repoPath(repoUrl), nil, nil, &repo); err != nil {
+		return nil, fmt.Errorf("error getting repository %s/%s: %w", repoUrl.Owner(), repoUrl.RepositoryName(), err)
+	}
+
+	return &repo, nil
+}
+
+func (p giteaGitProvider) RepositoryExists(ctx context.Context, repoUrl RepoURL) (bool, error) {
+	if _, err := p.getRepo(ctx, repoUrl); err != nil {
+		if errors.Is(err, gitprovider.ErrNotFound) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("could not get verify repository exists  %w", err)
+	}
+
+	return true, nil
+}
+
+func (p giteaGitProvider) DeployKeyExists(ctx context.Context, repoUrl RepoURL) (bool, error) {
+	var keys []giteaDeployKey
+	if err := p.client.do(ctx, http.MethodGet, p.repoPath(repoUrl)+"/keys", nil, nil, &keys); err != nil {
+		return false, fmt.Errorf("error getting deploy key %s: %w", DeployKeyName, err)
+	}
+
+	for _, k := range keys {
+		if k.Title == DeployKeyName {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (p giteaGitProvider) GetDefaultBranch(ctx context.Context, repoUrl RepoURL) (string, error) {
+	repo, err := p.getRepo(ctx, repoUrl)
+	if err != nil {
+		return "main", err
+	}
+
+	return repo.DefaultBranch, nil
+}
+
+func (p giteaGitProvider) GetRepoVisibility(ctx context.Context, repoUrl RepoURL) (*gitprovider.RepositoryVisibility, error) {
+	repo, err := p.getRepo(ctx, repoUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case repo.Internal:
+		return gitprovider.RepositoryVisibilityVar(gitprovider.RepositoryVisibilityInternal), nil
+	case repo.Private:
+		return gitprovider.RepositoryVisibilityVar(gitprovider.RepositoryVisibilityPrivate), nil
+	default:
+		return gitprovider.RepositoryVisibilityVar(gitprovider.RepositoryVisibilityPublic), nil
+	}
+}
+
+func (p giteaGitProvider) UploadDeployKey(ctx context.Context, repoUrl RepoURL, deployKey []byte) error {
+	key := giteaDeployKey{
+		Title:    DeployKeyName,
+		Key:      string(deployKey),
+		ReadOnly: false,
+	}
+
+	if err := p.client.do(ctx, http.MethodPost, p.repoPath(repoUrl)+"/keys", nil, key, nil); err != nil {
+		if errors.Is(err, gitprovider.ErrNotFound) {
+			return ErrRepositoryNoPermissionsOrDoesNotExist
+		}
+
+		return fmt.Errorf("error uploading deploy key %s", err)
+	}
+
+	return nil
+}
+
+func (p giteaGitProvider) DeleteDeployKey(ctx context.Context, repoUrl RepoURL, deployKey []byte) error {
+	var keys []giteaDeployKey
+	if err := p.client.do(ctx, http.MethodGet, p.repoPath(repoUrl)+"/keys", nil, nil, &keys); err != nil {
+		return fmt.Errorf("error listing deploy keys: %w", err)
+	}
+
+	for _, k := range keys {
+		if !sameDeployKey([]byte(k.Key), deployKey) {
+			continue
+		}
+
+		if err := p.client.do(ctx, http.MethodDelete, p.repoPath(repoUrl)+"/keys/"+strconv.FormatInt(k.ID, 10), nil, nil, nil); err != nil {
+			return fmt.Errorf("error deleting deploy key %s: %w", k.Title, err)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("deploy key not found: %w", gitprovider.ErrNotFound)
+}
+
+func (p giteaGitProvider) CreatePullRequest(ctx context.Context, repoUrl RepoURL, prInfo PullRequestInfo) (gitprovider.PullRequest, error) {
+	if prInfo.TargetBranch == "" {
+		branch, err := p.GetDefaultBranch(ctx, repoUrl)
+		if err != nil {
+			return nil, err
+		}
+
+		prInfo.TargetBranch = branch
+	}
+
+	if !prInfo.SkipAddingFilesOnCreation {
+		if err := p.commitFiles(ctx, repoUrl, prInfo); err != nil {
+			return nil, fmt.Errorf("error creating commit %s: %w", prInfo.NewBranch, err)
+		}
+	}
+
+	req := map[string]string{
+		"title": prInfo.Title,
+		"body":  prInfo.Description,
+		"head":  prInfo.NewBranch,
+		"base":  prInfo.TargetBranch,
+	}
+
+	var pr giteaPullRequest
+	if err := p.client.do(ctx, http.MethodPost, p.repoPath(repoUrl)+"/pulls", nil, req, &pr); err != nil {
+		return nil, fmt.Errorf("error creating pull request %s: %w", prInfo.Title, err)
+	}
+
+	return restPullRequest{
+		info:   gitprovider.PullRequestInfo{Number: pr.Number, WebURL: pr.HTMLURL, Merged: pr.Merged},
+		object: pr,
+	}, nil
+}
+
+// commitFiles commits the files of the pull request to its new branch,
+// created from the target branch. Files without content are deleted.
+func (p giteaGitProvider) commitFiles(ctx context.Context, repoUrl RepoURL, prInfo PullRequestInfo) error {
+	ops := []giteaFileOperation{}
+
+	for _, f := range prInfo.Files {
+		if f.Path == nil {
+			continue
+		}
+
+		op := giteaFileOperation{Path: *f.Path}
+
+		var existing giteaContent
+
+		err := p.client.do(ctx, http.MethodGet, p.repoPath(repoUrl)+"/contents/"+pathEscape(*f.Path), url.Values{"ref": {prInfo.TargetBranch}}, nil, &existing)
+
+		switch {
+		case err == nil:
+			op.SHA = existing.SHA
+		case !errors.Is(err, gitprovider.ErrNotFound):
+			return err
+		}
+
+		switch {
+		case f.Content == nil && op.SHA == "":
+			continue
+		case f.Content == nil:
+			op.Operation = "delete"
+		case op.SHA == "":
+			op.Operation = "create"
+		default:
+			op.Operation = "update"
+		}
+
+		if f.Content != nil {
+			op.Content = base64.StdEncoding.EncodeToString([]byte(*f.Content))
+		}
+
+		ops = append(ops, op)
+	}
+
+	req := map[string]interface{}{
+		"branch":     prInfo.TargetBranch,
+		"new_branch": prInfo.NewBranch,
+		"message":    prInfo.CommitMessage,
+		"files":      ops,
+	}
+
+	return p.client.do(ctx, http.MethodPost, p.repoPath(repoUrl)+"/contents", nil, req, nil)
+}
+
+func (p giteaGitProvider) GetCommits(ctx context.Context, repoUrl RepoURL, targetBranch string, pageSize int, pageToken int) ([]gitprovider.Commit, error) {
+	if pageToken < 1 {
+		pageToken = 1
+	}
+
+	query := url.Values{
+		"sha":   {targetBranch},
+		"limit": {strconv.Itoa(pageSize)},
+		"page":  {strconv.Itoa(pageToken)},
+	}
+
+	var commits []giteaCommit
+	if err := p.client.do(ctx, http.MethodGet, p.repoPath(repoUrl)+"/commits", query, nil, &commits); err != nil {
+		var restErr *restError
+		if errors.As(err, &restErr) && restErr.statusCode == http.StatusConflict {
+			// The repository is empty.
+			return []gitprovider.Commit{}, nil
+		}
+
+		return nil, fmt.Errorf("error getting commits: %s", err)
+	}
+
+	res := make([]gitprovider.Commit, 0, len(commits))
+	for _, c := range commits {
+		res = append(res, restCommit{
+			info: gitprovider.CommitInfo{
+				Sha:       c.SHA,
+				TreeSha:   c.Commit.Tree.SHA,
+				Author:    c.Commit.Author.Name,
+				Message:   c.Commit.Message,
+				CreatedAt: c.Commit.Author.Date,
+				URL:       c.HTMLURL,
+			},
+			object: c,
+		})
+	}
+
+	return res, nil
+}
+
+func (p giteaGitProvider) GetProviderDomain() string {
+	return p.domain
+}
+
+// GetRepoDirFiles returns the files found in a directory of a repository,
+// not recursively.
+func (p giteaGitProvider) GetRepoDirFiles(ctx context.Context, repoUrl RepoURL, dirPath, targetBranch string) ([]*gitprovider.CommitFile, error) {
+	ref := url.Values{"ref": {targetBranch}}
+
+	var entries []giteaContent
+	if err := p.client.do(ctx, http.MethodGet, p.repoPath(repoUrl)+"/contents/"+pathEscape(dirPath), ref, nil, &entries); err != nil {
+		return nil, err
+	}
+
+	files := []*gitprovider.CommitFile{}
+
+	for _, e := range entries {
+		if e.Type != "file" {
+			continue
+		}
+
+		var content string
+		if err := p.client.do(ctx, http.MethodGet, p.repoPath(repoUrl)+"/raw/"+pathEscape(e.Path), ref, nil, &content); err != nil {
+			return nil, err
+		}
+
+		path := e.Path
+		files = append(files, &gitprovider.CommitFile{Path: &path, Content: &content})
+	}
+
+	return files, nil
+}
+
+// MergePullRequest merges a pull request given the repository's URL and the PR's number with a commit message.
+func (p giteaGitProvider) MergePullRequest(ctx context.Context, repoUrl RepoURL, pullRequestNumber int, commitMesage string) error {
+	req := map[string]string{
+		"Do":                "merge",
+		"MergeMessageField": commitMesage,
+	}
+
+	return p.client.do(ctx, http.MethodPost, fmt.Sprintf("%s/pulls/%d/merge", p.repoPath(repoUrl), pullRequestNumber), nil, req, nil)
+}
+
+func (p giteaGitProvider) ListPullRequests(ctx context.Context, repoUrl RepoURL, state PullRequestState, filter PullRequestFilter) ([]PullRequest, error) {
+	query := url.Values{
+		"state": {"all"},
+		"limit": {strconv.Itoa(pullRequestsPageSize)},
+		"sort":  {"recentupdate"},
+	}
+
+	switch state {
+	case PullRequestStateOpen:
+		query.Set("state", "open")
+	case PullRequestStateMerged, PullRequestStateClosed:
+		query.Set("state", "closed")
+	}
+
+	var prs []giteaPullRequest
+	if err := p.client.do(ctx, http.MethodGet, p.repoPath(repoUrl)+"/pulls", query, nil, &prs); err != nil {
+		return nil, fmt.Errorf("error listing pull requests: %w", err)
+	}
+
+	res := []PullRequest{}
+
+	for _, pr := range prs {
+		converted := toGiteaPullRequestInfo(pr)
+		if (state != "" && converted.State != state) || !filter.Matches(converted) {
+			continue
+		}
+
+		if err := p.addPullRequestStatus(ctx, repoUrl, pr, &converted); err != nil {
+			return nil, err
+		}
+
+		res = append(res, converted)
+	}
+
+	return res, nil
+}
+
+func (p giteaGitProvider) GetPullRequest(ctx context.Context, repoUrl RepoURL, number int) (*PullRequest, error) {
+	var pr giteaPullRequest
+	if err := p.client.do(ctx, http.MethodGet, fmt.Sprintf("%s/pulls/%d", p.repoPath(repoUrl), number), nil, nil, &pr); err != nil {
+		return nil, fmt.Errorf("error getting pull request %d: %w", number, err)
+	}
+
+	res := toGiteaPullRequestInfo(pr)
+	if err := p.addPullRequestStatus(ctx, repoUrl, pr, &res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// toGiteaPullRequestInfo converts a pull request, without its CI status.
+func toGiteaPullRequestInfo(pr giteaPullRequest) PullRequest {
+	res := PullRequest{
+		Number:       pr.Number,
+		Title:        pr.Title,
+		Description:  pr.Body,
+		WebURL:       pr.HTMLURL,
+		SourceBranch: pr.Head.Ref,
+		TargetBranch: pr.Base.Ref,
+		Labels:       []string{},
+		State:        PullRequestStateClosed,
+		CIStatus:     CIStatusNone,
+		CreatedAt:    pr.CreatedAt,
+	}
+
+	for _, l := range pr.Labels {
+		res.Labels = append(res.Labels, l.Name)
+	}
+
+	switch {
+	case pr.Merged:
+		res.State = PullRequestStateMerged
+	case pr.State == "open":
+		res.State = PullRequestStateOpen
+	}
+
+	if res.State == PullRequestStateOpen {
+		res.Mergeable = gitprovider.BoolVar(pr.Mergeable)
+	}
+
+	return res
+}
+
+// addPullRequestStatus gets the combined status of the head of an open pull
+// request.
+func (p giteaGitProvider) addPullRequestStatus(ctx context.Context, repoUrl RepoURL, pr giteaPullRequest, res *PullRequest) error {
+	if res.State != PullRequestStateOpen {
+		return nil
+	}
+
+	var status giteaCombinedStatus
+	if err := p.client.do(ctx, http.MethodGet, p.repoPath(repoUrl)+"/commits/"+url.PathEscape(pr.Head.SHA)+"/status", nil, nil, &status); err != nil {
+		return fmt.Errorf("error getting commit status: %w", err)
+	}
+
+	if status.TotalCount > 0 {
+		switch status.State {
+		case "success":
+			res.CIStatus = CIStatusSuccess
+		case "pending":
+			res.CIStatus = CIStatusPending
+		default:
+			res.CIStatus = CIStatusFailure
+		}
+	}
+
+	return nil
+}
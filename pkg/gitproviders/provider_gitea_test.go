@@ -0,0 +1,258 @@
+package gitproviders
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/fluxcd/go-git-providers/gitprovider"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Gitea Provider", func() {
+	var (
+		server   *httptest.Server
+		provider GitProvider
+		repoUrl  RepoURL
+		requests []*http.Request
+		bodies   []map[string]interface{}
+		handlers map[string]http.HandlerFunc
+	)
+
+	BeforeEach(func() {
+		requests = nil
+		bodies = nil
+		handlers = map[string]http.HandlerFunc{}
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body := map[string]interface{}{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+
+			requests = append(requests, r)
+			bodies = append(bodies, body)
+
+			h, ok := handlers[r.Method+" "+r.URL.Path]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+
+			h(w, r)
+		}))
+
+		var err error
+		repoUrl, err = NewRepoURL("ssh://git@gitea.com/owner/repo.git")
+		Expect(err).NotTo(HaveOccurred())
+
+		provider, err = New(Config{Provider: GitProviderGitea, Hostname: "gitea.com", Token: "token", APIURL: server.URL}, repoUrl.Owner(), nil)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	jsonResponse := func(v interface{}) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(v)
+		}
+	}
+
+	It("requires a token", func() {
+		_, err := New(Config{Provider: GitProviderGitea, Hostname: "gitea.com"}, "owner", nil)
+		Expect(err).To(MatchError(ContainSubstring("no git provider token present")))
+	})
+
+	It("returns the provider domain", func() {
+		Expect(provider.GetProviderDomain()).To(Equal("gitea.com"))
+	})
+
+	Describe("RepositoryExists", func() {
+		It("finds the repository", func() {
+			handlers["GET /api/v1/repos/owner/repo"] = jsonResponse(giteaRepository{DefaultBranch: "main"})
+
+			exists, err := provider.RepositoryExists(context.Background(), repoUrl)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exists).To(BeTrue())
+			Expect(requests[0].Header.Get("Authorization")).To(Equal("token token"))
+		})
+
+		It("returns false when not found", func() {
+			exists, err := provider.RepositoryExists(context.Background(), repoUrl)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exists).To(BeFalse())
+		})
+	})
+
+	It("gets the default branch and visibility", func() {
+		handlers["GET /api/v1/repos/owner/repo"] = jsonResponse(giteaRepository{DefaultBranch: "trunk", Private: true})
+
+		branch, err := provider.GetDefaultBranch(context.Background(), repoUrl)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(branch).To(Equal("trunk"))
+
+		visibility, err := provider.GetRepoVisibility(context.Background(), repoUrl)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*visibility).To(Equal(gitprovider.RepositoryVisibilityPrivate))
+	})
+
+	Describe("deploy keys", func() {
+		It("checks if the deploy key exists", func() {
+			handlers["GET /api/v1/repos/owner/repo/keys"] = jsonResponse([]giteaDeployKey{{Title: "other"}, {Title: DeployKeyName}})
+
+			exists, err := provider.DeployKeyExists(context.Background(), repoUrl)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exists).To(BeTrue())
+		})
+
+		It("uploads a writable deploy key", func() {
+			handlers["POST /api/v1/repos/owner/repo/keys"] = jsonResponse(giteaDeployKey{ID: 1})
+
+			Expect(provider.UploadDeployKey(context.Background(), repoUrl, []byte("ssh-rsa AAAA"))).To(Succeed())
+			Expect(bodies[0]).To(Equal(map[string]interface{}{"title": DeployKeyName, "key": "ssh-rsa AAAA", "read_only": false}))
+		})
+
+		It("returns an error when the repository can't be accessed", func() {
+			err := provider.UploadDeployKey(context.Background(), repoUrl, []byte("ssh-rsa AAAA"))
+			Expect(err).To(MatchError(ErrRepositoryNoPermissionsOrDoesNotExist))
+		})
+
+		It("deletes the deploy key with the same key material", func() {
+			deleted := false
+			handlers["GET /api/v1/repos/owner/repo/keys"] = jsonResponse([]giteaDeployKey{
+				{ID: 1, Title: DeployKeyName, Key: "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIBNg/LBH/SscR2POZCeWNPkyDAMUXtn6+pK7vzVbafk7"},
+				{ID: 2, Title: DeployKeyName, Key: "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAILr+qeLzjlCTGvoeo2w5l1tqQFOt+bXjBertUS08fuv/"},
+			})
+			handlers["DELETE /api/v1/repos/owner/repo/keys/2"] = func(w http.ResponseWriter, r *http.Request) {
+				deleted = true
+				w.WriteHeader(http.StatusNoContent)
+			}
+
+			key := []byte("ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAILr+qeLzjlCTGvoeo2w5l1tqQFOt+bXjBertUS08fuv/ wego-deploy-key\n")
+			Expect(provider.DeleteDeployKey(context.Background(), repoUrl, key)).To(Succeed())
+			Expect(deleted).To(BeTrue())
+		})
+
+		It("returns not found when the deploy key doesn't exist", func() {
+			handlers["GET /api/v1/repos/owner/repo/keys"] = jsonResponse([]giteaDeployKey{
+				{ID: 1, Title: DeployKeyName, Key: "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIBNg/LBH/SscR2POZCeWNPkyDAMUXtn6+pK7vzVbafk7"},
+			})
+
+			key := []byte("ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAILr+qeLzjlCTGvoeo2w5l1tqQFOt+bXjBertUS08fuv/")
+			err := provider.DeleteDeployKey(context.Background(), repoUrl, key)
+			Expect(errors.Is(err, gitprovider.ErrNotFound)).To(BeTrue())
+		})
+	})
+
+	It("creates a pull request with the files", func() {
+		handlers["GET /api/v1/repos/owner/repo/contents/existing.yaml"] = jsonResponse(giteaContent{Path: "existing.yaml", SHA: "abc", Type: "file"})
+		handlers["POST /api/v1/repos/owner/repo/contents"] = jsonResponse(map[string]interface{}{})
+		handlers["POST /api/v1/repos/owner/repo/pulls"] = func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(giteaPullRequest{Number: 3, HTMLURL: "https://gitea.com/owner/repo/pulls/3"})
+		}
+
+		existing, added, removed, missing := "existing.yaml", "added.yaml", "removed.yaml", "missing.yaml"
+		content := "foo: bar"
+
+		handlers["GET /api/v1/repos/owner/repo/contents/removed.yaml"] = jsonResponse(giteaContent{Path: removed, SHA: "def", Type: "file"})
+
+		pr, err := provider.CreatePullRequest(context.Background(), repoUrl, PullRequestInfo{
+			Title:         "title",
+			Description:   "description",
+			CommitMessage: "message",
+			TargetBranch:  "main",
+			NewBranch:     "feature",
+			Files: []gitprovider.CommitFile{
+				{Path: &existing, Content: &content},
+				{Path: &added, Content: &content},
+				{Path: &removed},
+				{Path: &missing},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pr.Get().Number).To(Equal(3))
+		Expect(pr.Get().WebURL).To(Equal("https://gitea.com/owner/repo/pulls/3"))
+
+		var commit map[string]interface{}
+
+		for i, r := range requests {
+			if r.Method == http.MethodPost && r.URL.Path == "/api/v1/repos/owner/repo/contents" {
+				commit = bodies[i]
+			}
+		}
+
+		Expect(commit["branch"]).To(Equal("main"))
+		Expect(commit["new_branch"]).To(Equal("feature"))
+
+		encoded := base64.StdEncoding.EncodeToString([]byte(content))
+		Expect(commit["files"]).To(Equal([]interface{}{
+			map[string]interface{}{"operation": "update", "path": existing, "content": encoded, "sha": "abc"},
+			map[string]interface{}{"operation": "create", "path": added, "content": encoded},
+			map[string]interface{}{"operation": "delete", "path": removed, "sha": "def"},
+		}))
+	})
+
+	It("gets commits", func() {
+		commit := giteaCommit{SHA: "sha", HTMLURL: "https://gitea.com/owner/repo/commit/sha"}
+		commit.Commit.Message = "message"
+		commit.Commit.Author.Name = "author"
+		handlers["GET /api/v1/repos/owner/repo/commits"] = jsonResponse([]giteaCommit{commit})
+
+		commits, err := provider.GetCommits(context.Background(), repoUrl, "main", 10, 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(commits).To(HaveLen(1))
+		Expect(commits[0].Get().Sha).To(Equal("sha"))
+		Expect(commits[0].Get().Author).To(Equal("author"))
+		Expect(requests[0].URL.Query().Get("page")).To(Equal("1"))
+		Expect(requests[0].URL.Query().Get("sha")).To(Equal("main"))
+	})
+
+	It("returns no commits for an empty repository", func() {
+		handlers["GET /api/v1/repos/owner/repo/commits"] = func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusConflict)
+		}
+
+		commits, err := provider.GetCommits(context.Background(), repoUrl, "main", 10, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(commits).To(BeEmpty())
+	})
+
+	It("gets the files of a directory", func() {
+		handlers["GET /api/v1/repos/owner/repo/contents/dir"] = jsonResponse([]giteaContent{
+			{Path: "dir/a.yaml", Type: "file"},
+			{Path: "dir/sub", Type: "dir"},
+		})
+		handlers["GET /api/v1/repos/owner/repo/raw/dir/a.yaml"] = func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("a: b"))
+		}
+
+		files, err := provider.GetRepoDirFiles(context.Background(), repoUrl, "dir", "main")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(HaveLen(1))
+		Expect(*files[0].Path).To(Equal("dir/a.yaml"))
+		Expect(*files[0].Content).To(Equal("a: b"))
+	})
+
+	It("merges pull requests", func() {
+		handlers["POST /api/v1/repos/owner/repo/pulls/3/merge"] = func(w http.ResponseWriter, r *http.Request) {}
+
+		Expect(provider.MergePullRequest(context.Background(), repoUrl, 3, "merge it")).To(Succeed())
+		Expect(bodies[0]).To(Equal(map[string]interface{}{"Do": "merge", "MergeMessageField": "merge it"}))
+	})
+
+	It("returns the status of failed requests", func() {
+		handlers["GET /api/v1/repos/owner/repo"] = func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "nope", http.StatusForbidden)
+		}
+
+		_, err := provider.RepositoryExists(context.Background(), repoUrl)
+		Expect(err).To(HaveOccurred())
+		Expect(strings.Contains(err.Error(), "status 403: nope")).To(BeTrue())
+	})
+})
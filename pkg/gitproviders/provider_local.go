@@ -0,0 +1,784 @@
+package gitproviders
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fluxcd/go-git-providers/gitprovider"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+const (
+	// localPullRequestsDir is the directory, inside a bare repository, the
+	// metadata of its pull requests are kept in.
+	localPullRequestsDir = "pull-requests"
+
+	localPullRequestOpen   = "open"
+	localPullRequestMerged = "merged"
+)
+
+// localGitProvider works on bare repositories on the local filesystem,
+// addressed with file:// URLs, so that flows can be run without a git
+// hosting service. Pull requests are branches, with their metadata in a file
+// next to the repository's objects.
+type localGitProvider struct{}
+
+var _ GitProvider = localGitProvider{}
+
+// NewLocal returns a GitProvider for bare repositories on the local
+// filesystem.
+func NewLocal() GitProvider {
+	return localGitProvider{}
+}
+
+// localPullRequest is the metadata of a pull request.
+type localPullRequest struct {
+	Number      int        `json:"number"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Head        string     `json:"head"`
+	Base        string     `json:"base"`
+	State       string     `json:"state"`
+	MergeCommit string     `json:"mergeCommit,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	MergedAt    *time.Time `json:"mergedAt,omitempty"`
+}
+
+func localSignature() *object.Signature {
+	return &object.Signature{
+		Name:  "Weave GitOps",
+		Email: "weave-gitops@weave.works",
+		When:  time.Now(),
+	}
+}
+
+func (p localGitProvider) open(repoUrl RepoURL) (*gogit.Repository, error) {
+	repo, err := gogit.PlainOpen(repoUrl.URL().Path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening repository %s: %w", repoUrl.URL().Path, err)
+	}
+
+	return repo, nil
+}
+
+func (p localGitProvider) RepositoryExists(_ context.Context, repoUrl RepoURL) (bool, error) {
+	if _, err := p.open(repoUrl); err != nil {
+		if errors.Is(err, gogit.ErrRepositoryNotExists) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// DeployKeyExists always returns true, as local repositories need no keys.
+func (p localGitProvider) DeployKeyExists(_ context.Context, _ RepoURL) (bool, error) {
+	return true, nil
+}
+
+func (p localGitProvider) GetDefaultBranch(_ context.Context, repoUrl RepoURL) (string, error) {
+	repo, err := p.open(repoUrl)
+	if err != nil {
+		return "main", err
+	}
+
+	head, err := repo.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return "main", fmt.Errorf("error getting HEAD of %s: %w", repoUrl.URL().Path, err)
+	}
+
+	if head.Type() != plumbing.SymbolicReference {
+		return "main", fmt.Errorf("HEAD of %s is detached", repoUrl.URL().Path)
+	}
+
+	return head.Target().Short(), nil
+}
+
+func (p localGitProvider) GetRepoVisibility(_ context.Context, _ RepoURL) (*gitprovider.RepositoryVisibility, error) {
+	return gitprovider.RepositoryVisibilityVar(gitprovider.RepositoryVisibilityPrivate), nil
+}
+
+// UploadDeployKey does nothing, as local repositories need no keys.
+func (p localGitProvider) UploadDeployKey(_ context.Context, _ RepoURL, _ []byte) error {
+	return nil
+}
+
+// DeleteDeployKey does nothing, as local repositories need no keys.
+func (p localGitProvider) DeleteDeployKey(_ context.Context, _ RepoURL, _ []byte) error {
+	return nil
+}
+
+func (p localGitProvider) CreatePullRequest(ctx context.Context, repoUrl RepoURL, prInfo PullRequestInfo) (gitprovider.PullRequest, error) {
+	repo, err := p.open(repoUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	if prInfo.TargetBranch == "" {
+		branch, err := p.GetDefaultBranch(ctx, repoUrl)
+		if err != nil {
+			return nil, err
+		}
+
+		prInfo.TargetBranch = branch
+	}
+
+	if prInfo.SkipAddingFilesOnCreation {
+		if _, err := branchCommit(repo, prInfo.NewBranch); err != nil {
+			return nil, err
+		}
+	} else if err := p.commitFiles(repo, prInfo); err != nil {
+		return nil, fmt.Errorf("error creating commit %s: %w", prInfo.NewBranch, err)
+	}
+
+	pr := localPullRequest{
+		Title:       prInfo.Title,
+		Description: prInfo.Description,
+		Head:        prInfo.NewBranch,
+		Base:        prInfo.TargetBranch,
+		State:       localPullRequestOpen,
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	if err := createLocalPullRequest(repoUrl, &pr); err != nil {
+		return nil, fmt.Errorf("error creating pull request %s: %w", prInfo.Title, err)
+	}
+
+	return toLocalPullRequest(repoUrl, pr), nil
+}
+
+// commitFiles commits the files of the pull request to its new branch,
+// created from the target branch. The target branch may not exist yet in
+// empty repositories.
+func (p localGitProvider) commitFiles(repo *gogit.Repository, prInfo PullRequestInfo) error {
+	newRef := plumbing.NewBranchReferenceName(prInfo.NewBranch)
+	if _, err := repo.Storer.Reference(newRef); err == nil {
+		return fmt.Errorf("branch %s already exists", prInfo.NewBranch)
+	}
+
+	files := map[string]object.TreeEntry{}
+	parents := []plumbing.Hash{}
+
+	base, err := branchCommit(repo, prInfo.TargetBranch)
+
+	switch {
+	case err == nil:
+		if files, err = treeFiles(base); err != nil {
+			return err
+		}
+
+		parents = append(parents, base.Hash)
+	case !errors.Is(err, plumbing.ErrReferenceNotFound):
+		return err
+	}
+
+	for _, f := range prInfo.Files {
+		if f.Path == nil {
+			continue
+		}
+
+		path := strings.Trim(*f.Path, "/")
+
+		if f.Content == nil {
+			delete(files, path)
+			continue
+		}
+
+		hash, err := storeBlob(repo.Storer, []byte(*f.Content))
+		if err != nil {
+			return err
+		}
+
+		files[path] = object.TreeEntry{Name: path, Mode: filemode.Regular, Hash: hash}
+	}
+
+	tree, err := storeTree(repo.Storer, files)
+	if err != nil {
+		return err
+	}
+
+	commit, err := storeCommit(repo.Storer, prInfo.CommitMessage, tree, parents...)
+	if err != nil {
+		return err
+	}
+
+	return repo.Storer.SetReference(plumbing.NewHashReference(newRef, commit))
+}
+
+func (p localGitProvider) GetCommits(_ context.Context, repoUrl RepoURL, targetBranch string, pageSize int, pageToken int) ([]gitprovider.Commit, error) {
+	repo, err := p.open(repoUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	if pageToken < 1 {
+		pageToken = 1
+	}
+
+	head, err := branchCommit(repo, targetBranch)
+	if err != nil {
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			// The repository is empty.
+			return []gitprovider.Commit{}, nil
+		}
+
+		return nil, err
+	}
+
+	iter, err := repo.Log(&gogit.LogOptions{From: head.Hash, Order: gogit.LogOrderCommitterTime})
+	if err != nil {
+		return nil, fmt.Errorf("error getting commits: %w", err)
+	}
+	defer iter.Close()
+
+	skip := (pageToken - 1) * pageSize
+	res := []gitprovider.Commit{}
+
+	err = iter.ForEach(func(c *object.Commit) error {
+		if skip > 0 {
+			skip--
+			return nil
+		}
+
+		if len(res) == pageSize {
+			return storer.ErrStop
+		}
+
+		res = append(res, restCommit{
+			info: gitprovider.CommitInfo{
+				Sha:       c.Hash.String(),
+				TreeSha:   c.TreeHash.String(),
+				Author:    c.Author.Name,
+				Message:   c.Message,
+				CreatedAt: c.Author.When,
+			},
+			object: c,
+		})
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting commits: %w", err)
+	}
+
+	return res, nil
+}
+
+func (p localGitProvider) GetProviderDomain() string {
+	return string(GitProviderLocal)
+}
+
+// GetRepoDirFiles returns the files found in a directory of a repository,
+// not recursively.
+func (p localGitProvider) GetRepoDirFiles(_ context.Context, repoUrl RepoURL, dirPath, targetBranch string) ([]*gitprovider.CommitFile, error) {
+	repo, err := p.open(repoUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	commit, err := branchCommit(repo, targetBranch)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	dirPath = strings.Trim(dirPath, "/")
+	if dirPath != "" {
+		if tree, err = tree.Tree(dirPath); err != nil {
+			return nil, fmt.Errorf("error getting directory %s: %w", dirPath, err)
+		}
+	}
+
+	files := []*gitprovider.CommitFile{}
+
+	for _, e := range tree.Entries {
+		if !e.Mode.IsFile() {
+			continue
+		}
+
+		blob, err := repo.BlobObject(e.Hash)
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := readBlob(blob)
+		if err != nil {
+			return nil, err
+		}
+
+		path := e.Name
+		if dirPath != "" {
+			path = dirPath + "/" + e.Name
+		}
+
+		files = append(files, &gitprovider.CommitFile{Path: &path, Content: &content})
+	}
+
+	return files, nil
+}
+
+// MergePullRequest merges a pull request given the repository's URL and the PR's number with a commit message.
+// Files changed in both branches since they diverged are conflicts.
+func (p localGitProvider) MergePullRequest(_ context.Context, repoUrl RepoURL, pullRequestNumber int, commitMesage string) error {
+	repo, err := p.open(repoUrl)
+	if err != nil {
+		return err
+	}
+
+	pr, err := readLocalPullRequest(repoUrl, pullRequestNumber)
+	if err != nil {
+		return err
+	}
+
+	if pr.State != localPullRequestOpen {
+		return fmt.Errorf("pull request %d is %s", pr.Number, pr.State)
+	}
+
+	head, err := branchCommit(repo, pr.Head)
+	if err != nil {
+		return err
+	}
+
+	baseRef := plumbing.NewBranchReferenceName(pr.Base)
+
+	oldBase, err := repo.Storer.Reference(baseRef)
+	if err != nil && !errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return err
+	}
+
+	var merged plumbing.Hash
+
+	if oldBase == nil {
+		// The base branch doesn't exist yet, so it starts at the head.
+		merged = head.Hash
+	} else {
+		base, err := repo.CommitObject(oldBase.Hash())
+		if err != nil {
+			return err
+		}
+
+		tree, err := mergeTrees(repo, base, head)
+		if err != nil {
+			return fmt.Errorf("error merging pull request %d: %w", pr.Number, err)
+		}
+
+		if merged, err = storeCommit(repo.Storer, commitMesage, tree, base.Hash, head.Hash); err != nil {
+			return err
+		}
+	}
+
+	if err := repo.Storer.CheckAndSetReference(plumbing.NewHashReference(baseRef, merged), oldBase); err != nil {
+		return fmt.Errorf("error updating branch %s: %w", pr.Base, err)
+	}
+
+	now := time.Now().UTC()
+	pr.State = localPullRequestMerged
+	pr.MergeCommit = merged.String()
+	pr.MergedAt = &now
+
+	return writeLocalPullRequest(repoUrl, pr)
+}
+
+func (p localGitProvider) ListPullRequests(_ context.Context, repoUrl RepoURL, state PullRequestState, filter PullRequestFilter) ([]PullRequest, error) {
+	repo, err := p.open(repoUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(filepath.Join(repoUrl.URL().Path, localPullRequestsDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	numbers := []int{}
+
+	for _, e := range entries {
+		if n, err := strconv.Atoi(strings.TrimSuffix(e.Name(), ".json")); err == nil {
+			numbers = append(numbers, n)
+		}
+	}
+
+	// The latest pull requests come first, like on hosting services.
+	sort.Sort(sort.Reverse(sort.IntSlice(numbers)))
+
+	res := []PullRequest{}
+
+	for _, n := range numbers {
+		if len(res) == pullRequestsPageSize {
+			break
+		}
+
+		pr, err := readLocalPullRequest(repoUrl, n)
+		if err != nil {
+			return nil, err
+		}
+
+		converted := p.toPullRequestStatus(repo, repoUrl, pr)
+		if (state == "" || converted.State == state) && filter.Matches(converted) {
+			res = append(res, converted)
+		}
+	}
+
+	return res, nil
+}
+
+func (p localGitProvider) GetPullRequest(_ context.Context, repoUrl RepoURL, number int) (*PullRequest, error) {
+	repo, err := p.open(repoUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, err := readLocalPullRequest(repoUrl, number)
+	if err != nil {
+		return nil, err
+	}
+
+	res := p.toPullRequestStatus(repo, repoUrl, pr)
+
+	return &res, nil
+}
+
+// toPullRequestStatus converts a pull request, checking whether open ones
+// can be merged. Local repositories have no CI.
+func (p localGitProvider) toPullRequestStatus(repo *gogit.Repository, repoUrl RepoURL, pr localPullRequest) PullRequest {
+	res := PullRequest{
+		Number:       pr.Number,
+		Title:        pr.Title,
+		Description:  pr.Description,
+		WebURL:       toLocalPullRequest(repoUrl, pr).Get().WebURL,
+		SourceBranch: pr.Head,
+		TargetBranch: pr.Base,
+		Labels:       []string{},
+		State:        PullRequestState(pr.State),
+		CIStatus:     CIStatusNone,
+		CreatedAt:    pr.CreatedAt,
+	}
+
+	if res.State == PullRequestStateOpen {
+		res.Mergeable = gitprovider.BoolVar(canMerge(repo, pr))
+	}
+
+	return res
+}
+
+// canMerge returns whether the head branch of a pull request merges into its
+// base without conflicts.
+func canMerge(repo *gogit.Repository, pr localPullRequest) bool {
+	head, err := branchCommit(repo, pr.Head)
+	if err != nil {
+		return false
+	}
+
+	base, err := branchCommit(repo, pr.Base)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return true
+	}
+
+	if err != nil {
+		return false
+	}
+
+	_, err = mergeTrees(repo, base, head)
+
+	return err == nil
+}
+
+func toLocalPullRequest(repoUrl RepoURL, pr localPullRequest) gitprovider.PullRequest {
+	return restPullRequest{
+		info: gitprovider.PullRequestInfo{
+			Number: pr.Number,
+			WebURL: "file://" + localPullRequestPath(repoUrl, pr.Number),
+			Merged: pr.State == localPullRequestMerged,
+		},
+		object: pr,
+	}
+}
+
+func localPullRequestPath(repoUrl RepoURL, number int) string {
+	return filepath.Join(repoUrl.URL().Path, localPullRequestsDir, strconv.Itoa(number)+".json")
+}
+
+// createLocalPullRequest writes the metadata of a new pull request, numbered
+// after the existing ones.
+func createLocalPullRequest(repoUrl RepoURL, pr *localPullRequest) error {
+	dir := filepath.Join(repoUrl.URL().Path, localPullRequestsDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if n, err := strconv.Atoi(strings.TrimSuffix(e.Name(), ".json")); err == nil && n > pr.Number {
+			pr.Number = n
+		}
+	}
+
+	pr.Number++
+
+	data, err := json.MarshalIndent(pr, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Creating the file exclusively keeps concurrent pull requests from
+	// taking the same number.
+	f, err := os.OpenFile(localPullRequestPath(repoUrl, pr.Number), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(data)
+
+	return err
+}
+
+func readLocalPullRequest(repoUrl RepoURL, number int) (localPullRequest, error) {
+	var pr localPullRequest
+
+	data, err := os.ReadFile(localPullRequestPath(repoUrl, number))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return pr, fmt.Errorf("pull request %d: %w", number, gitprovider.ErrNotFound)
+		}
+
+		return pr, err
+	}
+
+	return pr, json.Unmarshal(data, &pr)
+}
+
+func writeLocalPullRequest(repoUrl RepoURL, pr localPullRequest) error {
+	data, err := json.MarshalIndent(pr, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(localPullRequestPath(repoUrl, pr.Number), data, 0644)
+}
+
+func branchCommit(repo *gogit.Repository, branch string) (*object.Commit, error) {
+	ref, err := repo.Storer.Reference(plumbing.NewBranchReferenceName(branch))
+	if err != nil {
+		return nil, fmt.Errorf("error getting branch %s: %w", branch, err)
+	}
+
+	return repo.CommitObject(ref.Hash())
+}
+
+// mergeTrees merges the changes of head into base, since their merge base.
+func mergeTrees(repo *gogit.Repository, base, head *object.Commit) (plumbing.Hash, error) {
+	ancestors, err := base.MergeBase(head)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	ancestorFiles := map[string]object.TreeEntry{}
+	if len(ancestors) > 0 {
+		if ancestorFiles, err = treeFiles(ancestors[0]); err != nil {
+			return plumbing.ZeroHash, err
+		}
+	}
+
+	baseFiles, err := treeFiles(base)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	headFiles, err := treeFiles(head)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	paths := map[string]bool{}
+	for _, files := range []map[string]object.TreeEntry{ancestorFiles, baseFiles, headFiles} {
+		for path := range files {
+			paths[path] = true
+		}
+	}
+
+	merged := map[string]object.TreeEntry{}
+	conflicts := []string{}
+
+	for path := range paths {
+		a, aOK := ancestorFiles[path]
+		b, bOK := baseFiles[path]
+		h, hOK := headFiles[path]
+
+		sameBase := bOK == aOK && b == a
+		sameHead := hOK == aOK && h == a
+
+		switch {
+		case bOK == hOK && b == h, sameHead:
+			if bOK {
+				merged[path] = b
+			}
+		case sameBase:
+			if hOK {
+				merged[path] = h
+			}
+		default:
+			conflicts = append(conflicts, path)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return plumbing.ZeroHash, fmt.Errorf("conflicts in %s", strings.Join(conflicts, ", "))
+	}
+
+	return storeTree(repo.Storer, merged)
+}
+
+// treeFiles returns the files of the tree of a commit by their paths.
+func treeFiles(commit *object.Commit) (map[string]object.TreeEntry, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string]object.TreeEntry{}
+	walker := object.NewTreeWalker(tree, true, nil)
+
+	defer walker.Close()
+
+	for {
+		path, entry, err := walker.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if entry.Mode != filemode.Dir {
+			entry.Name = path
+			files[path] = entry
+		}
+	}
+}
+
+func storeBlob(s storer.EncodedObjectStorer, content []byte) (plumbing.Hash, error) {
+	obj := s.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+
+	w, err := obj.Writer()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	if _, err := w.Write(content); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	if err := w.Close(); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return s.SetEncodedObject(obj)
+}
+
+// storeTree stores the trees of the given files by their paths, and returns
+// the hash of the root.
+func storeTree(s storer.EncodedObjectStorer, files map[string]object.TreeEntry) (plumbing.Hash, error) {
+	entries := []object.TreeEntry{}
+	dirs := map[string]map[string]object.TreeEntry{}
+
+	for path, entry := range files {
+		parts := strings.SplitN(path, "/", 2)
+		if len(parts) == 1 {
+			entry.Name = path
+			entries = append(entries, entry)
+
+			continue
+		}
+
+		if dirs[parts[0]] == nil {
+			dirs[parts[0]] = map[string]object.TreeEntry{}
+		}
+
+		dirs[parts[0]][parts[1]] = entry
+	}
+
+	for name, dirFiles := range dirs {
+		hash, err := storeTree(s, dirFiles)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+
+		entries = append(entries, object.TreeEntry{Name: name, Mode: filemode.Dir, Hash: hash})
+	}
+
+	// Git sorts directories as if their names ended with a slash.
+	sortName := func(e object.TreeEntry) string {
+		if e.Mode == filemode.Dir {
+			return e.Name + "/"
+		}
+
+		return e.Name
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return sortName(entries[i]) < sortName(entries[j])
+	})
+
+	obj := s.NewEncodedObject()
+	if err := (&object.Tree{Entries: entries}).Encode(obj); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return s.SetEncodedObject(obj)
+}
+
+func storeCommit(s storer.EncodedObjectStorer, message string, tree plumbing.Hash, parents ...plumbing.Hash) (plumbing.Hash, error) {
+	commit := &object.Commit{
+		Author:       *localSignature(),
+		Committer:    *localSignature(),
+		Message:      message,
+		TreeHash:     tree,
+		ParentHashes: parents,
+	}
+
+	obj := s.NewEncodedObject()
+	if err := commit.Encode(obj); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return s.SetEncodedObject(obj)
+}
+
+func readBlob(blob *object.Blob) (string, error) {
+	r, err := blob.Reader()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	b, err := io.ReadAll(r)
+
+	return string(b), err
+}
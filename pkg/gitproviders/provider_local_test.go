@@ -0,0 +1,204 @@
+package gitproviders
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/fluxcd/go-git-providers/gitprovider"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Local Provider", func() {
+	var (
+		ctx      context.Context
+		provider GitProvider
+		repoUrl  RepoURL
+		repoDir  string
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		repoDir = filepath.Join(GinkgoT().TempDir(), "owner", "repo.git")
+
+		repo, err := gogit.PlainInit(repoDir, true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName("main")))).To(Succeed())
+
+		repoUrl, err = NewRepoURL("file://" + repoDir)
+		Expect(err).NotTo(HaveOccurred())
+
+		provider, err = New(Config{Provider: repoUrl.Provider()}, repoUrl.Owner(), nil)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	file := func(path string, content *string) gitprovider.CommitFile {
+		return gitprovider.CommitFile{Path: &path, Content: content}
+	}
+
+	str := func(s string) *string {
+		return &s
+	}
+
+	createPR := func(branch, target string, files ...gitprovider.CommitFile) gitprovider.PullRequest {
+		pr, err := provider.CreatePullRequest(ctx, repoUrl, PullRequestInfo{
+			Title:         "add " + branch,
+			CommitMessage: "commit " + branch,
+			TargetBranch:  target,
+			NewBranch:     branch,
+			Files:         files,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		return pr
+	}
+
+	It("parses file URLs", func() {
+		Expect(repoUrl.Provider()).To(Equal(GitProviderLocal))
+		Expect(repoUrl.Protocol()).To(Equal(RepositoryURLProtocolFile))
+		Expect(repoUrl.Owner()).To(Equal("owner"))
+		Expect(repoUrl.RepositoryName()).To(Equal("repo"))
+		Expect(repoUrl.String()).To(Equal("file://" + repoDir))
+	})
+
+	It("describes the repository", func() {
+		exists, err := provider.RepositoryExists(ctx, repoUrl)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(exists).To(BeTrue())
+
+		branch, err := provider.GetDefaultBranch(ctx, repoUrl)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(branch).To(Equal("main"))
+
+		missing, err := NewRepoURL("file://" + filepath.Join(repoDir, "..", "missing.git"))
+		Expect(err).NotTo(HaveOccurred())
+
+		exists, err = provider.RepositoryExists(ctx, missing)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(exists).To(BeFalse())
+	})
+
+	It("creates and merges a pull request into an empty repository", func() {
+		pr := createPR("first", "", file("dir/a.yaml", str("a")), file("dir/sub/b.yaml", str("b")), file("c.yaml", str("c")))
+		Expect(pr.Get().Number).To(Equal(1))
+		Expect(pr.Get().Merged).To(BeFalse())
+		Expect(pr.Get().WebURL).To(Equal("file://" + filepath.Join(repoDir, "pull-requests", "1.json")))
+
+		files, err := provider.GetRepoDirFiles(ctx, repoUrl, "dir", "first")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(HaveLen(1))
+		Expect(*files[0].Path).To(Equal("dir/a.yaml"))
+		Expect(*files[0].Content).To(Equal("a"))
+
+		commits, err := provider.GetCommits(ctx, repoUrl, "main", 10, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(commits).To(BeEmpty())
+
+		Expect(provider.MergePullRequest(ctx, repoUrl, 1, "merge first")).To(Succeed())
+
+		files, err = provider.GetRepoDirFiles(ctx, repoUrl, "", "main")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(HaveLen(1))
+		Expect(*files[0].Path).To(Equal("c.yaml"))
+
+		Expect(provider.MergePullRequest(ctx, repoUrl, 1, "merge again")).To(MatchError("pull request 1 is merged"))
+	})
+
+	It("merges pull requests changing different files", func() {
+		createPR("first", "main", file("a.yaml", str("a")), file("b.yaml", str("b")))
+		Expect(provider.MergePullRequest(ctx, repoUrl, 1, "merge first")).To(Succeed())
+
+		createPR("second", "main", file("a.yaml", str("a2")))
+		third := createPR("third", "main", file("b.yaml", nil), file("c.yaml", str("c")))
+		Expect(third.Get().Number).To(Equal(3))
+
+		Expect(provider.MergePullRequest(ctx, repoUrl, 2, "merge second")).To(Succeed())
+		Expect(provider.MergePullRequest(ctx, repoUrl, 3, "merge third")).To(Succeed())
+
+		files, err := provider.GetRepoDirFiles(ctx, repoUrl, "", "main")
+		Expect(err).NotTo(HaveOccurred())
+
+		contents := map[string]string{}
+		for _, f := range files {
+			contents[*f.Path] = *f.Content
+		}
+
+		Expect(contents).To(Equal(map[string]string{"a.yaml": "a2", "c.yaml": "c"}))
+
+		messages := []string{}
+
+		for page := 1; page <= 3; page++ {
+			commits, err := provider.GetCommits(ctx, repoUrl, "main", 2, page)
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, c := range commits {
+				messages = append(messages, c.Get().Message)
+				Expect(c.Get().Author).To(Equal("Weave GitOps"))
+			}
+		}
+
+		Expect(messages).To(ConsistOf("merge third", "merge second", "commit third", "commit second", "commit first"))
+	})
+
+	It("refuses to merge conflicting pull requests", func() {
+		createPR("first", "main", file("a.yaml", str("a")))
+		Expect(provider.MergePullRequest(ctx, repoUrl, 1, "merge first")).To(Succeed())
+
+		createPR("second", "main", file("a.yaml", str("second")))
+		createPR("third", "main", file("a.yaml", str("third")))
+		Expect(provider.MergePullRequest(ctx, repoUrl, 2, "merge second")).To(Succeed())
+
+		err := provider.MergePullRequest(ctx, repoUrl, 3, "merge third")
+		Expect(err).To(MatchError("error merging pull request 3: conflicts in a.yaml"))
+	})
+
+	It("returns not found for unknown pull requests", func() {
+		err := provider.MergePullRequest(ctx, repoUrl, 42, "merge")
+		Expect(err).To(MatchError(gitprovider.ErrNotFound))
+	})
+
+	It("refuses to reuse branches", func() {
+		createPR("first", "main", file("a.yaml", str("a")))
+
+		_, err := provider.CreatePullRequest(ctx, repoUrl, PullRequestInfo{TargetBranch: "main", NewBranch: "first"})
+		Expect(err).To(MatchError(ContainSubstring("branch first already exists")))
+
+		_, err = os.Stat(filepath.Join(repoDir, "pull-requests", "2.json"))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("lists pull requests with their mergeability", func() {
+		createPR(PullRequestBranchPrefix+"first", "main", file("a.yaml", str("a")))
+		Expect(provider.MergePullRequest(ctx, repoUrl, 1, "merge first")).To(Succeed())
+
+		createPR(PullRequestBranchPrefix+"second", "main", file("a.yaml", str("second")))
+		createPR(PullRequestBranchPrefix+"third", "main", file("a.yaml", str("third")))
+		Expect(provider.MergePullRequest(ctx, repoUrl, 2, "merge second")).To(Succeed())
+		createPR("fourth", "main", file("a.yaml", str("fourth")))
+
+		prs, err := provider.ListPullRequests(ctx, repoUrl, "", DefaultPullRequestFilter())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(prs).To(HaveLen(3))
+		Expect(prs[0].Number).To(Equal(3))
+		Expect(prs[0].State).To(Equal(PullRequestStateOpen))
+		Expect(prs[0].Mergeable).To(Equal(gitprovider.BoolVar(false)))
+		Expect(prs[0].SourceBranch).To(Equal(PullRequestBranchPrefix + "third"))
+		Expect(prs[0].TargetBranch).To(Equal("main"))
+		Expect(prs[1].State).To(Equal(PullRequestStateMerged))
+		Expect(prs[1].Mergeable).To(BeNil())
+
+		prs, err = provider.ListPullRequests(ctx, repoUrl, PullRequestStateMerged, DefaultPullRequestFilter())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(prs).To(HaveLen(2))
+
+		pr, err := provider.GetPullRequest(ctx, repoUrl, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pr.Title).To(Equal("add " + PullRequestBranchPrefix + "first"))
+
+		_, err = provider.GetPullRequest(ctx, repoUrl, 42)
+		Expect(err).To(MatchError(gitprovider.ErrNotFound))
+	})
+})
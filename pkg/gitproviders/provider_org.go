@@ -55,6 +55,15 @@ func (p orgGitProvider) UploadDeployKey(ctx context.Context, repoUrl RepoURL, de
 	return uploadDeployKey(ctx, orgRepo, deployKeyInfo)
 }
 
+func (p orgGitProvider) DeleteDeployKey(ctx context.Context, repoUrl RepoURL, deployKey []byte) error {
+	orgRepo, err := p.getOrgRepo(ctx, repoUrl)
+	if err != nil {
+		return fmt.Errorf("error getting org repo reference for owner %s, repo %s, %w", repoUrl.Owner(), repoUrl.RepositoryName(), err)
+	}
+
+	return deleteDeployKey(ctx, orgRepo, deployKey)
+}
+
 func (p orgGitProvider) GetDefaultBranch(ctx context.Context, repoUrl RepoURL) (string, error) {
 	repoInfoRef, err := p.getRepoInfoFromUrl(ctx, repoUrl)
 	if err != nil {
@@ -151,3 +160,11 @@ func (p orgGitProvider) MergePullRequest(ctx context.Context, repoUrl RepoURL, p
 
 	return repo.PullRequests().Merge(ctx, pullRequestNumber, gitprovider.MergeMethodMerge, commitMesage)
 }
+
+func (p orgGitProvider) ListPullRequests(ctx context.Context, repoUrl RepoURL, state PullRequestState, filter PullRequestFilter) ([]PullRequest, error) {
+	return listPullRequests(ctx, p.provider, repoUrl, state, filter)
+}
+
+func (p orgGitProvider) GetPullRequest(ctx context.Context, repoUrl RepoURL, number int) (*PullRequest, error) {
+	return getPullRequest(ctx, p.provider, repoUrl, number)
+}
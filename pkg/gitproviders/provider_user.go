@@ -55,6 +55,15 @@ func (p userGitProvider) UploadDeployKey(ctx context.Context, repoUrl RepoURL, d
 	return uploadDeployKey(ctx, userRepo, deployKeyInfo)
 }
 
+func (p userGitProvider) DeleteDeployKey(ctx context.Context, repoUrl RepoURL, deployKey []byte) error {
+	userRepo, err := p.getUserRepo(ctx, repoUrl)
+	if err != nil {
+		return fmt.Errorf("error getting user repo reference for owner %s, repo %s, %w", repoUrl.Owner(), repoUrl.RepositoryName(), err)
+	}
+
+	return deleteDeployKey(ctx, userRepo, deployKey)
+}
+
 func (p userGitProvider) GetDefaultBranch(ctx context.Context, repoUrl RepoURL) (string, error) {
 	repoInfoRef, err := p.getRepoInfoFromUrl(ctx, repoUrl)
 	if err != nil {
@@ -140,3 +149,11 @@ func (p userGitProvider) MergePullRequest(ctx context.Context, repoUrl RepoURL,
 
 	return repo.PullRequests().Merge(ctx, pullRequestNumber, gitprovider.MergeMethodMerge, commitMesage)
 }
+
+func (p userGitProvider) ListPullRequests(ctx context.Context, repoUrl RepoURL, state PullRequestState, filter PullRequestFilter) ([]PullRequest, error) {
+	return listPullRequests(ctx, p.provider, repoUrl, state, filter)
+}
+
+func (p userGitProvider) GetPullRequest(ctx context.Context, repoUrl RepoURL, number int) (*PullRequest, error) {
+	return getPullRequest(ctx, p.provider, repoUrl, number)
+}
@@ -0,0 +1,424 @@
+package gitproviders
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fluxcd/go-git-providers/gitprovider"
+	"github.com/google/go-github/v42/github"
+	"github.com/xanzy/go-gitlab"
+)
+
+const (
+	// PullRequestBranchPrefix starts the names of the branches that pull
+	// requests are created from, unless a branch is given.
+	PullRequestBranchPrefix = "weave-gitops-"
+	// PullRequestLabel marks pull requests as created by Weave GitOps.
+	PullRequestLabel = "weave-gitops"
+
+	// pullRequestsPageSize is how many of the latest pull requests are listed.
+	pullRequestsPageSize = 100
+)
+
+// PullRequestState is the state of a pull request. Closed pull requests
+// weren't merged.
+type PullRequestState string
+
+const (
+	PullRequestStateOpen   PullRequestState = "open"
+	PullRequestStateMerged PullRequestState = "merged"
+	PullRequestStateClosed PullRequestState = "closed"
+)
+
+// CIStatus sums up the checks that ran on the head of a pull request.
+type CIStatus string
+
+const (
+	CIStatusNone    CIStatus = "none"
+	CIStatusPending CIStatus = "pending"
+	CIStatusSuccess CIStatus = "success"
+	CIStatusFailure CIStatus = "failure"
+)
+
+// PullRequest describes a pull request, or merge request on GitLab.
+type PullRequest struct {
+	Number       int
+	Title        string
+	Description  string
+	WebURL       string
+	SourceBranch string
+	TargetBranch string
+	Labels       []string
+	State        PullRequestState
+	// Mergeable is nil if the provider doesn't know yet whether the pull
+	// request can be merged, e.g. for merged pull requests.
+	Mergeable *bool
+	CIStatus  CIStatus
+	CreatedAt time.Time
+}
+
+// PullRequestFilter selects the pull requests created by Weave GitOps, by
+// the prefix of their branch or by label.
+type PullRequestFilter struct {
+	BranchPrefix string
+	Label        string
+}
+
+// DefaultPullRequestFilter selects pull requests from branches named by
+// default, or with the Weave GitOps label.
+func DefaultPullRequestFilter() PullRequestFilter {
+	return PullRequestFilter{
+		BranchPrefix: PullRequestBranchPrefix,
+		Label:        PullRequestLabel,
+	}
+}
+
+// Matches returns whether the pull request was created by Weave GitOps.
+func (f PullRequestFilter) Matches(pr PullRequest) bool {
+	if f.BranchPrefix != "" && strings.HasPrefix(pr.SourceBranch, f.BranchPrefix) {
+		return true
+	}
+
+	for _, l := range pr.Labels {
+		if f.Label != "" && l == f.Label {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Filter returns the pull requests created by Weave GitOps.
+func (f PullRequestFilter) Filter(prs []PullRequest) []PullRequest {
+	res := []PullRequest{}
+
+	for _, pr := range prs {
+		if f.Matches(pr) {
+			res = append(res, pr)
+		}
+	}
+
+	return res
+}
+
+// PullRequestBranch returns the name of a branch pull requests are created
+// from, prefixed so that the pull requests match the default filter.
+func PullRequestBranch(name string) string {
+	if strings.HasPrefix(name, PullRequestBranchPrefix) {
+		return name
+	}
+
+	return PullRequestBranchPrefix + name
+}
+
+// listPullRequests lists the pull requests of a repository hosted on GitHub
+// or GitLab, using the provider's own client as go-git-providers has no
+// access to CI statuses.
+func listPullRequests(ctx context.Context, client gitprovider.Client, repoUrl RepoURL, state PullRequestState, filter PullRequestFilter) ([]PullRequest, error) {
+	switch raw := client.Raw().(type) {
+	case *github.Client:
+		return listGitHubPullRequests(ctx, raw, repoUrl, state, filter)
+	case *gitlab.Client:
+		return listGitLabMergeRequests(ctx, raw, repoUrl, state, filter)
+	default:
+		return nil, fmt.Errorf("listing pull requests is not supported by %s", client.ProviderID())
+	}
+}
+
+func getPullRequest(ctx context.Context, client gitprovider.Client, repoUrl RepoURL, number int) (*PullRequest, error) {
+	switch raw := client.Raw().(type) {
+	case *github.Client:
+		return getGitHubPullRequest(ctx, raw, repoUrl, number)
+	case *gitlab.Client:
+		return getGitLabMergeRequest(ctx, raw, repoUrl, number)
+	default:
+		return nil, fmt.Errorf("getting pull requests is not supported by %s", client.ProviderID())
+	}
+}
+
+func listGitHubPullRequests(ctx context.Context, client *github.Client, repoUrl RepoURL, state PullRequestState, filter PullRequestFilter) ([]PullRequest, error) {
+	opts := &github.PullRequestListOptions{
+		State:       "all",
+		ListOptions: github.ListOptions{PerPage: pullRequestsPageSize},
+	}
+
+	switch state {
+	case PullRequestStateOpen:
+		opts.State = "open"
+	case PullRequestStateMerged, PullRequestStateClosed:
+		opts.State = "closed"
+	}
+
+	prs, _, err := client.PullRequests.List(ctx, repoUrl.Owner(), repoUrl.RepositoryName(), opts)
+	if err != nil {
+		return nil, fmt.Errorf("error listing pull requests: %w", err)
+	}
+
+	res := []PullRequest{}
+
+	for _, pr := range prs {
+		converted := toGitHubPullRequest(pr)
+		if (state != "" && converted.State != state) || !filter.Matches(converted) {
+			continue
+		}
+
+		if converted.State == PullRequestStateOpen {
+			// Only single pull requests tell whether they can be merged.
+			single, _, err := client.PullRequests.Get(ctx, repoUrl.Owner(), repoUrl.RepositoryName(), pr.GetNumber())
+			if err != nil {
+				return nil, fmt.Errorf("error getting pull request: %w", err)
+			}
+
+			converted.Mergeable = single.Mergeable
+
+			if converted.CIStatus, err = gitHubCIStatus(ctx, client, repoUrl, pr.GetHead().GetSHA()); err != nil {
+				return nil, err
+			}
+		}
+
+		res = append(res, converted)
+	}
+
+	return res, nil
+}
+
+func getGitHubPullRequest(ctx context.Context, client *github.Client, repoUrl RepoURL, number int) (*PullRequest, error) {
+	pr, res, err := client.PullRequests.Get(ctx, repoUrl.Owner(), repoUrl.RepositoryName(), number)
+	if err != nil {
+		if res != nil && res.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("pull request %d: %w", number, gitprovider.ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("error getting pull request: %w", err)
+	}
+
+	converted := toGitHubPullRequest(pr)
+
+	if converted.State == PullRequestStateOpen {
+		converted.Mergeable = pr.Mergeable
+
+		if converted.CIStatus, err = gitHubCIStatus(ctx, client, repoUrl, pr.GetHead().GetSHA()); err != nil {
+			return nil, err
+		}
+	}
+
+	return &converted, nil
+}
+
+func gitHubPullRequestState(pr *github.PullRequest) PullRequestState {
+	switch {
+	case pr.GetState() == "open":
+		return PullRequestStateOpen
+	case pr.MergedAt != nil:
+		return PullRequestStateMerged
+	default:
+		return PullRequestStateClosed
+	}
+}
+
+// toGitHubPullRequest converts a pull request, without its mergeability and
+// CI status, which need more requests.
+func toGitHubPullRequest(pr *github.PullRequest) PullRequest {
+	res := PullRequest{
+		Number:       pr.GetNumber(),
+		Title:        pr.GetTitle(),
+		Description:  pr.GetBody(),
+		WebURL:       pr.GetHTMLURL(),
+		SourceBranch: pr.GetHead().GetRef(),
+		TargetBranch: pr.GetBase().GetRef(),
+		Labels:       []string{},
+		State:        gitHubPullRequestState(pr),
+		CIStatus:     CIStatusNone,
+		CreatedAt:    pr.GetCreatedAt(),
+	}
+
+	for _, l := range pr.Labels {
+		res.Labels = append(res.Labels, l.GetName())
+	}
+
+	return res
+}
+
+// gitHubCIStatus combines the commit statuses and the check runs, used by
+// GitHub Actions, of a commit.
+func gitHubCIStatus(ctx context.Context, client *github.Client, repoUrl RepoURL, sha string) (CIStatus, error) {
+	statuses := []CIStatus{}
+
+	combined, _, err := client.Repositories.GetCombinedStatus(ctx, repoUrl.Owner(), repoUrl.RepositoryName(), sha, nil)
+	if err != nil {
+		return "", fmt.Errorf("error getting commit status: %w", err)
+	}
+
+	if combined.GetTotalCount() > 0 {
+		switch combined.GetState() {
+		case "success":
+			statuses = append(statuses, CIStatusSuccess)
+		case "pending":
+			statuses = append(statuses, CIStatusPending)
+		default:
+			statuses = append(statuses, CIStatusFailure)
+		}
+	}
+
+	runs, _, err := client.Checks.ListCheckRunsForRef(ctx, repoUrl.Owner(), repoUrl.RepositoryName(), sha, &github.ListCheckRunsOptions{ListOptions: github.ListOptions{PerPage: 100}})
+	if err != nil {
+		return "", fmt.Errorf("error getting check runs: %w", err)
+	}
+
+	for _, run := range runs.CheckRuns {
+		switch {
+		case run.GetStatus() != "completed":
+			statuses = append(statuses, CIStatusPending)
+		case run.GetConclusion() == "success", run.GetConclusion() == "neutral", run.GetConclusion() == "skipped":
+			statuses = append(statuses, CIStatusSuccess)
+		default:
+			statuses = append(statuses, CIStatusFailure)
+		}
+	}
+
+	return combineCIStatuses(statuses...), nil
+}
+
+// combineCIStatuses returns failure if any status failed, pending if any is
+// pending, and success if all succeeded.
+func combineCIStatuses(statuses ...CIStatus) CIStatus {
+	res := CIStatusNone
+
+	for _, s := range statuses {
+		switch {
+		case s == CIStatusFailure:
+			return CIStatusFailure
+		case s == CIStatusPending:
+			res = CIStatusPending
+		case s == CIStatusSuccess && res == CIStatusNone:
+			res = CIStatusSuccess
+		}
+	}
+
+	return res
+}
+
+func gitLabProject(repoUrl RepoURL) string {
+	return repoUrl.Owner() + "/" + repoUrl.RepositoryName()
+}
+
+func listGitLabMergeRequests(ctx context.Context, client *gitlab.Client, repoUrl RepoURL, state PullRequestState, filter PullRequestFilter) ([]PullRequest, error) {
+	opts := &gitlab.ListProjectMergeRequestsOptions{
+		ListOptions: gitlab.ListOptions{PerPage: pullRequestsPageSize},
+	}
+
+	switch state {
+	case PullRequestStateOpen:
+		opts.State = gitlab.String("opened")
+	case PullRequestStateMerged:
+		opts.State = gitlab.String("merged")
+	case PullRequestStateClosed:
+		opts.State = gitlab.String("closed")
+	}
+
+	mrs, _, err := client.MergeRequests.ListProjectMergeRequests(gitLabProject(repoUrl), opts, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("error listing merge requests: %w", err)
+	}
+
+	res := []PullRequest{}
+
+	for _, mr := range mrs {
+		converted := toGitLabMergeRequest(mr)
+		if !filter.Matches(converted) {
+			continue
+		}
+
+		if converted.State == PullRequestStateOpen {
+			// Only single merge requests have their pipeline.
+			single, _, err := client.MergeRequests.GetMergeRequest(gitLabProject(repoUrl), mr.IID, nil, gitlab.WithContext(ctx))
+			if err != nil {
+				return nil, fmt.Errorf("error getting merge request: %w", err)
+			}
+
+			converted.CIStatus = gitLabCIStatus(single.HeadPipeline)
+		}
+
+		res = append(res, converted)
+	}
+
+	return res, nil
+}
+
+func getGitLabMergeRequest(ctx context.Context, client *gitlab.Client, repoUrl RepoURL, number int) (*PullRequest, error) {
+	mr, res, err := client.MergeRequests.GetMergeRequest(gitLabProject(repoUrl), number, nil, gitlab.WithContext(ctx))
+	if err != nil {
+		if res != nil && res.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("merge request %d: %w", number, gitprovider.ErrNotFound)
+		}
+
+		return nil, fmt.Errorf("error getting merge request: %w", err)
+	}
+
+	pr := toGitLabMergeRequest(mr)
+
+	return &pr, nil
+}
+
+func toGitLabMergeRequest(mr *gitlab.MergeRequest) PullRequest {
+	res := PullRequest{
+		Number:       mr.IID,
+		Title:        mr.Title,
+		Description:  mr.Description,
+		WebURL:       mr.WebURL,
+		SourceBranch: mr.SourceBranch,
+		TargetBranch: mr.TargetBranch,
+		Labels:       append([]string{}, mr.Labels...),
+		CIStatus:     CIStatusNone,
+	}
+
+	if mr.CreatedAt != nil {
+		res.CreatedAt = *mr.CreatedAt
+	}
+
+	switch mr.State {
+	case "opened":
+		res.State = PullRequestStateOpen
+	case "merged":
+		res.State = PullRequestStateMerged
+	default:
+		res.State = PullRequestStateClosed
+	}
+
+	if res.State != PullRequestStateOpen {
+		return res
+	}
+
+	switch mr.MergeStatus {
+	case "can_be_merged":
+		res.Mergeable = gitprovider.BoolVar(true)
+	case "cannot_be_merged":
+		res.Mergeable = gitprovider.BoolVar(false)
+	}
+
+	res.CIStatus = gitLabCIStatus(mr.HeadPipeline)
+
+	return res
+}
+
+// gitLabCIStatus returns the status of the pipeline of the head of a merge
+// request, only single merge requests have it.
+func gitLabCIStatus(pipeline *gitlab.Pipeline) CIStatus {
+	if pipeline == nil {
+		return CIStatusNone
+	}
+
+	switch pipeline.Status {
+	case "success":
+		return CIStatusSuccess
+	case "failed", "canceled":
+		return CIStatusFailure
+	case "skipped":
+		return CIStatusNone
+	default:
+		return CIStatusPending
+	}
+}
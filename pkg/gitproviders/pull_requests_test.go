@@ -0,0 +1,176 @@
+package gitproviders
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"github.com/fluxcd/go-git-providers/gitprovider"
+	"github.com/google/go-github/v42/github"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/xanzy/go-gitlab"
+)
+
+var _ = Describe("PullRequestFilter", func() {
+	prs := []PullRequest{
+		{Number: 1, SourceBranch: "weave-gitops-1234"},
+		{Number: 2, SourceBranch: "feature", Labels: []string{"bug", "weave-gitops"}},
+		{Number: 3, SourceBranch: "feature"},
+	}
+
+	numbers := func(prs []PullRequest) []int {
+		res := []int{}
+		for _, pr := range prs {
+			res = append(res, pr.Number)
+		}
+
+		return res
+	}
+
+	It("selects pull requests by branch prefix or label", func() {
+		Expect(numbers(DefaultPullRequestFilter().Filter(prs))).To(Equal([]int{1, 2}))
+	})
+
+	It("ignores empty criteria", func() {
+		Expect(numbers(PullRequestFilter{Label: "bug"}.Filter(prs))).To(Equal([]int{2}))
+		Expect(numbers(PullRequestFilter{}.Filter(prs))).To(BeEmpty())
+	})
+})
+
+var _ = Describe("PullRequestBranch", func() {
+	It("prefixes branches", func() {
+		Expect(PullRequestBranch("upgrade")).To(Equal("weave-gitops-upgrade"))
+		Expect(PullRequestBranch("weave-gitops-upgrade")).To(Equal("weave-gitops-upgrade"))
+	})
+})
+
+var _ = DescribeTable("combineCIStatuses", func(statuses []CIStatus, expected CIStatus) {
+	Expect(combineCIStatuses(statuses...)).To(Equal(expected))
+},
+	Entry("no statuses", nil, CIStatusNone),
+	Entry("all succeeded", []CIStatus{CIStatusSuccess, CIStatusSuccess}, CIStatusSuccess),
+	Entry("some pending", []CIStatus{CIStatusSuccess, CIStatusPending}, CIStatusPending),
+	Entry("some failed", []CIStatus{CIStatusPending, CIStatusFailure, CIStatusSuccess}, CIStatusFailure),
+)
+
+var _ = Describe("GitHub pull requests", func() {
+	var (
+		server   *httptest.Server
+		client   *github.Client
+		repoUrl  RepoURL
+		handlers map[string]interface{}
+	)
+
+	BeforeEach(func() {
+		handlers = map[string]interface{}{}
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			v, ok := handlers[r.URL.Path]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+
+			_ = json.NewEncoder(w).Encode(v)
+		}))
+
+		client = github.NewClient(nil)
+		client.BaseURL, _ = url.Parse(server.URL + "/")
+
+		var err error
+		repoUrl, err = NewRepoURL("https://github.com/owner/repo")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("lists pull requests with their mergeability and CI status", func() {
+		created := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+		open := map[string]interface{}{
+			"number": 2, "state": "open", "title": "open", "html_url": "https://github.com/owner/repo/pull/2", "created_at": created,
+			"head": map[string]interface{}{"ref": "weave-gitops-abc", "sha": "abc"}, "base": map[string]interface{}{"ref": "main"},
+			"labels": []interface{}{map[string]interface{}{"name": "weave-gitops"}},
+		}
+
+		handlers["/repos/owner/repo/pulls"] = []interface{}{
+			open,
+			map[string]interface{}{"number": 1, "state": "closed", "merged_at": created, "head": map[string]interface{}{"ref": "weave-gitops-def"}},
+			// Pull requests that don't match the filter aren't fetched.
+			map[string]interface{}{"number": 3, "state": "open", "head": map[string]interface{}{"ref": "feature", "sha": "def"}},
+		}
+		handlers["/repos/owner/repo/pulls/2"] = func() map[string]interface{} {
+			open["mergeable"] = false
+			return open
+		}()
+		handlers["/repos/owner/repo/commits/abc/status"] = map[string]interface{}{"state": "success", "total_count": 1}
+		handlers["/repos/owner/repo/commits/abc/check-runs"] = map[string]interface{}{
+			"total_count": 1,
+			"check_runs":  []interface{}{map[string]interface{}{"status": "in_progress"}},
+		}
+
+		prs, err := listGitHubPullRequests(context.Background(), client, repoUrl, "", DefaultPullRequestFilter())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(prs).To(HaveLen(2))
+
+		Expect(prs[0].Number).To(Equal(2))
+		Expect(prs[0].State).To(Equal(PullRequestStateOpen))
+		Expect(prs[0].SourceBranch).To(Equal("weave-gitops-abc"))
+		Expect(prs[0].TargetBranch).To(Equal("main"))
+		Expect(prs[0].Labels).To(Equal([]string{"weave-gitops"}))
+		Expect(prs[0].Mergeable).To(Equal(gitprovider.BoolVar(false)))
+		Expect(prs[0].CIStatus).To(Equal(CIStatusPending))
+		Expect(prs[0].CreatedAt).To(BeTemporally("==", created))
+
+		Expect(prs[1].State).To(Equal(PullRequestStateMerged))
+		Expect(prs[1].Mergeable).To(BeNil())
+		Expect(prs[1].CIStatus).To(Equal(CIStatusNone))
+	})
+
+	It("filters merged and closed pull requests", func() {
+		handlers["/repos/owner/repo/pulls"] = []interface{}{
+			map[string]interface{}{"number": 1, "state": "closed", "merged_at": time.Now(), "head": map[string]interface{}{"ref": "weave-gitops-abc"}},
+			map[string]interface{}{"number": 2, "state": "closed", "head": map[string]interface{}{"ref": "weave-gitops-def"}},
+		}
+
+		prs, err := listGitHubPullRequests(context.Background(), client, repoUrl, PullRequestStateClosed, DefaultPullRequestFilter())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(prs).To(HaveLen(1))
+		Expect(prs[0].Number).To(Equal(2))
+	})
+
+	It("returns not found for unknown pull requests", func() {
+		_, err := getGitHubPullRequest(context.Background(), client, repoUrl, 42)
+		Expect(err).To(MatchError(gitprovider.ErrNotFound))
+	})
+})
+
+var _ = Describe("toGitLabMergeRequest", func() {
+	It("converts open merge requests", func() {
+		pr := toGitLabMergeRequest(&gitlab.MergeRequest{
+			IID:          3,
+			State:        "opened",
+			SourceBranch: "weave-gitops-abc",
+			MergeStatus:  "can_be_merged",
+			HeadPipeline: &gitlab.Pipeline{Status: "failed"},
+		})
+
+		Expect(pr.Number).To(Equal(3))
+		Expect(pr.State).To(Equal(PullRequestStateOpen))
+		Expect(pr.Mergeable).To(Equal(gitprovider.BoolVar(true)))
+		Expect(pr.CIStatus).To(Equal(CIStatusFailure))
+	})
+
+	It("doesn't report the mergeability of merged merge requests", func() {
+		pr := toGitLabMergeRequest(&gitlab.MergeRequest{State: "merged", MergeStatus: "can_be_merged"})
+
+		Expect(pr.State).To(Equal(PullRequestStateMerged))
+		Expect(pr.Mergeable).To(BeNil())
+		Expect(pr.CIStatus).To(Equal(CIStatusNone))
+	})
+})
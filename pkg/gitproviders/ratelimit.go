@@ -0,0 +1,311 @@
+package gitproviders
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fluxcd/go-git-providers/gitprovider"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// rateLimitMaxRetries is how many times a rate-limited request is retried.
+	rateLimitMaxRetries = 3
+	// rateLimitMaxWait is the longest wait before retrying a rate-limited
+	// request, requests that have to wait longer fail right away.
+	rateLimitMaxWait = 30 * time.Second
+	// rateLimitBaseDelay is the first wait before retrying a rate-limited
+	// request without Retry-After or reset headers, doubled on each retry.
+	rateLimitBaseDelay = time.Second
+
+	cacheResultHit         = "hit"
+	cacheResultRevalidated = "revalidated"
+	cacheResultMiss        = "miss"
+)
+
+var (
+	rateLimitRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gitops",
+		Subsystem: "gitprovider",
+		Name:      "rate_limit_remaining",
+		Help:      "Number of requests left in the current rate limit window of the git provider.",
+	}, []string{"provider"})
+
+	rateLimitLimit = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gitops",
+		Subsystem: "gitprovider",
+		Name:      "rate_limit_limit",
+		Help:      "Number of requests allowed in a rate limit window of the git provider.",
+	}, []string{"provider"})
+
+	rateLimitedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gitops",
+		Subsystem: "gitprovider",
+		Name:      "rate_limited_requests_total",
+		Help:      "Number of requests rejected by the git provider's rate limits, by whether they were retried.",
+	}, []string{"provider", "retried"})
+
+	cacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gitops",
+		Subsystem: "gitprovider",
+		Name:      "cache_requests_total",
+		Help:      "Number of read calls to the git provider, by whether they were served from the cache, revalidated with their ETag, or missed.",
+	}, []string{"provider", "result"})
+)
+
+func init() {
+	prometheus.MustRegister(rateLimitRemaining, rateLimitLimit, rateLimitedRequests, cacheRequests)
+}
+
+// rateLimitTransport retries the requests rejected by the rate limits of a
+// git provider, waiting as long as its Retry-After or rate limit reset
+// headers say, or backing off exponentially. GET responses with an ETag are
+// kept in the cache, and revalidated with it.
+type rateLimitTransport struct {
+	// next sends the requests, http.DefaultTransport is used if it's nil.
+	next     http.RoundTripper
+	provider GitProviderName
+	cache    *Cache
+	prefix   string
+
+	maxRetries int
+	maxWait    time.Duration
+	baseDelay  time.Duration
+	// sleep waits before retrying, sleepContext is used if it's nil.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+func newRateLimitTransport(next http.RoundTripper, config Config) *rateLimitTransport {
+	t := &rateLimitTransport{
+		next:       next,
+		provider:   config.Provider,
+		cache:      config.Cache,
+		maxRetries: rateLimitMaxRetries,
+		maxWait:    rateLimitMaxWait,
+		baseDelay:  rateLimitBaseDelay,
+	}
+
+	// The GitLab client already revalidates its responses with their ETag.
+	if config.Provider == GitProviderGitLab {
+		t.cache = nil
+	}
+
+	if t.cache != nil {
+		t.prefix = cachePrefix(config) + "responses/"
+	}
+
+	return t
+}
+
+// rateLimitTransportHook adds the rateLimitTransport to the transport chain of
+// go-git-providers clients, between the authentication and the API.
+func rateLimitTransportHook(config Config) gitprovider.ChainableRoundTripperFunc {
+	return func(in http.RoundTripper) http.RoundTripper {
+		return newRateLimitTransport(in, config)
+	}
+}
+
+// etagResponse is a cached response, served when the provider says it's
+// not modified.
+type etagResponse struct {
+	etag   string
+	header http.Header
+	body   []byte
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Requests that are already conditional are cached by the client.
+	cacheable := t.cache != nil && req.Method == http.MethodGet && req.Header.Get("If-None-Match") == ""
+	key := t.prefix + req.URL.String()
+
+	var cached *etagResponse
+
+	if cacheable {
+		if value, _, ok := t.cache.get(key); ok {
+			cached = value.(*etagResponse)
+			req = req.Clone(req.Context())
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+	}
+
+	res, err := t.roundTrip(req)
+	if err != nil || !cacheable {
+		return res, err
+	}
+
+	switch {
+	case res.StatusCode == http.StatusNotModified && cached != nil:
+		cacheRequests.WithLabelValues(string(t.provider), cacheResultRevalidated).Inc()
+
+		res.Body.Close()
+
+		res.StatusCode = http.StatusOK
+		res.Status = http.StatusText(http.StatusOK)
+		res.Header = cached.header.Clone()
+		res.Body = io.NopCloser(bytes.NewReader(cached.body))
+		res.ContentLength = int64(len(cached.body))
+	case res.StatusCode == http.StatusOK && res.Header.Get("ETag") != "":
+		body, err := io.ReadAll(res.Body)
+		res.Body.Close()
+
+		if err != nil {
+			return nil, err
+		}
+
+		t.cache.set(key, &etagResponse{etag: res.Header.Get("ETag"), header: res.Header.Clone(), body: body}, 0)
+
+		res.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	return res, nil
+}
+
+func (t *rateLimitTransport) roundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	for attempt := 0; ; attempt++ {
+		res, err := next.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+
+		t.recordRateLimit(res)
+
+		if !isRateLimited(res) {
+			return res, nil
+		}
+
+		delay, ok := t.retryDelay(res, attempt)
+		if !ok || attempt >= t.maxRetries || !replayable(req) {
+			rateLimitedRequests.WithLabelValues(string(t.provider), "false").Inc()
+			return res, nil
+		}
+
+		rateLimitedRequests.WithLabelValues(string(t.provider), "true").Inc()
+
+		// The body is drained so that the connection is reused.
+		_, _ = io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+
+		sleep := t.sleep
+		if sleep == nil {
+			sleep = sleepContext
+		}
+
+		if err := sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+
+		if req, err = rewind(req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryDelay returns how long to wait before retrying a rate-limited
+// request, and false if it's longer than the longest wait.
+func (t *rateLimitTransport) retryDelay(res *http.Response, attempt int) (time.Duration, bool) {
+	delay := t.baseDelay << attempt
+
+	if retryAfter := res.Header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			delay = time.Duration(seconds) * time.Second
+		} else if at, err := http.ParseTime(retryAfter); err == nil {
+			delay = time.Until(at)
+		}
+	} else if remaining, ok := rateLimitHeader(res.Header, "Remaining"); ok && remaining == 0 {
+		if reset, ok := rateLimitHeader(res.Header, "Reset"); ok {
+			delay = time.Until(time.Unix(int64(reset), 0)) + time.Second
+		}
+	}
+
+	if delay < 0 {
+		delay = 0
+	}
+
+	return delay, delay <= t.maxWait
+}
+
+func (t *rateLimitTransport) recordRateLimit(res *http.Response) {
+	if remaining, ok := rateLimitHeader(res.Header, "Remaining"); ok {
+		rateLimitRemaining.WithLabelValues(string(t.provider)).Set(float64(remaining))
+	}
+
+	if limit, ok := rateLimitHeader(res.Header, "Limit"); ok {
+		rateLimitLimit.WithLabelValues(string(t.provider)).Set(float64(limit))
+	}
+}
+
+// isRateLimited returns whether the response rejects the request because of
+// rate limits. GitHub rejects them with 403, and a Retry-After header for its
+// secondary rate limits.
+func isRateLimited(res *http.Response) bool {
+	switch res.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusForbidden:
+		if res.Header.Get("Retry-After") != "" {
+			return true
+		}
+
+		remaining, ok := rateLimitHeader(res.Header, "Remaining")
+
+		return ok && remaining == 0
+	default:
+		return false
+	}
+}
+
+// rateLimitHeader returns the value of the X-RateLimit-<name> header, or the
+// RateLimit-<name> one GitLab sends.
+func rateLimitHeader(header http.Header, name string) (int, bool) {
+	for _, key := range []string{"X-RateLimit-" + name, "RateLimit-" + name} {
+		if value := strings.TrimSpace(header.Get(key)); value != "" {
+			n, err := strconv.Atoi(value)
+			return n, err == nil
+		}
+	}
+
+	return 0, false
+}
+
+// replayable returns whether the body of the request can be sent again.
+func replayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+func rewind(req *http.Request) (*http.Request, error) {
+	if req.GetBody == nil {
+		return req, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Body = body
+
+	return req, nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,172 @@
+package gitproviders
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("rateLimitTransport", func() {
+	var (
+		server    *httptest.Server
+		handler   http.HandlerFunc
+		requests  []*http.Request
+		transport *rateLimitTransport
+		slept     []time.Duration
+	)
+
+	BeforeEach(func() {
+		requests = nil
+		slept = nil
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r)
+			handler(w, r)
+		}))
+
+		transport = newRateLimitTransport(nil, Config{
+			Provider: GitProviderGitHub,
+			Hostname: "github.com",
+			Token:    "token",
+			Cache:    NewCache(CacheConfig{Size: 10}),
+		})
+		transport.sleep = func(ctx context.Context, d time.Duration) error {
+			slept = append(slept, d)
+			return nil
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	get := func() *http.Response {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/repos/owner/repo", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		res, err := transport.RoundTrip(req)
+		Expect(err).NotTo(HaveOccurred())
+
+		return res
+	}
+
+	body := func(res *http.Response) string {
+		defer res.Body.Close()
+
+		b, err := io.ReadAll(res.Body)
+		Expect(err).NotTo(HaveOccurred())
+
+		return string(b)
+	}
+
+	It("retries after the time in Retry-After", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			if len(requests) == 1 {
+				w.Header().Set("Retry-After", "2")
+				w.WriteHeader(http.StatusTooManyRequests)
+
+				return
+			}
+
+			_, _ = w.Write([]byte("ok"))
+		}
+
+		res := get()
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		Expect(body(res)).To(Equal("ok"))
+		Expect(slept).To(Equal([]time.Duration{2 * time.Second}))
+	})
+
+	It("waits for the rate limit to reset", func() {
+		reset := time.Now().Add(10 * time.Second).Unix()
+
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			if len(requests) == 1 {
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+				w.WriteHeader(http.StatusForbidden)
+
+				return
+			}
+		}
+
+		Expect(get().StatusCode).To(Equal(http.StatusOK))
+		Expect(slept).To(HaveLen(1))
+		Expect(slept[0]).To(BeNumerically("~", 11*time.Second, 2*time.Second))
+	})
+
+	It("backs off exponentially and gives up", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+
+		Expect(get().StatusCode).To(Equal(http.StatusTooManyRequests))
+		Expect(requests).To(HaveLen(rateLimitMaxRetries + 1))
+		Expect(slept).To(Equal([]time.Duration{time.Second, 2 * time.Second, 4 * time.Second}))
+	})
+
+	It("doesn't wait longer than the longest wait", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+
+		Expect(get().StatusCode).To(Equal(http.StatusTooManyRequests))
+		Expect(requests).To(HaveLen(1))
+		Expect(slept).To(BeEmpty())
+	})
+
+	It("doesn't retry forbidden requests that aren't rate-limited", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-RateLimit-Remaining", "10")
+			w.WriteHeader(http.StatusForbidden)
+		}
+
+		Expect(get().StatusCode).To(Equal(http.StatusForbidden))
+		Expect(requests).To(HaveLen(1))
+	})
+
+	It("revalidates cached responses with their ETag", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = w.Write([]byte("repo"))
+		}
+
+		Expect(body(get())).To(Equal("repo"))
+
+		res := get()
+		Expect(res.StatusCode).To(Equal(http.StatusOK))
+		Expect(res.Header.Get("ETag")).To(Equal(`"v1"`))
+		Expect(body(res)).To(Equal("repo"))
+		Expect(requests[1].Header.Get("If-None-Match")).To(Equal(`"v1"`))
+	})
+
+	It("leaves the ETags of GitLab to its client", func() {
+		transport = newRateLimitTransport(nil, Config{
+			Provider: GitProviderGitLab,
+			Hostname: "gitlab.com",
+			Token:    "token",
+			Cache:    NewCache(CacheConfig{Size: 10}),
+		})
+
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = w.Write([]byte("repo"))
+		}
+
+		Expect(body(get())).To(Equal("repo"))
+		Expect(body(get())).To(Equal("repo"))
+		Expect(requests[1].Header.Get("If-None-Match")).To(BeEmpty())
+	})
+})
@@ -15,6 +15,9 @@ type RepositoryURLProtocol string
 
 const RepositoryURLProtocolHTTPS RepositoryURLProtocol = "https"
 const RepositoryURLProtocolSSH RepositoryURLProtocol = "ssh"
+const RepositoryURLProtocolFile RepositoryURLProtocol = "file"
+
+const giteaDefaultDomain = "gitea.com"
 
 type RepoURL struct {
 	repoName   string
@@ -26,11 +29,24 @@ type RepoURL struct {
 }
 
 func NewRepoURL(uri string) (RepoURL, error) {
-	providerName, err := detectGitProviderFromUrl(uri, ViperGetStringMapString("git-host-types"))
+	return NewRepoURLWithHostTypes(uri, ViperGetStringMapString("git-host-types"))
+}
+
+// NewRepoURLWithHostTypes parses a repository URL, using hostTypes, which
+// maps custom domains to the providers they run, instead of the
+// git-host-types flag.
+func NewRepoURLWithHostTypes(uri string, hostTypes map[string]string) (RepoURL, error) {
+	providerName, err := detectGitProviderFromUrl(uri, hostTypes)
 	if err != nil {
 		return RepoURL{}, fmt.Errorf("could not get provider name from URL %s: %w", uri, err)
 	}
 
+	if providerName == GitProviderBitbucketServer {
+		// Bitbucket Server serves HTTPS clones under /scm/, which isn't part
+		// of the repository's path.
+		uri = strings.Replace(uri, "/scm/", "/", 1)
+	}
+
 	normalized, err := normalizeRepoURLString(uri)
 	if err != nil {
 		return RepoURL{}, fmt.Errorf("could not normalize repo URL %s: %w", uri, err)
@@ -47,8 +63,12 @@ func NewRepoURL(uri string) (RepoURL, error) {
 	}
 
 	protocol := RepositoryURLProtocolSSH
-	if u.Scheme == "https" {
+
+	switch u.Scheme {
+	case "https":
 		protocol = RepositoryURLProtocolHTTPS
+	case "file":
+		protocol = RepositoryURLProtocolFile
 	}
 
 	return RepoURL{
@@ -93,6 +113,11 @@ func getOwnerFromUrl(url url.URL, providerName GitProviderName) (string, error)
 		return "", fmt.Errorf("could not get owner from url %v", url.String())
 	}
 
+	if providerName == GitProviderLocal {
+		// The owner of a local repository is the directory it's in.
+		return parts[len(parts)-2], nil
+	}
+
 	if providerName == GitProviderGitLab {
 		if len(parts) > 3 {
 			return "", fmt.Errorf("a subgroup in a subgroup is not currently supported")
@@ -114,11 +139,22 @@ func detectGitProviderFromUrl(raw string, gitHostTypes map[string]string) (GitPr
 		return "", fmt.Errorf("could not parse git repo url %q: %w", raw, err)
 	}
 
+	if u.Scheme == "file" {
+		return GitProviderLocal, nil
+	}
+
 	// defaults for github and gitlab
 	gitHostTypes[github.DefaultDomain] = string(GitProviderGitHub)
 	gitHostTypes[gitlab.DefaultDomain] = string(GitProviderGitLab)
+	gitHostTypes[giteaDefaultDomain] = string(GitProviderGitea)
 
 	provider := gitHostTypes[u.Host]
+	if provider == "" {
+		// Self-hosted servers often serve SSH on another port, e.g. 7999 for
+		// Bitbucket Server.
+		provider = gitHostTypes[u.Hostname()]
+	}
+
 	if provider == "" {
 		return "", fmt.Errorf("no git providers found for %q", raw)
 	}
@@ -146,6 +182,12 @@ func normalizeRepoURLString(url string) (string, error) {
 	// A trailing slash causes problems when naming secrets.
 	url = strings.TrimSuffix(url, "/")
 
+	if strings.HasPrefix(url, "file://") {
+		// Local repositories are used as is, and their names may not end
+		// with .git.
+		return url, nil
+	}
+
 	if !strings.HasSuffix(url, ".git") {
 		url = url + ".git"
 	}
@@ -15,6 +15,7 @@ var _ = DescribeTable("detectGitProviderFromUrl", func(input string, expected Gi
 },
 	Entry("ssh+github", "ssh://git@github.com/weaveworks/weave-gitops.git", GitProviderGitHub),
 	Entry("ssh+gitlab", "ssh://git@gitlab.com/weaveworks/weave-gitops.git", GitProviderGitLab),
+	Entry("ssh+gitea", "ssh://git@gitea.com/weaveworks/weave-gitops.git", GitProviderGitea),
 )
 
 var _ = Describe("get owner from url", func() {
@@ -134,4 +135,26 @@ var _ = DescribeTable("NewRepoURL", func(input, gitProviderEnv string, expected
 			provider: "gitlab",
 			protocol: RepositoryURLProtocolSSH,
 		}),
+	Entry(
+		"bitbucket server https",
+		"https://bitbucket.acme.org/scm/proj/podinfo-deploy.git",
+		"bitbucket.acme.org=bitbucket-server",
+		expectedRepoURL{
+			s:        "ssh://git@bitbucket.acme.org/proj/podinfo-deploy.git",
+			owner:    "proj",
+			name:     "podinfo-deploy",
+			provider: GitProviderBitbucketServer,
+			protocol: RepositoryURLProtocolSSH,
+		}),
+	Entry(
+		"bitbucket server ssh port",
+		"ssh://git@bitbucket.acme.org:7999/proj/podinfo-deploy.git",
+		"bitbucket.acme.org=bitbucket-server",
+		expectedRepoURL{
+			s:        "ssh://git@bitbucket.acme.org:7999/proj/podinfo-deploy.git",
+			owner:    "proj",
+			name:     "podinfo-deploy",
+			provider: GitProviderBitbucketServer,
+			protocol: RepositoryURLProtocolSSH,
+		}),
 )
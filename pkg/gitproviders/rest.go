@@ -0,0 +1,158 @@
+package gitproviders
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/fluxcd/go-git-providers/gitprovider"
+)
+
+// restClient talks to the REST API of providers that go-git-providers
+// doesn't support.
+type restClient struct {
+	baseURL string
+	// authorization is the value of the Authorization header.
+	authorization string
+	http          *http.Client
+}
+
+func newRESTClient(baseURL, authorization string, transport http.RoundTripper) *restClient {
+	return &restClient{
+		baseURL:       strings.TrimSuffix(baseURL, "/"),
+		authorization: authorization,
+		http:          &http.Client{Timeout: defaultTimeout, Transport: transport},
+	}
+}
+
+// restError is returned for responses with an unexpected status.
+type restError struct {
+	method     string
+	path       string
+	statusCode int
+	message    string
+}
+
+func (e *restError) Error() string {
+	return fmt.Sprintf("%s %s failed with status %d: %s", e.method, e.path, e.statusCode, e.message)
+}
+
+// Is makes 404 responses match gitprovider.ErrNotFound.
+func (e *restError) Is(target error) bool {
+	return target == gitprovider.ErrNotFound && e.statusCode == http.StatusNotFound
+}
+
+// do sends in as JSON, unless it's nil, and decodes the response into out,
+// unless it's nil.
+func (c *restClient) do(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	var (
+		body        io.Reader
+		contentType string
+	)
+
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+
+		body = bytes.NewReader(b)
+		contentType = "application/json"
+	}
+
+	return c.send(ctx, method, path, query, contentType, body, out)
+}
+
+// send sends the body with the given content type, and decodes the
+// response into out as JSON, or as is if out is a *string.
+func (c *restClient) send(ctx context.Context, method, path string, query url.Values, contentType string, body io.Reader, out interface{}) error {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", c.authorization)
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return &restError{method: method, path: path, statusCode: res.StatusCode, message: strings.TrimSpace(string(data))}
+	}
+
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+
+	if s, ok := out.(*string); ok {
+		*s = string(data)
+		return nil
+	}
+
+	return json.Unmarshal(data, out)
+}
+
+// restPullRequest and restCommit are the results of REST APIs, returned as
+// go-git-providers types.
+type restPullRequest struct {
+	info   gitprovider.PullRequestInfo
+	object interface{}
+}
+
+var _ gitprovider.PullRequest = restPullRequest{}
+
+func (p restPullRequest) Get() gitprovider.PullRequestInfo {
+	return p.info
+}
+
+func (p restPullRequest) APIObject() interface{} {
+	return p.object
+}
+
+type restCommit struct {
+	info   gitprovider.CommitInfo
+	object interface{}
+}
+
+var _ gitprovider.Commit = restCommit{}
+
+func (c restCommit) Get() gitprovider.CommitInfo {
+	return c.info
+}
+
+func (c restCommit) APIObject() interface{} {
+	return c.object
+}
+
+// pathEscape escapes each segment of a file path.
+func pathEscape(p string) string {
+	segments := strings.Split(strings.Trim(p, "/"), "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+
+	return strings.Join(segments, "/")
+}
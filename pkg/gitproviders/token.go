@@ -0,0 +1,94 @@
+package gitproviders
+
+import (
+	"fmt"
+)
+
+const missingTokenErr = "the %q environment variable needs to be set to a valid token"
+
+// apiURLVarNames hold the env vars that override the URL of the API of
+// self-hosted providers, when it isn't served over HTTPS on the repository's
+// host.
+var apiURLVarNames = map[GitProviderName]string{
+	GitProviderGitea:           "GITEA_URL",
+	GitProviderBitbucketServer: "BITBUCKET_SERVER_URL",
+}
+
+type tokenClient struct {
+	lookupEnvFunc func(key string) (string, bool)
+	cache         *Cache
+}
+
+// NewTokenClient returns a client whose providers use the token stored in the
+// <git provider>_TOKEN env var. The providers share the cache, so that it
+// outlives them, nothing is cached if it's nil.
+func NewTokenClient(lookupEnvFunc func(key string) (string, bool), cache *Cache) Client {
+	return &tokenClient{
+		lookupEnvFunc: lookupEnvFunc,
+		cache:         cache,
+	}
+}
+
+// GetProvider returns a GitProvider containing the token stored in the <git provider>_TOKEN
+func (c *tokenClient) GetProvider(repoUrl RepoURL, getAccountType AccountTypeGetter) (GitProvider, error) {
+	if repoUrl.Provider() == GitProviderLocal {
+		// Local repositories need no token.
+		return NewLocal(), nil
+	}
+
+	token, err := GetToken(repoUrl, c.lookupEnvFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	config := Config{
+		Provider: repoUrl.Provider(),
+		Token:    token,
+		Hostname: repoUrl.URL().Host,
+		Cache:    c.cache,
+	}
+
+	if apiURLVarName, ok := apiURLVarNames[repoUrl.Provider()]; ok {
+		// The host of self-hosted servers may have the SSH port.
+		config.Hostname = repoUrl.URL().Hostname()
+		config.APIURL, _ = c.lookupEnvFunc(apiURLVarName)
+	}
+
+	provider, err := New(config, repoUrl.Owner(), getAccountType)
+	if err != nil {
+		return nil, fmt.Errorf("error creating git provider client: %w", err)
+	}
+
+	return provider, nil
+}
+
+// TokenVarName returns the env var that holds the token of a provider.
+func TokenVarName(providerName GitProviderName) (string, error) {
+	switch providerName {
+	case GitProviderGitHub:
+		return "GITHUB_TOKEN", nil
+	case GitProviderGitLab:
+		return "GITLAB_TOKEN", nil
+	case GitProviderGitea:
+		return "GITEA_TOKEN", nil
+	case GitProviderBitbucketServer:
+		return "BITBUCKET_SERVER_TOKEN", nil
+	default:
+		return "", fmt.Errorf("unknown git provider: %q", providerName)
+	}
+}
+
+// GetToken returns the token stored in the <git provider>_TOKEN env var
+func GetToken(repoUrl RepoURL, lookupEnvFunc func(key string) (string, bool)) (string, error) {
+	tokenVarName, err := TokenVarName(repoUrl.Provider())
+	if err != nil {
+		return "", fmt.Errorf("could not determine git provider token name: %w", err)
+	}
+
+	token, exists := lookupEnvFunc(tokenVarName)
+	if !exists {
+		return "", fmt.Errorf(missingTokenErr, tokenVarName)
+	}
+
+	return token, nil
+}
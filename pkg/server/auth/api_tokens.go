@@ -0,0 +1,480 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultAPITokensSecretName is the name of the secret holding the
+	// hashed API tokens.
+	DefaultAPITokensSecretName = "gitops-api-tokens"
+	// DefaultAPITokenTTL is how long an API token is valid for if no
+	// expiry is requested.
+	DefaultAPITokenTTL = 30 * 24 * time.Hour
+	// MaxAPITokenTTL is the longest an API token can be valid for.
+	MaxAPITokenTTL = 365 * 24 * time.Hour
+	// apiTokenPrefix starts every API token, so that they can be told
+	// apart from other bearer tokens.
+	apiTokenPrefix = "wgt_"
+	// How long the token store reuses what it read, and so how long a
+	// revoked token may still be accepted by other replicas.
+	apiTokenStoreCacheTTL = 5 * time.Second
+)
+
+var (
+	// ErrAPITokenNotFound is returned when revoking a token that doesn't
+	// exist or belongs to another user.
+	ErrAPITokenNotFound = errors.New("API token not found")
+	// ErrAPITokenInvalid is returned when a token can't be authenticated.
+	ErrAPITokenInvalid = errors.New("invalid or expired API token")
+)
+
+// APITokenInfo describes a personal API token. The token itself is only shown
+// once, when it's created, and only its hash is stored.
+type APITokenInfo struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	UserID    string    `json:"userID"`
+	Groups    []string  `json:"groups"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func (t APITokenInfo) expired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
+
+// storedAPIToken is the form an API token is stored in.
+type storedAPIToken struct {
+	APITokenInfo
+	Hash string `json:"hash"`
+}
+
+// CreateAPITokenRequest is the payload to create an API token.
+type CreateAPITokenRequest struct {
+	Name string `json:"name"`
+	// Groups are the groups the token grants, which must be a subset of
+	// the user's groups. All of the user's groups are used if it's empty.
+	Groups []string `json:"groups"`
+	// ExpiresIn is how long the token is valid for, e.g. "720h".
+	ExpiresIn string `json:"expiresIn"`
+}
+
+// CreateAPITokenResponse returns the token, which can't be retrieved later.
+type CreateAPITokenResponse struct {
+	Token    string       `json:"token"`
+	APIToken APITokenInfo `json:"apiToken"`
+}
+
+// APITokenStore keeps the hashes of API tokens in a secret, one key per token.
+// What it reads is reused for a few seconds to avoid querying the API server
+// on every request.
+type APITokenStore struct {
+	data objectData
+	now  func() time.Time
+
+	mu       sync.Mutex
+	cached   map[string]storedAPIToken
+	cachedAt time.Time
+}
+
+// NewAPITokenStore creates a store using the named secret.
+func NewAPITokenStore(c ctrlclient.Client, namespace, name string) *APITokenStore {
+	return &APITokenStore{
+		data: objectData{
+			client: c,
+			kind:   objectKindSecret,
+			key:    ctrlclient.ObjectKey{Namespace: namespace, Name: name},
+		},
+		now: time.Now,
+	}
+}
+
+// Create issues a new token for the principal. It returns the token, which is
+// not stored, and its description.
+func (s *APITokenStore) Create(ctx context.Context, principal *UserPrincipal, name string, groups []string, ttl time.Duration) (string, *APITokenInfo, error) {
+	if principal == nil || principal.ID == "" {
+		return "", nil, errors.New("API tokens can only be created for a named user")
+	}
+
+	if ttl <= 0 || ttl > MaxAPITokenTTL {
+		return "", nil, fmt.Errorf("the expiry must be between 0 and %s", MaxAPITokenTTL)
+	}
+
+	if len(groups) == 0 {
+		groups = principal.Groups
+	}
+
+	for _, g := range groups {
+		if !contains(principal.Groups, g) {
+			return "", nil, fmt.Errorf("group %q is not one of the user's groups", g)
+		}
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return "", nil, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("could not generate API token: %w", err)
+	}
+
+	encodedSecret := base64.RawURLEncoding.EncodeToString(secret)
+	now := s.now().UTC()
+
+	token := storedAPIToken{
+		APITokenInfo: APITokenInfo{
+			ID:        id,
+			Name:      name,
+			UserID:    principal.ID,
+			Groups:    append([]string{}, groups...),
+			CreatedAt: now,
+			ExpiresAt: now.Add(ttl),
+		},
+		Hash: hashAPITokenSecret(encodedSecret),
+	}
+
+	err = s.update(ctx, func(tokens map[string]storedAPIToken) {
+		// Take the opportunity to drop expired tokens.
+		for k, t := range tokens {
+			if t.expired(now) {
+				delete(tokens, k)
+			}
+		}
+
+		tokens[id] = token
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return apiTokenPrefix + id + "_" + encodedSecret, &token.APITokenInfo, nil
+}
+
+// List returns the tokens of a user that haven't expired, oldest first.
+func (s *APITokenStore) List(ctx context.Context, userID string) ([]APITokenInfo, error) {
+	tokens, err := s.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	res := []APITokenInfo{}
+
+	for _, t := range tokens {
+		if t.UserID == userID && !t.expired(now) {
+			res = append(res, t.APITokenInfo)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].CreatedAt.Before(res[j].CreatedAt)
+	})
+
+	return res, nil
+}
+
+// Revoke deletes one of a user's tokens.
+func (s *APITokenStore) Revoke(ctx context.Context, userID, id string) error {
+	found := false
+
+	err := s.update(ctx, func(tokens map[string]storedAPIToken) {
+		t, ok := tokens[id]
+		if ok && t.UserID == userID {
+			found = true
+
+			delete(tokens, id)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		return ErrAPITokenNotFound
+	}
+
+	return nil
+}
+
+// Principal returns the principal an API token was issued for, limited to
+// the token's groups.
+func (s *APITokenStore) Principal(ctx context.Context, raw string) (*UserPrincipal, error) {
+	id, secret, ok := parseAPIToken(raw)
+	if !ok {
+		return nil, ErrAPITokenInvalid
+	}
+
+	tokens, err := s.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	t, ok := tokens[id]
+	if !ok || t.expired(s.now()) {
+		return nil, ErrAPITokenInvalid
+	}
+
+	if subtle.ConstantTimeCompare([]byte(t.Hash), []byte(hashAPITokenSecret(secret))) != 1 {
+		return nil, ErrAPITokenInvalid
+	}
+
+	return &UserPrincipal{ID: t.UserID, Groups: append([]string{}, t.Groups...)}, nil
+}
+
+func (s *APITokenStore) read(ctx context.Context) (map[string]storedAPIToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cached != nil && s.now().Sub(s.cachedAt) < apiTokenStoreCacheTTL {
+		return s.cached, nil
+	}
+
+	data, err := s.data.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cached, s.cachedAt = tokens, s.now()
+
+	return tokens, nil
+}
+
+// update applies fn to the stored tokens.
+func (s *APITokenStore) update(ctx context.Context, fn func(map[string]storedAPIToken)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Whatever happens, read the secret again next time.
+	s.cached = nil
+
+	return s.data.update(ctx, func(data map[string][]byte) error {
+		tokens, err := s.decode(data)
+		if err != nil {
+			return err
+		}
+
+		fn(tokens)
+
+		for id := range data {
+			delete(data, id)
+		}
+
+		for id, t := range tokens {
+			v, err := json.Marshal(t)
+			if err != nil {
+				return err
+			}
+
+			data[id] = v
+		}
+
+		return nil
+	})
+}
+
+func (s *APITokenStore) decode(data map[string][]byte) (map[string]storedAPIToken, error) {
+	tokens := map[string]storedAPIToken{}
+
+	for id, v := range data {
+		var t storedAPIToken
+		if err := json.Unmarshal(v, &t); err != nil {
+			return nil, fmt.Errorf("invalid API token %q in secret %s: %w", id, s.data.key, err)
+		}
+
+		tokens[id] = t
+	}
+
+	return tokens, nil
+}
+
+// parseAPIToken splits a token of the form wgt_<id>_<secret>.
+func parseAPIToken(raw string) (string, string, bool) {
+	if !strings.HasPrefix(raw, apiTokenPrefix) {
+		return "", "", false
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(raw, apiTokenPrefix), "_", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+
+	return parts[0], parts[1], true
+}
+
+// API tokens are long random strings, so a fast hash is enough to protect
+// them, unlike passwords.
+func hashAPITokenSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// isAPIToken reports whether the request is authenticated with an API token.
+func isAPIToken(r *http.Request) bool {
+	return strings.HasPrefix(extractToken(r.Header.Get(AuthorizationTokenHeaderName)), apiTokenPrefix)
+}
+
+// APITokenPrincipalGetter authenticates requests with an API token in the
+// Authorization header. Other bearer tokens are ignored.
+type APITokenPrincipalGetter struct {
+	log   logr.Logger
+	store *APITokenStore
+}
+
+// NewAPITokenPrincipalGetter creates a PrincipalGetter that looks API tokens
+// up in the store.
+func NewAPITokenPrincipalGetter(log logr.Logger, store *APITokenStore) PrincipalGetter {
+	return &APITokenPrincipalGetter{
+		log:   log,
+		store: store,
+	}
+}
+
+func (pg *APITokenPrincipalGetter) Principal(r *http.Request) (*UserPrincipal, error) {
+	token := extractToken(r.Header.Get(AuthorizationTokenHeaderName))
+	if !strings.HasPrefix(token, apiTokenPrefix) {
+		return nil, nil
+	}
+
+	return pg.store.Principal(r.Context(), token)
+}
+
+// APITokens handles listing (GET) and creating (POST) the API tokens of the
+// authenticated user.
+func (s *AuthServer) APITokens() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		principal := Principal(r.Context())
+		if principal == nil || s.apiTokens == nil {
+			JSONError(s.Log, rw, "API tokens are not enabled", http.StatusNotFound)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			tokens, err := s.apiTokens.List(r.Context(), principal.ID)
+			if err != nil {
+				s.Log.Error(err, "Failed to list API tokens")
+				JSONError(s.Log, rw, "Failed to list API tokens", http.StatusInternalServerError)
+
+				return
+			}
+
+			writeJSON(s.Log, rw, http.StatusOK, struct {
+				Tokens []APITokenInfo `json:"tokens"`
+			}{Tokens: tokens})
+		case http.MethodPost:
+			// A token can't be used to mint longer-lived tokens.
+			if isAPIToken(r) {
+				JSONError(s.Log, rw, "API tokens can't be created with an API token", http.StatusForbidden)
+				return
+			}
+
+			var req CreateAPITokenRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				JSONError(s.Log, rw, "Failed to read request body.", http.StatusBadRequest)
+				return
+			}
+
+			ttl := DefaultAPITokenTTL
+
+			if req.ExpiresIn != "" {
+				d, err := time.ParseDuration(req.ExpiresIn)
+				if err != nil {
+					JSONError(s.Log, rw, fmt.Sprintf("invalid expiresIn: %v", err), http.StatusBadRequest)
+					return
+				}
+
+				ttl = d
+			}
+
+			token, info, err := s.apiTokens.Create(r.Context(), principal, req.Name, req.Groups, ttl)
+			if err != nil {
+				JSONError(s.Log, rw, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			s.Log.Info("API token created", "user", principal.ID, "id", info.ID, "name", info.Name, "groups", info.Groups, "expiresAt", info.ExpiresAt)
+
+			writeJSON(s.Log, rw, http.StatusCreated, CreateAPITokenResponse{Token: token, APIToken: *info})
+		default:
+			rw.Header().Add("Allow", "GET, POST")
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// RevokeAPIToken handles revoking (DELETE) one of the API tokens of the
+// authenticated user.
+func (s *AuthServer) RevokeAPIToken(id string) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			rw.Header().Add("Allow", "DELETE")
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		principal := Principal(r.Context())
+		if principal == nil || s.apiTokens == nil {
+			JSONError(s.Log, rw, "API tokens are not enabled", http.StatusNotFound)
+			return
+		}
+
+		if err := s.apiTokens.Revoke(r.Context(), principal.ID, id); err != nil {
+			if errors.Is(err, ErrAPITokenNotFound) {
+				JSONError(s.Log, rw, err.Error(), http.StatusNotFound)
+				return
+			}
+
+			s.Log.Error(err, "Failed to revoke API token")
+			JSONError(s.Log, rw, "Failed to revoke API token", http.StatusInternalServerError)
+
+			return
+		}
+
+		s.Log.Info("API token revoked", "user", principal.ID, "id", id)
+
+		rw.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func writeJSON(log logr.Logger, rw http.ResponseWriter, status int, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+	rw.WriteHeader(status)
+
+	if err := json.NewEncoder(rw).Encode(v); err != nil {
+		log.Error(err, "Failed to write response")
+	}
+}
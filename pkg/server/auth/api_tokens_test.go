@@ -0,0 +1,191 @@
+package auth_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/weaveworks/weave-gitops/pkg/server/auth"
+	corev1 "k8s.io/api/core/v1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlclientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestAPITokenStore(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.Background()
+
+	fakeKubernetesClient := ctrlclientfake.NewClientBuilder().Build()
+	store := auth.NewAPITokenStore(fakeKubernetesClient, testNamespace, auth.DefaultAPITokensSecretName)
+
+	alice := &auth.UserPrincipal{ID: "alice", Groups: []string{"team-a", "viewers"}}
+
+	_, _, err := store.Create(ctx, alice, "ci", []string{"admins"}, time.Hour)
+	g.Expect(err).To(MatchError(ContainSubstring(`group "admins" is not one of the user's groups`)))
+
+	_, _, err = store.Create(ctx, alice, "ci", nil, auth.MaxAPITokenTTL+time.Hour)
+	g.Expect(err).To(HaveOccurred())
+
+	token, info, err := store.Create(ctx, alice, "ci", []string{"viewers"}, time.Hour)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(token).To(HavePrefix("wgt_" + info.ID + "_"))
+	g.Expect(info.Groups).To(Equal([]string{"viewers"}))
+
+	_, all, err := store.Create(ctx, alice, "laptop", nil, time.Hour)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(all.Groups).To(Equal([]string{"team-a", "viewers"}))
+
+	// Only the hash is stored.
+	var secret corev1.Secret
+	g.Expect(fakeKubernetesClient.Get(ctx, ctrlclient.ObjectKey{Namespace: testNamespace, Name: auth.DefaultAPITokensSecretName}, &secret)).To(Succeed())
+	g.Expect(secret.Data).To(HaveLen(2))
+	g.Expect(string(secret.Data[info.ID])).NotTo(ContainSubstring(strings.Split(token, "_")[2]))
+
+	principal, err := store.Principal(ctx, token)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(principal).To(Equal(&auth.UserPrincipal{ID: "alice", Groups: []string{"viewers"}}))
+
+	_, err = store.Principal(ctx, "wgt_"+info.ID+"_not-the-secret")
+	g.Expect(err).To(MatchError(auth.ErrAPITokenInvalid))
+
+	tokens, err := store.List(ctx, "alice")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(tokens).To(HaveLen(2))
+	g.Expect(tokens[0].Name).To(Equal("ci"))
+	g.Expect(tokens[1].Name).To(Equal("laptop"))
+
+	tokens, err = store.List(ctx, "bob")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(tokens).To(BeEmpty())
+
+	g.Expect(store.Revoke(ctx, "bob", info.ID)).To(MatchError(auth.ErrAPITokenNotFound))
+	g.Expect(store.Revoke(ctx, "alice", info.ID)).To(Succeed())
+
+	_, err = store.Principal(ctx, token)
+	g.Expect(err).To(MatchError(auth.ErrAPITokenInvalid))
+}
+
+func TestAPITokenStoreExpiry(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.Background()
+
+	store := auth.NewAPITokenStore(ctrlclientfake.NewClientBuilder().Build(), testNamespace, auth.DefaultAPITokensSecretName)
+
+	token, _, err := store.Create(ctx, &auth.UserPrincipal{ID: "alice"}, "short", nil, time.Millisecond)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	time.Sleep(10 * time.Millisecond)
+
+	_, err = store.Principal(ctx, token)
+	g.Expect(err).To(MatchError(auth.ErrAPITokenInvalid))
+
+	tokens, err := store.List(ctx, "alice")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(tokens).To(BeEmpty())
+}
+
+// countingClient counts the reads that reach the API server.
+type countingClient struct {
+	ctrlclient.Client
+	gets int
+}
+
+func (c *countingClient) Get(ctx context.Context, key ctrlclient.ObjectKey, obj ctrlclient.Object) error {
+	c.gets++
+	return c.Client.Get(ctx, key, obj)
+}
+
+func TestAPITokenStoreCachesTokens(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.Background()
+
+	c := &countingClient{Client: ctrlclientfake.NewClientBuilder().Build()}
+	store := auth.NewAPITokenStore(c, testNamespace, auth.DefaultAPITokensSecretName)
+
+	token, info, err := store.Create(ctx, &auth.UserPrincipal{ID: "alice"}, "ci", nil, time.Hour)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	gets := c.gets
+
+	for i := 0; i < 3; i++ {
+		_, err = store.Principal(ctx, token)
+		g.Expect(err).NotTo(HaveOccurred())
+	}
+
+	g.Expect(c.gets).To(Equal(gets + 1))
+
+	// Revoking drops what was read, so the token is rejected straight away.
+	g.Expect(store.Revoke(ctx, "alice", info.ID)).To(Succeed())
+
+	_, err = store.Principal(ctx, token)
+	g.Expect(err).To(MatchError(auth.ErrAPITokenInvalid))
+}
+
+func TestWithAPIAuthAcceptsAPITokens(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tokenSignerVerifier, err := auth.NewHMACTokenSignerVerifier(5 * time.Minute)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	s, _ := makeAuthServer(t, nil, tokenSignerVerifier, []auth.AuthMethod{auth.APIToken, auth.OIDC})
+
+	var principal *auth.UserPrincipal
+
+	handler := auth.WithAPIAuth(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		principal = auth.Principal(r.Context())
+	}), s, nil)
+
+	// Create a token as a signed in user.
+	body, err := json.Marshal(auth.CreateAPITokenRequest{Name: "ci", Groups: []string{"team-a"}, ExpiresIn: "24h"})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	req := httptest.NewRequest(http.MethodPost, "https://example.com/v1/api_tokens", bytes.NewReader(body))
+	req = req.WithContext(auth.WithPrincipal(req.Context(), &auth.UserPrincipal{ID: "alice", Groups: []string{"team-a", "team-b"}}))
+
+	w := httptest.NewRecorder()
+	s.APITokens().ServeHTTP(w, req)
+	g.Expect(w).To(HaveHTTPStatus(http.StatusCreated))
+
+	var created auth.CreateAPITokenResponse
+	g.Expect(json.NewDecoder(w.Body).Decode(&created)).To(Succeed())
+	g.Expect(created.APIToken.ExpiresAt.Sub(created.APIToken.CreatedAt)).To(Equal(24 * time.Hour))
+
+	// The token authenticates as the user, with the token's groups.
+	req = httptest.NewRequest(http.MethodGet, "https://example.com/v1/objects", nil)
+	req.Header.Set("Authorization", "Bearer "+created.Token)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	g.Expect(w).To(HaveHTTPStatus(http.StatusOK))
+	g.Expect(principal).To(Equal(&auth.UserPrincipal{ID: "alice", Groups: []string{"team-a"}}))
+
+	// A token can't create more tokens.
+	req = httptest.NewRequest(http.MethodPost, "https://example.com/v1/api_tokens", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+created.Token)
+	req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+	w = httptest.NewRecorder()
+	s.APITokens().ServeHTTP(w, req)
+	g.Expect(w).To(HaveHTTPStatus(http.StatusForbidden))
+
+	// Once revoked the token is rejected.
+	req = httptest.NewRequest(http.MethodDelete, "https://example.com/v1/api_tokens/"+created.APIToken.ID, nil)
+	req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+
+	w = httptest.NewRecorder()
+	s.RevokeAPIToken(created.APIToken.ID).ServeHTTP(w, req)
+	g.Expect(w).To(HaveHTTPStatus(http.StatusNoContent))
+
+	req = httptest.NewRequest(http.MethodGet, "https://example.com/v1/objects", nil)
+	req.Header.Set("Authorization", "Bearer "+created.Token)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	g.Expect(w).To(HaveHTTPStatus(http.StatusUnauthorized))
+}
@@ -53,6 +53,21 @@ func RegisterAuthServer(mux *http.ServeMux, prefix string, srv *AuthServer, logi
 
 	mux.Handle(prefix, srv.OAuth2Flow())
 	mux.Handle(prefix+"/callback", srv.Callback())
+	mux.Handle(prefix+"/providers", srv.Providers(prefix))
+
+	for _, p := range srv.providers {
+		if p.name == "" {
+			continue
+		}
+
+		mux.Handle(prefix+"/"+p.name, srv.ProviderOAuth2Flow(p.name))
+		mux.Handle(prefix+"/"+p.name+"/callback", srv.ProviderCallback(p.name))
+	}
+
+	for _, l := range srv.gitLogins {
+		mux.Handle(prefix+"/"+l.name(), srv.GitLoginFlow(l.name()))
+		mux.Handle(prefix+"/"+l.name()+"/callback", srv.GitLoginCallback(l.name()))
+	}
 	mux.Handle(prefix+"/sign_in", middleware.Handle(srv.SignIn()))
 	mux.Handle(prefix+"/userinfo", srv.UserInfo())
 	mux.Handle(prefix+"/logout", srv.Logout())
@@ -149,8 +164,13 @@ func WithAPIAuth(next http.Handler, srv *AuthServer, publicRoutes []string) http
 	multi := MultiAuthPrincipal{Log: srv.Log, Getters: []PrincipalGetter{}}
 
 	// FIXME: currently the order must be OIDC last, or it'll "shadow" the other
-	// methods so they don't work.
-	methods := []AuthMethod{UserAccount, TokenPassthrough, OIDC}
+	// methods so they don't work. API tokens go first so that they are not
+	// sent to the API server by the token passthrough.
+	methods := []AuthMethod{APIToken, UserAccount, GitHub, GitLab, TokenPassthrough, TokenReview, ClientCertificate, OIDC}
+
+	// Cluster user accounts and git provider logins share the signed cookie.
+	signedCookie := false
+
 	for _, method := range methods {
 		enabled, ok := srv.authMethods[method]
 		if !ok {
@@ -165,26 +185,67 @@ func WithAPIAuth(next http.Handler, srv *AuthServer, publicRoutes []string) http
 		switch method {
 		case OIDC:
 			if srv.oidcEnabled() {
-				// OIDC tokens may be passed by token or cookie
-				multi.Getters = append(multi.Getters, NewJWTAuthorizationHeaderPrincipalGetter(srv.Log, srv.verifier()))
-
 				if srv.oidcPassthroughEnabled() {
 					srv.Log.V(logger.LogLevelDebug).Info("JWT Token Passthrough Enabled")
-					multi.Getters = append(multi.Getters, NewJWTPassthroughCookiePrincipalGetter(srv.Log, srv.verifier(), IDTokenCookieName))
+				}
+
+				// Each provider has its own verifier and claim mapping,
+				// they are tried in the order they were configured.
+				var getters []PrincipalGetter
+
+				for _, p := range srv.providers {
+					claims := p.config.ClaimsConfig()
+
+					// OIDC tokens may be passed by token or cookie. The
+					// cookies of the UI are only accepted while its session
+					// is valid, the tokens of other clients have none.
+					getters = append(getters, NewJWTAuthorizationHeaderPrincipalGetter(srv.Log, p.verifier(), claims))
+
+					if srv.oidcPassthroughEnabled() {
+						getters = append(getters, srv.withSession(NewJWTPassthroughCookiePrincipalGetter(srv.Log, p.verifier(), IDTokenCookieName, claims)))
+					} else {
+						getters = append(getters, srv.withSession(NewJWTCookiePrincipalGetter(srv.Log, p.verifier(), IDTokenCookieName, claims)))
+					}
+				}
+
+				// A token that one provider rejects may be valid for the
+				// next one, so the errors of each are only returned once
+				// all of them have been tried.
+				if len(srv.providers) > 1 {
+					multi.Getters = append(multi.Getters, MultiOIDCPrincipal{Log: srv.Log, Getters: getters})
 				} else {
-					multi.Getters = append(multi.Getters, NewJWTCookiePrincipalGetter(srv.Log, srv.verifier(), IDTokenCookieName))
+					multi.Getters = append(multi.Getters, getters...)
 				}
 			}
 
-		case UserAccount:
-			if featureflags.Get(FeatureFlagClusterUser) == FeatureFlagSet {
+		case UserAccount, GitHub, GitLab:
+			enabled := featureflags.Get(FeatureFlagClusterUser) == FeatureFlagSet
+			if method != UserAccount {
+				enabled = srv.gitLogin(method.String()) != nil
+			}
+
+			if enabled && !signedCookie {
 				adminAuth := NewJWTAdminCookiePrincipalGetter(srv.Log, srv.tokenSignerVerifier, IDTokenCookieName)
-				multi.Getters = append(multi.Getters, adminAuth)
+				multi.Getters = append(multi.Getters, srv.withSession(adminAuth))
+				signedCookie = true
 			}
 
 		case TokenPassthrough:
 			tokenAuth := NewBearerTokenPassthroughPrincipalGetter(srv.Log, nil, AuthorizationTokenHeaderName, srv.kubernetesClient)
 			multi.Getters = append(multi.Getters, tokenAuth)
+
+		case APIToken:
+			if srv.apiTokens != nil {
+				multi.Getters = append(multi.Getters, NewAPITokenPrincipalGetter(srv.Log, srv.apiTokens))
+			}
+
+		case TokenReview:
+			multi.Getters = append(multi.Getters, srv.tokenReviewer)
+
+		case ClientCertificate:
+			if srv.clientCAs != nil {
+				multi.Getters = append(multi.Getters, NewClientCertPrincipalGetter(srv.Log, srv.clientCAs))
+			}
 		}
 	}
 
@@ -195,6 +256,27 @@ func WithAPIAuth(next http.Handler, srv *AuthServer, publicRoutes []string) http
 		}
 
 		principal, err := multi.Principal(r)
+
+		// The ID token may have expired, try to renew it before asking
+		// the user to log in again.
+		if (principal == nil || err != nil) && srv.oidcEnabled() && hasCookie(r, RefreshTokenCookieName) {
+			refreshed, refreshErr := srv.refreshSession(rw, r)
+			if refreshErr != nil {
+				srv.Log.V(logger.LogLevelWarn).Info("Failed to refresh OIDC session", "err", refreshErr)
+
+				// Clear the stale session so the UI sends the user back to login.
+				http.SetCookie(rw, srv.clearCookie(IDTokenCookieName))
+				http.SetCookie(rw, srv.clearCookie(AccessTokenCookieName))
+				http.SetCookie(rw, srv.clearCookie(RefreshTokenCookieName))
+				JSONError(srv.Log, rw, "Session expired, please log in again", http.StatusUnauthorized)
+
+				return
+			}
+
+			r = refreshed
+			principal, err = multi.Principal(r)
+		}
+
 		if err != nil {
 			srv.Log.Error(err, "failed to get principal")
 		}
@@ -208,6 +290,16 @@ func WithAPIAuth(next http.Handler, srv *AuthServer, publicRoutes []string) http
 	})
 }
 
+// withSession makes a getter for session cookies check the session store,
+// if there is one.
+func (s *AuthServer) withSession(pg PrincipalGetter) PrincipalGetter {
+	if s.sessionStore == nil {
+		return pg
+	}
+
+	return NewSessionPrincipalGetter(s.Log, s.sessionStore, pg)
+}
+
 func generateNonce() (string, error) {
 	b := make([]byte, 32)
 
@@ -228,3 +320,9 @@ func IsPublicRoute(u *url.URL, publicRoutes []string) bool {
 
 	return false
 }
+
+func hasCookie(r *http.Request, name string) bool {
+	_, err := r.Cookie(name)
+
+	return err == nil
+}
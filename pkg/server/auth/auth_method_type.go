@@ -17,6 +17,17 @@ const (
 	OIDC
 	// EE CLI tokens
 	TokenPassthrough
+	// Personal API tokens issued by the server
+	APIToken
+	// Kubernetes tokens, e.g. of service accounts, validated with the
+	// TokenReview API
+	TokenReview
+	// TLS client certificates signed by a configured CA
+	ClientCertificate
+	// Logging in with a GitHub OAuth app
+	GitHub
+	// Logging in with a GitLab OAuth application
+	GitLab
 )
 
 // This is a function to mimic a const slice
@@ -56,6 +67,16 @@ func (am *AuthMethod) String() string {
 		return "oidc"
 	case TokenPassthrough:
 		return "token-passthrough"
+	case APIToken:
+		return "api-token"
+	case TokenReview:
+		return "token-review"
+	case ClientCertificate:
+		return "client-certificate"
+	case GitHub:
+		return "github"
+	case GitLab:
+		return "gitlab"
 	default:
 		return fmt.Sprintf("AuthMethod(%d)", am)
 	}
@@ -70,6 +91,16 @@ func (am *AuthMethod) UnmarshalText(text []byte) error {
 		*am = OIDC
 	case "token-passthrough":
 		*am = TokenPassthrough
+	case "api-token":
+		*am = APIToken
+	case "token-review":
+		*am = TokenReview
+	case "client-certificate":
+		*am = ClientCertificate
+	case "github":
+		*am = GitHub
+	case "gitlab":
+		*am = GitLab
 	default:
 		return fmt.Errorf("Unknown auth method '%q'", text)
 	}
@@ -9,7 +9,7 @@ import (
 )
 
 func TestInvariant(t *testing.T) {
-	authMethods := []auth.AuthMethod{auth.UserAccount, auth.OIDC, auth.TokenPassthrough}
+	authMethods := []auth.AuthMethod{auth.UserAccount, auth.OIDC, auth.TokenPassthrough, auth.APIToken, auth.TokenReview, auth.ClientCertificate, auth.GitHub, auth.GitLab}
 
 	for _, method := range authMethods {
 		authstring := method.String()
@@ -50,8 +50,8 @@ func TestParseAuthMethodArray(t *testing.T) {
 		},
 		{
 			name:        "Array of all",
-			methodArray: []string{"oidc", "user-account", "token-passthrough"},
-			expectedMap: map[auth.AuthMethod]bool{auth.OIDC: true, auth.UserAccount: true, auth.TokenPassthrough: true},
+			methodArray: []string{"oidc", "user-account", "token-passthrough", "api-token", "token-review", "client-certificate", "github", "gitlab"},
+			expectedMap: map[auth.AuthMethod]bool{auth.OIDC: true, auth.UserAccount: true, auth.TokenPassthrough: true, auth.APIToken: true, auth.TokenReview: true, auth.ClientCertificate: true, auth.GitHub: true, auth.GitLab: true},
 			expectedErr: false,
 		},
 		{
@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// DefaultUsernameClaim is the claim used as the user's ID if none is configured.
+	DefaultUsernameClaim = "email"
+	// DefaultGroupsClaim is the claim used as the user's groups if none is configured.
+	DefaultGroupsClaim = "groups"
+)
+
+// ClaimsConfig configures how the claims in an OIDC token are mapped to a
+// UserPrincipal. The zero value uses the "email" and "groups" claims without
+// prefixes.
+type ClaimsConfig struct {
+	// UsernameClaim is the claim holding the user's ID. Nested claims
+	// can be referenced with a dot-separated path, e.g. "user.name".
+	UsernameClaim string
+	// GroupsClaim is the claim holding the user's groups. Nested claims
+	// can be referenced with a dot-separated path, e.g. "realm_access.roles".
+	GroupsClaim string
+	// UsernamePrefix is prepended to the user's ID, e.g. "oidc:".
+	UsernamePrefix string
+	// GroupsPrefix is prepended to each of the user's groups, e.g. "oidc:".
+	GroupsPrefix string
+	// RequiredClaims are claims that must be present in the token with
+	// the given value.
+	RequiredClaims map[string]string
+}
+
+// ClaimsConfig returns the claim mapping configured for this issuer.
+func (c OIDCConfig) ClaimsConfig() *ClaimsConfig {
+	return &ClaimsConfig{
+		UsernameClaim:  c.UsernameClaim,
+		GroupsClaim:    c.GroupsClaim,
+		UsernamePrefix: c.UsernamePrefix,
+		GroupsPrefix:   c.GroupsPrefix,
+		RequiredClaims: c.RequiredClaims,
+	}
+}
+
+// PrincipalFromClaims checks the required claims and builds a UserPrincipal
+// from the decoded claims of a token.
+func (cc *ClaimsConfig) PrincipalFromClaims(claims map[string]interface{}) (*UserPrincipal, error) {
+	if cc == nil {
+		cc = &ClaimsConfig{}
+	}
+
+	if err := cc.checkRequiredClaims(claims); err != nil {
+		return nil, err
+	}
+
+	usernameClaim := cc.UsernameClaim
+	if usernameClaim == "" {
+		usernameClaim = DefaultUsernameClaim
+	}
+
+	groupsClaim := cc.GroupsClaim
+	if groupsClaim == "" {
+		groupsClaim = DefaultGroupsClaim
+	}
+
+	var id string
+
+	if v, ok := lookupClaim(claims, usernameClaim); ok {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("claim %q is not a string", usernameClaim)
+		}
+
+		id = s
+	}
+
+	if id != "" {
+		id = cc.UsernamePrefix + id
+	}
+
+	groups := []string{}
+
+	if v, ok := lookupClaim(claims, groupsClaim); ok {
+		switch g := v.(type) {
+		case string:
+			groups = append(groups, cc.GroupsPrefix+g)
+		case []interface{}:
+			for _, item := range g {
+				s, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("claim %q contains a non-string value", groupsClaim)
+				}
+
+				groups = append(groups, cc.GroupsPrefix+s)
+			}
+		default:
+			return nil, fmt.Errorf("claim %q is not a string or a list of strings", groupsClaim)
+		}
+	}
+
+	return &UserPrincipal{ID: id, Groups: groups}, nil
+}
+
+func (cc *ClaimsConfig) checkRequiredClaims(claims map[string]interface{}) error {
+	names := make([]string, 0, len(cc.RequiredClaims))
+	for name := range cc.RequiredClaims {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		v, ok := lookupClaim(claims, name)
+		if !ok {
+			return fmt.Errorf("required claim %q is missing", name)
+		}
+
+		if s, ok := v.(string); !ok || s != cc.RequiredClaims[name] {
+			return fmt.Errorf("required claim %q does not have the expected value", name)
+		}
+	}
+
+	return nil
+}
+
+// lookupClaim finds a claim by name. A claim name that exists as-is at the
+// top level is preferred, as claim names are often URLs containing dots.
+// Otherwise the name is treated as a dot-separated path into nested objects.
+func lookupClaim(claims map[string]interface{}, path string) (interface{}, bool) {
+	if v, ok := claims[path]; ok {
+		return v, true
+	}
+
+	var current interface{} = claims
+
+	for _, part := range strings.Split(path, ".") {
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		current, ok = obj[part]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+// ParseRequiredClaims parses a comma-separated list of claim=value pairs,
+// as used in the OIDC secret.
+func ParseRequiredClaims(s string) (map[string]string, error) {
+	res := map[string]string{}
+
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid required claim %q, expected claim=value", pair)
+		}
+
+		res[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	return res, nil
+}
@@ -0,0 +1,104 @@
+package auth_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/weaveworks/weave-gitops/pkg/server/auth"
+)
+
+func TestPrincipalFromClaims(t *testing.T) {
+	claims := map[string]interface{}{
+		"email":              "jane@example.com",
+		"preferred_username": "jane",
+		"groups":             []interface{}{"dev", "ops"},
+		"realm_access": map[string]interface{}{
+			"roles": []interface{}{"admin"},
+		},
+		"https://example.com/team": "platform",
+		"hd":                       "example.com",
+	}
+
+	tests := []struct {
+		name   string
+		config *auth.ClaimsConfig
+		want   *auth.UserPrincipal
+		err    string
+	}{
+		{
+			name:   "defaults",
+			config: nil,
+			want:   &auth.UserPrincipal{ID: "jane@example.com", Groups: []string{"dev", "ops"}},
+		},
+		{
+			name: "custom claims and prefixes",
+			config: &auth.ClaimsConfig{
+				UsernameClaim:  "preferred_username",
+				GroupsClaim:    "realm_access.roles",
+				UsernamePrefix: "oidc:",
+				GroupsPrefix:   "oidc:",
+			},
+			want: &auth.UserPrincipal{ID: "oidc:jane", Groups: []string{"oidc:admin"}},
+		},
+		{
+			name:   "claim names containing dots",
+			config: &auth.ClaimsConfig{GroupsClaim: "https://example.com/team"},
+			want:   &auth.UserPrincipal{ID: "jane@example.com", Groups: []string{"platform"}},
+		},
+		{
+			name:   "missing groups claim",
+			config: &auth.ClaimsConfig{GroupsClaim: "realm_access.missing"},
+			want:   &auth.UserPrincipal{ID: "jane@example.com", Groups: []string{}},
+		},
+		{
+			name:   "required claims present",
+			config: &auth.ClaimsConfig{RequiredClaims: map[string]string{"hd": "example.com"}},
+			want:   &auth.UserPrincipal{ID: "jane@example.com", Groups: []string{"dev", "ops"}},
+		},
+		{
+			name:   "required claim with wrong value",
+			config: &auth.ClaimsConfig{RequiredClaims: map[string]string{"hd": "example.org"}},
+			err:    `required claim "hd" does not have the expected value`,
+		},
+		{
+			name:   "required claim missing",
+			config: &auth.ClaimsConfig{RequiredClaims: map[string]string{"tenant": "acme"}},
+			err:    `required claim "tenant" is missing`,
+		},
+		{
+			name:   "username claim is not a string",
+			config: &auth.ClaimsConfig{UsernameClaim: "groups"},
+			err:    `claim "groups" is not a string`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			principal, err := tt.config.PrincipalFromClaims(claims)
+			if tt.err != "" {
+				g.Expect(err).To(MatchError(tt.err))
+				return
+			}
+
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(principal).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestParseRequiredClaims(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	claims, err := auth.ParseRequiredClaims("hd=example.com, tenant = acme,")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(claims).To(Equal(map[string]string{"hd": "example.com", "tenant": "acme"}))
+
+	claims, err = auth.ParseRequiredClaims("")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(claims).To(BeEmpty())
+
+	_, err = auth.ParseRequiredClaims("hd")
+	g.Expect(err).To(HaveOccurred())
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/go-logr/logr"
+)
+
+// LoadClientCAs reads a PEM bundle of the CAs that client certificates must
+// be signed by.
+func LoadClientCAs(file string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if err := AppendClientCAs(pool, file); err != nil {
+		return nil, err
+	}
+
+	return pool, nil
+}
+
+// AppendClientCAs adds the CAs in a PEM bundle to an existing pool.
+func AppendClientCAs(pool *x509.CertPool, file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("could not read client CA file: %w", err)
+	}
+
+	if !pool.AppendCertsFromPEM(data) {
+		return fmt.Errorf("no certificates found in client CA file %s", file)
+	}
+
+	return nil
+}
+
+// ClientCertPrincipalGetter authenticates requests with the TLS client
+// certificate they were made with. Like the Kubernetes API server, the
+// common name of the subject is the user and its organizations are the
+// groups.
+type ClientCertPrincipalGetter struct {
+	log   logr.Logger
+	roots *x509.CertPool
+}
+
+// NewClientCertPrincipalGetter creates a PrincipalGetter that accepts client
+// certificates signed by one of the roots.
+func NewClientCertPrincipalGetter(log logr.Logger, roots *x509.CertPool) PrincipalGetter {
+	return &ClientCertPrincipalGetter{
+		log:   log,
+		roots: roots,
+	}
+}
+
+func (pg *ClientCertPrincipalGetter) Principal(r *http.Request) (*UserPrincipal, error) {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil, nil
+	}
+
+	// The certificate is verified again, the server may accept client
+	// certificates from other CAs.
+	cert := r.TLS.PeerCertificates[0]
+	intermediates := x509.NewCertPool()
+
+	for _, c := range r.TLS.PeerCertificates[1:] {
+		intermediates.AddCert(c)
+	}
+
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:         pg.roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to verify client certificate: %w", err)
+	}
+
+	if cert.Subject.CommonName == "" {
+		return nil, errors.New("client certificate has no common name")
+	}
+
+	groups := cert.Subject.Organization
+	if groups == nil {
+		groups = []string{}
+	}
+
+	return &UserPrincipal{ID: cert.Subject.CommonName, Groups: groups}, nil
+}
@@ -0,0 +1,139 @@
+package auth_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	"github.com/weaveworks/weave-gitops/pkg/server/auth"
+)
+
+func TestClientCertPrincipalGetter(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	ca, caKey := makeCA(t, "test-ca")
+	otherCA, otherCAKey := makeCA(t, "other-ca")
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.crt")
+	g.Expect(os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}), 0600)).To(Succeed())
+
+	roots, err := auth.LoadClientCAs(caFile)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	getter := auth.NewClientCertPrincipalGetter(logr.Discard(), roots)
+
+	request := func(certs ...*x509.Certificate) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "https://example.com/v1/objects", nil)
+		if certs != nil {
+			req.TLS = &tls.ConnectionState{PeerCertificates: certs}
+		}
+
+		return req
+	}
+
+	principal, err := getter.Principal(request(makeClientCert(t, ca, caKey, "alice", []string{"team-a", "team-b"}, x509.ExtKeyUsageClientAuth)))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(principal).To(Equal(&auth.UserPrincipal{ID: "alice", Groups: []string{"team-a", "team-b"}}))
+
+	principal, err = getter.Principal(request(makeClientCert(t, ca, caKey, "ci", nil, x509.ExtKeyUsageClientAuth)))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(principal).To(Equal(&auth.UserPrincipal{ID: "ci", Groups: []string{}}))
+
+	principal, err = getter.Principal(request())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(principal).To(BeNil())
+
+	_, err = getter.Principal(request(makeClientCert(t, otherCA, otherCAKey, "mallory", nil, x509.ExtKeyUsageClientAuth)))
+	g.Expect(err).To(MatchError(ContainSubstring("failed to verify client certificate")))
+
+	_, err = getter.Principal(request(makeClientCert(t, ca, caKey, "server", nil, x509.ExtKeyUsageServerAuth)))
+	g.Expect(err).To(MatchError(ContainSubstring("failed to verify client certificate")))
+
+	_, err = getter.Principal(request(makeClientCert(t, ca, caKey, "", []string{"team-a"}, x509.ExtKeyUsageClientAuth)))
+	g.Expect(err).To(MatchError(ContainSubstring("no common name")))
+}
+
+func TestLoadClientCAsRequiresCertificates(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	file := filepath.Join(t.TempDir(), "ca.crt")
+	g.Expect(os.WriteFile(file, []byte("not a certificate"), 0600)).To(Succeed())
+
+	_, err := auth.LoadClientCAs(file)
+	g.Expect(err).To(MatchError(ContainSubstring("no certificates found")))
+}
+
+func makeCA(t *testing.T, name string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert, key
+}
+
+func makeClientCert(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey, cn string, orgs []string, usage x509.ExtKeyUsage) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: cn, Organization: orgs},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert
+}
@@ -0,0 +1,405 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/oauth2"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// DefaultGitHubURL is the GitHub instance users log in with, unless a
+	// GitHub Enterprise URL is configured.
+	DefaultGitHubURL = "https://github.com"
+	// DefaultGitLabURL is the GitLab instance users log in with, unless a
+	// self-hosted URL is configured.
+	DefaultGitLabURL = "https://gitlab.com"
+	// DefaultGitHubAuthSecretName and DefaultGitLabAuthSecretName hold the
+	// OAuth application the server logs users in with.
+	DefaultGitHubAuthSecretName = "github-auth"
+	DefaultGitLabAuthSecretName = "gitlab-auth"
+	// gitLoginPageSize is the number of items requested per page from the
+	// GitHub API.
+	gitLoginPageSize = 100
+)
+
+// GitLoginConfig configures logging users in with an OAuth application
+// registered with GitHub or GitLab.
+type GitLoginConfig struct {
+	// URL of the GitHub or GitLab instance, defaults to github.com or
+	// gitlab.com.
+	URL          string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// AllowedGroups only lets users in that are a member of one of the
+	// GitHub organizations, GitHub teams in the form org/team, or GitLab
+	// groups. It is required, anyone with an account could log in otherwise.
+	AllowedGroups []string
+	// The prefixes are prepended to the username and groups of the
+	// principal to tell them apart from other users in RBAC. They default
+	// to the name of the method, e.g. github:.
+	UsernamePrefix string
+	GroupsPrefix   string
+}
+
+// NewGitLoginConfigFromSecret reads the OAuth application from a secret, with
+// the same keys as the OIDC secret.
+func NewGitLoginConfigFromSecret(secret corev1.Secret) GitLoginConfig {
+	cfg := GitLoginConfig{
+		URL:            string(secret.Data["url"]),
+		ClientID:       string(secret.Data["clientID"]),
+		ClientSecret:   string(secret.Data["clientSecret"]),
+		RedirectURL:    string(secret.Data["redirectURL"]),
+		UsernamePrefix: string(secret.Data["usernamePrefix"]),
+		GroupsPrefix:   string(secret.Data["groupsPrefix"]),
+	}
+
+	if groups := string(secret.Data["allowedGroups"]); groups != "" {
+		cfg.AllowedGroups = strings.Split(groups, ",")
+	}
+
+	return cfg
+}
+
+// gitLogin logs users in with GitHub or GitLab.
+type gitLogin struct {
+	method AuthMethod
+	config GitLoginConfig
+	// principal fetches the user and their groups with the access token
+	// of the user.
+	principal func(ctx context.Context, client *http.Client) (*UserPrincipal, error)
+	endpoint  oauth2.Endpoint
+	scopes    []string
+}
+
+func newGitLogin(method AuthMethod, cfg GitLoginConfig) (*gitLogin, error) {
+	if cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, fmt.Errorf("%s login requires a client ID and a redirect URL", method.String())
+	}
+
+	if len(cfg.AllowedGroups) == 0 {
+		return nil, fmt.Errorf("%s login requires allowed groups", method.String())
+	}
+
+	if cfg.UsernamePrefix == "" {
+		cfg.UsernamePrefix = method.String() + ":"
+	}
+
+	if cfg.GroupsPrefix == "" {
+		cfg.GroupsPrefix = method.String() + ":"
+	}
+
+	l := &gitLogin{method: method}
+
+	switch method {
+	case GitHub:
+		if cfg.URL == "" {
+			cfg.URL = DefaultGitHubURL
+		}
+
+		l.principal = l.githubPrincipal
+		// read:org is needed to list private memberships and teams.
+		l.scopes = []string{"read:user", "read:org"}
+		l.endpoint = oauth2.Endpoint{
+			AuthURL:  strings.TrimSuffix(cfg.URL, "/") + "/login/oauth/authorize",
+			TokenURL: strings.TrimSuffix(cfg.URL, "/") + "/login/oauth/access_token",
+		}
+	case GitLab:
+		if cfg.URL == "" {
+			cfg.URL = DefaultGitLabURL
+		}
+
+		l.principal = l.gitlabPrincipal
+		// The user info of the openid scope includes the groups, without
+		// giving access to the API.
+		l.scopes = []string{"openid"}
+		l.endpoint = oauth2.Endpoint{
+			AuthURL:  strings.TrimSuffix(cfg.URL, "/") + "/oauth/authorize",
+			TokenURL: strings.TrimSuffix(cfg.URL, "/") + "/oauth/token",
+		}
+	default:
+		return nil, fmt.Errorf("%s is not a git provider login method", method.String())
+	}
+
+	if _, err := url.Parse(cfg.URL); err != nil {
+		return nil, fmt.Errorf("invalid %s URL: %w", method.String(), err)
+	}
+
+	l.config = cfg
+
+	return l, nil
+}
+
+func (l *gitLogin) name() string {
+	return l.method.String()
+}
+
+func (l *gitLogin) oauth2Config() *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     l.config.ClientID,
+		ClientSecret: l.config.ClientSecret,
+		Endpoint:     l.endpoint,
+		RedirectURL:  l.config.RedirectURL,
+		Scopes:       l.scopes,
+	}
+}
+
+func (l *gitLogin) info(prefix string) OIDCProviderInfo {
+	displayName := "GitHub"
+	if l.method == GitLab {
+		displayName = "GitLab"
+	}
+
+	return OIDCProviderInfo{
+		Name:        l.name(),
+		DisplayName: displayName,
+		LoginURL:    prefix + "/" + l.name(),
+	}
+}
+
+// authorize checks the user is a member of one of the allowed groups, and
+// applies the prefixes.
+func (l *gitLogin) authorize(p *UserPrincipal) (*UserPrincipal, error) {
+	allowed := false
+
+	for _, g := range p.Groups {
+		if contains(l.config.AllowedGroups, g) {
+			allowed = true
+			break
+		}
+	}
+
+	if !allowed {
+		return nil, fmt.Errorf("user %q is not a member of any allowed group", p.ID)
+	}
+
+	groups := make([]string, 0, len(p.Groups))
+	for _, g := range p.Groups {
+		groups = append(groups, l.config.GroupsPrefix+g)
+	}
+
+	return &UserPrincipal{ID: l.config.UsernamePrefix + p.ID, Groups: groups}, nil
+}
+
+func (l *gitLogin) githubAPIURL() string {
+	if strings.TrimSuffix(l.config.URL, "/") == DefaultGitHubURL {
+		return "https://api.github.com"
+	}
+
+	// GitHub Enterprise Server
+	return strings.TrimSuffix(l.config.URL, "/") + "/api/v3"
+}
+
+// githubPrincipal maps the user to their login, and their organizations and
+// teams, as org/team, to groups.
+func (l *gitLogin) githubPrincipal(ctx context.Context, client *http.Client) (*UserPrincipal, error) {
+	api := l.githubAPIURL()
+
+	var user struct {
+		Login string `json:"login"`
+	}
+
+	if err := getJSON(ctx, client, api+"/user", &user); err != nil {
+		return nil, err
+	}
+
+	if user.Login == "" {
+		return nil, fmt.Errorf("GitHub returned no login for the user")
+	}
+
+	groups := []string{}
+
+	for page := 1; ; page++ {
+		var orgs []struct {
+			Login string `json:"login"`
+		}
+
+		if err := getJSON(ctx, client, fmt.Sprintf("%s/user/orgs?per_page=%d&page=%d", api, gitLoginPageSize, page), &orgs); err != nil {
+			return nil, err
+		}
+
+		for _, o := range orgs {
+			groups = append(groups, o.Login)
+		}
+
+		if len(orgs) < gitLoginPageSize {
+			break
+		}
+	}
+
+	for page := 1; ; page++ {
+		var teams []struct {
+			Slug         string `json:"slug"`
+			Organization struct {
+				Login string `json:"login"`
+			} `json:"organization"`
+		}
+
+		if err := getJSON(ctx, client, fmt.Sprintf("%s/user/teams?per_page=%d&page=%d", api, gitLoginPageSize, page), &teams); err != nil {
+			return nil, err
+		}
+
+		for _, t := range teams {
+			groups = append(groups, t.Organization.Login+"/"+t.Slug)
+		}
+
+		if len(teams) < gitLoginPageSize {
+			break
+		}
+	}
+
+	return &UserPrincipal{ID: user.Login, Groups: groups}, nil
+}
+
+// gitlabPrincipal maps the user to their username, and the full paths of
+// their groups, including subgroups, to groups.
+func (l *gitLogin) gitlabPrincipal(ctx context.Context, client *http.Client) (*UserPrincipal, error) {
+	var userInfo struct {
+		Nickname string   `json:"nickname"`
+		Groups   []string `json:"groups"`
+	}
+
+	if err := getJSON(ctx, client, strings.TrimSuffix(l.config.URL, "/")+"/oauth/userinfo", &userInfo); err != nil {
+		return nil, err
+	}
+
+	if userInfo.Nickname == "" {
+		return nil, fmt.Errorf("GitLab returned no username for the user")
+	}
+
+	groups := userInfo.Groups
+	if groups == nil {
+		groups = []string{}
+	}
+
+	return &UserPrincipal{ID: userInfo.Nickname, Groups: groups}, nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, u string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to %s failed: %w", req.URL.Path, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("request to %s failed with status %d", req.URL.Path, res.StatusCode)
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// gitLogin returns the git provider login with the given name.
+func (s *AuthServer) gitLogin(name string) *gitLogin {
+	for _, l := range s.gitLogins {
+		if l.name() == name {
+			return l
+		}
+	}
+
+	return nil
+}
+
+// GitLoginFlow starts the login flow with GitHub or GitLab.
+func (s *AuthServer) GitLoginFlow(name string) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		l := s.gitLogin(name)
+		if l == nil {
+			JSONError(s.Log, rw, fmt.Sprintf("%s login not configured", name), http.StatusBadRequest)
+			return
+		}
+
+		state, err := newAuthState(r)
+		if err != nil {
+			JSONError(s.Log, rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		http.SetCookie(rw, s.createCookie(StateCookieName, state))
+		http.Redirect(rw, r, l.oauth2Config().AuthCodeURL(state), http.StatusSeeOther)
+	}
+}
+
+// GitLoginCallback handles the redirect back from GitHub or GitLab. The
+// user's access token is only used to look them up, the session is signed
+// like the ones of cluster user accounts.
+func (s *AuthServer) GitLoginCallback(name string) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			rw.Header().Add("Allow", "GET")
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		l := s.gitLogin(name)
+		if l == nil {
+			JSONError(s.Log, rw, fmt.Sprintf("%s login not configured", name), http.StatusBadRequest)
+			return
+		}
+
+		state, code, err := verifyAuthCallback(r)
+		if err != nil {
+			s.Log.Info("invalid auth callback", "provider", name, "error", err)
+			rw.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), oauth2.HTTPClient, s.client)
+		oauthCfg := l.oauth2Config()
+
+		token, err := oauthCfg.Exchange(ctx, code)
+		if err != nil {
+			s.Log.Error(err, "failed to exchange auth code for token", "provider", name)
+			rw.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		principal, err := l.principal(ctx, oauthCfg.Client(ctx, token))
+		if err != nil {
+			JSONError(s.Log, rw, fmt.Sprintf("failed to get %s user: %v", name, err), http.StatusInternalServerError)
+			return
+		}
+
+		principal, err = l.authorize(principal)
+		if err != nil {
+			s.Log.Info("Login denied", "provider", name, "error", err)
+			JSONError(s.Log, rw, "You are not allowed to log in.", http.StatusForbidden)
+
+			return
+		}
+
+		signed, err := s.tokenSignerVerifier.Sign(principal.ID, principal.Groups...)
+		if err != nil {
+			s.Log.Error(err, "Failed to create and sign token")
+			rw.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		if err := s.startSession(rw, r, principal.ID, name, s.config.TokenDuration); err != nil {
+			JSONError(s.Log, rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		http.SetCookie(rw, s.createCookie(IDTokenCookieName, signed))
+		http.SetCookie(rw, s.clearCookie(StateCookieName))
+
+		http.Redirect(rw, r, state.ReturnURL, http.StatusSeeOther)
+	}
+}
@@ -0,0 +1,230 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	"github.com/weaveworks/weave-gitops/pkg/server/auth"
+	ctrlclientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGitLogin(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        auth.AuthMethod
+		allowedGroups []string
+		prefix        string
+		wantStatus    int
+		wantPrincipal *auth.UserPrincipal
+	}{
+		{
+			name:          "github",
+			method:        auth.GitHub,
+			allowedGroups: []string{"weaveworks"},
+			wantStatus:    http.StatusSeeOther,
+			wantPrincipal: &auth.UserPrincipal{ID: "github:octocat", Groups: []string{"github:weaveworks", "github:weaveworks/gitops"}},
+		},
+		{
+			name:          "github team allowed",
+			method:        auth.GitHub,
+			allowedGroups: []string{"weaveworks/gitops"},
+			wantStatus:    http.StatusSeeOther,
+			wantPrincipal: &auth.UserPrincipal{ID: "github:octocat", Groups: []string{"github:weaveworks", "github:weaveworks/gitops"}},
+		},
+		{
+			name:          "github not in allowed groups",
+			method:        auth.GitHub,
+			allowedGroups: []string{"other-org"},
+			wantStatus:    http.StatusForbidden,
+		},
+		{
+			name:          "gitlab",
+			method:        auth.GitLab,
+			allowedGroups: []string{"weaveworks/gitops"},
+			wantStatus:    http.StatusSeeOther,
+			wantPrincipal: &auth.UserPrincipal{ID: "gitlab:tanuki", Groups: []string{"gitlab:weaveworks", "gitlab:weaveworks/gitops"}},
+		},
+		{
+			name:          "gitlab with custom prefixes",
+			method:        auth.GitLab,
+			allowedGroups: []string{"weaveworks"},
+			prefix:        "git:",
+			wantStatus:    http.StatusSeeOther,
+			wantPrincipal: &auth.UserPrincipal{ID: "git:tanuki", Groups: []string{"git:weaveworks", "git:weaveworks/gitops"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			provider := newFakeGitProvider(t)
+
+			tokenSignerVerifier, err := auth.NewHMACTokenSignerVerifier(5 * time.Minute)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			authCfg, err := auth.NewAuthServerConfig(logr.Discard(), auth.OIDCConfig{TokenDuration: time.Hour}, ctrlclientfake.NewClientBuilder().Build(), tokenSignerVerifier, testNamespace, map[auth.AuthMethod]bool{tt.method: true})
+			g.Expect(err).NotTo(HaveOccurred())
+
+			g.Expect(authCfg.SetGitLogin(tt.method, auth.GitLoginConfig{
+				URL:            provider.URL,
+				ClientID:       "client-id",
+				ClientSecret:   "client-secret",
+				RedirectURL:    "https://gitops.example.com/oauth2/" + tt.method.String() + "/callback",
+				AllowedGroups:  tt.allowedGroups,
+				UsernamePrefix: tt.prefix,
+				GroupsPrefix:   tt.prefix,
+			})).To(Succeed())
+
+			s, err := auth.NewAuthServer(context.Background(), authCfg)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			w := httptest.NewRecorder()
+			s.GitLoginFlow(tt.method.String()).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://gitops.example.com/oauth2/"+tt.method.String()+"?return_url=/applications", nil))
+			g.Expect(w).To(HaveHTTPStatus(http.StatusSeeOther))
+
+			authorizeURL, err := url.Parse(w.Header().Get("Location"))
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(authorizeURL.Query().Get("client_id")).To(Equal("client-id"))
+
+			stateCookie := findCookie(w.Result().Cookies(), auth.StateCookieName)
+			g.Expect(stateCookie).NotTo(BeNil())
+
+			req := httptest.NewRequest(http.MethodGet, "https://gitops.example.com/oauth2/"+tt.method.String()+"/callback?code=the-code&state="+url.QueryEscape(authorizeURL.Query().Get("state")), nil)
+			req.AddCookie(stateCookie)
+
+			w = httptest.NewRecorder()
+			s.GitLoginCallback(tt.method.String()).ServeHTTP(w, req)
+			g.Expect(w).To(HaveHTTPStatus(tt.wantStatus))
+
+			if tt.wantPrincipal == nil {
+				g.Expect(findCookie(w.Result().Cookies(), auth.IDTokenCookieName)).To(BeNil())
+				return
+			}
+
+			g.Expect(w.Header().Get("Location")).To(Equal("/applications"))
+
+			idToken := findCookie(w.Result().Cookies(), auth.IDTokenCookieName)
+			g.Expect(idToken).NotTo(BeNil())
+
+			var principal *auth.UserPrincipal
+
+			handler := auth.WithAPIAuth(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				principal = auth.Principal(r.Context())
+			}), s, nil)
+
+			req = httptest.NewRequest(http.MethodGet, "https://gitops.example.com/v1/objects", nil)
+			req.AddCookie(idToken)
+
+			w = httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			g.Expect(w).To(HaveHTTPStatus(http.StatusOK))
+			g.Expect(principal).To(Equal(tt.wantPrincipal))
+		})
+	}
+}
+
+func TestGitLoginCallbackChecksState(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	provider := newFakeGitProvider(t)
+
+	tokenSignerVerifier, err := auth.NewHMACTokenSignerVerifier(5 * time.Minute)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	authCfg, err := auth.NewAuthServerConfig(logr.Discard(), auth.OIDCConfig{}, ctrlclientfake.NewClientBuilder().Build(), tokenSignerVerifier, testNamespace, map[auth.AuthMethod]bool{auth.GitHub: true})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(authCfg.SetGitLogin(auth.GitHub, auth.GitLoginConfig{URL: provider.URL, ClientID: "client-id", RedirectURL: "https://gitops.example.com/oauth2/github/callback", AllowedGroups: []string{"weaveworks"}})).To(Succeed())
+
+	s, err := auth.NewAuthServer(context.Background(), authCfg)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	req := httptest.NewRequest(http.MethodGet, "https://gitops.example.com/oauth2/github/callback?code=the-code&state=forged", nil)
+	req.AddCookie(&http.Cookie{Name: auth.StateCookieName, Value: "expected"})
+
+	w := httptest.NewRecorder()
+	s.GitLoginCallback("github").ServeHTTP(w, req)
+	g.Expect(w).To(HaveHTTPStatus(http.StatusBadRequest))
+}
+
+func TestSetGitLoginRequiresAllowedGroups(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tokenSignerVerifier, err := auth.NewHMACTokenSignerVerifier(5 * time.Minute)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	authCfg, err := auth.NewAuthServerConfig(logr.Discard(), auth.OIDCConfig{}, ctrlclientfake.NewClientBuilder().Build(), tokenSignerVerifier, testNamespace, map[auth.AuthMethod]bool{auth.GitHub: true})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(authCfg.SetGitLogin(auth.GitHub, auth.GitLoginConfig{ClientID: "client-id", RedirectURL: "https://gitops.example.com/oauth2/github/callback"})).To(Succeed())
+
+	_, err = auth.NewAuthServer(context.Background(), authCfg)
+	g.Expect(err).To(MatchError(ContainSubstring("requires allowed groups")))
+}
+
+func TestSetGitLoginRejectsOtherMethods(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	var cfg auth.AuthConfig
+	g.Expect(cfg.SetGitLogin(auth.OIDC, auth.GitLoginConfig{})).To(MatchError(ContainSubstring("not a git provider login method")))
+}
+
+// newFakeGitProvider serves the OAuth and user endpoints of both GitHub
+// Enterprise and GitLab.
+func newFakeGitProvider(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	const accessToken = "the-access-token"
+
+	writeJSON := func(rw http.ResponseWriter, v interface{}) {
+		rw.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(rw).Encode(v)
+	}
+
+	token := func(rw http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "the-code" {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		writeJSON(rw, map[string]string{"access_token": accessToken, "token_type": "bearer"})
+	}
+
+	authenticated := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(rw http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer "+accessToken {
+				rw.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			next(rw, r)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/oauth/access_token", token)
+	mux.HandleFunc("/oauth/token", token)
+	mux.HandleFunc("/api/v3/user", authenticated(func(rw http.ResponseWriter, r *http.Request) {
+		writeJSON(rw, map[string]string{"login": "octocat"})
+	}))
+	mux.HandleFunc("/api/v3/user/orgs", authenticated(func(rw http.ResponseWriter, r *http.Request) {
+		writeJSON(rw, []map[string]string{{"login": "weaveworks"}})
+	}))
+	mux.HandleFunc("/api/v3/user/teams", authenticated(func(rw http.ResponseWriter, r *http.Request) {
+		writeJSON(rw, []map[string]interface{}{{"slug": "gitops", "organization": map[string]string{"login": "weaveworks"}}})
+	}))
+	mux.HandleFunc("/oauth/userinfo", authenticated(func(rw http.ResponseWriter, r *http.Request) {
+		writeJSON(rw, map[string]interface{}{"nickname": "tanuki", "groups": []string{"weaveworks", "weaveworks/gitops"}})
+	}))
+
+	s := httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+
+	return s
+}
@@ -12,10 +12,48 @@ import (
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-func InitAuthServer(ctx context.Context, log logr.Logger, rawKubernetesClient ctrlclient.Client, oidcConfig OIDCConfig, oidcSecret string, namespace string, authMethodStrings []string) (*AuthServer, error) {
-	log.V(logger.LogLevelDebug).Info("Registering authentication methods", "methods", authMethodStrings)
+// InitAuthServerOptions configures the auth server built by InitAuthServer.
+type InitAuthServerOptions struct {
+	// AuthMethods are the names of the enabled auth methods.
+	AuthMethods []string
+	// Namespace holds the secrets and config maps of the auth server.
+	Namespace string
 
-	authMethods, err := ParseAuthMethodArray(authMethodStrings)
+	// OIDC is the OIDC config from the CLI, the one in OIDCSecret is
+	// preferred.
+	OIDC              OIDCConfig
+	OIDCSecret        string
+	OIDCProvidersFile string
+	// RefreshTokenKeySecret holds the key that encrypts refresh tokens.
+	RefreshTokenKeySecret string
+
+	// SigningKeysSecret holds the keys signing local user sessions.
+	SigningKeysSecret string
+	// LoginAttempts throttles the sign-ins of local users.
+	LoginAttempts LoginAttemptsConfig
+
+	// SessionStore is the kind of store the sessions are tracked in, and
+	// SessionStoreName the name of its object.
+	SessionStore     string
+	SessionStoreName string
+	// AdminGroups are the groups allowed to revoke other users' sessions.
+	AdminGroups []string
+
+	// ClientCAFile holds the CAs of the accepted client certificates.
+	ClientCAFile string
+
+	// GitHubLogin and GitLabLogin are the configs of the git provider
+	// logins from the CLI, their secrets are preferred.
+	GitHubLogin GitLoginConfig
+	GitLabLogin GitLoginConfig
+}
+
+func InitAuthServer(ctx context.Context, log logr.Logger, rawKubernetesClient ctrlclient.Client, opts InitAuthServerOptions) (*AuthServer, error) {
+	oidcConfig := opts.OIDC
+
+	log.V(logger.LogLevelDebug).Info("Registering authentication methods", "methods", opts.AuthMethods)
+
+	authMethods, err := ParseAuthMethodArray(opts.AuthMethods)
 	if err != nil {
 		return nil, err
 	}
@@ -24,28 +62,55 @@ func InitAuthServer(ctx context.Context, log logr.Logger, rawKubernetesClient ct
 		return nil, fmt.Errorf("No authentication methods set")
 	}
 
+	var oidcProviders []OIDCConfig
+
 	if authMethods[OIDC] {
-		if oidcSecret != DefaultOIDCAuthSecretName {
-			log.V(logger.LogLevelDebug).Info("Reading OIDC configuration from alternate secret", "secretName", oidcSecret)
+		if opts.OIDCSecret != DefaultOIDCAuthSecretName {
+			log.V(logger.LogLevelDebug).Info("Reading OIDC configuration from alternate secret", "secretName", opts.OIDCSecret)
 		}
 
 		// If OIDC auth secret is found prefer that over CLI parameters
 		var secret corev1.Secret
 		if err := rawKubernetesClient.Get(ctx, client.ObjectKey{
-			Namespace: namespace,
-			Name:      oidcSecret,
+			Namespace: opts.Namespace,
+			Name:      opts.OIDCSecret,
 		}, &secret); err == nil {
 			if oidcConfig.ClientSecret != "" && secret.Data["clientSecret"] != nil { // 'Data' is a byte array
 				log.V(logger.LogLevelWarn).Info("OIDC client configured by both CLI and secret. CLI values will be overridden.")
 			}
 
-			oidcConfig = NewOIDCConfigFromSecret(secret)
+			oidcConfig, err = NewOIDCConfigFromSecret(secret)
+			if err != nil {
+				return nil, err
+			}
+
+			if data, ok := secret.Data[OIDCProvidersSecretKey]; ok {
+				providers, err := ParseOIDCProviders(data)
+				if err != nil {
+					return nil, fmt.Errorf("invalid OIDC providers in secret %s: %w", opts.OIDCSecret, err)
+				}
+
+				oidcProviders = append(oidcProviders, providers...)
+			}
 		} else if err != nil {
-			log.V(logger.LogLevelDebug).Info("Could not read OIDC secret", "secretName", oidcSecret, "error", err)
+			log.V(logger.LogLevelDebug).Info("Could not read OIDC secret", "secretName", opts.OIDCSecret, "error", err)
+		}
+
+		if opts.OIDCProvidersFile != "" {
+			providers, err := ReadOIDCProvidersFile(opts.OIDCProvidersFile)
+			if err != nil {
+				return nil, err
+			}
+
+			oidcProviders = append(oidcProviders, providers...)
+		}
+
+		for _, p := range oidcProviders {
+			log.V(logger.LogLevelDebug).Info("OIDC provider config", "Name", p.Name, "IssuerURL", p.IssuerURL, "ClientID", p.ClientID, "RedirectURL", p.RedirectURL)
 		}
 
 		if oidcConfig.ClientSecret != "" {
-			log.V(logger.LogLevelDebug).Info("OIDC config", "IssuerURL", oidcConfig.IssuerURL, "ClientID", oidcConfig.ClientID, "ClientSecretLength", len(oidcConfig.ClientSecret), "RedirectURL", oidcConfig.RedirectURL, "TokenDuration", oidcConfig.TokenDuration)
+			log.V(logger.LogLevelDebug).Info("OIDC config", "IssuerURL", oidcConfig.IssuerURL, "ClientID", oidcConfig.ClientID, "ClientSecretLength", len(oidcConfig.ClientSecret), "RedirectURL", oidcConfig.RedirectURL, "TokenDuration", oidcConfig.TokenDuration, "RefreshTokenDuration", oidcConfig.RefreshTokenDuration, "UsernameClaim", oidcConfig.UsernameClaim, "GroupsClaim", oidcConfig.GroupsClaim, "UsernamePrefix", oidcConfig.UsernamePrefix, "GroupsPrefix", oidcConfig.GroupsPrefix, "RequiredClaims", oidcConfig.RequiredClaims)
 		}
 	} else {
 		// Make sure there is no OIDC config if it's not an enabled authorization method
@@ -58,16 +123,103 @@ func InitAuthServer(ctx context.Context, log logr.Logger, rawKubernetesClient ct
 		return nil, fmt.Errorf("could not create HMAC token signer: %w", err)
 	}
 
+	if opts.SigningKeysSecret != "" {
+		key := client.ObjectKey{Namespace: opts.Namespace, Name: opts.SigningKeysSecret}
+
+		if err := LoadSigningKeys(ctx, rawKubernetesClient, key, tsv); err != nil {
+			log.Error(err, "Failed to load the signing keys, using a random key. Local user sessions will not survive restarts or be shared between replicas.", "secret", key)
+		}
+
+		if wc, ok := rawKubernetesClient.(ctrlclient.WithWatch); ok {
+			go WatchSigningKeys(ctx, log, wc, key, tsv)
+		}
+	}
+
 	if featureflags.Get("WEAVE_GITOPS_FEATURE_DEV_MODE") == "true" {
 		log.V(logger.LogLevelWarn).Info("Dev-mode is enabled. This should be used for local work only.")
 		tsv.SetDevMode(true)
 	}
 
-	authCfg, err := NewAuthServerConfig(log, oidcConfig, rawKubernetesClient, tsv, namespace, authMethods)
+	authCfg, err := NewAuthServerConfig(log, oidcConfig, rawKubernetesClient, tsv, opts.Namespace, authMethods)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := authCfg.SetOIDCProviders(oidcProviders); err != nil {
+		return nil, err
+	}
+
+	if authMethods[OIDC] && opts.RefreshTokenKeySecret != "" {
+		key := client.ObjectKey{Namespace: opts.Namespace, Name: opts.RefreshTokenKeySecret}
+
+		refreshTokenKey, err := LoadRefreshTokenKey(ctx, rawKubernetesClient, key)
+		if err != nil {
+			log.Error(err, "Failed to load the refresh token key, using a random key. OIDC sessions will not be renewed after restarts or by other replicas.", "secret", key)
+		}
+
+		authCfg.SetRefreshTokenKey(refreshTokenKey)
+	}
+
+	sessionStore, err := NewSessionStore(opts.SessionStore, rawKubernetesClient, opts.Namespace, opts.SessionStoreName)
+	if err != nil {
+		return nil, err
+	}
+
+	authCfg.SetSessionStore(sessionStore)
+	authCfg.SetAdminGroups(opts.AdminGroups)
+
+	if authMethods[ClientCertificate] {
+		if opts.ClientCAFile == "" {
+			return nil, fmt.Errorf("a client CA file is required to authenticate with client certificates")
+		}
+
+		pool, err := LoadClientCAs(opts.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		authCfg.SetClientCAs(pool)
+	}
+
+	if authMethods[UserAccount] {
+		tracker, err := NewLoginAttemptTracker(opts.LoginAttempts, rawKubernetesClient, opts.Namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		authCfg.SetLoginAttemptTracker(tracker)
+	}
+
+	for _, l := range []struct {
+		method     AuthMethod
+		cfg        GitLoginConfig
+		secretName string
+	}{
+		{GitHub, opts.GitHubLogin, DefaultGitHubAuthSecretName},
+		{GitLab, opts.GitLabLogin, DefaultGitLabAuthSecretName},
+	} {
+		if !authMethods[l.method] {
+			continue
+		}
+
+		// Like for OIDC, the secret is preferred over CLI parameters.
+		var secret corev1.Secret
+		if err := rawKubernetesClient.Get(ctx, client.ObjectKey{
+			Namespace: opts.Namespace,
+			Name:      l.secretName,
+		}, &secret); err == nil {
+			l.cfg = NewGitLoginConfigFromSecret(secret)
+		} else {
+			log.V(logger.LogLevelDebug).Info("Could not read git provider login secret", "secretName", l.secretName, "error", err)
+		}
+
+		log.V(logger.LogLevelDebug).Info("Git provider login config", "method", l.method.String(), "URL", l.cfg.URL, "ClientID", l.cfg.ClientID, "RedirectURL", l.cfg.RedirectURL, "AllowedGroups", l.cfg.AllowedGroups)
+
+		if err := authCfg.SetGitLogin(l.method, l.cfg); err != nil {
+			return nil, err
+		}
+	}
+
 	authServer, err := NewAuthServer(ctx, authCfg)
 	if err != nil {
 		return nil, fmt.Errorf("could not create auth server: %w", err)
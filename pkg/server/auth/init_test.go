@@ -126,7 +126,13 @@ func TestInitAuthServer(t *testing.T) {
 
 			fakeKubernetesClient := partialKubernetesClient.Build()
 
-			srv, err := auth.InitAuthServer(context.Background(), logr.Discard(), fakeKubernetesClient, tt.cliOIDCConfig, tt.oidcSecretName, "test-namespace", tt.authMethods)
+			srv, err := auth.InitAuthServer(context.Background(), logr.Discard(), fakeKubernetesClient, auth.InitAuthServerOptions{
+				AuthMethods:   tt.authMethods,
+				Namespace:     "test-namespace",
+				OIDC:          tt.cliOIDCConfig,
+				OIDCSecret:    tt.oidcSecretName,
+				LoginAttempts: auth.DefaultLoginAttemptsConfig(),
+			})
 
 			if tt.expectErr {
 				g.Expect(err).To(gomega.HaveOccurred())
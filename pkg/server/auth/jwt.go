@@ -26,13 +26,17 @@ type JWTCookiePrincipalGetter struct {
 	log        logr.Logger
 	verifier   *oidc.IDTokenVerifier
 	cookieName string
+	claims     *ClaimsConfig
 }
 
-func NewJWTCookiePrincipalGetter(log logr.Logger, verifier *oidc.IDTokenVerifier, cookieName string) PrincipalGetter {
+// NewJWTCookiePrincipalGetter creates a PrincipalGetter that reads a JWT
+// from the named cookie. If claims is nil the default claim mapping is used.
+func NewJWTCookiePrincipalGetter(log logr.Logger, verifier *oidc.IDTokenVerifier, cookieName string, claims *ClaimsConfig) PrincipalGetter {
 	return &JWTCookiePrincipalGetter{
 		log:        log,
 		verifier:   verifier,
 		cookieName: cookieName,
+		claims:     claims,
 	}
 }
 
@@ -42,7 +46,7 @@ func (pg *JWTCookiePrincipalGetter) Principal(r *http.Request) (*UserPrincipal,
 		return nil, nil
 	}
 
-	return parseJWTToken(r.Context(), pg.verifier, cookie.Value)
+	return parseJWTToken(r.Context(), pg.verifier, pg.claims, cookie.Value)
 }
 
 // JWTAuthorizationHeaderPrincipalGetter inspects the Authorization
@@ -51,12 +55,17 @@ func (pg *JWTCookiePrincipalGetter) Principal(r *http.Request) (*UserPrincipal,
 type JWTAuthorizationHeaderPrincipalGetter struct {
 	log      logr.Logger
 	verifier *oidc.IDTokenVerifier
+	claims   *ClaimsConfig
 }
 
-func NewJWTAuthorizationHeaderPrincipalGetter(log logr.Logger, verifier *oidc.IDTokenVerifier) PrincipalGetter {
+// NewJWTAuthorizationHeaderPrincipalGetter creates a PrincipalGetter that reads
+// a JWT from the Authorization header. If claims is nil the default claim
+// mapping is used.
+func NewJWTAuthorizationHeaderPrincipalGetter(log logr.Logger, verifier *oidc.IDTokenVerifier, claims *ClaimsConfig) PrincipalGetter {
 	return &JWTAuthorizationHeaderPrincipalGetter{
 		log:      log,
 		verifier: verifier,
+		claims:   claims,
 	}
 }
 
@@ -68,7 +77,7 @@ func (pg *JWTAuthorizationHeaderPrincipalGetter) Principal(r *http.Request) (*Us
 		return nil, nil
 	}
 
-	return parseJWTToken(r.Context(), pg.verifier, extractToken(header))
+	return parseJWTToken(r.Context(), pg.verifier, pg.claims, extractToken(header))
 }
 
 func extractToken(s string) string {
@@ -84,22 +93,24 @@ func extractToken(s string) string {
 	return strings.TrimSpace(parts[1])
 }
 
-func parseJWTToken(ctx context.Context, verifier *oidc.IDTokenVerifier, rawIDToken string) (*UserPrincipal, error) {
+func parseJWTToken(ctx context.Context, verifier *oidc.IDTokenVerifier, claimsConfig *ClaimsConfig, rawIDToken string) (*UserPrincipal, error) {
 	token, err := verifier.Verify(ctx, rawIDToken)
 	if err != nil {
 		return nil, fmt.Errorf("failed to verify JWT token: %w", err)
 	}
 
-	var claims struct {
-		Email  string   `json:"email"`
-		Groups []string `json:"groups"`
-	}
+	var claims map[string]interface{}
 
 	if err := token.Claims(&claims); err != nil {
 		return nil, fmt.Errorf("failed to parse claims from the JWT token: %w", err)
 	}
 
-	return &UserPrincipal{ID: claims.Email, Groups: claims.Groups}, nil
+	principal, err := claimsConfig.PrincipalFromClaims(claims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to map claims from the JWT token: %w", err)
+	}
+
+	return principal, nil
 }
 
 type JWTAdminCookiePrincipalGetter struct {
@@ -134,7 +145,12 @@ func parseJWTAdminToken(verifier TokenSignerVerifier, rawIDToken string) (*UserP
 		return nil, nil
 	}
 
-	return &UserPrincipal{ID: claims.Subject, Groups: []string{}}, nil
+	groups := claims.Groups
+	if groups == nil {
+		groups = []string{}
+	}
+
+	return &UserPrincipal{ID: claims.Subject, Groups: groups}, nil
 }
 
 // MultiAuthPrincipal looks for a principal in an array of principal getters and
@@ -160,3 +176,35 @@ func (m MultiAuthPrincipal) Principal(r *http.Request) (*UserPrincipal, error) {
 
 	return nil, fmt.Errorf("Could not find valid principal")
 }
+
+// MultiOIDCPrincipal looks for a principal in the principal getters of
+// several OIDC providers, trying them in order. Unlike MultiAuthPrincipal,
+// errors don't stop the search, as a token is only valid for the provider
+// that issued it, but if no principal is found the first error is returned.
+type MultiOIDCPrincipal struct {
+	Log     logr.Logger
+	Getters []PrincipalGetter
+}
+
+func (m MultiOIDCPrincipal) Principal(r *http.Request) (*UserPrincipal, error) {
+	var firstErr error
+
+	for _, v := range m.Getters {
+		p, err := v.Principal(r)
+		if err != nil {
+			m.Log.V(logger.LogLevelDebug).Info("OIDC principal getter failed", "method", reflect.TypeOf(v), "error", err)
+
+			if firstErr == nil {
+				firstErr = err
+			}
+
+			continue
+		}
+
+		if p != nil {
+			return p, nil
+		}
+	}
+
+	return nil, firstErr
+}
@@ -35,7 +35,7 @@ func TestJWTCookiePrincipalGetter(t *testing.T) {
 
 	for _, tt := range authTests {
 		t.Run(tt.name, func(t *testing.T) {
-			principal, err := auth.NewJWTCookiePrincipalGetter(logr.Discard(), verifier, cookieName).Principal(makeCookieRequest(cookieName, tt.cookie))
+			principal, err := auth.NewJWTCookiePrincipalGetter(logr.Discard(), verifier, cookieName, nil).Principal(makeCookieRequest(cookieName, tt.cookie))
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -46,6 +46,39 @@ func TestJWTCookiePrincipalGetter(t *testing.T) {
 	}
 }
 
+func TestJWTCookiePrincipalGetterWithClaimsConfig(t *testing.T) {
+	const cookieName = "auth-token"
+
+	privKey := testutils.MakeRSAPrivateKey(t)
+
+	srv := testutils.MakeKeysetServer(t, privKey)
+	keySet := oidc.NewRemoteKeySet(oidc.ClientContext(context.TODO(), srv.Client()), srv.URL)
+	verifier := oidc.NewVerifier("http://127.0.0.1:5556/dex", keySet, &oidc.Config{ClientID: "test-service"})
+
+	claims := &auth.ClaimsConfig{
+		UsernameClaim:  "preferred_username",
+		UsernamePrefix: "oidc:",
+		GroupsPrefix:   "oidc:",
+	}
+
+	principal, err := auth.NewJWTCookiePrincipalGetter(logr.Discard(), verifier, cookieName, claims).Principal(makeCookieRequest(cookieName, testutils.MakeJWToken(t, privKey, "example@example.com")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &auth.UserPrincipal{ID: "oidc:example", Groups: []string{"oidc:testing"}}
+	if diff := cmp.Diff(want, principal, allowUnexportedPrincipal()); diff != "" {
+		t.Fatalf("failed to get principal:\n%s", diff)
+	}
+
+	claims.RequiredClaims = map[string]string{"hd": "example.com"}
+
+	_, err = auth.NewJWTCookiePrincipalGetter(logr.Discard(), verifier, cookieName, claims).Principal(makeCookieRequest(cookieName, testutils.MakeJWToken(t, privKey, "example@example.com")))
+	if err == nil {
+		t.Fatal("expected an error for a missing required claim")
+	}
+}
+
 func TestJWTAuthorizationHeaderPrincipalGetter(t *testing.T) {
 	privKey := testutils.MakeRSAPrivateKey(t)
 	authTests := []struct {
@@ -63,7 +96,7 @@ func TestJWTAuthorizationHeaderPrincipalGetter(t *testing.T) {
 
 	for _, tt := range authTests {
 		t.Run(tt.name, func(t *testing.T) {
-			principal, err := auth.NewJWTAuthorizationHeaderPrincipalGetter(logr.Discard(), verifier).Principal(makeAuthenticatedRequest(tt.authorization))
+			principal, err := auth.NewJWTAuthorizationHeaderPrincipalGetter(logr.Discard(), verifier, nil).Principal(makeAuthenticatedRequest(tt.authorization))
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -141,6 +174,12 @@ func TestMultiAuth(t *testing.T) {
 			want:  nil,
 			err:   err,
 		},
+		{
+			name:  "error followed by a successful auth",
+			auths: []auth.PrincipalGetter{errorPrincipalGetter{err: err}, stubPrincipalGetter{id: "testing"}},
+			want:  nil,
+			err:   err,
+		},
 	}
 
 	for _, tt := range multiAuthTests {
@@ -158,6 +197,52 @@ func TestMultiAuth(t *testing.T) {
 	}
 }
 
+func TestMultiOIDC(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	err := errors.New("oops")
+
+	multiOIDCTests := []struct {
+		name  string
+		auths []auth.PrincipalGetter
+		want  *auth.UserPrincipal
+		err   error
+	}{
+		{
+			name:  "no successful auths",
+			auths: []auth.PrincipalGetter{stubPrincipalGetter{}, stubPrincipalGetter{}},
+			want:  nil,
+		},
+		{
+			name:  "error followed by a successful auth",
+			auths: []auth.PrincipalGetter{errorPrincipalGetter{err: err}, stubPrincipalGetter{id: "testing"}},
+			want:  &auth.UserPrincipal{ID: "testing"},
+		},
+		{
+			name:  "error followed by no successful auths",
+			auths: []auth.PrincipalGetter{errorPrincipalGetter{err: err}, stubPrincipalGetter{}},
+			want:  nil,
+			err:   err,
+		},
+	}
+
+	for _, tt := range multiOIDCTests {
+		t.Run(tt.name, func(t *testing.T) {
+			mg := auth.MultiOIDCPrincipal{Log: logr.Discard(), Getters: tt.auths}
+			req := httptest.NewRequest("GET", "http://example.com/", nil)
+
+			principal, err := mg.Principal(req)
+
+			if tt.err != nil {
+				g.Expect(err).To(MatchError(tt.err))
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+			g.Expect(principal).To(Equal(tt.want))
+		})
+	}
+}
+
 type stubPrincipalGetter struct {
 	id string
 }
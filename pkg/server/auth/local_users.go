@@ -0,0 +1,200 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"golang.org/x/crypto/bcrypt"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// LocalUsersSecretKey is the key in the cluster user secret that holds the
+// list of local users. The "username" and "password" keys are still read for
+// the single user configured by the Helm chart.
+const LocalUsersSecretKey = "users"
+
+var (
+	// ErrLocalUserExists is returned when adding a user that already exists.
+	ErrLocalUserExists = errors.New("local user already exists")
+	// ErrLocalUserNotFound is returned when changing a user that doesn't exist.
+	ErrLocalUserNotFound = errors.New("local user not found")
+	// ErrLocalUserManaged is returned when removing the user set in the
+	// "username" and "password" keys of the cluster user secret.
+	ErrLocalUserManaged = errors.New("local user is set in the username and password keys of the secret")
+)
+
+// LocalUser is a user that can sign in with a username and password, without
+// an OIDC provider. The user's groups are used when impersonating them.
+type LocalUser struct {
+	Username     string   `json:"username"`
+	PasswordHash string   `json:"passwordHash"`
+	Groups       []string `json:"groups,omitempty"`
+}
+
+// CheckPassword returns an error if the password doesn't match the user's hash.
+func (u LocalUser) CheckPassword(password string) error {
+	return bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password))
+}
+
+// HashPassword returns the bcrypt hash of a password, as stored in LocalUser.
+func HashPassword(password string) (string, error) {
+	if password == "" {
+		return "", errors.New("password must not be empty")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// LocalUsersFromSecret reads the local users from the cluster user secret.
+// The single user in the "username" and "password" keys is included, unless
+// a user with the same name is in the list.
+func LocalUsersFromSecret(secret *corev1.Secret) ([]LocalUser, error) {
+	users := []LocalUser{}
+
+	if data, ok := secret.Data[LocalUsersSecretKey]; ok {
+		if err := yaml.UnmarshalStrict(data, &users); err != nil {
+			return nil, fmt.Errorf("failed to parse local users in secret %s: %w", secret.Name, err)
+		}
+
+		if err := validateLocalUsers(users); err != nil {
+			return nil, fmt.Errorf("invalid local users in secret %s: %w", secret.Name, err)
+		}
+	}
+
+	username := string(secret.Data["username"])
+	if _, ok := secret.Data["password"]; ok && FindLocalUser(users, username) == nil {
+		users = append([]LocalUser{{
+			Username:     username,
+			PasswordHash: string(secret.Data["password"]),
+		}}, users...)
+	}
+
+	return users, nil
+}
+
+// SetLocalUsers writes the local users to the cluster user secret. The
+// "username" and "password" keys are managed by the Helm chart, so they are
+// left alone: their user is only added to the list if it was changed, which
+// then takes precedence, and it can't be removed.
+func SetLocalUsers(secret *corev1.Secret, users []LocalUser) error {
+	if err := validateLocalUsers(users); err != nil {
+		return err
+	}
+
+	username := string(secret.Data["username"])
+	passwordHash, hasSingleUser := secret.Data["password"]
+
+	if hasSingleUser && FindLocalUser(users, username) == nil {
+		return fmt.Errorf("%w: %s", ErrLocalUserManaged, username)
+	}
+
+	stored := []LocalUser{}
+
+	for _, u := range users {
+		if hasSingleUser && u.Username == username && u.PasswordHash == string(passwordHash) && len(u.Groups) == 0 {
+			continue
+		}
+
+		stored = append(stored, u)
+	}
+
+	sort.SliceStable(stored, func(i, j int) bool {
+		return stored[i].Username < stored[j].Username
+	})
+
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+
+	if len(stored) == 0 {
+		delete(secret.Data, LocalUsersSecretKey)
+		return nil
+	}
+
+	data, err := yaml.Marshal(stored)
+	if err != nil {
+		return err
+	}
+
+	secret.Data[LocalUsersSecretKey] = data
+
+	return nil
+}
+
+// FindLocalUser returns the user with the given username, or nil.
+func FindLocalUser(users []LocalUser, username string) *LocalUser {
+	for i := range users {
+		if users[i].Username == username {
+			return &users[i]
+		}
+	}
+
+	return nil
+}
+
+// AddLocalUser adds a user to the list.
+func AddLocalUser(users []LocalUser, user LocalUser) ([]LocalUser, error) {
+	if FindLocalUser(users, user.Username) != nil {
+		return nil, fmt.Errorf("%w: %s", ErrLocalUserExists, user.Username)
+	}
+
+	return append(users, user), nil
+}
+
+// RemoveLocalUser removes a user from the list.
+func RemoveLocalUser(users []LocalUser, username string) ([]LocalUser, error) {
+	res := []LocalUser{}
+
+	for _, u := range users {
+		if u.Username != username {
+			res = append(res, u)
+		}
+	}
+
+	if len(res) == len(users) {
+		return nil, fmt.Errorf("%w: %s", ErrLocalUserNotFound, username)
+	}
+
+	return res, nil
+}
+
+// SetLocalUserPassword replaces the password hash of a user.
+func SetLocalUserPassword(users []LocalUser, username, passwordHash string) ([]LocalUser, error) {
+	user := FindLocalUser(users, username)
+	if user == nil {
+		return nil, fmt.Errorf("%w: %s", ErrLocalUserNotFound, username)
+	}
+
+	user.PasswordHash = passwordHash
+
+	return users, nil
+}
+
+func validateLocalUsers(users []LocalUser) error {
+	seen := map[string]bool{}
+
+	for _, u := range users {
+		if u.Username == "" {
+			return errors.New("local user without a username")
+		}
+
+		if u.PasswordHash == "" {
+			return fmt.Errorf("local user %q has no password hash", u.Username)
+		}
+
+		if seen[u.Username] {
+			return fmt.Errorf("local user %q is configured more than once", u.Username)
+		}
+
+		seen[u.Username] = true
+	}
+
+	return nil
+}
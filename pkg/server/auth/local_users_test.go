@@ -0,0 +1,200 @@
+package auth_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/weaveworks/weave-gitops/pkg/server/auth"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlclientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestLocalUsersFromSecret(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    map[string][]byte
+		want    []auth.LocalUser
+		wantErr string
+	}{
+		{
+			name: "no users",
+			data: map[string][]byte{},
+			want: []auth.LocalUser{},
+		},
+		{
+			name: "single user",
+			data: map[string][]byte{
+				"username": []byte("admin"),
+				"password": []byte("hash"),
+			},
+			want: []auth.LocalUser{
+				{Username: "admin", PasswordHash: "hash"},
+			},
+		},
+		{
+			name: "list of users and single user",
+			data: map[string][]byte{
+				"username": []byte("admin"),
+				"password": []byte("hash"),
+				"users":    []byte("- username: alice\n  passwordHash: alice-hash\n  groups: [team-a]\n"),
+			},
+			want: []auth.LocalUser{
+				{Username: "admin", PasswordHash: "hash"},
+				{Username: "alice", PasswordHash: "alice-hash", Groups: []string{"team-a"}},
+			},
+		},
+		{
+			name: "list overrides single user",
+			data: map[string][]byte{
+				"username": []byte("admin"),
+				"password": []byte("old-hash"),
+				"users":    []byte("- username: admin\n  passwordHash: new-hash\n"),
+			},
+			want: []auth.LocalUser{
+				{Username: "admin", PasswordHash: "new-hash"},
+			},
+		},
+		{
+			name: "duplicate users",
+			data: map[string][]byte{
+				"users": []byte("- username: alice\n  passwordHash: a\n- username: alice\n  passwordHash: b\n"),
+			},
+			wantErr: "more than once",
+		},
+		{
+			name: "missing hash",
+			data: map[string][]byte{
+				"users": []byte("- username: alice\n"),
+			},
+			wantErr: "no password hash",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			users, err := auth.LocalUsersFromSecret(&corev1.Secret{Data: tt.data})
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				return
+			}
+
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(users).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestSetLocalUsersKeepsSingleUser(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	secret := &corev1.Secret{
+		Data: map[string][]byte{
+			"username": []byte("admin"),
+			"password": []byte("hash"),
+		},
+	}
+
+	users, err := auth.LocalUsersFromSecret(secret)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	users, err = auth.AddLocalUser(users, auth.LocalUser{Username: "alice", PasswordHash: "alice-hash", Groups: []string{"team-a"}})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	_, err = auth.AddLocalUser(users, auth.LocalUser{Username: "alice", PasswordHash: "other"})
+	g.Expect(err).To(MatchError(auth.ErrLocalUserExists))
+
+	g.Expect(auth.SetLocalUsers(secret, users)).To(Succeed())
+	g.Expect(secret.Data).To(HaveKeyWithValue("username", []byte("admin")))
+	g.Expect(secret.Data).To(HaveKeyWithValue("password", []byte("hash")))
+	g.Expect(string(secret.Data["users"])).NotTo(ContainSubstring("admin"))
+
+	users, err = auth.SetLocalUserPassword(users, "admin", "new-hash")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	g.Expect(auth.SetLocalUsers(secret, users)).To(Succeed())
+	g.Expect(secret.Data).To(HaveKeyWithValue("username", []byte("admin")))
+	g.Expect(secret.Data).To(HaveKeyWithValue("password", []byte("hash")))
+
+	users, err = auth.LocalUsersFromSecret(secret)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(users).To(Equal([]auth.LocalUser{
+		{Username: "admin", PasswordHash: "new-hash"},
+		{Username: "alice", PasswordHash: "alice-hash", Groups: []string{"team-a"}},
+	}))
+
+	users, err = auth.RemoveLocalUser(users, "admin")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(users).To(HaveLen(1))
+	g.Expect(auth.SetLocalUsers(secret, users)).To(MatchError(auth.ErrLocalUserManaged))
+
+	_, err = auth.RemoveLocalUser(users, "admin")
+	g.Expect(err).To(MatchError(auth.ErrLocalUserNotFound))
+
+	users, err = auth.RemoveLocalUser([]auth.LocalUser{{Username: "admin", PasswordHash: "hash"}, {Username: "alice", PasswordHash: "alice-hash"}}, "alice")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(auth.SetLocalUsers(secret, users)).To(Succeed())
+	g.Expect(secret.Data).NotTo(HaveKey("users"))
+}
+
+func TestSignInLocalUserWithGroups(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	hash, err := auth.HashPassword("alice-password")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      auth.ClusterUserAuthSecretName,
+			Namespace: testNamespace,
+		},
+		Data: map[string][]byte{},
+	}
+
+	g.Expect(auth.SetLocalUsers(secret, []auth.LocalUser{
+		{Username: "alice", PasswordHash: hash, Groups: []string{"team-a", "viewers"}},
+	})).To(Succeed())
+
+	fakeKubernetesClient := ctrlclientfake.NewClientBuilder().WithObjects(secret).Build()
+
+	tokenSignerVerifier, err := auth.NewHMACTokenSignerVerifier(5 * time.Minute)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	s, _ := makeAuthServer(t, fakeKubernetesClient, tokenSignerVerifier, []auth.AuthMethod{auth.UserAccount})
+
+	signIn := func(username, password string) *httptest.ResponseRecorder {
+		j, err := json.Marshal(auth.LoginRequest{Username: username, Password: password})
+		g.Expect(err).NotTo(HaveOccurred())
+
+		w := httptest.NewRecorder()
+		s.SignIn().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "https://example.com/signin", bytes.NewReader(j)))
+
+		return w
+	}
+
+	g.Expect(signIn("alice", "wrong")).To(HaveHTTPStatus(http.StatusUnauthorized))
+	g.Expect(signIn("bob", "alice-password")).To(HaveHTTPStatus(http.StatusUnauthorized))
+
+	w := signIn("alice", "alice-password")
+	g.Expect(w).To(HaveHTTPStatus(http.StatusOK))
+
+	cookie := findCookie(w.Result().Cookies(), auth.IDTokenCookieName)
+	g.Expect(cookie).NotTo(BeNil())
+
+	claims, err := tokenSignerVerifier.Verify(cookie.Value)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(claims.Subject).To(Equal("alice"))
+	g.Expect(claims.Groups).To(Equal([]string{"team-a", "viewers"}))
+
+	principal, err := auth.NewJWTAdminCookiePrincipalGetter(s.Log, tokenSignerVerifier, auth.IDTokenCookieName).Principal(makeCookieRequest(auth.IDTokenCookieName, cookie.Value))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(principal.ID).To(Equal("alice"))
+	g.Expect(principal.Groups).To(Equal([]string{"team-a", "viewers"}))
+}
@@ -0,0 +1,424 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultLoginAttemptsStoreName is the name of the config map failed
+	// sign-in attempts are tracked in.
+	DefaultLoginAttemptsStoreName = "gitops-login-attempts"
+	// LoginAttemptsStoreMemory tracks attempts in memory, for a single
+	// replica.
+	LoginAttemptsStoreMemory = "memory"
+	// LoginAttemptsStoreConfigMap tracks attempts in a config map shared by
+	// all replicas.
+	LoginAttemptsStoreConfigMap = objectKindConfigMap
+)
+
+// LoginAttemptsConfig configures how failed sign-in attempts with a username
+// and password are throttled.
+type LoginAttemptsConfig struct {
+	// Store is where attempts are tracked, LoginAttemptsStoreMemory or
+	// LoginAttemptsStoreConfigMap.
+	Store     string
+	StoreName string
+	// FreeFailures is how many failures are allowed for a user before they
+	// have to wait between attempts. The wait starts at BaseDelay and
+	// doubles with every failure, up to MaxDelay.
+	FreeFailures int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	// A user is locked out for LockoutDuration after MaxFailures, and a
+	// client address after MaxFailuresPerIP. Failures older than
+	// LockoutDuration are forgotten.
+	MaxFailures      int
+	MaxFailuresPerIP int
+	LockoutDuration  time.Duration
+	// TrustedProxies are the addresses or CIDR ranges of the proxies in
+	// front of the server. The client address of requests they forward is
+	// read from the X-Forwarded-For header.
+	TrustedProxies []string
+}
+
+// DefaultLoginAttemptsConfig returns the default throttling of sign-in
+// attempts, tracked in a config map. Client addresses are not locked out by
+// default, as behind a proxy they're all the same.
+func DefaultLoginAttemptsConfig() LoginAttemptsConfig {
+	return LoginAttemptsConfig{
+		Store:           LoginAttemptsStoreConfigMap,
+		StoreName:       DefaultLoginAttemptsStoreName,
+		FreeFailures:    3,
+		BaseDelay:       time.Second,
+		MaxDelay:        30 * time.Second,
+		MaxFailures:     10,
+		LockoutDuration: 15 * time.Minute,
+	}
+}
+
+// loginAttempts are the recent failures of a user or client address. An
+// attempt is counted as a failure when it starts, so that concurrent attempts
+// are throttled too, and uncounted if it succeeds.
+type loginAttempts struct {
+	Failures    int       `json:"failures"`
+	LastFailure time.Time `json:"lastFailure"`
+}
+
+// loginAttemptStore holds the attempts of users and client addresses.
+type loginAttemptStore interface {
+	update(ctx context.Context, fn func(map[string]loginAttempts)) error
+}
+
+// LoginAttemptTracker throttles sign-in attempts with a username and
+// password, by user and by client address.
+type LoginAttemptTracker struct {
+	cfg            LoginAttemptsConfig
+	store          loginAttemptStore
+	trustedProxies []*net.IPNet
+	now            func() time.Time
+}
+
+// NewLoginAttemptTracker creates a tracker using the configured store. The
+// config map store is created in namespace.
+func NewLoginAttemptTracker(cfg LoginAttemptsConfig, c ctrlclient.Client, namespace string) (*LoginAttemptTracker, error) {
+	var store loginAttemptStore
+
+	switch cfg.Store {
+	case LoginAttemptsStoreMemory:
+		store = &memoryLoginAttemptStore{attempts: map[string]loginAttempts{}}
+	case LoginAttemptsStoreConfigMap:
+		store = &kubeLoginAttemptStore{data: objectData{
+			client: c,
+			kind:   objectKindConfigMap,
+			key:    ctrlclient.ObjectKey{Namespace: namespace, Name: cfg.StoreName},
+		}}
+	default:
+		return nil, fmt.Errorf("unknown login attempts store %q, valid values are %q and %q", cfg.Store, LoginAttemptsStoreMemory, LoginAttemptsStoreConfigMap)
+	}
+
+	trustedProxies, err := parseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginAttemptTracker{
+		cfg:            cfg,
+		store:          store,
+		trustedProxies: trustedProxies,
+		now:            time.Now,
+	}, nil
+}
+
+// LoginLockout describes a user or client address that is locked out.
+type LoginLockout struct {
+	Scope string
+	Until time.Time
+}
+
+// LoginAttempt is the outcome of starting a sign-in attempt.
+type LoginAttempt struct {
+	// Wait is how long the client has to wait before it can try to sign in
+	// as the user, and Locked whether that's because of a lockout. The
+	// attempt was not counted if Wait isn't 0.
+	Wait   time.Duration
+	Locked bool
+	// Lockouts are caused by the attempt if it fails.
+	Lockouts []LoginLockout
+}
+
+// Attempt checks whether the client may try to sign in as the user and, if
+// so, counts the attempt as a failure until Succeeded is called. Checking and
+// counting is a single update of the store, so concurrent attempts can't get
+// around the throttling, and an attempt that can't be counted is an error.
+func (t *LoginAttemptTracker) Attempt(ctx context.Context, username, ip string) (LoginAttempt, error) {
+	var res LoginAttempt
+
+	now := t.now()
+
+	err := t.store.update(ctx, func(attempts map[string]loginAttempts) {
+		res = LoginAttempt{}
+
+		t.prune(attempts, now)
+
+		scopes := t.scopes(username, ip)
+
+		for _, scope := range scopes {
+			a, ok := attempts[scope.key]
+			if !ok {
+				continue
+			}
+
+			if scope.max > 0 && a.Failures >= scope.max {
+				if d := t.lockedUntil(a).Sub(now); d > res.Wait || !res.Locked {
+					res.Wait, res.Locked = d, true
+				}
+
+				continue
+			}
+
+			if res.Locked {
+				continue
+			}
+
+			if d := a.LastFailure.Add(t.delay(scope.name, a.Failures)).Sub(now); d > res.Wait {
+				res.Wait = d
+			}
+		}
+
+		if res.Wait > 0 {
+			return
+		}
+
+		for _, scope := range scopes {
+			a := attempts[scope.key]
+			a.Failures++
+			a.LastFailure = now
+
+			if scope.max > 0 && a.Failures == scope.max {
+				res.Lockouts = append(res.Lockouts, LoginLockout{Scope: scope.name, Until: t.lockedUntil(a)})
+			}
+
+			attempts[scope.key] = a
+		}
+	})
+	if err != nil {
+		return LoginAttempt{}, err
+	}
+
+	return res, nil
+}
+
+// Succeeded forgets the failures of a user, and uncounts the attempt of the
+// client address. Its other failures are kept, so that signing in to one
+// account doesn't allow to keep guessing the password of others.
+func (t *LoginAttemptTracker) Succeeded(ctx context.Context, username, ip string) error {
+	now := t.now()
+
+	return t.store.update(ctx, func(attempts map[string]loginAttempts) {
+		t.prune(attempts, now)
+
+		delete(attempts, userAttemptsKey(username))
+
+		key := ipAttemptsKey(ip)
+		if a, ok := attempts[key]; ok {
+			a.Failures--
+			if a.Failures <= 0 {
+				delete(attempts, key)
+			} else {
+				attempts[key] = a
+			}
+		}
+	})
+}
+
+type loginAttemptScope struct {
+	name, key string
+	max       int
+}
+
+func (t *LoginAttemptTracker) scopes(username, ip string) []loginAttemptScope {
+	return []loginAttemptScope{
+		{"user", userAttemptsKey(username), t.cfg.MaxFailures},
+		{"ip", ipAttemptsKey(ip), t.cfg.MaxFailuresPerIP},
+	}
+}
+
+// delay is how long to wait after the given number of failures.
+func (t *LoginAttemptTracker) delay(scope string, failures int) time.Duration {
+	// Client addresses may be shared, they are only locked out.
+	if scope == "ip" || failures < t.cfg.FreeFailures {
+		return 0
+	}
+
+	d := t.cfg.BaseDelay
+	for i := t.cfg.FreeFailures; i < failures && d < t.cfg.MaxDelay; i++ {
+		d *= 2
+	}
+
+	if d > t.cfg.MaxDelay {
+		d = t.cfg.MaxDelay
+	}
+
+	return d
+}
+
+func (t *LoginAttemptTracker) lockedUntil(a loginAttempts) time.Time {
+	return a.LastFailure.Add(t.cfg.LockoutDuration)
+}
+
+// prune drops the attempts that are no longer throttled or locked out, so
+// that the store doesn't keep growing.
+func (t *LoginAttemptTracker) prune(attempts map[string]loginAttempts, now time.Time) {
+	for key, a := range attempts {
+		if !now.Before(t.lockedUntil(a)) {
+			delete(attempts, key)
+		}
+	}
+}
+
+// The keys are hashed so that they are valid config map keys, and usernames
+// are not stored.
+func userAttemptsKey(username string) string {
+	return "user-" + attemptsKeyHash(username)
+}
+
+func ipAttemptsKey(ip string) string {
+	return "ip-" + attemptsKeyHash(ip)
+}
+
+func attemptsKeyHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:16])
+}
+
+// clientIP returns the address of the client that made the request. The
+// X-Forwarded-For header is only read when the request comes from a trusted
+// proxy, as it can be set by the client, and the address is the last one
+// that wasn't added by a trusted proxy.
+func (t *LoginAttemptTracker) clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	if !t.trusted(ip) {
+		return ip
+	}
+
+	var forwarded []string
+
+	for _, h := range r.Header.Values("X-Forwarded-For") {
+		for _, addr := range strings.Split(h, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				forwarded = append(forwarded, addr)
+			}
+		}
+	}
+
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip = forwarded[i]
+
+		if !t.trusted(ip) {
+			break
+		}
+	}
+
+	return ip
+}
+
+func (t *LoginAttemptTracker) trusted(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+
+	for _, n := range t.trustedProxies {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseTrustedProxies parses addresses and CIDR ranges.
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	var res []*net.IPNet
+
+	for _, p := range proxies {
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy address %q", p)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+
+			res = append(res, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+
+			continue
+		}
+
+		_, n, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy range %q: %w", p, err)
+		}
+
+		res = append(res, n)
+	}
+
+	return res, nil
+}
+
+type memoryLoginAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]loginAttempts
+}
+
+func (m *memoryLoginAttemptStore) update(_ context.Context, fn func(map[string]loginAttempts)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fn(m.attempts)
+
+	return nil
+}
+
+type kubeLoginAttemptStore struct {
+	data objectData
+}
+
+func (k *kubeLoginAttemptStore) update(ctx context.Context, fn func(map[string]loginAttempts)) error {
+	return k.data.update(ctx, func(data map[string][]byte) error {
+		attempts, err := k.decode(data)
+		if err != nil {
+			return err
+		}
+
+		fn(attempts)
+
+		for key := range data {
+			delete(data, key)
+		}
+
+		for key, a := range attempts {
+			v, err := json.Marshal(a)
+			if err != nil {
+				return err
+			}
+
+			data[key] = v
+		}
+
+		return nil
+	})
+}
+
+func (k *kubeLoginAttemptStore) decode(data map[string][]byte) (map[string]loginAttempts, error) {
+	attempts := map[string]loginAttempts{}
+
+	for key, v := range data {
+		var a loginAttempts
+		if err := json.Unmarshal(v, &a); err != nil {
+			return nil, fmt.Errorf("invalid login attempts %q in %s %s: %w", key, k.data.kind, k.data.key, err)
+		}
+
+		attempts[key] = a
+	}
+
+	return attempts, nil
+}
@@ -0,0 +1,339 @@
+package auth_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/weaveworks/weave-gitops/pkg/server/auth"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlclientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestLoginAttemptTracker(t *testing.T) {
+	for _, store := range []string{auth.LoginAttemptsStoreMemory, auth.LoginAttemptsStoreConfigMap} {
+		t.Run(store, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			ctx := context.Background()
+
+			tracker, err := auth.NewLoginAttemptTracker(auth.LoginAttemptsConfig{
+				Store:            store,
+				StoreName:        auth.DefaultLoginAttemptsStoreName,
+				FreeFailures:     100,
+				MaxFailures:      3,
+				MaxFailuresPerIP: 5,
+				LockoutDuration:  time.Hour,
+			}, ctrlclientfake.NewClientBuilder().Build(), testNamespace)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			attempt := func(username, ip string) auth.LoginAttempt {
+				a, err := tracker.Attempt(ctx, username, ip)
+				g.Expect(err).NotTo(HaveOccurred())
+
+				return a
+			}
+
+			g.Expect(attempt("alice", "10.0.0.1")).To(Equal(auth.LoginAttempt{}))
+			g.Expect(attempt("alice", "10.0.0.1")).To(Equal(auth.LoginAttempt{}))
+
+			// The third failure would lock the user out.
+			a := attempt("alice", "10.0.0.1")
+			g.Expect(a.Wait).To(BeZero())
+			g.Expect(a.Lockouts).To(HaveLen(1))
+			g.Expect(a.Lockouts[0].Scope).To(Equal("user"))
+
+			a = attempt("alice", "10.0.0.2")
+			g.Expect(a.Locked).To(BeTrue())
+			g.Expect(a.Wait).To(BeNumerically("~", time.Hour, time.Minute))
+
+			// Signing in resets the user, and uncounts the attempt of the
+			// address.
+			g.Expect(tracker.Succeeded(ctx, "alice", "10.0.0.1")).To(Succeed())
+			g.Expect(attempt("alice", "10.0.0.1")).To(Equal(auth.LoginAttempt{}))
+
+			// Guessing the passwords of many users locks the address out.
+			g.Expect(attempt("bob", "10.0.0.1")).To(Equal(auth.LoginAttempt{}))
+
+			a = attempt("carol", "10.0.0.1")
+			g.Expect(a.Lockouts).To(HaveLen(1))
+			g.Expect(a.Lockouts[0].Scope).To(Equal("ip"))
+
+			g.Expect(attempt("erin", "10.0.0.1").Locked).To(BeTrue())
+			g.Expect(attempt("erin", "10.0.0.2")).To(Equal(auth.LoginAttempt{}))
+		})
+	}
+}
+
+func TestLoginAttemptTrackerDelays(t *testing.T) {
+	for _, store := range []string{auth.LoginAttemptsStoreMemory, auth.LoginAttemptsStoreConfigMap} {
+		t.Run(store, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			ctx := context.Background()
+
+			tracker, err := auth.NewLoginAttemptTracker(auth.LoginAttemptsConfig{
+				Store:           store,
+				StoreName:       auth.DefaultLoginAttemptsStoreName,
+				FreeFailures:    2,
+				BaseDelay:       time.Minute,
+				MaxDelay:        4 * time.Minute,
+				LockoutDuration: time.Hour,
+			}, ctrlclientfake.NewClientBuilder().Build(), testNamespace)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			// Concurrent attempts are counted before they're checked, so
+			// only the free ones get through.
+			var (
+				wg      sync.WaitGroup
+				mu      sync.Mutex
+				allowed int
+			)
+
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+
+				go func() {
+					defer wg.Done()
+
+					a, err := tracker.Attempt(ctx, "alice", "10.0.0.1")
+					if err == nil && a.Wait == 0 {
+						mu.Lock()
+						allowed++
+						mu.Unlock()
+					}
+				}()
+			}
+
+			wg.Wait()
+			g.Expect(allowed).To(Equal(2))
+
+			a, err := tracker.Attempt(ctx, "alice", "10.0.0.1")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(a.Locked).To(BeFalse())
+			g.Expect(a.Wait).To(BeNumerically("~", time.Minute, time.Second))
+
+			// Other users can still sign in from the same address.
+			a, err = tracker.Attempt(ctx, "bob", "10.0.0.1")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(a.Wait).To(BeZero())
+		})
+	}
+}
+
+func TestLoginAttemptTrackerPrunesExpiredAttempts(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.Background()
+
+	fakeKubernetesClient := ctrlclientfake.NewClientBuilder().Build()
+
+	cfg := auth.DefaultLoginAttemptsConfig()
+	cfg.LockoutDuration = 10 * time.Millisecond
+
+	tracker, err := auth.NewLoginAttemptTracker(cfg, fakeKubernetesClient, testNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	_, err = tracker.Attempt(ctx, "alice", "10.0.0.1")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	time.Sleep(20 * time.Millisecond)
+
+	_, err = tracker.Attempt(ctx, "bob", "10.0.0.2")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	var cm corev1.ConfigMap
+	g.Expect(fakeKubernetesClient.Get(ctx, ctrlclient.ObjectKey{Namespace: testNamespace, Name: auth.DefaultLoginAttemptsStoreName}, &cm)).To(Succeed())
+	g.Expect(cm.Data).To(HaveLen(2), "only bob and their address are left")
+}
+
+func TestNewLoginAttemptTrackerInvalidTrustedProxy(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cfg := auth.DefaultLoginAttemptsConfig()
+	cfg.TrustedProxies = []string{"10.0.0.0/8", "192.0.2.1", "proxy"}
+
+	_, err := auth.NewLoginAttemptTracker(cfg, nil, testNamespace)
+	g.Expect(err).To(MatchError(ContainSubstring(`invalid trusted proxy address "proxy"`)))
+}
+
+func TestNewLoginAttemptTrackerUnknownStore(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cfg := auth.DefaultLoginAttemptsConfig()
+	cfg.Store = "redis"
+
+	_, err := auth.NewLoginAttemptTracker(cfg, nil, testNamespace)
+	g.Expect(err).To(MatchError(ContainSubstring(`unknown login attempts store "redis"`)))
+}
+
+func TestSignInLockout(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	hash, err := auth.HashPassword("alice-password")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      auth.ClusterUserAuthSecretName,
+			Namespace: testNamespace,
+		},
+	}
+
+	g.Expect(auth.SetLocalUsers(secret, []auth.LocalUser{
+		{Username: "alice", PasswordHash: hash},
+	})).To(Succeed())
+
+	fakeKubernetesClient := ctrlclientfake.NewClientBuilder().WithObjects(secret).Build()
+
+	tokenSignerVerifier, err := auth.NewHMACTokenSignerVerifier(5 * time.Minute)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	s, _ := makeAuthServer(t, fakeKubernetesClient, tokenSignerVerifier, []auth.AuthMethod{auth.UserAccount})
+
+	cfg := auth.DefaultLoginAttemptsConfig()
+	cfg.MaxFailures = 3
+
+	tracker, err := auth.NewLoginAttemptTracker(cfg, fakeKubernetesClient, testNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	s.SetLoginAttemptTracker(tracker)
+
+	signIn := func(password string) *httptest.ResponseRecorder {
+		j, err := json.Marshal(auth.LoginRequest{Username: "alice", Password: password})
+		g.Expect(err).NotTo(HaveOccurred())
+
+		w := httptest.NewRecorder()
+		s.SignIn().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "https://example.com/signin", bytes.NewReader(j)))
+
+		return w
+	}
+
+	for i := 0; i < 3; i++ {
+		g.Expect(signIn("wrong")).To(HaveHTTPStatus(http.StatusUnauthorized))
+	}
+
+	// Once locked out, not even the right password is accepted.
+	w := signIn("alice-password")
+	g.Expect(w).To(HaveHTTPStatus(http.StatusTooManyRequests))
+	g.Expect(w.Header().Get("Retry-After")).To(Equal("900"))
+
+	var cm corev1.ConfigMap
+	g.Expect(fakeKubernetesClient.Get(context.Background(), ctrlclient.ObjectKey{Namespace: testNamespace, Name: auth.DefaultLoginAttemptsStoreName}, &cm)).To(Succeed())
+	g.Expect(cm.Data).To(HaveLen(2), "the user and the client address are tracked")
+}
+
+func TestSignInLockoutBehindProxy(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	hash, err := auth.HashPassword("alice-password")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      auth.ClusterUserAuthSecretName,
+			Namespace: testNamespace,
+		},
+	}
+
+	g.Expect(auth.SetLocalUsers(secret, []auth.LocalUser{
+		{Username: "alice", PasswordHash: hash},
+	})).To(Succeed())
+
+	fakeKubernetesClient := ctrlclientfake.NewClientBuilder().WithObjects(secret).Build()
+
+	tokenSignerVerifier, err := auth.NewHMACTokenSignerVerifier(5 * time.Minute)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	s, _ := makeAuthServer(t, fakeKubernetesClient, tokenSignerVerifier, []auth.AuthMethod{auth.UserAccount})
+
+	cfg := auth.DefaultLoginAttemptsConfig()
+	cfg.MaxFailuresPerIP = 2
+	cfg.TrustedProxies = []string{"192.0.2.0/24"}
+
+	tracker, err := auth.NewLoginAttemptTracker(cfg, fakeKubernetesClient, testNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	s.SetLoginAttemptTracker(tracker)
+
+	signIn := func(username, remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+		j, err := json.Marshal(auth.LoginRequest{Username: username, Password: "wrong"})
+		g.Expect(err).NotTo(HaveOccurred())
+
+		req := httptest.NewRequest(http.MethodPost, "https://example.com/signin", bytes.NewReader(j))
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+
+		w := httptest.NewRecorder()
+		s.SignIn().ServeHTTP(w, req)
+
+		return w
+	}
+
+	g.Expect(signIn("bob", "192.0.2.1:1234", "10.0.0.1")).To(HaveHTTPStatus(http.StatusUnauthorized))
+	g.Expect(signIn("carol", "192.0.2.2:1234", "203.0.113.9, 10.0.0.1")).To(HaveHTTPStatus(http.StatusUnauthorized))
+
+	// The client behind the proxy is locked out, not the proxy.
+	g.Expect(signIn("alice", "192.0.2.1:1234", "10.0.0.1")).To(HaveHTTPStatus(http.StatusTooManyRequests))
+	g.Expect(signIn("alice", "192.0.2.1:1234", "10.0.0.2")).To(HaveHTTPStatus(http.StatusUnauthorized))
+
+	// Untrusted clients can't pick their address.
+	g.Expect(signIn("alice", "198.51.100.1:1234", "10.0.0.3")).To(HaveHTTPStatus(http.StatusUnauthorized))
+	g.Expect(signIn("alice", "198.51.100.1:1234", "10.0.0.4")).To(HaveHTTPStatus(http.StatusUnauthorized))
+	g.Expect(signIn("alice", "198.51.100.1:1234", "10.0.0.5")).To(HaveHTTPStatus(http.StatusTooManyRequests))
+}
+
+// failingWritesClient can read objects, but not write them.
+type failingWritesClient struct {
+	ctrlclient.Client
+}
+
+func (c failingWritesClient) Create(ctx context.Context, obj ctrlclient.Object, opts ...ctrlclient.CreateOption) error {
+	return errors.New("forbidden")
+}
+
+func (c failingWritesClient) Update(ctx context.Context, obj ctrlclient.Object, opts ...ctrlclient.UpdateOption) error {
+	return errors.New("forbidden")
+}
+
+func TestSignInFailsClosedWhenAttemptsCantBeRecorded(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	hash, err := auth.HashPassword("alice-password")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      auth.ClusterUserAuthSecretName,
+			Namespace: testNamespace,
+		},
+	}
+
+	g.Expect(auth.SetLocalUsers(secret, []auth.LocalUser{
+		{Username: "alice", PasswordHash: hash},
+	})).To(Succeed())
+
+	fakeKubernetesClient := failingWritesClient{Client: ctrlclientfake.NewClientBuilder().WithObjects(secret).Build()}
+
+	tokenSignerVerifier, err := auth.NewHMACTokenSignerVerifier(5 * time.Minute)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	s, _ := makeAuthServer(t, fakeKubernetesClient, tokenSignerVerifier, []auth.AuthMethod{auth.UserAccount})
+
+	tracker, err := auth.NewLoginAttemptTracker(auth.DefaultLoginAttemptsConfig(), fakeKubernetesClient, testNamespace)
+	g.Expect(err).NotTo(HaveOccurred())
+	s.SetLoginAttemptTracker(tracker)
+
+	for _, password := range []string{"wrong", "alice-password"} {
+		j, err := json.Marshal(auth.LoginRequest{Username: "alice", Password: password})
+		g.Expect(err).NotTo(HaveOccurred())
+
+		w := httptest.NewRecorder()
+		s.SignIn().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "https://example.com/signin", bytes.NewReader(j)))
+		g.Expect(w).To(HaveHTTPStatus(http.StatusInternalServerError))
+	}
+}
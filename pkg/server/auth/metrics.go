@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	signInFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "gitops",
+		Subsystem: "auth",
+		Name:      "sign_in_failures_total",
+		Help:      "Number of sign-in attempts with a wrong username or password.",
+	})
+
+	signInThrottled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gitops",
+		Subsystem: "auth",
+		Name:      "sign_in_throttled_total",
+		Help:      "Number of sign-in attempts rejected because of earlier failures.",
+	}, []string{"reason"})
+
+	signInLockouts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gitops",
+		Subsystem: "auth",
+		Name:      "sign_in_lockouts_total",
+		Help:      "Number of users or client addresses locked out after repeated sign-in failures.",
+	}, []string{"scope"})
+)
+
+func init() {
+	prometheus.MustRegister(signInFailures, signInThrottled, signInLockouts)
+}
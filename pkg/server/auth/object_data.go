@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The kinds of object that objectData can keep data in.
+const (
+	objectKindSecret    = "secret"
+	objectKindConfigMap = "configmap"
+)
+
+// objectData keeps the state of the server in the data of a secret or config
+// map, so that it's shared between replicas. The object is created when it's
+// first written to.
+type objectData struct {
+	client ctrlclient.Client
+	kind   string
+	key    ctrlclient.ObjectKey
+}
+
+// read returns the data of the object, which is empty if it doesn't exist.
+func (o objectData) read(ctx context.Context) (map[string][]byte, error) {
+	obj := o.newObject()
+	if err := o.client.Get(ctx, o.key, obj); err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("could not read %s %s: %w", o.kind, o.key, err)
+	}
+
+	return o.data(obj), nil
+}
+
+// update applies fn to the data of the object and writes it back, retrying on
+// conflicts with other replicas, including when one of them created the object
+// first.
+func (o objectData) update(ctx context.Context, fn func(map[string][]byte) error) error {
+	return retry.OnError(retry.DefaultRetry, isWriteConflict, func() error {
+		obj := o.newObject()
+		exists := true
+
+		if err := o.client.Get(ctx, o.key, obj); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return fmt.Errorf("could not read %s %s: %w", o.kind, o.key, err)
+			}
+
+			exists = false
+		}
+
+		data := o.data(obj)
+
+		if err := fn(data); err != nil {
+			return err
+		}
+
+		o.setData(obj, data)
+
+		if exists {
+			return o.client.Update(ctx, obj)
+		}
+
+		return o.client.Create(ctx, obj)
+	})
+}
+
+func isWriteConflict(err error) bool {
+	return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+}
+
+func (o objectData) newObject() ctrlclient.Object {
+	meta := metav1.ObjectMeta{
+		Name:      o.key.Name,
+		Namespace: o.key.Namespace,
+	}
+
+	if o.kind == objectKindConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: meta}
+	}
+
+	return &corev1.Secret{ObjectMeta: meta, Type: corev1.SecretTypeOpaque}
+}
+
+func (o objectData) data(obj ctrlclient.Object) map[string][]byte {
+	data := map[string][]byte{}
+
+	switch obj := obj.(type) {
+	case *corev1.Secret:
+		for k, v := range obj.Data {
+			data[k] = v
+		}
+	case *corev1.ConfigMap:
+		for k, v := range obj.Data {
+			data[k] = []byte(v)
+		}
+	}
+
+	return data
+}
+
+func (o objectData) setData(obj ctrlclient.Object, data map[string][]byte) {
+	switch obj := obj.(type) {
+	case *corev1.Secret:
+		obj.Data = data
+	case *corev1.ConfigMap:
+		obj.Data = map[string]string{}
+
+		for k, v := range data {
+			obj.Data[k] = string(v)
+		}
+	}
+}
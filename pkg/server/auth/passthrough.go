@@ -61,12 +61,14 @@ func (pg *BearerTokenPassthroughPrincipalGetter) Principal(r *http.Request) (*Us
 }
 
 // NewJWTPassthroughCookiePrincipalGetter creates and returns a new
-// JWTPassthroughCookiePrincipalGetter.
-func NewJWTPassthroughCookiePrincipalGetter(log logr.Logger, verifier *oidc.IDTokenVerifier, cookieName string) PrincipalGetter {
+// JWTPassthroughCookiePrincipalGetter. If claims is nil the default claim
+// mapping is used.
+func NewJWTPassthroughCookiePrincipalGetter(log logr.Logger, verifier *oidc.IDTokenVerifier, cookieName string, claims *ClaimsConfig) PrincipalGetter {
 	return &JWTPassthroughCookiePrincipalGetter{
 		log:        log,
 		verifier:   verifier,
 		cookieName: cookieName,
+		claims:     claims,
 	}
 }
 
@@ -78,6 +80,7 @@ type JWTPassthroughCookiePrincipalGetter struct {
 	log        logr.Logger
 	verifier   *oidc.IDTokenVerifier
 	cookieName string
+	claims     *ClaimsConfig
 }
 
 // Principal implements the PrincipalGetter by pasing the cookie, and if it's
@@ -88,7 +91,7 @@ func (pg *JWTPassthroughCookiePrincipalGetter) Principal(r *http.Request) (*User
 		return nil, nil
 	}
 
-	principal, err := parseJWTToken(r.Context(), pg.verifier, cookie.Value)
+	principal, err := parseJWTToken(r.Context(), pg.verifier, pg.claims, cookie.Value)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse for passthrough: %w", err)
 	}
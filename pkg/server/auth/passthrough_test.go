@@ -31,7 +31,7 @@ func TestPassthroughPrincipalGetter(t *testing.T) {
 
 	for _, tt := range authTests {
 		t.Run(tt.name, func(t *testing.T) {
-			principal, err := auth.NewJWTPassthroughCookiePrincipalGetter(logr.Discard(), verifier, cookieName).Principal(makeCookieRequest(cookieName, tt.cookie))
+			principal, err := auth.NewJWTPassthroughCookiePrincipalGetter(logr.Discard(), verifier, cookieName, nil).Principal(makeCookieRequest(cookieName, tt.cookie))
 			if err != nil {
 				t.Fatal(err)
 			}
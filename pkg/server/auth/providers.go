@@ -0,0 +1,320 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/golang-jwt/jwt/v4"
+	"golang.org/x/oauth2"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultOIDCProviderName is the name under which the provider configured
+// with the OIDC flags, or the top-level keys of the OIDC secret, is listed.
+// Its login and callback routes are the prefix itself and <prefix>/callback.
+const DefaultOIDCProviderName = "oidc"
+
+// OIDCProvidersSecretKey is the key in the OIDC secret that holds the
+// configuration of additional named providers.
+const OIDCProvidersSecretKey = "providers"
+
+var (
+	providerNameRegexp = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+	// Names that would clash with the other routes registered by RegisterAuthServer.
+	reservedProviderNames = []string{DefaultOIDCProviderName, "callback", "sign_in", "userinfo", "logout", "providers", LoginGitHub, LoginGitLab}
+)
+
+// OIDCProviderInfo describes a provider the user can log in with.
+type OIDCProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+	LoginURL    string `json:"loginURL"`
+}
+
+// oidcProviderSpec is the serialised form of an OIDCConfig, as found in the
+// providers file or the providers key of the OIDC secret.
+type oidcProviderSpec struct {
+	Name                 string            `json:"name"`
+	DisplayName          string            `json:"displayName"`
+	IssuerURL            string            `json:"issuerURL"`
+	ClientID             string            `json:"clientID"`
+	ClientSecret         string            `json:"clientSecret"`
+	RedirectURL          string            `json:"redirectURL"`
+	TokenDuration        string            `json:"tokenDuration"`
+	RefreshTokenDuration string            `json:"refreshTokenDuration"`
+	UsernameClaim        string            `json:"usernameClaim"`
+	GroupsClaim          string            `json:"groupsClaim"`
+	UsernamePrefix       string            `json:"usernamePrefix"`
+	GroupsPrefix         string            `json:"groupsPrefix"`
+	RequiredClaims       map[string]string `json:"requiredClaims"`
+}
+
+// ParseOIDCProviders parses a list of named OIDC providers from YAML or JSON,
+// in the form:
+//
+//	providers:
+//	- name: corp
+//	  displayName: Corporate SSO
+//	  issuerURL: https://sso.example.com
+//	  clientID: weave-gitops
+//	  clientSecret: ...
+//	  redirectURL: https://gitops.example.com/oauth2/corp/callback
+func ParseOIDCProviders(data []byte) ([]OIDCConfig, error) {
+	var file struct {
+		Providers []oidcProviderSpec `json:"providers"`
+	}
+
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse OIDC providers: %w", err)
+	}
+
+	seen := map[string]bool{}
+	res := []OIDCConfig{}
+
+	for _, spec := range file.Providers {
+		if err := validateProviderName(spec.Name); err != nil {
+			return nil, err
+		}
+
+		if seen[spec.Name] {
+			return nil, fmt.Errorf("OIDC provider %q is configured more than once", spec.Name)
+		}
+
+		seen[spec.Name] = true
+
+		if spec.IssuerURL == "" || spec.ClientID == "" {
+			return nil, fmt.Errorf("OIDC provider %q must set issuerURL and clientID", spec.Name)
+		}
+
+		cfg := OIDCConfig{
+			Name:                 spec.Name,
+			DisplayName:          spec.DisplayName,
+			IssuerURL:            spec.IssuerURL,
+			ClientID:             spec.ClientID,
+			ClientSecret:         spec.ClientSecret,
+			RedirectURL:          spec.RedirectURL,
+			TokenDuration:        time.Hour,
+			RefreshTokenDuration: DefaultRefreshTokenDuration,
+			UsernameClaim:        spec.UsernameClaim,
+			GroupsClaim:          spec.GroupsClaim,
+			UsernamePrefix:       spec.UsernamePrefix,
+			GroupsPrefix:         spec.GroupsPrefix,
+			RequiredClaims:       spec.RequiredClaims,
+		}
+
+		if spec.TokenDuration != "" {
+			d, err := time.ParseDuration(spec.TokenDuration)
+			if err != nil {
+				return nil, fmt.Errorf("invalid tokenDuration for OIDC provider %q: %w", spec.Name, err)
+			}
+
+			cfg.TokenDuration = d
+		}
+
+		if spec.RefreshTokenDuration != "" {
+			d, err := time.ParseDuration(spec.RefreshTokenDuration)
+			if err != nil {
+				return nil, fmt.Errorf("invalid refreshTokenDuration for OIDC provider %q: %w", spec.Name, err)
+			}
+
+			cfg.RefreshTokenDuration = d
+		}
+
+		res = append(res, cfg)
+	}
+
+	return res, nil
+}
+
+// ReadOIDCProvidersFile reads a list of named OIDC providers from a file,
+// see ParseOIDCProviders.
+func ReadOIDCProvidersFile(path string) ([]OIDCConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OIDC providers file: %w", err)
+	}
+
+	return ParseOIDCProviders(data)
+}
+
+func validateProviderName(name string) error {
+	if !providerNameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid OIDC provider name %q, it must consist of lower case alphanumeric characters or '-'", name)
+	}
+
+	if contains(reservedProviderNames, name) {
+		return fmt.Errorf("invalid OIDC provider name %q, it is reserved", name)
+	}
+
+	return nil
+}
+
+// oidcProvider is an OIDC issuer the AuthServer can log users in with.
+type oidcProvider struct {
+	// name is empty for the default provider.
+	name     string
+	config   *OIDCConfig
+	provider *oidc.Provider
+}
+
+func newOIDCProvider(ctx context.Context, name string, cfg *OIDCConfig) (*oidcProvider, error) {
+	provider, err := oidc.NewProvider(ctx, cfg.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("could not create provider: %w", err)
+	}
+
+	return &oidcProvider{
+		name:     name,
+		config:   cfg,
+		provider: provider,
+	}, nil
+}
+
+func (p *oidcProvider) verifier() *oidc.IDTokenVerifier {
+	return p.provider.Verifier(&oidc.Config{ClientID: p.config.ClientID})
+}
+
+func (p *oidcProvider) oauth2Config(scopes []string) *oauth2.Config {
+	// Ensure "openid" scope is always present.
+	if !contains(scopes, oidc.ScopeOpenID) {
+		scopes = append(scopes, oidc.ScopeOpenID)
+	}
+
+	// Request "email" scope to get user's email address.
+	if !contains(scopes, scopeEmail) {
+		scopes = append(scopes, scopeEmail)
+	}
+
+	// Request "groups" scope to get user's groups.
+	if !contains(scopes, scopeGroups) {
+		scopes = append(scopes, scopeGroups)
+	}
+
+	// Request "offline_access" scope to get a refresh token.
+	if !contains(scopes, scopeOfflineAccess) {
+		scopes = append(scopes, scopeOfflineAccess)
+	}
+
+	return &oauth2.Config{
+		ClientID:     p.config.ClientID,
+		ClientSecret: p.config.ClientSecret,
+		Endpoint:     p.provider.Endpoint(),
+		RedirectURL:  p.config.RedirectURL,
+		Scopes:       scopes,
+	}
+}
+
+func (p *oidcProvider) info(prefix string) OIDCProviderInfo {
+	name := p.name
+	loginURL := prefix
+
+	if name == "" {
+		name = DefaultOIDCProviderName
+	} else {
+		loginURL = prefix + "/" + p.name
+	}
+
+	displayName := p.config.DisplayName
+	if displayName == "" {
+		displayName = name
+	}
+
+	return OIDCProviderInfo{
+		Name:        name,
+		DisplayName: displayName,
+		LoginURL:    loginURL,
+	}
+}
+
+// oidcProvider returns the provider with the given name, the empty name being
+// the default provider.
+func (s *AuthServer) oidcProvider(name string) *oidcProvider {
+	for _, p := range s.providers {
+		if p.name == name {
+			return p
+		}
+	}
+
+	return nil
+}
+
+// providerForToken finds the provider that issued a token by looking at its
+// unverified issuer claim. The token still needs to be verified by the
+// provider returned.
+func (s *AuthServer) providerForToken(rawToken string) *oidcProvider {
+	if len(s.providers) == 1 {
+		return s.providers[0]
+	}
+
+	claims := jwt.RegisteredClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(rawToken, &claims); err == nil {
+		var issuerMatch *oidcProvider
+
+		for _, p := range s.providers {
+			if p.config.IssuerURL != claims.Issuer {
+				continue
+			}
+
+			// Several clients may be registered with the same issuer.
+			if claims.VerifyAudience(p.config.ClientID, true) {
+				return p
+			}
+
+			if issuerMatch == nil {
+				issuerMatch = p
+			}
+		}
+
+		if issuerMatch != nil {
+			return issuerMatch
+		}
+	}
+
+	if len(s.providers) > 0 {
+		return s.providers[0]
+	}
+
+	return nil
+}
+
+// Providers lists the OIDC providers users can log in with, so the UI can
+// offer a choice between them.
+func (s *AuthServer) Providers(prefix string) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			rw.Header().Add("Allow", "GET")
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		res := struct {
+			Providers []OIDCProviderInfo `json:"providers"`
+		}{
+			Providers: []OIDCProviderInfo{},
+		}
+
+		if s.oidcEnabled() {
+			for _, p := range s.providers {
+				res.Providers = append(res.Providers, p.info(prefix))
+			}
+		}
+
+		for _, l := range s.gitLogins {
+			res.Providers = append(res.Providers, l.info(prefix))
+		}
+
+		rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		if err := json.NewEncoder(rw).Encode(res); err != nil {
+			s.Log.Error(err, "Failing to write response")
+		}
+	}
+}
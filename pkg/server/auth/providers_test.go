@@ -0,0 +1,240 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/oauth2-proxy/mockoidc"
+	. "github.com/onsi/gomega"
+	"github.com/weaveworks/weave-gitops/pkg/featureflags"
+	"github.com/weaveworks/weave-gitops/pkg/server/auth"
+	ctrlclientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestParseOIDCProviders(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []auth.OIDCConfig
+		wantErr string
+	}{
+		{
+			name: "empty",
+			data: "",
+			want: []auth.OIDCConfig{},
+		},
+		{
+			name: "defaults durations",
+			data: `
+providers:
+- name: corp
+  displayName: Corporate SSO
+  issuerURL: https://sso.example.com
+  clientID: weave-gitops
+  clientSecret: secret
+  redirectURL: https://gitops.example.com/oauth2/corp/callback
+  groupsPrefix: "corp:"
+- name: partners
+  issuerURL: https://partners.example.com
+  clientID: weave-gitops
+  tokenDuration: 20m
+  refreshTokenDuration: 24h
+`,
+			want: []auth.OIDCConfig{
+				{
+					Name:                 "corp",
+					DisplayName:          "Corporate SSO",
+					IssuerURL:            "https://sso.example.com",
+					ClientID:             "weave-gitops",
+					ClientSecret:         "secret",
+					RedirectURL:          "https://gitops.example.com/oauth2/corp/callback",
+					TokenDuration:        time.Hour,
+					RefreshTokenDuration: auth.DefaultRefreshTokenDuration,
+					GroupsPrefix:         "corp:",
+				},
+				{
+					Name:                 "partners",
+					IssuerURL:            "https://partners.example.com",
+					ClientID:             "weave-gitops",
+					TokenDuration:        20 * time.Minute,
+					RefreshTokenDuration: 24 * time.Hour,
+				},
+			},
+		},
+		{
+			name:    "reserved name",
+			data:    "providers:\n- name: callback\n  issuerURL: https://sso.example.com\n  clientID: a\n",
+			wantErr: "reserved",
+		},
+		{
+			name:    "invalid name",
+			data:    "providers:\n- name: Corp SSO\n  issuerURL: https://sso.example.com\n  clientID: a\n",
+			wantErr: "invalid OIDC provider name",
+		},
+		{
+			name:    "duplicate name",
+			data:    "providers:\n- name: corp\n  issuerURL: https://sso.example.com\n  clientID: a\n- name: corp\n  issuerURL: https://sso.example.com\n  clientID: b\n",
+			wantErr: "more than once",
+		},
+		{
+			name:    "missing issuer",
+			data:    "providers:\n- name: corp\n  clientID: a\n",
+			wantErr: "must set issuerURL and clientID",
+		},
+		{
+			name:    "unknown field",
+			data:    "providers:\n- name: corp\n  issuer: https://sso.example.com\n",
+			wantErr: "failed to parse",
+		},
+		{
+			name:    "invalid duration",
+			data:    "providers:\n- name: corp\n  issuerURL: https://sso.example.com\n  clientID: a\n  tokenDuration: soon\n",
+			wantErr: "invalid tokenDuration",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			providers, err := auth.ParseOIDCProviders([]byte(tt.data))
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				return
+			}
+
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(providers).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestProvidersListsDefaultAndNamedProviders(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s, _, _ := makeMultiProviderAuthServer(t)
+
+	w := httptest.NewRecorder()
+	s.Providers("/oauth2").ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/oauth2/providers", nil))
+
+	g.Expect(w).To(HaveHTTPStatus(http.StatusOK))
+
+	var res struct {
+		Providers []auth.OIDCProviderInfo `json:"providers"`
+	}
+
+	g.Expect(json.NewDecoder(w.Body).Decode(&res)).To(Succeed())
+	g.Expect(res.Providers).To(Equal([]auth.OIDCProviderInfo{
+		{Name: auth.DefaultOIDCProviderName, DisplayName: auth.DefaultOIDCProviderName, LoginURL: "/oauth2"},
+		{Name: "corp", DisplayName: "Corporate SSO", LoginURL: "/oauth2/corp"},
+	}))
+}
+
+func TestProviderOAuth2FlowRedirectsToNamedProvider(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s, m, corp := makeMultiProviderAuthServer(t)
+
+	w := httptest.NewRecorder()
+	s.ProviderOAuth2Flow("corp").ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/oauth2/corp", nil))
+
+	g.Expect(w).To(HaveHTTPStatus(http.StatusSeeOther))
+	g.Expect(w.Result().Header.Get("Location")).To(HavePrefix(corp.AuthorizationEndpoint()))
+
+	w = httptest.NewRecorder()
+	s.OAuth2Flow().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/oauth2", nil))
+
+	g.Expect(w).To(HaveHTTPStatus(http.StatusSeeOther))
+	g.Expect(w.Result().Header.Get("Location")).To(HavePrefix(m.AuthorizationEndpoint()))
+
+	w = httptest.NewRecorder()
+	s.ProviderOAuth2Flow("unknown").ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/oauth2/unknown", nil))
+
+	g.Expect(w).To(HaveHTTPStatus(http.StatusBadRequest))
+}
+
+func TestWithAPIAuthAcceptsTokensFromNamedProviders(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s, _, corp := makeMultiProviderAuthServer(t)
+
+	token, err := corp.Keypair.SignJWT(jwt.MapClaims{
+		"iss":   corp.Issuer(),
+		"sub":   "1234",
+		"aud":   corp.Config().ClientID,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"email": "jane.doe@example.com",
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	var principal *auth.UserPrincipal
+
+	handler := auth.WithAPIAuth(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		principal = auth.Principal(r.Context())
+	}), s, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/v1/objects", nil)
+	req.AddCookie(&http.Cookie{Name: auth.IDTokenCookieName, Value: token})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	g.Expect(w).To(HaveHTTPStatus(http.StatusOK))
+	g.Expect(principal).NotTo(BeNil())
+	g.Expect(principal.ID).To(Equal("jane.doe@example.com"))
+}
+
+// makeMultiProviderAuthServer creates an AuthServer with a default provider
+// and a named "corp" provider, each backed by their own mock OIDC issuer.
+func makeMultiProviderAuthServer(t *testing.T) (*auth.AuthServer, *mockoidc.MockOIDC, *mockoidc.MockOIDC) {
+	t.Helper()
+	g := NewGomegaWithT(t)
+
+	featureflags.Set("OIDC_AUTH", "")
+
+	m, err := mockoidc.Run()
+	g.Expect(err).NotTo(HaveOccurred())
+
+	corp, err := mockoidc.Run()
+	g.Expect(err).NotTo(HaveOccurred())
+
+	t.Cleanup(func() {
+		_ = m.Shutdown()
+		_ = corp.Shutdown()
+	})
+
+	oidcCfg := auth.OIDCConfig{
+		ClientID:     m.Config().ClientID,
+		ClientSecret: m.Config().ClientSecret,
+		IssuerURL:    m.Config().Issuer,
+	}
+
+	tsv, err := auth.NewHMACTokenSignerVerifier(5 * time.Minute)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	authCfg, err := auth.NewAuthServerConfig(logr.Discard(), oidcCfg, ctrlclientfake.NewClientBuilder().Build(), tsv, testNamespace, map[auth.AuthMethod]bool{auth.OIDC: true})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	g.Expect(authCfg.SetOIDCProviders([]auth.OIDCConfig{
+		{
+			Name:         "corp",
+			DisplayName:  "Corporate SSO",
+			IssuerURL:    corp.Config().Issuer,
+			ClientID:     corp.Config().ClientID,
+			ClientSecret: corp.Config().ClientSecret,
+			RedirectURL:  "https://example.com/oauth2/corp/callback",
+		},
+	})).To(Succeed())
+
+	s, err := auth.NewAuthServer(context.Background(), authCfg)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	return s, m, corp
+}
@@ -0,0 +1,301 @@
+package auth
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/cheshir/ttlcache"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"golang.org/x/sync/singleflight"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// RefreshTokenCookieName is the name of the cookie that holds the encrypted
+	// refresh token issued by the OIDC Provider. It's used to renew the ID token
+	// once it expires without sending the user through the login flow again.
+	RefreshTokenCookieName = "refresh_token"
+	// ScopeOfflineAccess is the "offline_access" scope, required by most
+	// providers to issue a refresh token.
+	scopeOfflineAccess = "offline_access"
+	// How long the result of a refresh is reused. The browser may send several
+	// requests with the old cookies before it sees the renewed ones, and
+	// providers that rotate refresh tokens will only accept the old one once.
+	refreshResultTTL = 30 * time.Second
+	// DefaultRefreshTokenKeySecretName is the name of the secret holding the
+	// key that encrypts refresh token cookies.
+	DefaultRefreshTokenKeySecretName = "gitops-refresh-token-key"
+	refreshTokenKeySecretKey         = "key"
+	refreshTokenKeySize              = 32
+)
+
+// ErrRefreshTokenInvalid is returned when the refresh token cookie can't be
+// decrypted, e.g. after the encryption key has changed.
+var ErrRefreshTokenInvalid = errors.New("invalid refresh token cookie")
+
+// cookieCipher encrypts and authenticates cookie values with AES-GCM.
+type cookieCipher struct {
+	aead cipher.AEAD
+}
+
+// newCookieCipher creates a cookieCipher from a key. The key is hashed so
+// that any length can be used. If the key is empty a random one is
+// generated, in which case cookies can't be read after a restart or by
+// other replicas.
+func newCookieCipher(key []byte) (*cookieCipher, error) {
+	var aesKey [32]byte
+
+	if len(key) == 0 {
+		if _, err := rand.Read(aesKey[:]); err != nil {
+			return nil, fmt.Errorf("could not generate random cookie key: %w", err)
+		}
+	} else {
+		aesKey = sha256.Sum256(append([]byte("weave-gitops-refresh-token:"), key...))
+	}
+
+	block, err := aes.NewCipher(aesKey[:])
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cookieCipher{aead: aead}, nil
+}
+
+// LoadRefreshTokenKey reads the key that encrypts refresh token cookies from
+// the secret, creating the secret with a random key if it doesn't exist yet.
+// The key doesn't depend on the OIDC client secrets, so that rotating them
+// doesn't log everybody out.
+func LoadRefreshTokenKey(ctx context.Context, c ctrlclient.Client, key ctrlclient.ObjectKey) ([]byte, error) {
+	var secret corev1.Secret
+
+	err := c.Get(ctx, key, &secret)
+	if apierrors.IsNotFound(err) {
+		value := make([]byte, refreshTokenKeySize)
+		if _, err := rand.Read(value); err != nil {
+			return nil, fmt.Errorf("could not generate refresh token key: %w", err)
+		}
+
+		secret = corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Type:       corev1.SecretTypeOpaque,
+			Data:       map[string][]byte{refreshTokenKeySecretKey: value},
+		}
+
+		err = c.Create(ctx, &secret)
+		if apierrors.IsAlreadyExists(err) {
+			// Another replica created it first.
+			err = c.Get(ctx, key, &secret)
+		}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not read refresh token key secret: %w", err)
+	}
+
+	value := secret.Data[refreshTokenKeySecretKey]
+	if len(value) < 32 {
+		return nil, fmt.Errorf("refresh token key in secret %s is shorter than 32 bytes", key)
+	}
+
+	return value, nil
+}
+
+func (c *cookieCipher) Encrypt(value string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, []byte(value), nil)
+
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+func (c *cookieCipher) Decrypt(value string) (string, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return "", ErrRefreshTokenInvalid
+	}
+
+	if len(sealed) < c.aead.NonceSize() {
+		return "", ErrRefreshTokenInvalid
+	}
+
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+
+	plain, err := c.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrRefreshTokenInvalid
+	}
+
+	return string(plain), nil
+}
+
+// refreshSession is the content of the refresh token cookie.
+type refreshSession struct {
+	Provider     string `json:"p"`
+	RefreshToken string `json:"t"`
+}
+
+// tokenRefresher exchanges refresh tokens with the OIDC Provider, reusing
+// recent results for the same refresh token. Concurrent refreshes of the
+// same token share a single request, other tokens don't wait for it.
+type tokenRefresher struct {
+	group singleflight.Group
+	cache *ttlcache.Cache
+}
+
+func newTokenRefresher() *tokenRefresher {
+	return &tokenRefresher{
+		cache: ttlcache.New(refreshResultTTL),
+	}
+}
+
+func (tr *tokenRefresher) Refresh(ctx context.Context, cfg *oauth2.Config, refreshToken string) (*oauth2.Token, error) {
+	key := ttlcache.StringKey(refreshToken)
+
+	if val, found := tr.cache.Get(key); found {
+		return val.(*oauth2.Token), nil
+	}
+
+	val, err, _ := tr.group.Do(refreshToken, func() (interface{}, error) {
+		// The token may have been refreshed since it was looked up.
+		if val, found := tr.cache.Get(key); found {
+			return val, nil
+		}
+
+		token, err := cfg.TokenSource(ctx, &oauth2.Token{RefreshToken: refreshToken}).Token()
+		if err != nil {
+			return nil, err
+		}
+
+		tr.cache.Set(key, token, refreshResultTTL)
+
+		return token, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return val.(*oauth2.Token), nil
+}
+
+// refreshSession uses the refresh token cookie to obtain a new ID token from
+// the OIDC Provider. The renewed cookies are set on the response, and a copy
+// of the request carrying them is returned so the principal can be read from
+// it.
+func (s *AuthServer) refreshSession(rw http.ResponseWriter, r *http.Request) (*http.Request, error) {
+	// A revoked session must not be renewed.
+	if _, err := s.checkSession(r, ""); err != nil {
+		return nil, err
+	}
+
+	cookie, err := r.Cookie(RefreshTokenCookieName)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := s.cookieCipher.Decrypt(cookie.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	var session refreshSession
+	if err := json.Unmarshal([]byte(value), &session); err != nil {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	p := s.oidcProvider(session.Provider)
+	if p == nil {
+		return nil, fmt.Errorf("OIDC provider %q is no longer configured", session.Provider)
+	}
+
+	ctx := oidc.ClientContext(r.Context(), s.client)
+
+	token, err := s.refresher.Refresh(ctx, p.oauth2Config(nil), session.RefreshToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh token: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("no id_token in refresh token response")
+	}
+
+	if _, err := p.verifier().Verify(ctx, rawIDToken); err != nil {
+		return nil, fmt.Errorf("failed to verify refreshed ID token: %w", err)
+	}
+
+	if err := s.setOIDCCookies(rw, p, token, rawIDToken); err != nil {
+		return nil, err
+	}
+
+	return withCookies(r, map[string]string{
+		IDTokenCookieName:     rawIDToken,
+		AccessTokenCookieName: token.AccessToken,
+	}), nil
+}
+
+// setOIDCCookies issues the cookies for a token obtained from the OIDC
+// Provider. The refresh token is only replaced if the provider sent a new one.
+func (s *AuthServer) setOIDCCookies(rw http.ResponseWriter, p *oidcProvider, token *oauth2.Token, rawIDToken string) error {
+	http.SetCookie(rw, s.newCookie(IDTokenCookieName, rawIDToken, p.config.TokenDuration))
+	http.SetCookie(rw, s.newCookie(AccessTokenCookieName, token.AccessToken, p.config.TokenDuration))
+
+	if token.RefreshToken == "" {
+		return nil
+	}
+
+	value, err := json.Marshal(refreshSession{Provider: p.name, RefreshToken: token.RefreshToken})
+	if err != nil {
+		return err
+	}
+
+	encrypted, err := s.cookieCipher.Encrypt(string(value))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt refresh token: %w", err)
+	}
+
+	http.SetCookie(rw, s.newCookie(RefreshTokenCookieName, encrypted, p.config.RefreshTokenDuration))
+
+	return nil
+}
+
+// withCookies returns a copy of the request with the named cookies replaced.
+func withCookies(r *http.Request, values map[string]string) *http.Request {
+	clone := r.Clone(r.Context())
+	clone.Header.Del("Cookie")
+
+	for _, c := range r.Cookies() {
+		if _, ok := values[c.Name]; ok {
+			continue
+		}
+
+		clone.AddCookie(c)
+	}
+
+	for name, value := range values {
+		clone.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+
+	return clone
+}
@@ -0,0 +1,145 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/oauth2-proxy/mockoidc"
+	"github.com/onsi/gomega"
+	. "github.com/onsi/gomega"
+	"github.com/weaveworks/weave-gitops/pkg/server/auth"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlclientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestWithAPIAuthRefreshesExpiredSession(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	tokenSignerVerifier, err := auth.NewHMACTokenSignerVerifier(5 * time.Minute)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	s, m := makeAuthServer(t, nil, tokenSignerVerifier, []auth.AuthMethod{auth.OIDC})
+
+	// Log in with the OIDC provider to get a refresh token cookie.
+	refreshCookie := findCookie(oidcSignIn(t, s, m), auth.RefreshTokenCookieName)
+	g.Expect(refreshCookie).NotTo(BeNil())
+	g.Expect(refreshCookie.HttpOnly).To(BeTrue())
+
+	// The ID token cookie has expired, only the refresh token is left.
+	var principal *auth.UserPrincipal
+
+	handler := auth.WithAPIAuth(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		principal = auth.Principal(r.Context())
+	}), s, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/v1/objects", nil)
+	req.AddCookie(refreshCookie)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	g.Expect(w).To(HaveHTTPStatus(http.StatusOK))
+	g.Expect(principal).NotTo(BeNil())
+	g.Expect(principal.ID).To(Equal("jane.doe@example.com"))
+
+	idTokenCookie := findCookie(w.Result().Cookies(), auth.IDTokenCookieName)
+	g.Expect(idTokenCookie).NotTo(BeNil())
+	g.Expect(idTokenCookie.Value).NotTo(BeEmpty())
+}
+
+func TestWithAPIAuthClearsSessionWhenRefreshFails(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	s, _ := makeAuthServer(t, nil, nil, []auth.AuthMethod{auth.OIDC})
+
+	handler := auth.WithAPIAuth(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}), s, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/v1/objects", nil)
+	req.AddCookie(&http.Cookie{Name: auth.RefreshTokenCookieName, Value: "not-encrypted"})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	g.Expect(w).To(HaveHTTPStatus(http.StatusUnauthorized))
+
+	refreshCookie := findCookie(w.Result().Cookies(), auth.RefreshTokenCookieName)
+	g.Expect(refreshCookie).NotTo(BeNil())
+	g.Expect(refreshCookie.Value).To(BeEmpty())
+}
+
+// oidcSignIn logs in with the mock OIDC provider through the callback of the
+// server, and returns the cookies it sets.
+func oidcSignIn(t *testing.T, s *auth.AuthServer, m *mockoidc.MockOIDC) []*http.Cookie {
+	t.Helper()
+
+	const code = "mnopqr"
+
+	g := gomega.NewGomegaWithT(t)
+
+	b, err := json.Marshal(auth.SessionState{Nonce: "ghijkl", ReturnURL: "/"})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	state := base64.StdEncoding.EncodeToString(b)
+
+	authorizeQuery := url.Values{}
+	authorizeQuery.Set("client_id", m.Config().ClientID)
+	authorizeQuery.Set("scope", "openid email profile groups")
+	authorizeQuery.Set("response_type", "code")
+	authorizeQuery.Set("redirect_uri", "https://example.com/oauth2/callback")
+	authorizeQuery.Set("state", state)
+	authorizeQuery.Set("nonce", "ghijkl")
+
+	m.QueueCode(code)
+
+	authorizeResp, err := httpClient.Get(m.AuthorizationEndpoint() + "?" + authorizeQuery.Encode())
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(authorizeResp.StatusCode).To(Equal(http.StatusFound))
+
+	callbackQuery := url.Values{}
+	callbackQuery.Set("code", code)
+	callbackQuery.Set("state", state)
+
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/oauth2/callback?"+callbackQuery.Encode(), nil)
+	req.AddCookie(&http.Cookie{Name: auth.StateCookieName, Value: state})
+
+	w := httptest.NewRecorder()
+	s.Callback().ServeHTTP(w, req)
+	g.Expect(w).To(HaveHTTPStatus(http.StatusSeeOther))
+
+	return w.Result().Cookies()
+}
+
+func findCookie(cookies []*http.Cookie, name string) *http.Cookie {
+	for _, c := range cookies {
+		if c.Name == name {
+			return c
+		}
+	}
+
+	return nil
+}
+
+func TestLoadRefreshTokenKey(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	c := ctrlclientfake.NewClientBuilder().Build()
+	key := client.ObjectKey{Name: auth.DefaultRefreshTokenKeySecretName, Namespace: testNamespace}
+
+	created, err := auth.LoadRefreshTokenKey(context.Background(), c, key)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(created).To(HaveLen(32))
+
+	var secret corev1.Secret
+	g.Expect(c.Get(context.Background(), key, &secret)).To(Succeed())
+
+	loaded, err := auth.LoadRefreshTokenKey(context.Background(), c, key)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(loaded).To(Equal(created), "the stored key is reused")
+}
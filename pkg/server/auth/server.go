@@ -2,17 +2,20 @@ package auth
 
 import (
 	"context"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/coreos/go-oidc/v3/oidc"
 	"github.com/go-logr/logr"
 	"github.com/weaveworks/weave-gitops/pkg/featureflags"
-	"golang.org/x/crypto/bcrypt"
 	"golang.org/x/oauth2"
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -22,10 +25,14 @@ import (
 const (
 	LoginOIDC                  string = "oidc"
 	LoginUsername              string = "username"
+	LoginGitHub                string = "github"
+	LoginGitLab                string = "gitlab"
 	ClusterUserAuthSecretName  string = "cluster-user-auth"
 	DefaultOIDCAuthSecretName  string = "oidc-auth"
 	FeatureFlagClusterUser     string = "CLUSTER_USER_AUTH"
 	FeatureFlagOIDCAuth        string = "OIDC_AUTH"
+	FeatureFlagGitHubAuth      string = "GITHUB_AUTH"
+	FeatureFlagGitLabAuth      string = "GITLAB_AUTH"
 	FeatureFlagOIDCPassthrough string = "WEAVE_GITOPS_FEATURE_OIDC_AUTH_PASSTHROUGH"
 	FeatureFlagSet             string = "true"
 )
@@ -33,17 +40,36 @@ const (
 // OIDCConfig is used to configure an AuthServer to interact with
 // an OIDC issuer.
 type OIDCConfig struct {
+	// Name and DisplayName identify additional named providers, they
+	// are empty for the default provider.
+	Name          string
+	DisplayName   string
 	IssuerURL     string
 	ClientID      string
 	ClientSecret  string
 	RedirectURL   string
 	TokenDuration time.Duration
+	// RefreshTokenDuration is how long the encrypted refresh token cookie
+	// is kept, and so how long a session can be silently renewed for.
+	RefreshTokenDuration time.Duration
+	// UsernameClaim, GroupsClaim, UsernamePrefix, GroupsPrefix and
+	// RequiredClaims configure how token claims map to a UserPrincipal,
+	// see ClaimsConfig.
+	UsernameClaim  string
+	GroupsClaim    string
+	UsernamePrefix string
+	GroupsPrefix   string
+	RequiredClaims map[string]string
 }
 
 // This is only used if the OIDCConfig doesn't have a TokenDuration set. If
 // that is set then it is used for both OIDC cookies and other cookies.
 const defaultCookieDuration time.Duration = time.Hour
 
+// DefaultRefreshTokenDuration is used if the OIDCConfig doesn't have a
+// RefreshTokenDuration set.
+const DefaultRefreshTokenDuration time.Duration = 7 * 24 * time.Hour
+
 // AuthConfig is used to configure an AuthServer.
 type AuthConfig struct {
 	Log                 logr.Logger
@@ -53,12 +79,114 @@ type AuthConfig struct {
 	config              OIDCConfig
 	authMethods         map[AuthMethod]bool
 	namespace           string
+	oidcProviders       []OIDCConfig
+	sessionStore        SessionStore
+	adminGroups         []string
+	clientCAs           *x509.CertPool
+	loginAttempts       *LoginAttemptTracker
+	gitLoginConfigs     map[AuthMethod]GitLoginConfig
+	refreshTokenKey     []byte
+}
+
+// SetRefreshTokenKey configures the key that encrypts refresh token cookies.
+// Without it a random key is used, and users have to log in again after a
+// restart or when their requests reach another replica.
+func (c *AuthConfig) SetRefreshTokenKey(key []byte) {
+	c.refreshTokenKey = key
+}
+
+// SetGitLogin configures the OAuth application users log in with, for the
+// GitHub or GitLab auth method.
+func (c *AuthConfig) SetGitLogin(method AuthMethod, cfg GitLoginConfig) error {
+	if method != GitHub && method != GitLab {
+		return fmt.Errorf("%s is not a git provider login method", method.String())
+	}
+
+	if c.gitLoginConfigs == nil {
+		c.gitLoginConfigs = map[AuthMethod]GitLoginConfig{}
+	}
+
+	c.gitLoginConfigs[method] = cfg
+
+	return nil
+}
+
+// SetLoginAttemptTracker configures how failed sign-ins with a username and
+// password are throttled. By default they are tracked in memory, with the
+// default limits.
+func (c *AuthConfig) SetLoginAttemptTracker(tracker *LoginAttemptTracker) {
+	c.loginAttempts = tracker
+}
+
+// SetClientCAs configures the CAs that sign the client certificates accepted
+// by the client-certificate auth method.
+func (c *AuthConfig) SetClientCAs(pool *x509.CertPool) {
+	c.clientCAs = pool
+}
+
+// SetSessionStore configures where the sessions of users signed in through
+// the UI are kept. Without a store sessions only live in cookies, and can't
+// be listed or revoked.
+func (c *AuthConfig) SetSessionStore(store SessionStore) {
+	c.sessionStore = store
+}
+
+// SetAdminGroups configures the groups whose members can manage the sessions
+// of other users.
+func (c *AuthConfig) SetAdminGroups(groups []string) {
+	c.adminGroups = groups
+}
+
+// SetOIDCProviders configures additional named OIDC providers, tried in
+// order after the default one.
+func (c *AuthConfig) SetOIDCProviders(providers []OIDCConfig) error {
+	seen := map[string]bool{}
+
+	for _, p := range providers {
+		if err := validateProviderName(p.Name); err != nil {
+			return err
+		}
+
+		if seen[p.Name] {
+			return fmt.Errorf("OIDC provider %q is configured more than once", p.Name)
+		}
+
+		seen[p.Name] = true
+
+		if _, err := url.Parse(p.IssuerURL); err != nil {
+			return fmt.Errorf("invalid issuer URL for OIDC provider %q: %w", p.Name, err)
+		}
+
+		if _, err := url.Parse(p.RedirectURL); err != nil {
+			return fmt.Errorf("invalid redirect URL for OIDC provider %q: %w", p.Name, err)
+		}
+
+		if p.TokenDuration == 0 {
+			p.TokenDuration = time.Hour
+		}
+
+		if p.RefreshTokenDuration == 0 {
+			p.RefreshTokenDuration = DefaultRefreshTokenDuration
+		}
+
+		c.oidcProviders = append(c.oidcProviders, p)
+	}
+
+	return nil
 }
 
 // AuthServer interacts with an OIDC issuer to handle the OAuth2 process flow.
 type AuthServer struct {
 	AuthConfig
-	provider *oidc.Provider
+	// providers holds the default provider, if configured, followed by
+	// the named providers.
+	providers    []*oidcProvider
+	cookieCipher *cookieCipher
+	refresher    *tokenRefresher
+	apiTokens    *APITokenStore
+	// tokenReviewer is shared by all handlers so that they share its cache.
+	tokenReviewer PrincipalGetter
+	gitLogins     []*gitLogin
 }
 
 // LoginRequest represents the data submitted by client when the auth flow (non-OIDC) is used.
@@ -73,14 +201,25 @@ type UserInfo struct {
 	Groups []string `json:"groups"`
 }
 
-func NewOIDCConfigFromSecret(secret corev1.Secret) OIDCConfig {
+func NewOIDCConfigFromSecret(secret corev1.Secret) (OIDCConfig, error) {
 	cfg := OIDCConfig{
-		IssuerURL:    string(secret.Data["issuerURL"]),
-		ClientID:     string(secret.Data["clientID"]),
-		ClientSecret: string(secret.Data["clientSecret"]),
-		RedirectURL:  string(secret.Data["redirectURL"]),
+		IssuerURL:      string(secret.Data["issuerURL"]),
+		ClientID:       string(secret.Data["clientID"]),
+		ClientSecret:   string(secret.Data["clientSecret"]),
+		RedirectURL:    string(secret.Data["redirectURL"]),
+		UsernameClaim:  string(secret.Data["usernameClaim"]),
+		GroupsClaim:    string(secret.Data["groupsClaim"]),
+		UsernamePrefix: string(secret.Data["usernamePrefix"]),
+		GroupsPrefix:   string(secret.Data["groupsPrefix"]),
+	}
+
+	requiredClaims, err := ParseRequiredClaims(string(secret.Data["requiredClaims"]))
+	if err != nil {
+		return OIDCConfig{}, fmt.Errorf("invalid requiredClaims in secret %s: %w", secret.Name, err)
 	}
 
+	cfg.RequiredClaims = requiredClaims
+
 	tokenDuration, err := time.ParseDuration(string(secret.Data["tokenDuration"]))
 	if err != nil {
 		tokenDuration = time.Hour
@@ -88,7 +227,14 @@ func NewOIDCConfigFromSecret(secret corev1.Secret) OIDCConfig {
 
 	cfg.TokenDuration = tokenDuration
 
-	return cfg
+	refreshTokenDuration, err := time.ParseDuration(string(secret.Data["refreshTokenDuration"]))
+	if err != nil {
+		refreshTokenDuration = DefaultRefreshTokenDuration
+	}
+
+	cfg.RefreshTokenDuration = refreshTokenDuration
+
+	return cfg, nil
 }
 
 func NewAuthServerConfig(log logr.Logger, oidcCfg OIDCConfig, kubernetesClient ctrlclient.Client, tsv TokenSignerVerifier, namespace string, authMethods map[AuthMethod]bool) (AuthConfig, error) {
@@ -102,6 +248,10 @@ func NewAuthServerConfig(log logr.Logger, oidcCfg OIDCConfig, kubernetesClient c
 		}
 	}
 
+	if oidcCfg.RefreshTokenDuration == 0 {
+		oidcCfg.RefreshTokenDuration = DefaultRefreshTokenDuration
+	}
+
 	return AuthConfig{
 		Log:                 log.WithName("auth-server"),
 		client:              http.DefaultClient,
@@ -131,25 +281,91 @@ func NewAuthServer(ctx context.Context, cfg AuthConfig) (*AuthServer, error) {
 		featureflags.Set(FeatureFlagClusterUser, "false")
 	}
 
-	var provider *oidc.Provider
+	s := &AuthServer{
+		AuthConfig: cfg,
+		refresher:  newTokenRefresher(),
+	}
 
-	if cfg.config.IssuerURL == "" {
+	if s.config.IssuerURL == "" && len(s.oidcProviders) == 0 {
 		featureflags.Set(FeatureFlagOIDCAuth, "false")
 	} else if cfg.authMethods[OIDC] {
-		var err error
+		if s.config.IssuerURL != "" {
+			provider, err := newOIDCProvider(ctx, "", &s.config)
+			if err != nil {
+				return nil, err
+			}
 
-		provider, err = oidc.NewProvider(ctx, cfg.config.IssuerURL)
-		if err != nil {
-			return nil, fmt.Errorf("could not create provider: %w", err)
+			s.providers = append(s.providers, provider)
 		}
+
+		for i := range s.oidcProviders {
+			cfg := &s.oidcProviders[i]
+
+			provider, err := newOIDCProvider(ctx, cfg.Name, cfg)
+			if err != nil {
+				return nil, fmt.Errorf("OIDC provider %q: %w", cfg.Name, err)
+			}
+
+			s.providers = append(s.providers, provider)
+		}
+
 		featureflags.Set(FeatureFlagOIDCAuth, FeatureFlagSet)
 	}
 
-	if featureflags.Get(FeatureFlagOIDCAuth) != FeatureFlagSet && featureflags.Get(FeatureFlagClusterUser) != FeatureFlagSet {
-		return nil, fmt.Errorf("Neither OIDC auth or local auth enabled, can't start")
+	for _, method := range []AuthMethod{GitHub, GitLab} {
+		flag := FeatureFlagGitHubAuth
+		if method == GitLab {
+			flag = FeatureFlagGitLabAuth
+		}
+
+		gitCfg, ok := cfg.gitLoginConfigs[method]
+		if !cfg.authMethods[method] || !ok {
+			featureflags.Set(flag, "false")
+			continue
+		}
+
+		l, err := newGitLogin(method, gitCfg)
+		if err != nil {
+			return nil, err
+		}
+
+		s.gitLogins = append(s.gitLogins, l)
+
+		featureflags.Set(flag, FeatureFlagSet)
+	}
+
+	if featureflags.Get(FeatureFlagOIDCAuth) != FeatureFlagSet && featureflags.Get(FeatureFlagClusterUser) != FeatureFlagSet && len(s.gitLogins) == 0 {
+		return nil, fmt.Errorf("Neither OIDC auth, local auth or git provider auth enabled, can't start")
+	}
+
+	cc, err := newCookieCipher(s.refreshTokenKey)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cookieCipher = cc
+
+	if s.loginAttempts == nil {
+		loginAttemptsCfg := DefaultLoginAttemptsConfig()
+		loginAttemptsCfg.Store = LoginAttemptsStoreMemory
+
+		tracker, err := NewLoginAttemptTracker(loginAttemptsCfg, cfg.kubernetesClient, cfg.namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		s.loginAttempts = tracker
+	}
+
+	if cfg.authMethods[APIToken] {
+		s.apiTokens = NewAPITokenStore(cfg.kubernetesClient, cfg.namespace, DefaultAPITokensSecretName)
 	}
 
-	return &AuthServer{cfg, provider}, nil
+	if cfg.authMethods[TokenReview] {
+		s.tokenReviewer = NewTokenReviewPrincipalGetter(s.Log, cfg.kubernetesClient, DefaultTokenReviewCacheTTL)
+	}
+
+	return s, nil
 }
 
 // SetRedirectURL is used to set the redirect URL. This is meant to be used
@@ -166,53 +382,46 @@ func (s *AuthServer) oidcPassthroughEnabled() bool {
 	return featureflags.Get(FeatureFlagOIDCPassthrough) == FeatureFlagSet
 }
 
-func (s *AuthServer) verifier() *oidc.IDTokenVerifier {
-	return s.provider.Verifier(&oidc.Config{ClientID: s.config.ClientID})
-}
-
-func (s *AuthServer) oauth2Config(scopes []string) *oauth2.Config {
-	// Ensure "openid" scope is always present.
-	if !contains(scopes, oidc.ScopeOpenID) {
-		scopes = append(scopes, oidc.ScopeOpenID)
+// loginProvider returns the provider a login route is for. The default
+// route falls back to the first named provider if there is no default one.
+func (s *AuthServer) loginProvider(name string) *oidcProvider {
+	if p := s.oidcProvider(name); p != nil {
+		return p
 	}
 
-	// Request "email" scope to get user's email address.
-	if !contains(scopes, scopeEmail) {
-		scopes = append(scopes, scopeEmail)
+	if name == "" && len(s.providers) > 0 {
+		return s.providers[0]
 	}
 
-	// Request "groups" scope to get user's groups.
-	if !contains(scopes, scopeGroups) {
-		scopes = append(scopes, scopeGroups)
-	}
-
-	return &oauth2.Config{
-		ClientID:     s.config.ClientID,
-		ClientSecret: s.config.ClientSecret,
-		Endpoint:     s.provider.Endpoint(),
-		RedirectURL:  s.config.RedirectURL,
-		Scopes:       scopes,
-	}
+	return nil
 }
 
+// OAuth2Flow starts the login flow with the default OIDC provider.
 func (s *AuthServer) OAuth2Flow() http.HandlerFunc {
+	return s.ProviderOAuth2Flow("")
+}
+
+// ProviderOAuth2Flow starts the login flow with the named OIDC provider.
+func (s *AuthServer) ProviderOAuth2Flow(name string) http.HandlerFunc {
 	return func(rw http.ResponseWriter, r *http.Request) {
-		if !s.oidcEnabled() {
+		p := s.loginProvider(name)
+		if !s.oidcEnabled() || p == nil {
 			JSONError(s.Log, rw, "oidc provider not configured", http.StatusBadRequest)
 			return
 		}
 
-		s.startAuthFlow(rw, r)
+		s.startAuthFlow(rw, r, p)
 	}
 }
 
+// Callback handles the redirect back from the default OIDC provider.
 func (s *AuthServer) Callback() http.HandlerFunc {
-	return func(rw http.ResponseWriter, r *http.Request) {
-		var (
-			token *oauth2.Token
-			state SessionState
-		)
+	return s.ProviderCallback("")
+}
 
+// ProviderCallback handles the redirect back from the named OIDC provider.
+func (s *AuthServer) ProviderCallback(name string) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			rw.Header().Add("Allow", "GET")
 			rw.WriteHeader(http.StatusMethodNotAllowed)
@@ -220,55 +429,23 @@ func (s *AuthServer) Callback() http.HandlerFunc {
 			return
 		}
 
-		ctx := oidc.ClientContext(r.Context(), s.client)
-
-		// Authorization redirect callback from OAuth2 auth flow.
-		if errorCode := r.FormValue("error"); errorCode != "" {
-			s.Log.Info("authz redirect callback failed", "error", errorCode, "error_description", r.FormValue("error_description"))
-			rw.WriteHeader(http.StatusBadRequest)
-
-			return
-		}
-
-		code := r.FormValue("code")
-		if code == "" {
-			s.Log.Info("code value was empty")
-			rw.WriteHeader(http.StatusBadRequest)
-
-			return
-		}
-
-		cookie, err := r.Cookie(StateCookieName)
-		if err != nil {
-			s.Log.Error(err, "cookie was not found in the request", "cookie", StateCookieName)
-			rw.WriteHeader(http.StatusBadRequest)
-
+		p := s.loginProvider(name)
+		if p == nil {
+			JSONError(s.Log, rw, "oidc provider not configured", http.StatusBadRequest)
 			return
 		}
 
-		if state := r.FormValue("state"); state != cookie.Value {
-			s.Log.Info("cookie value does not match state form value")
-			rw.WriteHeader(http.StatusBadRequest)
-
-			return
-		}
+		ctx := oidc.ClientContext(r.Context(), s.client)
 
-		b, err := base64.StdEncoding.DecodeString(cookie.Value)
+		state, code, err := verifyAuthCallback(r)
 		if err != nil {
-			s.Log.Error(err, "cannot base64 decode cookie", "cookie", StateCookieName, "cookie_value", cookie.Value)
+			s.Log.Info("invalid auth callback", "error", err)
 			rw.WriteHeader(http.StatusBadRequest)
 
 			return
 		}
 
-		if err := json.Unmarshal(b, &state); err != nil {
-			s.Log.Error(err, "failed to unmarshal state to JSON", "state", string(b))
-			rw.WriteHeader(http.StatusBadRequest)
-
-			return
-		}
-
-		token, err = s.oauth2Config(nil).Exchange(ctx, code)
+		token, err := p.oauth2Config(nil).Exchange(ctx, code)
 		if err != nil {
 			s.Log.Error(err, "failed to exchange auth code for token", "code", code)
 			rw.WriteHeader(http.StatusInternalServerError)
@@ -282,15 +459,23 @@ func (s *AuthServer) Callback() http.HandlerFunc {
 			return
 		}
 
-		_, err = s.verifier().Verify(r.Context(), rawIDToken)
+		principal, err := parseJWTToken(r.Context(), p.verifier(), p.config.ClaimsConfig(), rawIDToken)
 		if err != nil {
 			JSONError(s.Log, rw, fmt.Sprintf("failed to verify ID token: %v", err), http.StatusInternalServerError)
 			return
 		}
 
-		// Issue ID token cookie
-		http.SetCookie(rw, s.createCookie(IDTokenCookieName, rawIDToken))
-		http.SetCookie(rw, s.createCookie(AccessTokenCookieName, token.AccessToken))
+		// Issue ID token, access token and refresh token cookies
+		if err := s.setOIDCCookies(rw, p, token, rawIDToken); err != nil {
+			JSONError(s.Log, rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// The session lasts as long as it can be refreshed.
+		if err := s.startSession(rw, r, principal.ID, LoginOIDC, p.config.RefreshTokenDuration); err != nil {
+			JSONError(s.Log, rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
 
 		// Clear state cookie
 		http.SetCookie(rw, s.clearCookie(StateCookieName))
@@ -318,6 +503,30 @@ func (s *AuthServer) SignIn() http.HandlerFunc {
 			return
 		}
 
+		ip := s.loginAttempts.clientIP(r)
+
+		attempt, err := s.loginAttempts.Attempt(r.Context(), loginRequest.Username, ip)
+		if err != nil {
+			// Fail closed, the attempt could not be checked or counted.
+			s.Log.Error(err, "Failed to record sign-in attempt")
+			rw.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		if attempt.Wait > 0 {
+			reason := "delayed"
+			if attempt.Locked {
+				reason = "locked"
+			}
+
+			signInThrottled.WithLabelValues(reason).Inc()
+			rw.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(attempt.Wait.Seconds()))))
+			JSONError(s.Log, rw, "Too many failed sign-in attempts, please try again later.", http.StatusTooManyRequests)
+
+			return
+		}
+
 		var hashedSecret corev1.Secret
 
 		if err := s.kubernetesClient.Get(r.Context(), ctrlclient.ObjectKey{
@@ -330,21 +539,36 @@ func (s *AuthServer) SignIn() http.HandlerFunc {
 			return
 		}
 
-		if loginRequest.Username != string(hashedSecret.Data["username"]) {
+		users, err := LocalUsersFromSecret(&hashedSecret)
+		if err != nil {
+			s.Log.Error(err, "Failed to read local users")
+			JSONError(s.Log, rw, "Failed to read local users.", http.StatusInternalServerError)
+
+			return
+		}
+
+		user := FindLocalUser(users, loginRequest.Username)
+		if user == nil {
 			s.Log.Info("Wrong username")
+			s.signInFailed(loginRequest.Username, ip, attempt)
 			rw.WriteHeader(http.StatusUnauthorized)
 
 			return
 		}
 
-		if err := bcrypt.CompareHashAndPassword(hashedSecret.Data["password"], []byte(loginRequest.Password)); err != nil {
+		if err := user.CheckPassword(loginRequest.Password); err != nil {
 			s.Log.Error(err, "Failed to compare hash with password")
+			s.signInFailed(loginRequest.Username, ip, attempt)
 			rw.WriteHeader(http.StatusUnauthorized)
 
 			return
 		}
 
-		signed, err := s.tokenSignerVerifier.Sign(loginRequest.Username)
+		if err := s.loginAttempts.Succeeded(r.Context(), user.Username, ip); err != nil {
+			s.Log.Error(err, "Failed to reset sign-in attempts")
+		}
+
+		signed, err := s.tokenSignerVerifier.Sign(user.Username, user.Groups...)
 		if err != nil {
 			s.Log.Error(err, "Failed to create and sign token")
 			rw.WriteHeader(http.StatusInternalServerError)
@@ -352,11 +576,29 @@ func (s *AuthServer) SignIn() http.HandlerFunc {
 			return
 		}
 
+		if err := s.startSession(rw, r, user.Username, LoginUsername, s.config.TokenDuration); err != nil {
+			s.Log.Error(err, "Failed to start session")
+			rw.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
 		http.SetCookie(rw, s.createCookie(IDTokenCookieName, signed))
 		rw.WriteHeader(http.StatusOK)
 	}
 }
 
+// signInFailed audits a failed sign-in, which was already counted when the
+// attempt started, and the lockouts it causes.
+func (s *AuthServer) signInFailed(username, ip string, attempt LoginAttempt) {
+	signInFailures.Inc()
+
+	for _, l := range attempt.Lockouts {
+		signInLockouts.WithLabelValues(l.Scope).Inc()
+		s.Log.WithName("audit").Info("Locked out after repeated sign-in failures", "scope", l.Scope, "username", username, "remoteAddr", ip, "until", l.Until)
+	}
+}
+
 // UserInfo inspects the cookie and attempts to verify it as an admin token. If successful,
 // it returns a UserInfo object with the email set to the admin token subject. Otherwise it
 // uses the token to query the OIDC provider's user info endpoint and return a UserInfo object
@@ -387,10 +629,16 @@ func (s *AuthServer) UserInfo() http.HandlerFunc {
 			}
 		}
 
+		if _, err := s.checkSession(r, ""); err != nil {
+			JSONError(s.Log, rw, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
 		claims, err := s.tokenSignerVerifier.Verify(c.Value)
 		if err == nil {
 			ui := UserInfo{
-				Email: claims.Subject,
+				Email:  claims.Subject,
+				Groups: claims.Groups,
 			}
 			toJson(rw, ui, s.Log)
 
@@ -404,7 +652,14 @@ func (s *AuthServer) UserInfo() http.HandlerFunc {
 			return
 		}
 
-		info, err := s.provider.UserInfo(r.Context(), oauth2.StaticTokenSource(&oauth2.Token{
+		var rawIDToken string
+		if idToken, err := r.Cookie(IDTokenCookieName); err == nil {
+			rawIDToken = idToken.Value
+		}
+
+		p := s.providerForToken(rawIDToken)
+
+		info, err := p.provider.UserInfo(r.Context(), oauth2.StaticTokenSource(&oauth2.Token{
 			AccessToken: c.Value,
 		}))
 		if err != nil {
@@ -412,17 +667,28 @@ func (s *AuthServer) UserInfo() http.HandlerFunc {
 			return
 		}
 
-		var userPrincipal UserPrincipal
-
-		// Extract custom claims
-		if err := info.Claims(&userPrincipal); err != nil {
+		var userClaims map[string]interface{}
+		if err := info.Claims(&userClaims); err != nil {
 			JSONError(s.Log, rw, fmt.Sprintf("failed to decode user claims: %v", err), http.StatusUnauthorized)
 			return
 		}
 
+		// Map the claims the same way as when the user is impersonated, so
+		// the UI shows the identity that is used to access the clusters. The
+		// required claims were checked against the ID token already, and
+		// needn't be returned by the user info endpoint.
+		cc := p.config.ClaimsConfig()
+		cc.RequiredClaims = nil
+
+		principal, err := cc.PrincipalFromClaims(userClaims)
+		if err != nil {
+			JSONError(s.Log, rw, fmt.Sprintf("failed to map user claims: %v", err), http.StatusUnauthorized)
+			return
+		}
+
 		ui := UserInfo{
-			Email:  info.Email,
-			Groups: userPrincipal.Groups,
+			Email:  principal.ID,
+			Groups: principal.Groups,
 		}
 
 		toJson(rw, ui, s.Log)
@@ -442,13 +708,32 @@ func toJson(rw http.ResponseWriter, ui UserInfo, log logr.Logger) {
 	}
 }
 
-func (c *AuthServer) startAuthFlow(rw http.ResponseWriter, r *http.Request) {
-	nonce, err := generateNonce()
+func (c *AuthServer) startAuthFlow(rw http.ResponseWriter, r *http.Request, p *oidcProvider) {
+	state, err := newAuthState(r)
 	if err != nil {
-		JSONError(c.Log, rw, fmt.Sprintf("failed to generate nonce: %v", err), http.StatusInternalServerError)
+		JSONError(c.Log, rw, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	var scopes []string
+	// "openid", "email", "groups" and "offline_access" scopes added by default
+	scopes = append(scopes, scopeProfile)
+	authCodeUrl := p.oauth2Config(scopes).AuthCodeURL(state)
+
+	// Issue state cookie
+	http.SetCookie(rw, c.createCookie(StateCookieName, state))
+
+	http.Redirect(rw, r, authCodeUrl, http.StatusSeeOther)
+}
+
+// newAuthState encodes the state passed through an OAuth2 authorization
+// flow, returning to the return_url of the request when it completes.
+func newAuthState(r *http.Request) (string, error) {
+	nonce, err := generateNonce()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
 	returnUrl := r.URL.Query().Get("return_url")
 
 	if returnUrl == "" {
@@ -460,21 +745,45 @@ func (c *AuthServer) startAuthFlow(rw http.ResponseWriter, r *http.Request) {
 		ReturnURL: returnUrl,
 	})
 	if err != nil {
-		JSONError(c.Log, rw, fmt.Sprintf("failed to marshal state to JSON: %v", err), http.StatusInternalServerError)
-		return
+		return "", fmt.Errorf("failed to marshal state to JSON: %w", err)
 	}
 
-	state := base64.StdEncoding.EncodeToString(b)
+	return base64.StdEncoding.EncodeToString(b), nil
+}
 
-	var scopes []string
-	// "openid", "email" and "groups" scopes added by default
-	scopes = append(scopes, scopeProfile)
-	authCodeUrl := c.oauth2Config(scopes).AuthCodeURL(state)
+// verifyAuthCallback checks the redirect back from an OAuth2 authorization
+// flow matches the state cookie, and returns the state and code.
+func verifyAuthCallback(r *http.Request) (SessionState, string, error) {
+	var state SessionState
 
-	// Issue state cookie
-	http.SetCookie(rw, c.createCookie(StateCookieName, state))
+	if errorCode := r.FormValue("error"); errorCode != "" {
+		return state, "", fmt.Errorf("authz redirect callback failed: %s: %s", errorCode, r.FormValue("error_description"))
+	}
 
-	http.Redirect(rw, r, authCodeUrl, http.StatusSeeOther)
+	code := r.FormValue("code")
+	if code == "" {
+		return state, "", errors.New("code value was empty")
+	}
+
+	cookie, err := r.Cookie(StateCookieName)
+	if err != nil {
+		return state, "", fmt.Errorf("cookie %s was not found in the request: %w", StateCookieName, err)
+	}
+
+	if r.FormValue("state") != cookie.Value {
+		return state, "", errors.New("cookie value does not match state form value")
+	}
+
+	b, err := base64.StdEncoding.DecodeString(cookie.Value)
+	if err != nil {
+		return state, "", fmt.Errorf("cannot base64 decode cookie %s: %w", StateCookieName, err)
+	}
+
+	if err := json.Unmarshal(b, &state); err != nil {
+		return state, "", fmt.Errorf("failed to unmarshal state to JSON: %w", err)
+	}
+
+	return state, code, nil
 }
 
 func (s *AuthServer) Logout() http.HandlerFunc {
@@ -486,18 +795,26 @@ func (s *AuthServer) Logout() http.HandlerFunc {
 			return
 		}
 
+		s.endSession(r)
+
 		http.SetCookie(rw, s.clearCookie(IDTokenCookieName))
 		http.SetCookie(rw, s.clearCookie(AccessTokenCookieName))
+		http.SetCookie(rw, s.clearCookie(RefreshTokenCookieName))
+		http.SetCookie(rw, s.clearCookie(SessionCookieName))
 		rw.WriteHeader(http.StatusOK)
 	}
 }
 
 func (c *AuthServer) createCookie(name, value string) *http.Cookie {
+	return c.newCookie(name, value, c.config.TokenDuration)
+}
+
+func (c *AuthServer) newCookie(name, value string, duration time.Duration) *http.Cookie {
 	cookie := &http.Cookie{
 		Name:     name,
 		Value:    value,
 		Path:     "/",
-		Expires:  time.Now().UTC().Add(c.config.TokenDuration),
+		Expires:  time.Now().UTC().Add(duration),
 		HttpOnly: true,
 		Secure:   false,
 	}
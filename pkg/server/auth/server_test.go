@@ -538,6 +538,68 @@ func TestUserInfoAdminFlowBadCookie(t *testing.T) {
 }
 
 func TestUserInfoOIDCFlow(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	tokenSignerVerifier, err := auth.NewHMACTokenSignerVerifier(5 * time.Minute)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	s, m := makeAuthServer(t, nil, tokenSignerVerifier, []auth.AuthMethod{auth.OIDC})
+
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/userinfo", nil)
+	req.AddCookie(&http.Cookie{
+		Name:  auth.IDTokenCookieName,
+		Value: mockOIDCIDToken(t, m),
+	})
+
+	w := httptest.NewRecorder()
+	s.UserInfo().ServeHTTP(w, req)
+
+	resp := w.Result()
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+	var info auth.UserInfo
+
+	g.Expect(json.NewDecoder(resp.Body).Decode(&info)).To(Succeed())
+	g.Expect(info.Email).To(Equal("jane.doe@example.com"))
+}
+
+func TestUserInfoOIDCFlowMapsClaims(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	tokenSignerVerifier, err := auth.NewHMACTokenSignerVerifier(5 * time.Minute)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	s, m := makeAuthServerWithOIDCConfig(t, nil, tokenSignerVerifier, []auth.AuthMethod{auth.OIDC}, func(cfg *auth.OIDCConfig) {
+		cfg.UsernameClaim = "preferred_username"
+		cfg.UsernamePrefix = "oidc:"
+		cfg.GroupsPrefix = "oidc:"
+		cfg.RequiredClaims = map[string]string{"hd": "example.com"}
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/userinfo", nil)
+	req.AddCookie(&http.Cookie{
+		Name:  auth.IDTokenCookieName,
+		Value: mockOIDCIDToken(t, m),
+	})
+
+	w := httptest.NewRecorder()
+	s.UserInfo().ServeHTTP(w, req)
+
+	resp := w.Result()
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+	var info auth.UserInfo
+
+	g.Expect(json.NewDecoder(resp.Body).Decode(&info)).To(Succeed())
+	g.Expect(info.Email).To(Equal("oidc:jane.doe"))
+	g.Expect(info.Groups).To(Equal([]string{"oidc:engineering", "oidc:design"}))
+}
+
+// mockOIDCIDToken runs the authorization code flow against the mock provider
+// and returns the raw ID token.
+func mockOIDCIDToken(t *testing.T, m *mockoidc.MockOIDC) string {
+	t.Helper()
+
 	const (
 		state = "abcdef"
 		nonce = "ghijkl"
@@ -546,11 +608,6 @@ func TestUserInfoOIDCFlow(t *testing.T) {
 
 	g := gomega.NewGomegaWithT(t)
 
-	tokenSignerVerifier, err := auth.NewHMACTokenSignerVerifier(5 * time.Minute)
-	g.Expect(err).NotTo(HaveOccurred())
-
-	s, m := makeAuthServer(t, nil, tokenSignerVerifier, []auth.AuthMethod{auth.OIDC})
-
 	authorizeQuery := url.Values{}
 	authorizeQuery.Set("client_id", m.Config().ClientID)
 	authorizeQuery.Set("scope", "openid email profile groups")
@@ -607,22 +664,7 @@ func TestUserInfoOIDCFlow(t *testing.T) {
 	idToken, err := m.Keypair.VerifyJWT(tokens["id_token"].(string))
 	g.Expect(err).NotTo(HaveOccurred())
 
-	req := httptest.NewRequest(http.MethodGet, "https://example.com/userinfo", nil)
-	req.AddCookie(&http.Cookie{
-		Name:  auth.IDTokenCookieName,
-		Value: idToken.Raw,
-	})
-
-	w := httptest.NewRecorder()
-	s.UserInfo().ServeHTTP(w, req)
-
-	resp := w.Result()
-	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
-
-	var info auth.UserInfo
-
-	g.Expect(json.NewDecoder(resp.Body).Decode(&info)).To(Succeed())
-	g.Expect(info.Email).To(Equal("jane.doe@example.com"))
+	return idToken.Raw
 }
 
 func TestLogoutSuccess(t *testing.T) {
@@ -694,6 +736,12 @@ func TestLogoutWithWrongMethod(t *testing.T) {
 
 func makeAuthServer(t *testing.T, client ctrlclient.Client, tsv auth.TokenSignerVerifier, authMethods []auth.AuthMethod) (*auth.AuthServer, *mockoidc.MockOIDC) {
 	t.Helper()
+
+	return makeAuthServerWithOIDCConfig(t, client, tsv, authMethods, func(*auth.OIDCConfig) {})
+}
+
+func makeAuthServerWithOIDCConfig(t *testing.T, client ctrlclient.Client, tsv auth.TokenSignerVerifier, authMethods []auth.AuthMethod, configure func(*auth.OIDCConfig)) (*auth.AuthServer, *mockoidc.MockOIDC) {
+	t.Helper()
 	g := gomega.NewGomegaWithT(t)
 
 	featureflags.Set("OIDC_AUTH", "") // Reset this
@@ -716,6 +764,7 @@ func makeAuthServer(t *testing.T, client ctrlclient.Client, tsv auth.TokenSigner
 		ClientSecret: cfg.ClientSecret,
 		IssuerURL:    cfg.Issuer,
 	}
+	configure(&oidcCfg)
 
 	authMethodsMap := map[auth.AuthMethod]bool{}
 	for _, mthd := range authMethods {
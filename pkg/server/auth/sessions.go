@@ -0,0 +1,609 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// SessionCookieName is the name of the cookie that identifies the
+	// server-side session of a user signed in through the UI.
+	SessionCookieName = "session"
+	// DefaultSessionStoreName is the name of the secret or config map
+	// sessions are stored in.
+	DefaultSessionStoreName = "gitops-sessions"
+	// How long a session store backed by a Kubernetes object reuses what it
+	// read, and so how long a revoked session may still be accepted by other
+	// replicas.
+	sessionStoreCacheTTL = 5 * time.Second
+)
+
+// The kinds of session store that can be configured.
+const (
+	SessionStoreNone      = ""
+	SessionStoreMemory    = "memory"
+	SessionStoreSecret    = objectKindSecret
+	SessionStoreConfigMap = objectKindConfigMap
+)
+
+var (
+	// ErrSessionNotFound is returned when revoking a session that doesn't
+	// exist or belongs to another user.
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrSessionInvalid is returned when the session of a request has expired
+	// or been revoked.
+	ErrSessionInvalid = errors.New("session expired or revoked")
+)
+
+// Session is a server-side record of a user signed in through the UI. Its ID
+// is a hash of the value of the session cookie, so the stored sessions can't
+// be used to forge a cookie.
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"userID"`
+	Method     string    `json:"method"`
+	CreatedAt  time.Time `json:"createdAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	UserAgent  string    `json:"userAgent,omitempty"`
+	RemoteAddr string    `json:"remoteAddr,omitempty"`
+}
+
+func (s Session) expired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
+
+// SessionStore keeps track of the sessions of signed in users, so that they
+// can be listed and revoked.
+type SessionStore interface {
+	// Create adds a session.
+	Create(ctx context.Context, session Session) error
+	// Get returns a session, or nil if it doesn't exist or has expired.
+	Get(ctx context.Context, id string) (*Session, error)
+	// List returns the sessions of a user, oldest first.
+	List(ctx context.Context, userID string) ([]Session, error)
+	// Revoke deletes one of the sessions of a user.
+	Revoke(ctx context.Context, userID, id string) error
+	// RevokeAll deletes all the sessions of a user, and returns how many
+	// there were.
+	RevokeAll(ctx context.Context, userID string) (int, error)
+}
+
+// NewSessionStore creates a session store of the given kind. Sessions kept in
+// memory are lost on restart and are not shared between replicas, the secret
+// and configmap kinds store them in a Kubernetes object with the given name.
+// It returns nil if kind is SessionStoreNone.
+func NewSessionStore(kind string, c ctrlclient.Client, namespace, name string) (SessionStore, error) {
+	switch kind {
+	case SessionStoreNone:
+		return nil, nil
+	case SessionStoreMemory:
+		return NewMemorySessionStore(), nil
+	case SessionStoreSecret, SessionStoreConfigMap:
+		return NewKubeSessionStore(c, kind, namespace, name), nil
+	default:
+		return nil, fmt.Errorf("unknown session store %q, valid values are %q, %q and %q", kind, SessionStoreMemory, SessionStoreSecret, SessionStoreConfigMap)
+	}
+}
+
+// sessions is the set of sessions held by a store, keyed by ID.
+type sessions map[string]Session
+
+func (ss sessions) get(id string, now time.Time) *Session {
+	s, ok := ss[id]
+	if !ok || s.expired(now) {
+		return nil
+	}
+
+	return &s
+}
+
+func (ss sessions) list(userID string, now time.Time) []Session {
+	res := []Session{}
+
+	for _, s := range ss {
+		if s.UserID == userID && !s.expired(now) {
+			res = append(res, s)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].CreatedAt.Before(res[j].CreatedAt)
+	})
+
+	return res
+}
+
+func (ss sessions) revoke(userID, id string) bool {
+	s, ok := ss[id]
+	if !ok || s.UserID != userID {
+		return false
+	}
+
+	delete(ss, id)
+
+	return true
+}
+
+func (ss sessions) revokeAll(userID string) int {
+	n := 0
+
+	for id, s := range ss {
+		if s.UserID == userID {
+			delete(ss, id)
+
+			n++
+		}
+	}
+
+	return n
+}
+
+func (ss sessions) prune(now time.Time) {
+	for id, s := range ss {
+		if s.expired(now) {
+			delete(ss, id)
+		}
+	}
+}
+
+// MemorySessionStore keeps sessions in memory. It's only suitable for a
+// single replica, and everybody is logged out when the server restarts.
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions sessions
+	now      func() time.Time
+}
+
+// NewMemorySessionStore creates an empty MemorySessionStore.
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{
+		sessions: sessions{},
+		now:      time.Now,
+	}
+}
+
+func (m *MemorySessionStore) Create(_ context.Context, session Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sessions.prune(m.now())
+	m.sessions[session.ID] = session
+
+	return nil
+}
+
+func (m *MemorySessionStore) Get(_ context.Context, id string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.sessions.get(id, m.now()), nil
+}
+
+func (m *MemorySessionStore) List(_ context.Context, userID string) ([]Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.sessions.list(userID, m.now()), nil
+}
+
+func (m *MemorySessionStore) Revoke(_ context.Context, userID, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.sessions.revoke(userID, id) {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+func (m *MemorySessionStore) RevokeAll(_ context.Context, userID string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.sessions.revokeAll(userID), nil
+}
+
+// KubeSessionStore keeps sessions in a secret or config map, one key per
+// session, so that they are shared between replicas. What it reads is reused
+// for a few seconds to avoid querying the API server on every request.
+type KubeSessionStore struct {
+	data objectData
+	now  func() time.Time
+
+	mu       sync.Mutex
+	cached   sessions
+	cachedAt time.Time
+}
+
+// NewKubeSessionStore creates a store using the named object, of kind
+// SessionStoreSecret or SessionStoreConfigMap.
+func NewKubeSessionStore(c ctrlclient.Client, kind, namespace, name string) *KubeSessionStore {
+	return &KubeSessionStore{
+		data: objectData{
+			client: c,
+			kind:   kind,
+			key:    ctrlclient.ObjectKey{Namespace: namespace, Name: name},
+		},
+		now: time.Now,
+	}
+}
+
+func (k *KubeSessionStore) Create(ctx context.Context, session Session) error {
+	return k.update(ctx, func(ss sessions) {
+		ss.prune(k.now())
+		ss[session.ID] = session
+	})
+}
+
+func (k *KubeSessionStore) Get(ctx context.Context, id string) (*Session, error) {
+	ss, err := k.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return ss.get(id, k.now()), nil
+}
+
+func (k *KubeSessionStore) List(ctx context.Context, userID string) ([]Session, error) {
+	ss, err := k.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return ss.list(userID, k.now()), nil
+}
+
+func (k *KubeSessionStore) Revoke(ctx context.Context, userID, id string) error {
+	found := false
+
+	err := k.update(ctx, func(ss sessions) {
+		found = ss.revoke(userID, id)
+	})
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+func (k *KubeSessionStore) RevokeAll(ctx context.Context, userID string) (int, error) {
+	n := 0
+
+	err := k.update(ctx, func(ss sessions) {
+		n = ss.revokeAll(userID)
+	})
+
+	return n, err
+}
+
+func (k *KubeSessionStore) read(ctx context.Context) (sessions, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.cached != nil && k.now().Sub(k.cachedAt) < sessionStoreCacheTTL {
+		return k.cached, nil
+	}
+
+	data, err := k.data.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ss, err := k.decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	k.cached, k.cachedAt = ss, k.now()
+
+	return ss, nil
+}
+
+// update applies fn to the stored sessions.
+func (k *KubeSessionStore) update(ctx context.Context, fn func(sessions)) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	// Whatever happens, read the object again next time.
+	k.cached = nil
+
+	return k.data.update(ctx, func(data map[string][]byte) error {
+		ss, err := k.decode(data)
+		if err != nil {
+			return err
+		}
+
+		fn(ss)
+
+		for id := range data {
+			delete(data, id)
+		}
+
+		for id, s := range ss {
+			v, err := json.Marshal(s)
+			if err != nil {
+				return err
+			}
+
+			data[id] = v
+		}
+
+		return nil
+	})
+}
+
+func (k *KubeSessionStore) decode(data map[string][]byte) (sessions, error) {
+	ss := sessions{}
+
+	for id, v := range data {
+		var s Session
+		if err := json.Unmarshal(v, &s); err != nil {
+			return nil, fmt.Errorf("invalid session %q in %s %s: %w", id, k.data.kind, k.data.key, err)
+		}
+
+		ss[id] = s
+	}
+
+	return ss, nil
+}
+
+// hashSessionToken returns the ID of the session a session cookie is for.
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// startSession records a new session for the user and sets the session
+// cookie. It does nothing if no session store is configured.
+func (s *AuthServer) startSession(rw http.ResponseWriter, r *http.Request, userID, method string, duration time.Duration) error {
+	if s.sessionStore == nil {
+		return nil
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Errorf("could not generate session: %w", err)
+	}
+
+	if duration <= 0 {
+		duration = defaultCookieDuration
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+	now := time.Now().UTC()
+
+	err := s.sessionStore.Create(r.Context(), Session{
+		ID:         hashSessionToken(token),
+		UserID:     userID,
+		Method:     method,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(duration),
+		UserAgent:  r.UserAgent(),
+		RemoteAddr: r.RemoteAddr,
+	})
+	if err != nil {
+		return fmt.Errorf("could not store session: %w", err)
+	}
+
+	http.SetCookie(rw, s.newCookie(SessionCookieName, token, duration))
+
+	return nil
+}
+
+// checkSession returns the session of the request, or an error if it doesn't
+// have a valid one. If userID isn't empty the session must belong to that
+// user. Any request is valid if no session store is configured.
+func (s *AuthServer) checkSession(r *http.Request, userID string) (*Session, error) {
+	return checkSession(s.sessionStore, r, userID)
+}
+
+func checkSession(store SessionStore, r *http.Request, userID string) (*Session, error) {
+	if store == nil {
+		return nil, nil
+	}
+
+	cookie, err := r.Cookie(SessionCookieName)
+	if err != nil {
+		return nil, ErrSessionInvalid
+	}
+
+	session, err := store.Get(r.Context(), hashSessionToken(cookie.Value))
+	if err != nil {
+		return nil, err
+	}
+
+	if session == nil || (userID != "" && session.UserID != userID) {
+		return nil, ErrSessionInvalid
+	}
+
+	return session, nil
+}
+
+// endSession revokes the session of the request, if any.
+func (s *AuthServer) endSession(r *http.Request) {
+	if s.sessionStore == nil {
+		return
+	}
+
+	session, err := s.checkSession(r, "")
+	if err != nil || session == nil {
+		return
+	}
+
+	if err := s.sessionStore.Revoke(r.Context(), session.UserID, session.ID); err != nil && !errors.Is(err, ErrSessionNotFound) {
+		s.Log.Error(err, "Failed to revoke session", "user", session.UserID)
+	}
+}
+
+func (s *AuthServer) isAdmin(p *UserPrincipal) bool {
+	for _, g := range p.Groups {
+		if contains(s.adminGroups, g) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SessionPrincipalGetter only accepts the principal found by another getter
+// if the request has a valid session for the same user. It's used for the
+// cookies set when signing in through the UI.
+type SessionPrincipalGetter struct {
+	log   logr.Logger
+	store SessionStore
+	next  PrincipalGetter
+}
+
+// NewSessionPrincipalGetter wraps a PrincipalGetter to check the session of
+// the requests it authenticates.
+func NewSessionPrincipalGetter(log logr.Logger, store SessionStore, next PrincipalGetter) PrincipalGetter {
+	return &SessionPrincipalGetter{
+		log:   log,
+		store: store,
+		next:  next,
+	}
+}
+
+func (pg *SessionPrincipalGetter) Principal(r *http.Request) (*UserPrincipal, error) {
+	principal, err := pg.next.Principal(r)
+	if err != nil || principal == nil {
+		return principal, err
+	}
+
+	if _, err := checkSession(pg.store, r, principal.ID); err != nil {
+		return nil, err
+	}
+
+	return principal, nil
+}
+
+// sessionsResponse is returned when listing sessions. Current is the ID of
+// the session of the request, if it's one of them.
+type sessionsResponse struct {
+	Sessions []Session `json:"sessions"`
+	Current  string    `json:"current,omitempty"`
+}
+
+// Sessions handles listing (GET) and revoking (DELETE) all the sessions of
+// the authenticated user.
+func (s *AuthServer) Sessions() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		principal := Principal(r.Context())
+		if principal == nil || s.sessionStore == nil {
+			JSONError(s.Log, rw, "Sessions are not enabled", http.StatusNotFound)
+			return
+		}
+
+		s.handleUserSessions(rw, r, principal.ID)
+	}
+}
+
+// RevokeSession handles revoking (DELETE) one of the sessions of the
+// authenticated user.
+func (s *AuthServer) RevokeSession(id string) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			rw.Header().Add("Allow", "DELETE")
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		principal := Principal(r.Context())
+		if principal == nil || s.sessionStore == nil {
+			JSONError(s.Log, rw, "Sessions are not enabled", http.StatusNotFound)
+			return
+		}
+
+		if err := s.sessionStore.Revoke(r.Context(), principal.ID, id); err != nil {
+			if errors.Is(err, ErrSessionNotFound) {
+				JSONError(s.Log, rw, err.Error(), http.StatusNotFound)
+				return
+			}
+
+			s.Log.Error(err, "Failed to revoke session")
+			JSONError(s.Log, rw, "Failed to revoke session", http.StatusInternalServerError)
+
+			return
+		}
+
+		s.Log.Info("Session revoked", "user", principal.ID, "id", id)
+
+		rw.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// UserSessions handles listing (GET) and revoking (DELETE) all the sessions
+// of another user. Only members of the admin groups are allowed to do so.
+func (s *AuthServer) UserSessions(userID string) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		principal := Principal(r.Context())
+		if principal == nil || s.sessionStore == nil {
+			JSONError(s.Log, rw, "Sessions are not enabled", http.StatusNotFound)
+			return
+		}
+
+		if !s.isAdmin(principal) {
+			JSONError(s.Log, rw, "Only admins can manage the sessions of other users", http.StatusForbidden)
+			return
+		}
+
+		s.handleUserSessions(rw, r, userID)
+	}
+}
+
+func (s *AuthServer) handleUserSessions(rw http.ResponseWriter, r *http.Request, userID string) {
+	principal := Principal(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := s.sessionStore.List(r.Context(), userID)
+		if err != nil {
+			s.Log.Error(err, "Failed to list sessions")
+			JSONError(s.Log, rw, "Failed to list sessions", http.StatusInternalServerError)
+
+			return
+		}
+
+		res := sessionsResponse{Sessions: list}
+
+		if current, err := s.checkSession(r, userID); err == nil && current != nil {
+			res.Current = current.ID
+		}
+
+		writeJSON(s.Log, rw, http.StatusOK, res)
+	case http.MethodDelete:
+		n, err := s.sessionStore.RevokeAll(r.Context(), userID)
+		if err != nil {
+			s.Log.Error(err, "Failed to revoke sessions")
+			JSONError(s.Log, rw, "Failed to revoke sessions", http.StatusInternalServerError)
+
+			return
+		}
+
+		s.Log.Info("All sessions revoked", "user", userID, "sessions", n, "by", principal.ID)
+
+		rw.WriteHeader(http.StatusNoContent)
+	default:
+		rw.Header().Add("Allow", "GET, DELETE")
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,276 @@
+package auth_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/weaveworks/weave-gitops/pkg/server/auth"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlclientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSessionStores(t *testing.T) {
+	for _, kind := range []string{auth.SessionStoreMemory, auth.SessionStoreSecret, auth.SessionStoreConfigMap} {
+		t.Run(kind, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			ctx := context.Background()
+
+			store, err := auth.NewSessionStore(kind, ctrlclientfake.NewClientBuilder().Build(), testNamespace, auth.DefaultSessionStoreName)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			now := time.Now().UTC()
+
+			for _, s := range []auth.Session{
+				{ID: "a1", UserID: "alice", CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+				{ID: "a2", UserID: "alice", CreatedAt: now.Add(time.Second), ExpiresAt: now.Add(time.Hour)},
+				{ID: "a3", UserID: "alice", CreatedAt: now.Add(-time.Hour), ExpiresAt: now.Add(-time.Minute)},
+				{ID: "b1", UserID: "bob", CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+			} {
+				g.Expect(store.Create(ctx, s)).To(Succeed())
+			}
+
+			s, err := store.Get(ctx, "a1")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(s.UserID).To(Equal("alice"))
+
+			s, err = store.Get(ctx, "a3")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(s).To(BeNil(), "expired sessions are ignored")
+
+			list, err := store.List(ctx, "alice")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(list).To(HaveLen(2))
+			g.Expect(list[0].ID).To(Equal("a1"))
+			g.Expect(list[1].ID).To(Equal("a2"))
+
+			g.Expect(store.Revoke(ctx, "bob", "a1")).To(MatchError(auth.ErrSessionNotFound))
+			g.Expect(store.Revoke(ctx, "alice", "a1")).To(Succeed())
+
+			s, err = store.Get(ctx, "a1")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(s).To(BeNil())
+
+			n, err := store.RevokeAll(ctx, "alice")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(n).To(Equal(1))
+
+			list, err = store.List(ctx, "alice")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(list).To(BeEmpty())
+
+			s, err = store.Get(ctx, "b1")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(s).NotTo(BeNil())
+		})
+	}
+}
+
+// racingClient lets another replica write before the first object is created.
+type racingClient struct {
+	ctrlclient.Client
+	race func()
+}
+
+func (c *racingClient) Create(ctx context.Context, obj ctrlclient.Object, opts ...ctrlclient.CreateOption) error {
+	if race := c.race; race != nil {
+		c.race = nil
+		race()
+	}
+
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func TestSessionStoresConcurrentCreate(t *testing.T) {
+	for _, kind := range []string{auth.SessionStoreSecret, auth.SessionStoreConfigMap} {
+		t.Run(kind, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+			ctx := context.Background()
+			now := time.Now().UTC()
+
+			k := ctrlclientfake.NewClientBuilder().Build()
+
+			other, err := auth.NewSessionStore(kind, k, testNamespace, auth.DefaultSessionStoreName)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			c := &racingClient{Client: k}
+			c.race = func() {
+				g.Expect(other.Create(ctx, auth.Session{ID: "b1", UserID: "bob", CreatedAt: now, ExpiresAt: now.Add(time.Hour)})).To(Succeed())
+			}
+
+			store, err := auth.NewSessionStore(kind, c, testNamespace, auth.DefaultSessionStoreName)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			g.Expect(store.Create(ctx, auth.Session{ID: "a1", UserID: "alice", CreatedAt: now, ExpiresAt: now.Add(time.Hour)})).To(Succeed())
+
+			for _, id := range []string{"a1", "b1"} {
+				s, err := store.Get(ctx, id)
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(s).NotTo(BeNil())
+			}
+		})
+	}
+}
+
+func TestNewSessionStore(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	store, err := auth.NewSessionStore(auth.SessionStoreNone, nil, testNamespace, auth.DefaultSessionStoreName)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(store).To(BeNil())
+
+	_, err = auth.NewSessionStore("redis", nil, testNamespace, auth.DefaultSessionStoreName)
+	g.Expect(err).To(MatchError(ContainSubstring(`unknown session store "redis"`)))
+}
+
+func TestSessionRevocation(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	hash, err := auth.HashPassword("alice-password")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      auth.ClusterUserAuthSecretName,
+			Namespace: testNamespace,
+		},
+	}
+
+	g.Expect(auth.SetLocalUsers(secret, []auth.LocalUser{
+		{Username: "alice", PasswordHash: hash, Groups: []string{"team-a"}},
+	})).To(Succeed())
+
+	fakeKubernetesClient := ctrlclientfake.NewClientBuilder().WithObjects(secret).Build()
+
+	tokenSignerVerifier, err := auth.NewHMACTokenSignerVerifier(5 * time.Minute)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	s, _ := makeAuthServer(t, fakeKubernetesClient, tokenSignerVerifier, []auth.AuthMethod{auth.UserAccount})
+	s.SetSessionStore(auth.NewMemorySessionStore())
+	s.SetAdminGroups([]string{"admins"})
+
+	handler := auth.WithAPIAuth(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}), s, nil)
+
+	signIn := func() []*http.Cookie {
+		j, err := json.Marshal(auth.LoginRequest{Username: "alice", Password: "alice-password"})
+		g.Expect(err).NotTo(HaveOccurred())
+
+		w := httptest.NewRecorder()
+		s.SignIn().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "https://example.com/signin", bytes.NewReader(j)))
+		g.Expect(w).To(HaveHTTPStatus(http.StatusOK))
+
+		return w.Result().Cookies()
+	}
+
+	request := func(method, target string, cookies []*http.Cookie, principal *auth.UserPrincipal) *http.Request {
+		req := httptest.NewRequest(method, target, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+
+		if principal != nil {
+			req = req.WithContext(auth.WithPrincipal(req.Context(), principal))
+		}
+
+		return req
+	}
+
+	serve := func(h http.Handler, req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		return w
+	}
+
+	alice := &auth.UserPrincipal{ID: "alice", Groups: []string{"team-a"}}
+	laptop := signIn()
+	phone := signIn()
+
+	g.Expect(findCookie(laptop, auth.SessionCookieName)).NotTo(BeNil())
+	g.Expect(serve(handler, request(http.MethodGet, "https://example.com/v1/objects", laptop, nil))).To(HaveHTTPStatus(http.StatusOK))
+
+	// The signed token alone is not enough.
+	g.Expect(serve(handler, request(http.MethodGet, "https://example.com/v1/objects", []*http.Cookie{findCookie(laptop, auth.IDTokenCookieName)}, nil))).To(HaveHTTPStatus(http.StatusUnauthorized))
+
+	w := serve(s.Sessions(), request(http.MethodGet, "https://example.com/v1/sessions", laptop, alice))
+	g.Expect(w).To(HaveHTTPStatus(http.StatusOK))
+
+	var res struct {
+		Sessions []auth.Session `json:"sessions"`
+		Current  string         `json:"current"`
+	}
+	g.Expect(json.NewDecoder(w.Body).Decode(&res)).To(Succeed())
+	g.Expect(res.Sessions).To(HaveLen(2))
+	g.Expect(res.Current).To(Equal(res.Sessions[0].ID))
+
+	// Revoking the laptop session from the phone logs the laptop out.
+	g.Expect(serve(s.RevokeSession(res.Sessions[0].ID), request(http.MethodDelete, "https://example.com/v1/sessions/x", phone, alice))).To(HaveHTTPStatus(http.StatusNoContent))
+	g.Expect(serve(handler, request(http.MethodGet, "https://example.com/v1/objects", laptop, nil))).To(HaveHTTPStatus(http.StatusUnauthorized))
+	g.Expect(serve(handler, request(http.MethodGet, "https://example.com/v1/objects", phone, nil))).To(HaveHTTPStatus(http.StatusOK))
+
+	// Only admins can log other users out.
+	g.Expect(serve(s.UserSessions("alice"), request(http.MethodDelete, "https://example.com/v1/users/alice/sessions", nil, &auth.UserPrincipal{ID: "bob", Groups: []string{"team-a"}}))).To(HaveHTTPStatus(http.StatusForbidden))
+	g.Expect(serve(s.UserSessions("alice"), request(http.MethodDelete, "https://example.com/v1/users/alice/sessions", nil, &auth.UserPrincipal{ID: "carol", Groups: []string{"admins"}}))).To(HaveHTTPStatus(http.StatusNoContent))
+	g.Expect(serve(handler, request(http.MethodGet, "https://example.com/v1/objects", phone, nil))).To(HaveHTTPStatus(http.StatusUnauthorized))
+
+	// Logging out revokes the session server-side.
+	tablet := signIn()
+	g.Expect(serve(s.Logout(), request(http.MethodPost, "https://example.com/oauth2/logout", tablet, nil))).To(HaveHTTPStatus(http.StatusOK))
+	g.Expect(serve(handler, request(http.MethodGet, "https://example.com/v1/objects", tablet, nil))).To(HaveHTTPStatus(http.StatusUnauthorized))
+}
+
+func TestSessionRevocationWithOIDC(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tokenSignerVerifier, err := auth.NewHMACTokenSignerVerifier(5 * time.Minute)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	s, m := makeAuthServer(t, nil, tokenSignerVerifier, []auth.AuthMethod{auth.OIDC})
+	s.SetSessionStore(auth.NewMemorySessionStore())
+
+	handler := auth.WithAPIAuth(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}), s, nil)
+
+	cookies := oidcSignIn(t, s, m)
+	idToken := findCookie(cookies, auth.IDTokenCookieName)
+	session := findCookie(cookies, auth.SessionCookieName)
+	g.Expect(idToken).NotTo(BeNil())
+	g.Expect(session).NotTo(BeNil())
+
+	request := func(method string, cookies ...*http.Cookie) *http.Request {
+		req := httptest.NewRequest(method, "https://example.com/v1/objects", nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+
+		return req
+	}
+
+	serve := func(h http.Handler, req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		return w
+	}
+
+	bearer := request(http.MethodGet)
+	bearer.Header.Set("Authorization", "Bearer "+idToken.Value)
+
+	g.Expect(serve(handler, request(http.MethodGet, idToken, session))).To(HaveHTTPStatus(http.StatusOK))
+
+	// The cookie alone is not enough.
+	g.Expect(serve(handler, request(http.MethodGet, idToken))).To(HaveHTTPStatus(http.StatusUnauthorized))
+
+	// Clients sending the token in the header have no session.
+	g.Expect(serve(handler, bearer)).To(HaveHTTPStatus(http.StatusOK))
+
+	// Once the session is revoked its cookies are rejected.
+	g.Expect(serve(s.Logout(), request(http.MethodPost, idToken, session))).To(HaveHTTPStatus(http.StatusOK))
+	g.Expect(serve(handler, request(http.MethodGet, idToken, session))).To(HaveHTTPStatus(http.StatusUnauthorized))
+}
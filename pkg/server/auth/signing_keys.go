@@ -0,0 +1,249 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/weaveworks/weave-gitops/core/logger"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultSigningKeysSecretName is the name of the secret holding the keys
+	// that sign the session tokens of local users.
+	DefaultSigningKeysSecretName = "gitops-signing-keys"
+	// DefaultSigningKeysRetained is how many keys are kept when rotating,
+	// including the new one. Tokens signed with the previous key stay valid
+	// until they expire.
+	DefaultSigningKeysRetained = 2
+	// The key in the signing keys secret naming the active key. All other
+	// keys hold a signing key, keyed by its ID.
+	activeSigningKeyIDKey = "active"
+	signingKeySize        = 64
+	// How long to wait before watching the signing keys secret again after
+	// the watch failed.
+	signingKeysWatchRetry = 10 * time.Second
+)
+
+var signingKeyIDRegexp = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+
+// SigningKeys are the keys used to sign and verify session tokens. Tokens are
+// signed with the active key, and tokens signed with any of the keys are
+// accepted, so that keys can be rotated without logging everybody out.
+type SigningKeys struct {
+	ActiveKeyID string
+	Keys        map[string][]byte
+}
+
+// SigningKeysFromSecret reads the signing keys from a secret in the form:
+//
+//	data:
+//	  active: <key ID>
+//	  <key ID>: <key>
+//	  <previous key ID>: <previous key>
+func SigningKeysFromSecret(secret *corev1.Secret) (SigningKeys, error) {
+	keys := SigningKeys{
+		ActiveKeyID: string(secret.Data[activeSigningKeyIDKey]),
+		Keys:        map[string][]byte{},
+	}
+
+	for id, key := range secret.Data {
+		if id == activeSigningKeyIDKey {
+			continue
+		}
+
+		if len(key) < 32 {
+			return SigningKeys{}, fmt.Errorf("signing key %q in secret %s is shorter than 32 bytes", id, secret.Name)
+		}
+
+		keys.Keys[id] = key
+	}
+
+	if keys.ActiveKeyID == "" {
+		return SigningKeys{}, fmt.Errorf("secret %s has no active signing key", secret.Name)
+	}
+
+	if _, ok := keys.Keys[keys.ActiveKeyID]; !ok {
+		return SigningKeys{}, fmt.Errorf("active signing key %q not found in secret %s", keys.ActiveKeyID, secret.Name)
+	}
+
+	return keys, nil
+}
+
+// RotateSigningKeys adds a new key to the secret and makes it the active one.
+// Only the newest keys are kept, up to retain keys including the new one.
+// It returns the ID of the new key.
+func RotateSigningKeys(secret *corev1.Secret, retain int, now time.Time) (string, error) {
+	if retain < 1 {
+		return "", errors.New("at least one signing key must be retained")
+	}
+
+	id, err := newSigningKeyID(now)
+	if err != nil {
+		return "", err
+	}
+
+	key := make([]byte, signingKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("could not generate signing key: %w", err)
+	}
+
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+
+	secret.Data[id] = key
+	secret.Data[activeSigningKeyIDKey] = []byte(id)
+
+	ids := []string{}
+
+	for k := range secret.Data {
+		if k != activeSigningKeyIDKey {
+			ids = append(ids, k)
+		}
+	}
+
+	// Key IDs start with their creation time, so they sort oldest first.
+	sort.Strings(ids)
+
+	for len(ids) > retain {
+		delete(secret.Data, ids[0])
+		ids = ids[1:]
+	}
+
+	return id, nil
+}
+
+// newSigningKeyID returns an ID starting with the creation time, so that
+// keys can be ordered, followed by a random suffix.
+func newSigningKeyID(now time.Time) (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("could not generate signing key ID: %w", err)
+	}
+
+	id := now.UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+
+	if !signingKeyIDRegexp.MatchString(id) {
+		return "", fmt.Errorf("invalid signing key ID %q", id)
+	}
+
+	return id, nil
+}
+
+// LoadSigningKeys reads the signing keys from the secret and configures the
+// token signer with them. If the secret doesn't exist yet, it's created with
+// a new key, so that all replicas sign sessions with the same key.
+func LoadSigningKeys(ctx context.Context, c ctrlclient.Client, key ctrlclient.ObjectKey, sv *HMACTokenSignerVerifier) error {
+	var secret corev1.Secret
+
+	err := c.Get(ctx, key, &secret)
+	if apierrors.IsNotFound(err) {
+		secret = corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Type:       corev1.SecretTypeOpaque,
+		}
+
+		if _, err := RotateSigningKeys(&secret, DefaultSigningKeysRetained, time.Now()); err != nil {
+			return err
+		}
+
+		err = c.Create(ctx, &secret)
+		if apierrors.IsAlreadyExists(err) {
+			// Another replica created it first.
+			err = c.Get(ctx, key, &secret)
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("could not read signing keys secret: %w", err)
+	}
+
+	keys, err := SigningKeysFromSecret(&secret)
+	if err != nil {
+		return err
+	}
+
+	return sv.SetKeys(keys)
+}
+
+// WatchSigningKeys updates the token signer whenever the signing keys secret
+// changes, until the context is cancelled. A deleted secret leaves the
+// current keys in place.
+func WatchSigningKeys(ctx context.Context, log logr.Logger, c ctrlclient.WithWatch, key ctrlclient.ObjectKey, sv *HMACTokenSignerVerifier) {
+	for {
+		if err := watchSigningKeys(ctx, log, c, key, sv); err != nil {
+			log.Error(err, "Failed to watch signing keys", "secret", key)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(signingKeysWatchRetry):
+		}
+	}
+}
+
+func watchSigningKeys(ctx context.Context, log logr.Logger, c ctrlclient.WithWatch, key ctrlclient.ObjectKey, sv *HMACTokenSignerVerifier) error {
+	w, err := c.Watch(ctx, &corev1.SecretList{},
+		ctrlclient.InNamespace(key.Namespace),
+		ctrlclient.MatchingFields{"metadata.name": key.Name},
+	)
+	if err != nil {
+		return err
+	}
+	defer w.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-w.ResultChan():
+			if !ok {
+				return nil
+			}
+
+			switch ev.Type {
+			case watch.Added, watch.Modified:
+				secret, ok := ev.Object.(*corev1.Secret)
+				if !ok || secret.Name != key.Name {
+					continue
+				}
+
+				keys, err := SigningKeysFromSecret(secret)
+				if err != nil {
+					log.Error(err, "Ignoring invalid signing keys", "secret", key)
+					continue
+				}
+
+				rotated := keys.ActiveKeyID != sv.ActiveKeyID()
+
+				if err := sv.SetKeys(keys); err != nil {
+					log.Error(err, "Failed to set signing keys", "secret", key)
+					continue
+				}
+
+				if rotated {
+					log.Info("Loaded signing keys", "secret", key, "activeKeyID", keys.ActiveKeyID, "keys", len(keys.Keys))
+				} else {
+					log.V(logger.LogLevelDebug).Info("Reloaded signing keys", "secret", key, "activeKeyID", keys.ActiveKeyID, "keys", len(keys.Keys))
+				}
+			case watch.Deleted:
+				log.V(logger.LogLevelWarn).Info("Signing keys secret was deleted, keeping the current keys", "secret", key)
+			case watch.Error:
+				return apierrors.FromObject(ev.Object)
+			}
+		}
+	}
+}
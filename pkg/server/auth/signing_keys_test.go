@@ -0,0 +1,187 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	"github.com/weaveworks/weave-gitops/pkg/server/auth"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlclientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRotateSigningKeys(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	secret := &corev1.Secret{}
+	now := time.Date(2022, 8, 1, 12, 0, 0, 0, time.UTC)
+
+	first, err := auth.RotateSigningKeys(secret, 2, now)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(first).To(HavePrefix("20220801T120000Z-"))
+
+	second, err := auth.RotateSigningKeys(secret, 2, now.Add(time.Hour))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	keys, err := auth.SigningKeysFromSecret(secret)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(keys.ActiveKeyID).To(Equal(second))
+	g.Expect(keys.Keys).To(HaveKey(first))
+	g.Expect(keys.Keys).To(HaveKey(second))
+
+	third, err := auth.RotateSigningKeys(secret, 2, now.Add(2*time.Hour))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	keys, err = auth.SigningKeysFromSecret(secret)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(keys.ActiveKeyID).To(Equal(third))
+	g.Expect(keys.Keys).NotTo(HaveKey(first))
+	g.Expect(keys.Keys).To(HaveLen(2))
+
+	_, err = auth.RotateSigningKeys(secret, 0, now)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestSigningKeysFromSecretValidation(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	key := make([]byte, 64)
+
+	_, err := auth.SigningKeysFromSecret(&corev1.Secret{Data: map[string][]byte{"a": key}})
+	g.Expect(err).To(MatchError(ContainSubstring("no active signing key")))
+
+	_, err = auth.SigningKeysFromSecret(&corev1.Secret{Data: map[string][]byte{"active": []byte("b"), "a": key}})
+	g.Expect(err).To(MatchError(ContainSubstring(`active signing key "b" not found`)))
+
+	_, err = auth.SigningKeysFromSecret(&corev1.Secret{Data: map[string][]byte{"active": []byte("a"), "a": []byte("short")}})
+	g.Expect(err).To(MatchError(ContainSubstring("shorter than 32 bytes")))
+}
+
+func TestHMACTokenSignerVerifierAcceptsPreviousKeys(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	secret := &corev1.Secret{}
+
+	_, err := auth.RotateSigningKeys(secret, 2, time.Now())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	keys, err := auth.SigningKeysFromSecret(secret)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	tsv, err := auth.NewHMACTokenSignerVerifier(5 * time.Minute)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(tsv.SetKeys(keys)).To(Succeed())
+
+	oldToken, err := tsv.Sign("alice")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// Another replica with the same keys accepts the token.
+	other, err := auth.NewHMACTokenSignerVerifier(5 * time.Minute)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(other.SetKeys(keys)).To(Succeed())
+
+	claims, err := other.Verify(oldToken)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(claims.Subject).To(Equal("alice"))
+
+	// After a rotation the previous key is still accepted.
+	_, err = auth.RotateSigningKeys(secret, 2, time.Now().Add(time.Second))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	keys, err = auth.SigningKeysFromSecret(secret)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(tsv.SetKeys(keys)).To(Succeed())
+
+	_, err = tsv.Verify(oldToken)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// Once it's dropped tokens signed with it are rejected.
+	_, err = auth.RotateSigningKeys(secret, 1, time.Now().Add(2*time.Second))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	keys, err = auth.SigningKeysFromSecret(secret)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(tsv.SetKeys(keys)).To(Succeed())
+
+	_, err = tsv.Verify(oldToken)
+	g.Expect(err).To(MatchError(ContainSubstring("unknown signing key")))
+}
+
+func TestWatchSigningKeys(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      auth.DefaultSigningKeysSecretName,
+			Namespace: testNamespace,
+		},
+	}
+
+	first, err := auth.RotateSigningKeys(secret, 2, time.Now())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	c := ctrlclientfake.NewClientBuilder().WithObjects(secret).Build()
+	key := client.ObjectKeyFromObject(secret)
+
+	tsv, err := auth.NewHMACTokenSignerVerifier(5 * time.Minute)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	g.Expect(auth.LoadSigningKeys(context.Background(), c, key, tsv)).To(Succeed())
+	g.Expect(tsv.ActiveKeyID()).To(Equal(first))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	go auth.WatchSigningKeys(ctx, logr.Discard(), c, key, tsv)
+
+	g.Expect(c.Get(ctx, key, secret)).To(Succeed())
+
+	second, err := auth.RotateSigningKeys(secret, 2, time.Now().Add(time.Second))
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// The watch may not have started yet, keep updating until it's seen.
+	g.Eventually(func() string {
+		var current corev1.Secret
+		if err := c.Get(ctx, key, &current); err == nil {
+			current.Data = secret.Data
+			current.Labels = map[string]string{"updated": time.Now().Format("150405.000000")}
+			_ = c.Update(ctx, &current)
+		}
+
+		return tsv.ActiveKeyID()
+	}, 5*time.Second, 50*time.Millisecond).Should(Equal(second))
+}
+
+func TestLoadSigningKeysCreatesSecret(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	c := ctrlclientfake.NewClientBuilder().Build()
+	key := client.ObjectKey{Name: auth.DefaultSigningKeysSecretName, Namespace: testNamespace}
+
+	tsv, err := auth.NewHMACTokenSignerVerifier(5 * time.Minute)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	g.Expect(auth.LoadSigningKeys(context.Background(), c, key, tsv)).To(Succeed())
+
+	var secret corev1.Secret
+	g.Expect(c.Get(context.Background(), key, &secret)).To(Succeed())
+
+	keys, err := auth.SigningKeysFromSecret(&secret)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(tsv.ActiveKeyID()).To(Equal(keys.ActiveKeyID))
+
+	token, err := tsv.Sign("alice")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// Another replica loads the same key and accepts the token.
+	other, err := auth.NewHMACTokenSignerVerifier(5 * time.Minute)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(auth.LoadSigningKeys(context.Background(), c, key, other)).To(Succeed())
+
+	_, err = other.Verify(token)
+	g.Expect(err).NotTo(HaveOccurred())
+}
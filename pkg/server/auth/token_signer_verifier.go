@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -11,10 +12,12 @@ import (
 
 type AdminClaims struct {
 	jwt.RegisteredClaims
+	// Groups are the groups of the local user, used when impersonating them.
+	Groups []string `json:"groups,omitempty"`
 }
 
 type TokenSigner interface {
-	Sign(subject string) (string, error)
+	Sign(subject string, groups ...string) (string, error)
 }
 
 type TokenVerifier interface {
@@ -28,13 +31,18 @@ type TokenSignerVerifier interface {
 
 type HMACTokenSignerVerifier struct {
 	expireAfter time.Duration
-	hmacSecret  []byte
+
+	mu sync.RWMutex
+	// activeKeyID is the ID of the key new tokens are signed with, it is
+	// empty for the random key generated when no keys are loaded.
+	activeKeyID string
+	hmacSecrets map[string][]byte
 
 	devMode bool
 }
 
 func NewHMACTokenSignerVerifier(expireAfter time.Duration) (*HMACTokenSignerVerifier, error) {
-	hmacSecret := make([]byte, 64)
+	hmacSecret := make([]byte, signingKeySize)
 
 	_, err := rand.Read(hmacSecret)
 	if err != nil {
@@ -43,11 +51,40 @@ func NewHMACTokenSignerVerifier(expireAfter time.Duration) (*HMACTokenSignerVeri
 
 	return &HMACTokenSignerVerifier{
 		expireAfter: expireAfter,
-		hmacSecret:  hmacSecret,
+		hmacSecrets: map[string][]byte{"": hmacSecret},
 	}, nil
 }
 
-func (sv *HMACTokenSignerVerifier) Sign(subject string) (string, error) {
+// SetKeys replaces the signing keys. New tokens are signed with the active
+// key, and tokens signed with any of the keys are accepted.
+func (sv *HMACTokenSignerVerifier) SetKeys(keys SigningKeys) error {
+	if _, ok := keys.Keys[keys.ActiveKeyID]; !ok {
+		return fmt.Errorf("active signing key %q not found", keys.ActiveKeyID)
+	}
+
+	secrets := make(map[string][]byte, len(keys.Keys))
+	for id, key := range keys.Keys {
+		secrets[id] = key
+	}
+
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+
+	sv.activeKeyID = keys.ActiveKeyID
+	sv.hmacSecrets = secrets
+
+	return nil
+}
+
+// ActiveKeyID returns the ID of the key new tokens are signed with.
+func (sv *HMACTokenSignerVerifier) ActiveKeyID() string {
+	sv.mu.RLock()
+	defer sv.mu.RUnlock()
+
+	return sv.activeKeyID
+}
+
+func (sv *HMACTokenSignerVerifier) Sign(subject string, groups ...string) (string, error) {
 	claims := AdminClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
 			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
@@ -55,11 +92,19 @@ func (sv *HMACTokenSignerVerifier) Sign(subject string) (string, error) {
 			NotBefore: jwt.NewNumericDate(time.Now().UTC()),
 			Subject:   subject,
 		},
+		Groups: groups,
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
-	return token.SignedString(sv.hmacSecret)
+	sv.mu.RLock()
+	defer sv.mu.RUnlock()
+
+	if sv.activeKeyID != "" {
+		token.Header["kid"] = sv.activeKeyID
+	}
+
+	return token.SignedString(sv.hmacSecrets[sv.activeKeyID])
 }
 
 func (sv *HMACTokenSignerVerifier) Verify(tokenString string) (*AdminClaims, error) {
@@ -80,7 +125,17 @@ func (sv *HMACTokenSignerVerifier) Verify(tokenString string) (*AdminClaims, err
 				return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
 			}
 
-			return sv.hmacSecret, nil
+			kid, _ := token.Header["kid"].(string)
+
+			sv.mu.RLock()
+			defer sv.mu.RUnlock()
+
+			secret, ok := sv.hmacSecrets[kid]
+			if !ok {
+				return nil, fmt.Errorf("unknown signing key %q", kid)
+			}
+
+			return secret, nil
 		})
 	if err != nil {
 		return nil, fmt.Errorf("failed to verify token: %w", err)
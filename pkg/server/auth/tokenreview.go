@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cheshir/ttlcache"
+	"github.com/go-logr/logr"
+	"github.com/weaveworks/weave-gitops/core/logger"
+	authv1 "k8s.io/api/authentication/v1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultTokenReviewCacheTTL is how long the result of a TokenReview is
+	// reused for the same token.
+	DefaultTokenReviewCacheTTL = 10 * time.Second
+	tokenReviewCacheResolution = time.Second
+)
+
+// tokenReviewResult is what's cached for a token, either the principal, why
+// it was rejected, or neither if the cluster doesn't know it.
+type tokenReviewResult struct {
+	principal *UserPrincipal
+	err       error
+}
+
+// get returns a copy of the principal, so that the cached one can't be
+// changed by a request.
+func (r tokenReviewResult) get() (*UserPrincipal, error) {
+	if r.principal == nil {
+		return nil, r.err
+	}
+
+	p := *r.principal
+
+	return &p, nil
+}
+
+// TokenReviewPrincipalGetter authenticates bearer tokens, like service
+// account tokens, with the TokenReview API of the cluster the server runs
+// in. The reviewed user and groups are impersonated, the token itself is not
+// used to talk to the cluster.
+type TokenReviewPrincipalGetter struct {
+	log              logr.Logger
+	kubernetesClient ctrlclient.Client
+	cache            *ttlcache.Cache
+	ttl              time.Duration
+}
+
+// NewTokenReviewPrincipalGetter creates a PrincipalGetter that reviews the
+// bearer token of requests, caching the results for ttl.
+func NewTokenReviewPrincipalGetter(log logr.Logger, kubernetesClient ctrlclient.Client, ttl time.Duration) PrincipalGetter {
+	return &TokenReviewPrincipalGetter{
+		log:              log,
+		kubernetesClient: kubernetesClient,
+		cache:            ttlcache.New(tokenReviewCacheResolution),
+		ttl:              ttl,
+	}
+}
+
+func (pg *TokenReviewPrincipalGetter) Principal(r *http.Request) (*UserPrincipal, error) {
+	token := extractToken(r.Header.Get(AuthorizationTokenHeaderName))
+
+	// API tokens are only known to this server.
+	if token == "" || strings.HasPrefix(token, apiTokenPrefix) {
+		return nil, nil
+	}
+
+	// Only a hash of the token is kept in memory.
+	sum := sha256.Sum256([]byte(token))
+	key := ttlcache.StringKey(hex.EncodeToString(sum[:]))
+
+	if val, found := pg.cache.Get(key); found {
+		return val.(tokenReviewResult).get()
+	}
+
+	tr := authv1.TokenReview{
+		Spec: authv1.TokenReviewSpec{
+			Token: token,
+		},
+	}
+
+	if err := pg.kubernetesClient.Create(r.Context(), &tr); err != nil {
+		// Don't cache errors talking to the API server.
+		return nil, fmt.Errorf("failed to review token: %w", err)
+	}
+
+	var res tokenReviewResult
+
+	switch {
+	case !tr.Status.Authenticated:
+		// The token may be for another method, like an OIDC ID token.
+		pg.log.V(logger.LogLevelDebug).Info("Token review did not authenticate the token", "error", tr.Status.Error)
+	case tr.Status.User.Username == "":
+		res.err = fmt.Errorf("token review returned no username")
+	default:
+		groups := tr.Status.User.Groups
+		if groups == nil {
+			groups = []string{}
+		}
+
+		res.principal = &UserPrincipal{ID: tr.Status.User.Username, Groups: groups}
+	}
+
+	pg.cache.Set(key, res, pg.ttl)
+
+	return res.get()
+}
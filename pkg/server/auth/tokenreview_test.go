@@ -0,0 +1,150 @@
+package auth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+	"github.com/weaveworks/weave-gitops/pkg/server/auth"
+	authv1 "k8s.io/api/authentication/v1"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlclientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// tokenReviewClient answers TokenReviews from a map of tokens to users.
+type tokenReviewClient struct {
+	ctrlclient.Client
+	users   map[string]authv1.UserInfo
+	reviews int
+}
+
+func (c *tokenReviewClient) Create(ctx context.Context, obj ctrlclient.Object, opts ...ctrlclient.CreateOption) error {
+	tr, ok := obj.(*authv1.TokenReview)
+	if !ok {
+		return c.Client.Create(ctx, obj, opts...)
+	}
+
+	c.reviews++
+
+	if user, ok := c.users[tr.Spec.Token]; ok {
+		tr.Status = authv1.TokenReviewStatus{Authenticated: true, User: user}
+	} else {
+		tr.Status = authv1.TokenReviewStatus{Error: "invalid token"}
+	}
+
+	return nil
+}
+
+func TestTokenReviewPrincipalGetter(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	client := &tokenReviewClient{
+		Client: ctrlclientfake.NewClientBuilder().Build(),
+		users: map[string]authv1.UserInfo{
+			"sa-token": {
+				Username: "system:serviceaccount:flux-system:automation",
+				Groups:   []string{"system:serviceaccounts", "system:serviceaccounts:flux-system"},
+			},
+		},
+	}
+
+	getter := auth.NewTokenReviewPrincipalGetter(logr.Discard(), client, time.Minute)
+
+	request := func(header string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "https://example.com/v1/objects", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+
+		return req
+	}
+
+	principal, err := getter.Principal(request("Bearer sa-token"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(principal).To(Equal(&auth.UserPrincipal{
+		ID:     "system:serviceaccount:flux-system:automation",
+		Groups: []string{"system:serviceaccounts", "system:serviceaccounts:flux-system"},
+	}))
+
+	// The result is cached.
+	principal.Groups = nil
+	principal, err = getter.Principal(request("Bearer sa-token"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(principal.Groups).To(HaveLen(2))
+	g.Expect(client.reviews).To(Equal(1))
+
+	// Tokens the cluster doesn't know are left to other methods too.
+	principal, err = getter.Principal(request("Bearer oidc-token"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(principal).To(BeNil())
+
+	principal, err = getter.Principal(request("Bearer oidc-token"))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(principal).To(BeNil())
+	g.Expect(client.reviews).To(Equal(2))
+
+	// Requests without a token, or with an API token, are left to other
+	// methods.
+	for _, header := range []string{"", "Bearer wgt_0123_secret"} {
+		principal, err = getter.Principal(request(header))
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(principal).To(BeNil())
+	}
+
+	g.Expect(client.reviews).To(Equal(2))
+}
+
+func TestWithAPIAuthAcceptsReviewedTokens(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	client := &tokenReviewClient{
+		Client: ctrlclientfake.NewClientBuilder().Build(),
+		users: map[string]authv1.UserInfo{
+			"sa-token": {Username: "system:serviceaccount:flux-system:automation"},
+		},
+	}
+
+	tokenSignerVerifier, err := auth.NewHMACTokenSignerVerifier(5 * time.Minute)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	s, m := makeAuthServer(t, client, tokenSignerVerifier, []auth.AuthMethod{auth.TokenReview, auth.OIDC})
+
+	var principal *auth.UserPrincipal
+
+	handler := auth.WithAPIAuth(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		principal = auth.Principal(r.Context())
+	}), s, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/v1/objects", nil)
+	req.Header.Set("Authorization", "Bearer sa-token")
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	g.Expect(w).To(HaveHTTPStatus(http.StatusOK))
+	g.Expect(principal.ID).To(Equal("system:serviceaccount:flux-system:automation"))
+	g.Expect(principal.Token()).To(BeEmpty(), "the user is impersonated")
+
+	req = httptest.NewRequest(http.MethodGet, "https://example.com/v1/objects", nil)
+	req.Header.Set("Authorization", "Bearer other-token")
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	g.Expect(w).To(HaveHTTPStatus(http.StatusUnauthorized))
+
+	// OIDC ID tokens are still accepted after the cluster rejected them.
+	idToken := findCookie(oidcSignIn(t, s, m), auth.IDTokenCookieName)
+	g.Expect(idToken).NotTo(BeNil())
+
+	req = httptest.NewRequest(http.MethodGet, "https://example.com/v1/objects", nil)
+	req.Header.Set("Authorization", "Bearer "+idToken.Value)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	g.Expect(w).To(HaveHTTPStatus(http.StatusOK))
+	g.Expect(principal.ID).To(Equal("jane.doe@example.com"))
+	g.Expect(client.reviews).To(Equal(3))
+}
@@ -32,7 +32,59 @@ func NewHandlers(ctx context.Context, log logr.Logger, cfg *Config) (http.Handle
 		return nil, fmt.Errorf("could not start up core servers: %w", err)
 	}
 
+	if err := registerAPITokenRoutes(mux, cfg.AuthServer); err != nil {
+		return nil, fmt.Errorf("could not register API token routes: %w", err)
+	}
+
+	if err := registerSessionRoutes(mux, cfg.AuthServer); err != nil {
+		return nil, fmt.Errorf("could not register session routes: %w", err)
+	}
+
 	httpHandler := auth.WithAPIAuth(mux, cfg.AuthServer, PublicRoutes)
 
 	return httpHandler, nil
 }
+
+// registerAPITokenRoutes adds the routes to manage the API tokens of the
+// signed in user, they are authenticated like the other API routes.
+func registerAPITokenRoutes(mux *runtime.ServeMux, srv *auth.AuthServer) error {
+	list := func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		srv.APITokens().ServeHTTP(w, r)
+	}
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		if err := mux.HandlePath(method, "/v1/api_tokens", list); err != nil {
+			return err
+		}
+	}
+
+	return mux.HandlePath(http.MethodDelete, "/v1/api_tokens/{id}", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		srv.RevokeAPIToken(params["id"]).ServeHTTP(w, r)
+	})
+}
+
+// registerSessionRoutes adds the routes to list and revoke the sessions of
+// the signed in user, and for admins those of other users.
+func registerSessionRoutes(mux *runtime.ServeMux, srv *auth.AuthServer) error {
+	own := func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		srv.Sessions().ServeHTTP(w, r)
+	}
+
+	user := func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		srv.UserSessions(params["user"]).ServeHTTP(w, r)
+	}
+
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		if err := mux.HandlePath(method, "/v1/sessions", own); err != nil {
+			return err
+		}
+
+		if err := mux.HandlePath(method, "/v1/users/{user}/sessions", user); err != nil {
+			return err
+		}
+	}
+
+	return mux.HandlePath(http.MethodDelete, "/v1/sessions/{id}", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		srv.RevokeSession(params["id"]).ServeHTTP(w, r)
+	})
+}
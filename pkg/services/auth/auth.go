@@ -45,9 +45,11 @@ func (sn SecretName) NamespacedName() types.NamespacedName {
 }
 
 type AuthService interface {
-	CreateGitClient(ctx context.Context, repoUrl gitproviders.RepoURL, namespace string, dryRun bool) (git.Git, error)
+	CreateGitClient(ctx context.Context, repoUrl gitproviders.RepoURL, namespace string, dryRun bool, opts ...git.Option) (git.Git, error)
 	GetGitProvider() gitproviders.GitProvider
 	SetupDeployKey(ctx context.Context, namespace string, repo gitproviders.RepoURL) (*ssh.PublicKeys, error)
+	// RotateDeployKey replaces the deploy key of a GitRepository.
+	RotateDeployKey(ctx context.Context, name types.NamespacedName) (*DeployKeyRotation, error)
 }
 
 type authSvc struct {
@@ -76,10 +78,15 @@ func (a *authSvc) GetGitProvider() gitproviders.GitProvider {
 
 // CreateGitClient creates a git.Git client instrumented with existing or generated deploy keys.
 // This ensures that git operations are done with stored deploy keys instead of a user's local ssh-agent or equivalent.
-func (a *authSvc) CreateGitClient(ctx context.Context, repoUrl gitproviders.RepoURL, namespace string, dryRun bool) (git.Git, error) {
+func (a *authSvc) CreateGitClient(ctx context.Context, repoUrl gitproviders.RepoURL, namespace string, dryRun bool, opts ...git.Option) (git.Git, error) {
 	if dryRun {
 		d, _ := makePublicKey([]byte(""))
-		return git.New(d, wrapper.NewGoGit()), nil
+		return git.New(d, wrapper.NewGoGit(), opts...), nil
+	}
+
+	if repoUrl.Provider() == gitproviders.GitProviderLocal {
+		// Local repositories are cloned without keys.
+		return git.New(nil, wrapper.NewGoGit(), opts...), nil
 	}
 
 	pubKey, keyErr := a.SetupDeployKey(ctx, namespace, repoUrl)
@@ -90,11 +97,11 @@ func (a *authSvc) CreateGitClient(ctx context.Context, repoUrl gitproviders.Repo
 	if pubKey == nil {
 		// Don't return git.New(pubkey, wrapper.NewGoGit()), nil here. It will fail
 		// "nil" of type *ssh.PublicKeys does not behave correctly
-		return git.New(nil, wrapper.NewGoGit()), nil
+		return git.New(nil, wrapper.NewGoGit(), opts...), nil
 	}
 
 	// Set the git client to use the existing deploy key.
-	return git.New(pubKey, wrapper.NewGoGit()), nil
+	return git.New(pubKey, wrapper.NewGoGit(), opts...), nil
 }
 
 // SetupDeployKey creates a git.Git client instrumented with existing or generated deploy keys.
@@ -10,10 +10,11 @@ import (
 	"github.com/fluxcd/go-git-providers/gitprovider"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
 	"github.com/weaveworks/weave-gitops/pkg/flux"
 	"github.com/weaveworks/weave-gitops/pkg/gitproviders"
 	"github.com/weaveworks/weave-gitops/pkg/gitproviders/gitprovidersfakes"
-	"github.com/weaveworks/weave-gitops/pkg/runner"
+	"github.com/weaveworks/weave-gitops/pkg/testutils"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/rand"
 )
@@ -30,10 +31,15 @@ var _ = Describe("auth", func() {
 	)
 
 	BeforeEach(func() {
-		repoUrlString := "ssh://git@github.com/my-org/my-repo.git"
+		// The host key of the repository's host is scanned for the deploy key.
+		host, _, err := testutils.StartSSHHost()
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(host.Close)
+
+		viper.Set("git-host-types", "127.0.0.1=github")
+		DeferCleanup(viper.Set, "git-host-types", "")
 
-		var err error
-		configRepoUrl, err = gitproviders.NewRepoURL(repoUrlString)
+		configRepoUrl, err = gitproviders.NewRepoURL("ssh://git@" + host.Addr().String() + "/my-org/my-repo.git")
 		Expect(err).NotTo(HaveOccurred())
 
 		namespace = &corev1.Namespace{}
@@ -45,7 +51,7 @@ var _ = Describe("auth", func() {
 		Expect(err).NotTo(HaveOccurred())
 		gp = gitprovidersfakes.FakeGitProvider{}
 		gp.GetRepoVisibilityReturns(gitprovider.RepositoryVisibilityVar(gitprovider.RepositoryVisibilityPrivate), nil)
-		fluxClient = flux.New(&actualFluxRunner{Runner: &runner.CLIRunner{}})
+		fluxClient = flux.New()
 
 		as = auth.NewAuthService(fluxClient, k8sClient, &gp, logr.Discard())
 	})
@@ -109,13 +115,3 @@ var _ = Describe("auth", func() {
 		Expect(gp.UploadDeployKeyCallCount()).To(Equal(1))
 	})
 })
-
-type actualFluxRunner struct {
-	runner.Runner
-}
-
-func (r *actualFluxRunner) Run(command string, args ...string) ([]byte, error) {
-	cmd := "flux"
-
-	return r.Runner.Run(cmd, args...)
-}
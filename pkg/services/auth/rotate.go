@@ -0,0 +1,278 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/fluxcd/go-git-providers/gitprovider"
+	"github.com/fluxcd/pkg/apis/meta"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta2"
+	"github.com/weaveworks/weave-gitops/pkg/gitproviders"
+	"github.com/weaveworks/weave-gitops/pkg/names"
+	"golang.org/x/crypto/ssh"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DeployKeyRotatedAtAnnotation records when the deploy key of a secret
+	// was last rotated.
+	DeployKeyRotatedAtAnnotation = "weave.works/deploy-key-rotated-at"
+	// DeployKeyFingerprintAnnotation records the SHA256 fingerprint of the
+	// deploy key of a secret.
+	DeployKeyFingerprintAnnotation = "weave.works/deploy-key-fingerprint"
+
+	reconcilePollInterval = time.Second
+)
+
+// deployKeyParts are the keys of a git secret that change with its deploy key.
+var deployKeyParts = []string{"identity", "identity.pub", "known_hosts"}
+
+// DeployKeyRotation records the rotation of the deploy key of a
+// GitRepository, for audits.
+type DeployKeyRotation struct {
+	GitRepository  string `json:"gitRepository"`
+	RepoURL        string `json:"repoUrl"`
+	Secret         string `json:"secret"`
+	OldFingerprint string `json:"oldFingerprint"`
+	NewFingerprint string `json:"newFingerprint"`
+	// Revision is the revision the GitRepository fetched with the new key.
+	Revision string `json:"revision"`
+	// OldKeyDeleted is false if the old key wasn't found on the git provider.
+	OldKeyDeleted bool      `json:"oldKeyDeleted"`
+	RotatedAt     time.Time `json:"rotatedAt"`
+}
+
+// RotateDeployKey replaces the deploy key of a GitRepository. The new key is
+// uploaded and stored in the GitRepository's secret, and the old key is only
+// deleted from the git provider once the GitRepository fetched with the new
+// one. If it fails to, the old key is restored.
+func (a *authSvc) RotateDeployKey(ctx context.Context, name types.NamespacedName) (*DeployKeyRotation, error) {
+	repository := &sourcev1.GitRepository{}
+	if err := a.k8sClient.Get(ctx, name, repository); err != nil {
+		return nil, fmt.Errorf("error getting GitRepository %s: %w", name, err)
+	}
+
+	if repository.Spec.SecretRef == nil {
+		return nil, fmt.Errorf("GitRepository %s has no secret", name)
+	}
+
+	repoUrl, err := gitproviders.NewRepoURL(repository.Spec.URL)
+	if err != nil {
+		return nil, fmt.Errorf("error normalizing URL of GitRepository %s: %w", name, err)
+	}
+
+	// The URL is normalized to SSH, so check the one the GitRepository uses.
+	if u, err := url.Parse(repository.Spec.URL); err != nil || u.Scheme != "ssh" {
+		return nil, fmt.Errorf("GitRepository %s doesn't clone over SSH, it has no deploy key", name)
+	}
+
+	secretName := SecretName{
+		Name:      names.GeneratedSecretName(repository.Spec.SecretRef.Name),
+		Namespace: name.Namespace,
+	}
+
+	secret, err := a.retrieveDeployKey(ctx, secretName)
+	if err != nil {
+		return nil, err
+	}
+
+	oldPublicKey, err := deployPublicKey(secret)
+	if err != nil {
+		return nil, fmt.Errorf("error reading deploy key from secret %s: %w", secretName, err)
+	}
+
+	newSecret, err := a.createKeyPairSecret(secretName, repoUrl)
+	if err != nil {
+		return nil, fmt.Errorf("error generating deploy key: %w", err)
+	}
+
+	newPublicKey := extractPublicKey(newSecret)
+
+	rotation := &DeployKeyRotation{
+		GitRepository:  name.String(),
+		RepoURL:        repoUrl.String(),
+		Secret:         secretName.String(),
+		OldFingerprint: fingerprint(oldPublicKey),
+		NewFingerprint: fingerprint(newPublicKey),
+		RotatedAt:      time.Now().UTC(),
+	}
+
+	if err := a.gitProvider.UploadDeployKey(ctx, repoUrl, newPublicKey); err != nil {
+		return nil, fmt.Errorf("error uploading deploy key: %w", err)
+	}
+
+	a.log.Info("New deploy key uploaded to git provider", "fingerprint", rotation.NewFingerprint)
+
+	original := secret.DeepCopy()
+
+	setDeployKey(secret, newSecret)
+
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+
+	secret.Annotations[DeployKeyRotatedAtAnnotation] = rotation.RotatedAt.Format(time.RFC3339)
+	secret.Annotations[DeployKeyFingerprintAnnotation] = rotation.NewFingerprint
+
+	if err := a.k8sClient.Update(ctx, secret); err != nil {
+		a.rollbackDeployKey(repoUrl, newPublicKey, nil)
+		return nil, fmt.Errorf("error updating secret %s: %w", secretName, err)
+	}
+
+	rotation.Revision, err = a.reconcileGitRepository(ctx, name)
+	if err != nil {
+		a.rollbackDeployKey(repoUrl, newPublicKey, original)
+		return nil, fmt.Errorf("GitRepository %s failed to fetch with the new deploy key, the old one was restored: %w", name, err)
+	}
+
+	a.log.Info("GitRepository fetched with the new deploy key", "revision", rotation.Revision)
+
+	if err := a.gitProvider.DeleteDeployKey(ctx, repoUrl, oldPublicKey); err != nil {
+		if !errors.Is(err, gitprovider.ErrNotFound) {
+			return rotation, fmt.Errorf("error deleting old deploy key: %w", err)
+		}
+
+		a.log.Info("The old deploy key wasn't found on the git provider", "fingerprint", rotation.OldFingerprint)
+	} else {
+		rotation.OldKeyDeleted = true
+	}
+
+	return rotation, nil
+}
+
+// reconcileGitRepository requests a reconciliation of the GitRepository and
+// waits until it's done, returning the fetched revision.
+func (a *authSvc) reconcileGitRepository(ctx context.Context, name types.NamespacedName) (string, error) {
+	requestedAt := time.Now().Format(time.RFC3339Nano)
+
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		repository := &sourcev1.GitRepository{}
+		if err := a.k8sClient.Get(ctx, name, repository); err != nil {
+			return err
+		}
+
+		patch := client.MergeFrom(repository.DeepCopy())
+
+		if repository.Annotations == nil {
+			repository.Annotations = map[string]string{}
+		}
+
+		repository.Annotations[meta.ReconcileRequestAnnotation] = requestedAt
+
+		return a.k8sClient.Patch(ctx, repository, patch)
+	})
+	if err != nil {
+		return "", fmt.Errorf("error requesting reconciliation: %w", err)
+	}
+
+	revision := ""
+
+	err = wait.PollImmediateUntil(reconcilePollInterval, func() (bool, error) {
+		repository := &sourcev1.GitRepository{}
+		if err := a.k8sClient.Get(ctx, name, repository); err != nil {
+			return false, err
+		}
+
+		if repository.Status.GetLastHandledReconcileRequest() != requestedAt {
+			return false, nil
+		}
+
+		ready := apimeta.FindStatusCondition(repository.Status.Conditions, meta.ReadyCondition)
+		if ready == nil || ready.Status == metav1.ConditionUnknown {
+			return false, nil
+		}
+
+		if ready.Status == metav1.ConditionFalse {
+			return false, fmt.Errorf("%s: %s", ready.Reason, ready.Message)
+		}
+
+		if repository.Status.Artifact != nil {
+			revision = repository.Status.Artifact.Revision
+		}
+
+		return true, nil
+	}, ctx.Done())
+	if errors.Is(err, wait.ErrWaitTimeout) {
+		return "", errors.New("timed out waiting for the reconciliation")
+	}
+
+	return revision, err
+}
+
+// rollbackDeployKey deletes the new deploy key from the git provider, and
+// restores the original secret if it was updated.
+func (a *authSvc) rollbackDeployKey(repoUrl gitproviders.RepoURL, newPublicKey []byte, original *corev1.Secret) {
+	// The context may be done already, when the reconciliation timed out.
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if original != nil {
+		err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+			secret := &corev1.Secret{}
+			if err := a.k8sClient.Get(ctx, client.ObjectKeyFromObject(original), secret); err != nil {
+				return err
+			}
+
+			secret.Data = original.Data
+			secret.StringData = original.StringData
+			secret.Annotations = original.Annotations
+
+			return a.k8sClient.Update(ctx, secret)
+		})
+		if err != nil {
+			a.log.Error(err, "Failed to restore the old deploy key", "secret", client.ObjectKeyFromObject(original).String())
+		}
+	}
+
+	if err := a.gitProvider.DeleteDeployKey(ctx, repoUrl, newPublicKey); err != nil {
+		a.log.Error(err, "Failed to delete the new deploy key from the git provider", "fingerprint", fingerprint(newPublicKey))
+	}
+}
+
+// setDeployKey copies the deploy key of a generated secret.
+func setDeployKey(secret, generated *corev1.Secret) {
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+
+	for _, key := range deployKeyParts {
+		delete(secret.StringData, key)
+
+		if value := extractSecretPart(generated, key); len(value) > 0 {
+			secret.Data[key] = value
+		}
+	}
+}
+
+// deployPublicKey returns the public key of a git secret, deriving it from the
+// private key if the secret doesn't have it.
+func deployPublicKey(secret *corev1.Secret) ([]byte, error) {
+	if publicKey := extractPublicKey(secret); len(publicKey) > 0 {
+		return publicKey, nil
+	}
+
+	signer, err := ssh.ParsePrivateKey(extractPrivateKey(secret))
+	if err != nil {
+		return nil, err
+	}
+
+	return ssh.MarshalAuthorizedKey(signer.PublicKey()), nil
+}
+
+func fingerprint(publicKey []byte) string {
+	key, _, _, _, err := ssh.ParseAuthorizedKey(publicKey)
+	if err != nil {
+		return ""
+	}
+
+	return ssh.FingerprintSHA256(key)
+}
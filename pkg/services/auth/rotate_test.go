@@ -0,0 +1,204 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"time"
+
+	"github.com/fluxcd/go-git-providers/gitprovider"
+	"github.com/fluxcd/pkg/apis/meta"
+	sourcev1 "github.com/fluxcd/source-controller/api/v1beta2"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/weaveworks/weave-gitops/pkg/flux/fluxfakes"
+	"github.com/weaveworks/weave-gitops/pkg/gitproviders/gitprovidersfakes"
+	"github.com/weaveworks/weave-gitops/pkg/services/auth"
+	"golang.org/x/crypto/ssh"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	krand "k8s.io/apimachinery/pkg/util/rand"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+var _ = Describe("RotateDeployKey", func() {
+	var (
+		ctx          context.Context
+		cancel       context.CancelFunc
+		namespace    *corev1.Namespace
+		repository   *sourcev1.GitRepository
+		gp           *gitprovidersfakes.FakeGitProvider
+		fakeFlux     *fluxfakes.FakeFlux
+		as           auth.AuthService
+		oldPublicKey []byte
+		newPublicKey []byte
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+
+		namespace = &corev1.Namespace{}
+		namespace.Name = "kube-test-" + krand.String(5)
+		Expect(k8sClient.Create(ctx, namespace)).To(Succeed())
+
+		var oldSecret *corev1.Secret
+		oldSecret, oldPublicKey = gitSecret()
+		oldSecret.Name = "flux-system"
+		oldSecret.Namespace = namespace.Name
+		Expect(k8sClient.Create(ctx, oldSecret)).To(Succeed())
+
+		repository = &sourcev1.GitRepository{
+			Spec: sourcev1.GitRepositorySpec{
+				URL:       "ssh://git@github.com/my-org/my-repo.git",
+				SecretRef: &meta.LocalObjectReference{Name: oldSecret.Name},
+			},
+		}
+		repository.Name = "flux-system"
+		repository.Namespace = namespace.Name
+		Expect(k8sClient.Create(ctx, repository)).To(Succeed())
+
+		var newSecret *corev1.Secret
+		newSecret, newPublicKey = gitSecret()
+		secretYAML, err := yaml.Marshal(newSecret)
+		Expect(err).NotTo(HaveOccurred())
+
+		fakeFlux = &fluxfakes.FakeFlux{}
+		fakeFlux.CreateSecretGitReturns(secretYAML, nil)
+
+		gp = &gitprovidersfakes.FakeGitProvider{}
+
+		as = auth.NewAuthService(fakeFlux, k8sClient, gp, logr.Discard())
+	})
+
+	AfterEach(func() {
+		cancel()
+		Expect(k8sClient.Delete(context.Background(), namespace)).To(Succeed())
+	})
+
+	It("replaces the deploy key once the GitRepository fetched with it", func() {
+		go reconcileRepository(ctx, client.ObjectKeyFromObject(repository), metav1.ConditionTrue)
+
+		rotation, err := as.RotateDeployKey(ctx, client.ObjectKeyFromObject(repository))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rotation.Revision).To(Equal("main/abc123"))
+		Expect(rotation.OldKeyDeleted).To(BeTrue())
+		Expect(rotation.NewFingerprint).NotTo(Equal(rotation.OldFingerprint))
+
+		Expect(gp.UploadDeployKeyCallCount()).To(Equal(1))
+		_, _, uploaded := gp.UploadDeployKeyArgsForCall(0)
+		Expect(uploaded).To(Equal(newPublicKey))
+
+		Expect(gp.DeleteDeployKeyCallCount()).To(Equal(1))
+		_, _, deleted := gp.DeleteDeployKeyArgsForCall(0)
+		Expect(deleted).To(Equal(oldPublicKey))
+
+		secret := &corev1.Secret{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(repository), secret)).To(Succeed())
+		Expect(secret.Data["identity.pub"]).To(Equal(newPublicKey))
+		Expect(secret.Annotations).To(HaveKeyWithValue(auth.DeployKeyFingerprintAnnotation, rotation.NewFingerprint))
+		Expect(secret.Annotations).To(HaveKey(auth.DeployKeyRotatedAtAnnotation))
+	})
+
+	It("doesn't fail when the old deploy key is gone", func() {
+		go reconcileRepository(ctx, client.ObjectKeyFromObject(repository), metav1.ConditionTrue)
+
+		gp.DeleteDeployKeyReturns(gitprovider.ErrNotFound)
+
+		rotation, err := as.RotateDeployKey(ctx, client.ObjectKeyFromObject(repository))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rotation.OldKeyDeleted).To(BeFalse())
+	})
+
+	It("restores the old deploy key when the GitRepository fails to fetch", func() {
+		go reconcileRepository(ctx, client.ObjectKeyFromObject(repository), metav1.ConditionFalse)
+
+		_, err := as.RotateDeployKey(ctx, client.ObjectKeyFromObject(repository))
+		Expect(err).To(MatchError(ContainSubstring("the old one was restored")))
+
+		Expect(gp.DeleteDeployKeyCallCount()).To(Equal(1))
+		_, _, deleted := gp.DeleteDeployKeyArgsForCall(0)
+		Expect(deleted).To(Equal(newPublicKey))
+
+		secret := &corev1.Secret{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(repository), secret)).To(Succeed())
+		Expect(secret.StringData["identity.pub"]).To(Equal(string(oldPublicKey)))
+		Expect(secret.Annotations).NotTo(HaveKey(auth.DeployKeyFingerprintAnnotation))
+	})
+
+	It("fails for GitRepositories cloning over HTTPS", func() {
+		repository.Spec.URL = "https://github.com/my-org/my-repo.git"
+		Expect(k8sClient.Update(ctx, repository)).To(Succeed())
+
+		_, err := as.RotateDeployKey(ctx, client.ObjectKeyFromObject(repository))
+		Expect(err).To(MatchError(ContainSubstring("doesn't clone over SSH")))
+		Expect(gp.UploadDeployKeyCallCount()).To(Equal(0))
+	})
+})
+
+// gitSecret returns a git secret with a new deploy key, like the ones
+// generated by flux, and its public key.
+func gitSecret() (*corev1.Secret, []byte) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+
+	der, err := x509.MarshalECPrivateKey(privateKey)
+	Expect(err).NotTo(HaveOccurred())
+
+	publicKey, err := ssh.NewPublicKey(&privateKey.PublicKey)
+	Expect(err).NotTo(HaveOccurred())
+
+	authorizedKey := ssh.MarshalAuthorizedKey(publicKey)
+
+	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		StringData: map[string]string{
+			"identity":     string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})),
+			"identity.pub": string(authorizedKey),
+			"known_hosts":  "github.com ecdsa-sha2-nistp256 AAAA",
+		},
+	}
+
+	return secret, authorizedKey
+}
+
+// reconcileRepository handles the reconciliation requests of a GitRepository
+// like source-controller would, setting its Ready condition to ready.
+func reconcileRepository(ctx context.Context, name types.NamespacedName, ready metav1.ConditionStatus) {
+	defer GinkgoRecover()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		repository := &sourcev1.GitRepository{}
+		if err := k8sClient.Get(ctx, name, repository); err != nil {
+			continue
+		}
+
+		requestedAt, ok := meta.ReconcileAnnotationValue(repository.Annotations)
+		if !ok || requestedAt == repository.Status.GetLastHandledReconcileRequest() {
+			continue
+		}
+
+		repository.Status.SetLastHandledReconcileRequest(requestedAt)
+		repository.Status.Artifact = &sourcev1.Artifact{Revision: "main/abc123"}
+		apimeta.SetStatusCondition(&repository.Status.Conditions, metav1.Condition{
+			Type:    meta.ReadyCondition,
+			Status:  ready,
+			Reason:  "Reconciled",
+			Message: "reconciled",
+		})
+
+		_ = k8sClient.Status().Update(ctx, repository)
+	}
+}
@@ -26,6 +26,8 @@ type GitConfigParams struct {
 	Namespace        string
 	IsHelmRepository bool
 	DryRun           bool
+	// SigningKey signs the commits of the git client, if set.
+	SigningKey *git.SigningKey
 }
 
 type defaultFactory struct {
@@ -51,7 +53,12 @@ func (f *defaultFactory) GetGitClients(ctx context.Context, kubeClient *kube.Kub
 		return nil, nil, fmt.Errorf("error getting auth service: %w", err)
 	}
 
-	client, err := authSvc.CreateGitClient(ctx, configNormalizedUrl, params.Namespace, params.DryRun)
+	opts := []git.Option{}
+	if params.SigningKey != nil {
+		opts = append(opts, git.WithSigningKey(params.SigningKey))
+	}
+
+	client, err := authSvc.CreateGitClient(ctx, configNormalizedUrl, params.Namespace, params.DryRun, opts...)
 	if err != nil {
 		return nil, nil, err
 	}
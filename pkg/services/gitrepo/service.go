@@ -36,14 +36,14 @@ func CommitAndPush(ctx context.Context, client git.Git, commitMsg string, logger
 
 // CloneRepo uses the git client to clone the reop from the URL and branch.  It clones into a temp
 // directory and returns a function to use by the caller for cleanup.  The temp directory is
-// also returned.
-func CloneRepo(ctx context.Context, client git.Git, url gitproviders.RepoURL, branch string) (func(), string, error) {
+// also returned. The options limit what's cloned, see git.CloneOption.
+func CloneRepo(ctx context.Context, client git.Git, url gitproviders.RepoURL, branch string, opts ...git.CloneOption) (func(), string, error) {
 	repoDir, err := os.MkdirTemp("", "user-repo-")
 	if err != nil {
 		return nil, "", fmt.Errorf("failed creating temp. directory to clone repo: %w", err)
 	}
 
-	_, err = client.Clone(ctx, repoDir, url.String(), branch)
+	_, err = client.Clone(ctx, repoDir, url.String(), branch, opts...)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed cloning user repo: %s: %w", url, err)
 	}
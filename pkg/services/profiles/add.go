@@ -64,7 +64,7 @@ func (s *ProfilesSvc) Add(ctx context.Context, r ProfilesRetriever, gitProvider
 	}
 
 	path := git.GetProfilesPath(opts.Cluster, ManifestFileName)
-	pr, err := gitProvider.CreatePullRequest(ctx, configRepoURL, prInfo(opts, "add", defaultBranch, gitprovider.CommitFile{
+	pr, err := s.createPullRequest(ctx, gitProvider, opts.GitClient, configRepoURL, prInfo(opts, "add", defaultBranch, gitprovider.CommitFile{
 		Path:    &path,
 		Content: &content,
 	}))
@@ -112,9 +112,9 @@ func prInfo(opts Options, action, defaultBranch string, commitFile gitprovider.C
 		headBranch = opts.HeadBranch
 	}
 
-	newBranch := uuid.New().String()
+	newBranch := gitproviders.PullRequestBranchPrefix + uuid.New().String()
 	if opts.BaseBranch != "" {
-		newBranch = opts.BaseBranch
+		newBranch = gitproviders.PullRequestBranch(opts.BaseBranch)
 	}
 
 	return gitproviders.PullRequestInfo{
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/weaveworks/weave-gitops/pkg/git"
+	"github.com/weaveworks/weave-gitops/pkg/git/gitfakes"
 	"github.com/weaveworks/weave-gitops/pkg/gitproviders/gitprovidersfakes"
 	"github.com/weaveworks/weave-gitops/pkg/helm"
 	"github.com/weaveworks/weave-gitops/pkg/logger/loggerfakes"
@@ -105,12 +106,42 @@ var _ = Describe("Add", func() {
 						Expect(prInfo.Description).To(Equal("so cool"))
 						Expect(prInfo.CommitMessage).To(Equal("sup"))
 						Expect(prInfo.TargetBranch).To(Equal("foo"))
-						Expect(prInfo.NewBranch).To(Equal("bar"))
+						Expect(prInfo.NewBranch).To(Equal("weave-gitops-bar"))
 						Expect(prInfo.Files).To(HaveLen(1))
 						Expect(*prInfo.Files[0].Path).To(Equal(".weave-gitops/clusters/prod/system/profiles.yaml"))
 					})
 				})
 
+				When("a git client is set", func() {
+					It("pushes the commit with it before opening the PR", func() {
+						gitClient := &gitfakes.FakeGit{}
+						gitClient.CommitReturns("sha", nil)
+						addOptions.GitClient = gitClient
+
+						fakePR.GetReturns(gitprovider.PullRequestInfo{
+							WebURL: "url",
+						})
+						gitProviders.CreatePullRequestReturns(fakePR, nil)
+
+						Expect(profilesSvc.Add(context.TODO(), client, gitProviders, addOptions)).Should(Succeed())
+						Expect(gitClient.CloneCallCount()).To(Equal(1))
+						_, _, url, branch, cloneOpts := gitClient.CloneArgsForCall(0)
+						Expect(url).To(Equal("ssh://git@github.com/owner/config-repo.git"))
+						Expect(branch).To(Equal("main"))
+						Expect(cloneOpts).To(HaveLen(3))
+
+						Expect(gitClient.WriteCallCount()).To(Equal(1))
+						path, _ := gitClient.WriteArgsForCall(0)
+						Expect(path).To(Equal(".weave-gitops/clusters/prod/system/profiles.yaml"))
+						Expect(gitClient.CommitCallCount()).To(Equal(1))
+						Expect(gitClient.PushCallCount()).To(Equal(1))
+
+						_, _, prInfo := gitProviders.CreatePullRequestArgsForCall(0)
+						Expect(gitClient.CheckoutArgsForCall(0)).To(Equal(prInfo.NewBranch))
+						Expect(prInfo.SkipAddingFilesOnCreation).To(BeTrue())
+					})
+				})
+
 				When("auto-merge is enabled", func() {
 					It("merges the PR that was created", func() {
 						fakePR.GetReturns(gitprovider.PullRequestInfo{
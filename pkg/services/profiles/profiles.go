@@ -3,10 +3,13 @@ package profiles
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 
 	"github.com/fluxcd/go-git-providers/gitprovider"
+	"github.com/weaveworks/weave-gitops/pkg/git"
 	"github.com/weaveworks/weave-gitops/pkg/gitproviders"
 	"github.com/weaveworks/weave-gitops/pkg/logger"
+	"github.com/weaveworks/weave-gitops/pkg/services/gitrepo"
 
 	"k8s.io/apimachinery/pkg/types"
 )
@@ -40,6 +43,9 @@ type Options struct {
 	Title        string
 	Description  string
 	Endpoint     string
+	// GitClient commits the changes, e.g. to sign them, instead of the git
+	// provider's API if it's set.
+	GitClient git.Git
 }
 
 type ProfilesSvc struct {
@@ -77,3 +83,51 @@ func getGitCommitFileContent(files []*gitprovider.CommitFile, filePath string) s
 
 	return ""
 }
+
+// createPullRequest creates a pull request with the changes. When there's a
+// git client, the changes are committed and pushed with it first, so the
+// pull request is created from the pushed branch.
+func (s *ProfilesSvc) createPullRequest(ctx context.Context, gitProvider gitproviders.GitProvider, gitClient git.Git, repoURL gitproviders.RepoURL, info gitproviders.PullRequestInfo) (gitprovider.PullRequest, error) {
+	if gitClient == nil {
+		return gitProvider.CreatePullRequest(ctx, repoURL, info)
+	}
+
+	dirs := []string{}
+	for _, f := range info.Files {
+		dirs = append(dirs, filepath.Dir(*f.Path))
+	}
+
+	// The files are written with the git client, so the clone never needs
+	// to touch the disk.
+	remover, _, err := gitrepo.CloneRepo(ctx, gitClient, repoURL, info.TargetBranch,
+		git.WithDepth(1), git.WithInMemory(), git.WithSparsePaths(dirs...))
+	if err != nil {
+		return nil, err
+	}
+
+	defer remover()
+
+	if err := gitClient.Checkout(info.NewBranch); err != nil {
+		return nil, fmt.Errorf("failed to create new branch %s: %w", info.NewBranch, err)
+	}
+
+	for _, f := range info.Files {
+		if f.Content == nil {
+			err = gitClient.Remove(*f.Path)
+		} else {
+			err = gitClient.Write(*f.Path, []byte(*f.Content))
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", *f.Path, err)
+		}
+	}
+
+	if err := gitrepo.CommitAndPush(ctx, gitClient, info.CommitMessage, s.Logger); err != nil {
+		return nil, err
+	}
+
+	info.SkipAddingFilesOnCreation = true
+
+	return gitProvider.CreatePullRequest(ctx, repoURL, info)
+}
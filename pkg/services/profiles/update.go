@@ -58,7 +58,7 @@ func (s *ProfilesSvc) Update(ctx context.Context, r ProfilesRetriever, gitProvid
 
 	path := git.GetProfilesPath(opts.Cluster, ManifestFileName)
 
-	pr, err := gitProvider.CreatePullRequest(ctx, configRepoURL, prInfo(opts, "update", defaultBranch, gitprovider.CommitFile{
+	pr, err := s.createPullRequest(ctx, gitProvider, opts.GitClient, configRepoURL, prInfo(opts, "update", defaultBranch, gitprovider.CommitFile{
 		Path:    &path,
 		Content: &content,
 	}))
@@ -121,7 +121,7 @@ var _ = Describe("Update Profile(s)", func() {
 								Expect(prInfo.Description).To(Equal("so cool"))
 								Expect(prInfo.CommitMessage).To(Equal("sup"))
 								Expect(prInfo.TargetBranch).To(Equal("foo"))
-								Expect(prInfo.NewBranch).To(Equal("bar"))
+								Expect(prInfo.NewBranch).To(Equal("weave-gitops-bar"))
 								Expect(prInfo.Files).To(HaveLen(1))
 								Expect(*prInfo.Files[0].Path).To(Equal(".weave-gitops/clusters/prod/system/profiles.yaml"))
 							})
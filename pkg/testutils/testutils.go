@@ -2,11 +2,13 @@ package testutils
 
 import (
 	"context"
+	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/rsa"
 	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -17,6 +19,7 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/weaveworks/weave-gitops/pkg/kube"
 	"github.com/weaveworks/weave-gitops/pkg/vendorfakes/fakelogr"
+	"golang.org/x/crypto/ssh"
 	"gopkg.in/square/go-jose.v2"
 	"gopkg.in/square/go-jose.v2/jwt"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -228,6 +231,46 @@ func MakeKeysetServer(t *testing.T, key *rsa.PrivateKey) *httptest.Server {
 	return ts
 }
 
+// StartSSHHost starts an SSH server that only serves the handshake, for its
+// host key to be scanned. Close the listener to stop it.
+func StartSSHHost() (net.Listener, ssh.PublicKey, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				// Scans disconnect once they have the host key.
+				_, _, _, _ = ssh.NewServerConn(conn, config)
+			}()
+		}
+	}()
+
+	return listener, signer.PublicKey(), nil
+}
+
 // DeleteAllOf loops through all namespaces and deletes all resources from the given type
 func DeleteAllOf(g *gomega.GomegaWithT, obj client.Object) {
 	ctx := context.Background()
@@ -97,16 +97,27 @@ func upgrade(ctx context.Context, uv UpgradeValues, kubeClient *kube.KubeHTTP, g
 		}
 	}
 
-	remover, repoDir, err := gitrepo.CloneRepo(ctx, gitClient, normalizedURL, configBranch)
+	// Only the cluster path is changed, so the history and the other files
+	// aren't needed.
+	cloneOpts := []git.CloneOption{git.WithDepth(1)}
+	if uv.ClusterPath != "" && !filepath.IsAbs(uv.ClusterPath) {
+		cloneOpts = append(cloneOpts, git.WithSparsePaths(uv.ClusterPath))
+	}
+
+	remover, repoDir, err := gitrepo.CloneRepo(ctx, gitClient, normalizedURL, configBranch, cloneOpts...)
 	if err != nil {
 		return fmt.Errorf("failed to clone configuration repo: %w", err)
 	}
 
 	defer remover()
 
-	err = gitClient.Checkout(uv.HeadBranch)
+	// The prefix lets the pull request be listed with the other ones created
+	// by Weave GitOps.
+	headBranch := gitproviders.PullRequestBranch(uv.HeadBranch)
+
+	err = gitClient.Checkout(headBranch)
 	if err != nil {
-		return fmt.Errorf("failed to create new branch %s: %w", uv.HeadBranch, err)
+		return fmt.Errorf("failed to create new branch %s: %w", headBranch, err)
 	}
 
 	err = upgradeGitManifests(gitClient, repoDir, uv.ClusterPath, cname, stringOut, logger)
@@ -124,7 +135,7 @@ func upgrade(ctx context.Context, uv UpgradeValues, kubeClient *kube.KubeHTTP, g
 		Description:               "Pull request to upgrade to Weave GitOps Enterprise",
 		SkipAddingFilesOnCreation: true,
 		TargetBranch:              configBranch,
-		NewBranch:                 uv.HeadBranch,
+		NewBranch:                 headBranch,
 	}
 
 	pr, err := gitProvider.CreatePullRequest(ctx, normalizedURL, pri)
@@ -7,6 +7,7 @@ export enum AuthRoutes {
   USER_INFO = "/oauth2/userinfo",
   SIGN_IN = "/oauth2/sign_in",
   LOG_OUT = "/oauth2/logout",
+  PROVIDERS = "/oauth2/providers",
   AUTH_PATH_SIGNIN = "/sign_in",
 }
 
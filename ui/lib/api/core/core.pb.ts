@@ -6,6 +6,15 @@
 
 import * as fm from "../../fetch.pb"
 import * as Gitops_coreV1Types from "./types.pb"
+
+type Absent<T, K extends keyof T> = { [k in Exclude<keyof T, K>]?: undefined };
+type OneOf<T> =
+  | { [k in keyof T]?: undefined }
+  | (
+    keyof T extends infer K ?
+      (K extends string & keyof T ? { [k in K]: T[K] } & Absent<T, K>
+        : never)
+    : never);
 export type Pagination = {
   pageSize?: number
   pageToken?: string
@@ -20,6 +29,7 @@ export type ListError = {
 export type ListKustomizationsRequest = {
   namespace?: string
   pagination?: Pagination
+  tenant?: string
 }
 
 export type ListKustomizationsResponse = {
@@ -30,6 +40,7 @@ export type ListKustomizationsResponse = {
 
 export type ListHelmReleasesRequest = {
   namespace?: string
+  tenant?: string
 }
 
 export type ListHelmReleasesResponse = {
@@ -49,6 +60,7 @@ export type GetHelmReleaseResponse = {
 
 export type ListGitRepositoriesRequest = {
   namespace?: string
+  tenant?: string
 }
 
 export type ListGitRepositoriesResponse = {
@@ -58,6 +70,7 @@ export type ListGitRepositoriesResponse = {
 
 export type ListHelmRepositoriesRequest = {
   namespace?: string
+  tenant?: string
 }
 
 export type ListHelmRepositoriesResponse = {
@@ -67,6 +80,7 @@ export type ListHelmRepositoriesResponse = {
 
 export type ListBucketRequest = {
   namespace?: string
+  tenant?: string
 }
 
 export type ListBucketsResponse = {
@@ -76,6 +90,7 @@ export type ListBucketsResponse = {
 
 export type ListOCIRepositoriesRequest = {
   namespace?: string
+  tenant?: string
 }
 
 export type ListOCIRepositoriesResponse = {
@@ -86,6 +101,7 @@ export type ListOCIRepositoriesResponse = {
 export type ListFluxRuntimeObjectsRequest = {
   namespace?: string
   clusterName?: string
+  tenant?: string
 }
 
 export type ListFluxRuntimeObjectsResponse = {
@@ -104,6 +120,7 @@ export type ListFluxCrdsResponse = {
 
 export type ListHelmChartsRequest = {
   namespace?: string
+  tenant?: string
 }
 
 export type ListHelmChartsResponse = {
@@ -115,6 +132,7 @@ export type GetKustomizationRequest = {
   name?: string
   namespace?: string
   clusterName?: string
+  withCommitInfo?: boolean
 }
 
 export type GetKustomizationResponse = {
@@ -163,6 +181,7 @@ export type GetFluxNamespaceResponse = {
 }
 
 export type ListNamespacesRequest = {
+  tenant?: string
 }
 
 export type ListNamespacesResponse = {
@@ -171,6 +190,7 @@ export type ListNamespacesResponse = {
 
 export type ListEventsRequest = {
   involvedObject?: Gitops_coreV1Types.ObjectRef
+  tenant?: string
 }
 
 export type ListEventsResponse = {
@@ -218,6 +238,92 @@ export type ToggleSuspendResourceRequest = {
 export type ToggleSuspendResourceResponse = {
 }
 
+export type GetUserPermissionsRequest = {
+  objects?: Gitops_coreV1Types.ClusterObjectRef[]
+}
+
+export type ObjectPermissions = {
+  object?: Gitops_coreV1Types.ClusterObjectRef
+  canPatch?: boolean
+  canReadEvents?: boolean
+  canReadPods?: boolean
+  canReadSecrets?: boolean
+  error?: string
+}
+
+export type GetUserPermissionsResponse = {
+  principalId?: string
+  principalGroups?: string[]
+  permissions?: ObjectPermissions[]
+}
+
+export type ListTenantsRequest = {
+}
+
+export type ListTenantsResponse = {
+  tenants?: Gitops_coreV1Types.Tenant[]
+  errors?: ListError[]
+}
+
+export type GetTenantRequest = {
+  name?: string
+}
+
+export type GetTenantResponse = {
+  tenant?: Gitops_coreV1Types.Tenant
+  errors?: ListError[]
+}
+
+export type ListPullRequestsRequest = {
+  repoUrl?: string
+  state?: string
+  branchPrefix?: string
+  label?: string
+}
+
+export type ListPullRequestsResponse = {
+  pullRequests?: Gitops_coreV1Types.PullRequest[]
+  errors?: ListError[]
+}
+
+export type GetPullRequestRequest = {
+  repoUrl?: string
+  number?: number
+}
+
+export type GetPullRequestResponse = {
+  pullRequest?: Gitops_coreV1Types.PullRequest
+}
+
+
+type BaseProposeChangeRequest = {
+  kind?: Gitops_coreV1Types.FluxObjectKind
+  name?: string
+  namespace?: string
+  clusterName?: string
+  title?: string
+  description?: string
+}
+
+export type ProposeChangeRequest = BaseProposeChangeRequest
+  & OneOf<{ suspend: boolean; interval: string; chartVersion: string; sourceRef: Gitops_coreV1Types.ObjectRef }>
+
+export type ProposeChangeResponse = {
+  pullRequest?: Gitops_coreV1Types.PullRequest
+  path?: string
+}
+
+export type GetCommitInfoRequest = {
+  gitRepositoryName?: string
+  namespace?: string
+  clusterName?: string
+  revision?: string
+}
+
+export type GetCommitInfoResponse = {
+  commit?: Gitops_coreV1Types.CommitInfo
+}
+
 export class Core {
   static ListKustomizations(req: ListKustomizationsRequest, initReq?: fm.InitReq): Promise<ListKustomizationsResponse> {
     return fm.fetchReq<ListKustomizationsRequest, ListKustomizationsResponse>(`/v1/kustomizations?${fm.renderURLSearchParams(req, [])}`, {...initReq, method: "GET"})
@@ -282,4 +388,25 @@ export class Core {
   static ToggleSuspendResource(req: ToggleSuspendResourceRequest, initReq?: fm.InitReq): Promise<ToggleSuspendResourceResponse> {
     return fm.fetchReq<ToggleSuspendResourceRequest, ToggleSuspendResourceResponse>(`/v1/suspend`, {...initReq, method: "POST", body: JSON.stringify(req)})
   }
+  static ListTenants(req: ListTenantsRequest, initReq?: fm.InitReq): Promise<ListTenantsResponse> {
+    return fm.fetchReq<ListTenantsRequest, ListTenantsResponse>(`/v1/tenants?${fm.renderURLSearchParams(req, [])}`, {...initReq, method: "GET"})
+  }
+  static GetTenant(req: GetTenantRequest, initReq?: fm.InitReq): Promise<GetTenantResponse> {
+    return fm.fetchReq<GetTenantRequest, GetTenantResponse>(`/v1/tenants/${req["name"]}?${fm.renderURLSearchParams(req, ["name"])}`, {...initReq, method: "GET"})
+  }
+  static GetUserPermissions(req: GetUserPermissionsRequest, initReq?: fm.InitReq): Promise<GetUserPermissionsResponse> {
+    return fm.fetchReq<GetUserPermissionsRequest, GetUserPermissionsResponse>(`/v1/user_permissions`, {...initReq, method: "POST", body: JSON.stringify(req)})
+  }
+  static ListPullRequests(req: ListPullRequestsRequest, initReq?: fm.InitReq): Promise<ListPullRequestsResponse> {
+    return fm.fetchReq<ListPullRequestsRequest, ListPullRequestsResponse>(`/v1/pullrequests?${fm.renderURLSearchParams(req, [])}`, {...initReq, method: "GET"})
+  }
+  static GetPullRequest(req: GetPullRequestRequest, initReq?: fm.InitReq): Promise<GetPullRequestResponse> {
+    return fm.fetchReq<GetPullRequestRequest, GetPullRequestResponse>(`/v1/pullrequests/${req["number"]}?${fm.renderURLSearchParams(req, ["number"])}`, {...initReq, method: "GET"})
+  }
+  static ProposeChange(req: ProposeChangeRequest, initReq?: fm.InitReq): Promise<ProposeChangeResponse> {
+    return fm.fetchReq<ProposeChangeRequest, ProposeChangeResponse>(`/v1/pullrequests`, {...initReq, method: "POST", body: JSON.stringify(req)})
+  }
+  static GetCommitInfo(req: GetCommitInfoRequest, initReq?: fm.InitReq): Promise<GetCommitInfoResponse> {
+    return fm.fetchReq<GetCommitInfoRequest, GetCommitInfoResponse>(`/v1/commits?${fm.renderURLSearchParams(req, [])}`, {...initReq, method: "GET"})
+  }
 }
\ No newline at end of file
@@ -44,6 +44,13 @@ export type ObjectRef = {
   namespace?: string
 }
 
+export type ClusterObjectRef = {
+  kind?: string
+  name?: string
+  namespace?: string
+  clusterName?: string
+}
+
 export type Condition = {
   type?: string
   status?: string
@@ -79,6 +86,7 @@ export type Kustomization = {
   clusterName?: string
   apiVersion?: string
   tenant?: string
+  lastAppliedCommit?: CommitInfo
 }
 
 export type HelmChart = {
@@ -229,4 +237,65 @@ export type Event = {
   component?: string
   host?: string
   name?: string
+}
+
+export type TenantNamespaces = {
+  clusterName?: string
+  namespaces?: string[]
+}
+
+export type TenantServiceAccount = {
+  clusterName?: string
+  namespace?: string
+  name?: string
+}
+
+export type TenantObject = {
+  kind?: string
+  name?: string
+  namespace?: string
+  clusterName?: string
+  status?: string
+  suspended?: boolean
+}
+
+export type TenantHealth = {
+  status?: string
+  current?: number
+  inProgress?: number
+  failed?: number
+  unknown?: number
+  suspended?: number
+}
+
+export type Tenant = {
+  name?: string
+  namespaces?: TenantNamespaces[]
+  objects?: TenantObject[]
+  serviceAccounts?: TenantServiceAccount[]
+  health?: TenantHealth
+}
+
+export type PullRequest = {
+  repoUrl?: string
+  number?: number
+  title?: string
+  description?: string
+  url?: string
+  sourceBranch?: string
+  targetBranch?: string
+  labels?: string[]
+  state?: string
+  mergeability?: string
+  ciStatus?: string
+  createdAt?: string
+}
+
+export type CommitInfo = {
+  revision?: string
+  sha?: string
+  message?: string
+  author?: string
+  date?: string
+  url?: string
 }
\ No newline at end of file
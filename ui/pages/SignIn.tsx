@@ -6,7 +6,7 @@ import Alert from "../components/Alert";
 import Button from "../components/Button";
 import Flex from "../components/Flex";
 import LoadingPage from "../components/LoadingPage";
-import { Auth } from "../contexts/AuthContext";
+import { Auth, AuthRoutes } from "../contexts/AuthContext";
 import { useFeatureFlags } from "../hooks/featureflags";
 import images from "../lib/images";
 import { theme } from "../lib/theme";
@@ -59,6 +59,12 @@ const DocsWrapper = styled(Flex)`
   }
 `;
 
+type LoginProvider = {
+  name: string;
+  displayName: string;
+  loginURL: string;
+};
+
 function SignIn() {
   const { data } = useFeatureFlags();
   const flags = data?.flags || {};
@@ -72,10 +78,18 @@ function SignIn() {
   const [password, setPassword] = React.useState<string>("");
   const [username, setUsername] = React.useState<string>("");
   const [showPassword, setShowPassword] = React.useState<boolean>(false);
+  const [providers, setProviders] = React.useState<LoginProvider[]>([]);
+
+  React.useEffect(() => {
+    fetch(AuthRoutes.PROVIDERS)
+      .then((response) => response.json())
+      .then((data) => setProviders(data.providers || []))
+      .catch(() => setProviders([]));
+  }, []);
 
-  const handleOIDCSubmit = () => {
+  const handleOIDCSubmit = (loginURL: string) => {
     const CURRENT_URL = window.origin;
-    return (window.location.href = `/oauth2?return_url=${encodeURIComponent(
+    return (window.location.href = `${loginURL}?return_url=${encodeURIComponent(
       CURRENT_URL
     )}`);
   };
@@ -121,20 +135,20 @@ function SignIn() {
           <Logo wide center>
             <img src={images.weaveLogo} />
           </Logo>
-          {flags.OIDC_AUTH ? (
-            <Flex wide center>
+          {providers.map((p) => (
+            <Flex wide center key={p.loginURL}>
               <Button
                 type="submit"
                 onClick={(e) => {
                   e.preventDefault();
-                  handleOIDCSubmit();
+                  handleOIDCSubmit(p.loginURL);
                 }}
               >
-                LOGIN WITH OIDC PROVIDER
+                LOGIN WITH {p.displayName.toUpperCase()}
               </Button>
             </Flex>
-          ) : null}
-          {flags.OIDC_AUTH && flags.CLUSTER_USER_AUTH ? (
+          ))}
+          {providers.length > 0 && flags.CLUSTER_USER_AUTH ? (
             <Divider variant="middle" style={{ margin: theme.spacing.base }} />
           ) : null}
           {flags.CLUSTER_USER_AUTH ? (